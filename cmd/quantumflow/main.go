@@ -3,37 +3,125 @@ package main
 import (
 "bufio"
 "context"
+"errors"
+"flag"
 "fmt"
+"log/slog"
+"net"
+"net/http"
 "os"
 "os/signal"
+"sort"
+"strconv"
 "strings"
+"sync"
 "syscall"
 "time"
 
+"go.opentelemetry.io/otel"
+"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
 "github.com/quantumflow/quantumflow/internal/agent"
+appconfig "github.com/quantumflow/quantumflow/internal/config"
 "github.com/quantumflow/quantumflow/internal/inference"
+"github.com/quantumflow/quantumflow/internal/memory"
+"github.com/quantumflow/quantumflow/internal/metrics"
 "github.com/quantumflow/quantumflow/internal/models"
 )
 
 const version = "0.1.0-alpha"
 
+// interruptWindow is how soon a second Ctrl-C must follow the first to exit
+const interruptWindow = 2 * time.Second
+
 func main() {
+configPath := flag.String("config", "", "Config file to load (default ~/.quantumflow/config.yaml)")
+noMemory := flag.Bool("no-memory", false, "Disable persistent memory (Redis, Dgraph, BadgerDB)")
+traceRouting := flag.Bool("trace", false, "Show why each query was routed to its agent")
+logFile := flag.String("log-file", "", "Write structured JSON logs to this file (disabled when empty)")
+logLevel := flag.String("log-level", "info", "Minimum log level: debug, info, warn or error")
+metricsAddr := flag.String("metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9090 (disabled when empty)")
+otelFile := flag.String("otel-file", "", "Write OpenTelemetry spans as JSON to this file (disabled when empty)")
+regenerate := flag.Int("regenerate", 1, "Times to ask an agent to fix generated Go files that don't parse before the task fails")
+format := flag.Bool("format", true, "Format generated files with gofmt, prettier or black (when installed)")
+outputDir := flag.String("output-dir", "", "Directory that plan files and commands are rooted in (default the current directory)")
+sandbox := flag.Bool("sandbox", false, "Confine plan commands to the output directory and run them without API keys or other credentials in the environment")
+commandTimeout := flag.Duration("command-timeout", 2*time.Minute, "Kill plan commands that run longer than this (0 disables the limit)")
+onDuplicate := flag.String("on-duplicate", string(agent.DuplicateSkip), "When a plan phase writes a file an earlier phase created: skip, overwrite, merge (append new lines) or error")
+infraDryRun := flag.Bool("infra-dry-run", false, "Preview docker, kubectl and terraform tool calls (terraform plan, kubectl --dry-run=client) instead of changing anything")
+warmup := flag.Bool("warmup", true, "Load the model into memory at startup so the first query doesn't wait for it")
+verify := flag.String("verify", string(agent.VerifyWarn), "Run project tests and linter after plan phases: off, warn or strict (fail the phase)")
+flag.Parse()
+
+verifyMode, err := agent.ParseVerifyMode(*verify)
+if err != nil {
+fmt.Printf("❌ %v\n", err)
+os.Exit(1)
+}
+duplicatePolicy, err := agent.ParseDuplicatePolicy(*onDuplicate)
+if err != nil {
+fmt.Printf("❌ %v\n", err)
+os.Exit(1)
+}
+
+logger, closeLog, err := setupLogger(*logFile, *logLevel)
+if err != nil {
+fmt.Printf("❌ %v\n", err)
+os.Exit(1)
+}
+defer closeLog()
+
+shutdownTracing, err := setupTracing(*otelFile)
+if err != nil {
+fmt.Printf("❌ %v\n", err)
+os.Exit(1)
+}
+defer shutdownTracing()
+
 printBanner()
 
 ctx, cancel := context.WithCancel(context.Background())
 defer cancel()
 
+interrupts := &interruptHandler{}
 sigChan := make(chan os.Signal, 1)
 signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 go func() {
-<-sigChan
-fmt.Println("\n\nShutting down...")
+shuttingDown := false
+for sig := range sigChan {
+if shuttingDown {
+// Shutdown is stuck, e.g. waiting on a prompt; give up on cleanup
+os.Exit(1)
+}
+if sig == syscall.SIGINT && !interrupts.interrupt() {
+continue
+}
+// Cancelling ctx ends the main loop, so stores finish and deferred
+// closes and log/trace flushes run
+fmt.Println("\n\nShutting down... (Ctrl-C again to force)")
+shuttingDown = true
 cancel()
-os.Exit(0)
+}
 }()
 
-config := inference.DefaultConfig()
+settings, err := appconfig.Load(*configPath)
+if err != nil {
+fmt.Printf("❌ %v\n", err)
+os.Exit(1)
+}
+if settings.Path != "" {
+fmt.Printf("✓ Loaded config from %s\n", settings.Path)
+}
+for _, warning := range settings.Warnings {
+fmt.Printf("⚠️ %s %s (ignored)\n", settings.Path, warning)
+}
+
+config := settings.Inference
 client := inference.NewClient(config)
+if logger != nil {
+client.Use(inference.LoggingInterceptor(logger))
+}
 
 availableModels, err := client.ListModels(ctx)
 if err != nil {
@@ -48,46 +136,132 @@ break
 }
 }
 
-if !modelFound && len(availableModels) > 0 {
-fmt.Printf("⚠️ Model '%s' not found\n", config.Model)
+if !modelFound && err == nil {
+if offerPull(ctx, client, config.Model) {
+availableModels = append(availableModels, config.Model)
+modelFound = true
+}
+}
+
+fmt.Printf("✓ Connected to Ollama | Model: %s\n", config.Model)
+if *warmup && modelFound {
+warmupModel(ctx, client, config.Model)
 }
+fmt.Println()
 
-fmt.Printf("✓ Connected to Ollama | Model: %s\n\n", config.Model)
+// Initialize memory; the assistant still works without it. Warnings it
+// raises mid-request are shown before the next prompt.
+notices := make(chan string, noticeBuffer)
+var memService memory.Service
+if !*noMemory && settings.MemoryEnabled {
+if svc := setupMemory(client, settings.Memory, logger, notices); svc != nil {
+memService = svc
+defer svc.Close()
+}
+}
 
 orchestratorConfig := agent.DefaultOrchestratorConfig()
-orchestrator := agent.NewAgentOrchestrator(orchestratorConfig, nil, client)
+orchestratorConfig.Logger = logger
+if *metricsAddr != "" {
+collector := metrics.NewCollector(metrics.Config{Memory: memService})
+if err := serveMetrics(*metricsAddr, collector, logger); err != nil {
+fmt.Printf("⚠️ Metrics disabled: %v\n", err)
+} else {
+orchestratorConfig.Observer = collector
+client.Use(collector.Interceptor())
+}
+}
+orchestrator := agent.NewAgentOrchestrator(orchestratorConfig, memService, client)
 
 orchestrator.RegisterAgent(agent.NewCodeAgent(client, nil))
-orchestrator.RegisterAgent(agent.NewDataAgent(client, nil))
-orchestrator.RegisterAgent(agent.NewInfraAgent(client, nil))
+dataAgent := agent.NewDataAgent(client, nil)
+if settings.DatabaseDSN != "" {
+dbCtx, dbCancel := context.WithTimeout(ctx, 10*time.Second)
+db, err := agent.NewDatabaseTool(dbCtx, settings.DatabaseDSN)
+dbCancel()
+if err != nil {
+fmt.Printf("⚠️  Database unavailable; DataAgent tools are disabled: %v\n", err)
+} else {
+dataAgent.SetDatabase(db)
+defer db.Close()
+}
+}
+orchestrator.RegisterAgent(dataAgent)
+infraAgent := agent.NewInfraAgent(client, nil)
+infraAgent.SetDryRun(*infraDryRun)
+orchestrator.RegisterAgent(infraAgent)
 orchestrator.RegisterAgent(agent.NewSecAgent(client, nil))
 // Initialize planner for Plan Mode
 planner := agent.NewPlanner(client)
-executor := agent.NewExecutor(orchestrator)
+planner.SetMemory(memService)
+planner.SetLogger(logger)
 approval := agent.NewApprovalWorkflow(planner)
+executor := agent.NewExecutor(orchestrator, approval)
+executor.SetConstraints(buildContext().Constraints)
+executor.SetVerifyMode(verifyMode)
+executor.SetDuplicatePolicy(duplicatePolicy)
+executor.SetFormatFiles(*format)
+executor.SetRegenerateAttempts(*regenerate)
+if *outputDir != "" {
+if err := executor.SetOutputDir(*outputDir); err != nil {
+fmt.Printf("❌ %v\n", err)
+os.Exit(1)
+}
+}
+executor.SetSandbox(*sandbox)
+executor.SetCommandTimeout(*commandTimeout)
 
 fmt.Println("🤖 Multi-Agent System Active (Quantum Router):")
 fmt.Println("   • CodeAgent  - Code analysis")
 fmt.Println("   • DataAgent  - SQL & analytics")
 fmt.Println("   • InfraAgent - DevOps")
-fmt.Println("   • SecAgent   - Security\n")
+fmt.Print("   • SecAgent   - Security\n\n")
+
+// Summarizes history when it outgrows its budget, and the session on exit
+summarizer := memory.NewQwenExtractor(client)
+
+tty := isTerminal(os.Stdout)
+sess := &session{
+trace:      *traceRouting,
+streaming:  true,
+tty:        tty,
+memory:     memService != nil,
+settings:   settings,
+interrupts: interrupts,
+summarizer: summarizer,
+window:     memory.NewHistoryWindow(summarizer),
+notices:    notices,
+started:    time.Now(),
+}
+if tty {
+sess.streamDelay = defaultStreamDelay
+}
 
 scanner := bufio.NewScanner(os.Stdin)
 history := []models.Message{}
 
+// Interactions are stored in the background; wait for them before the
+// memory service is closed
+var stores sync.WaitGroup
+
 for {
+sess.printNotices()
 fmt.Print("You: ")
-if !scanner.Scan() {
+line, ok := readLine(ctx, scanner)
+if !ok {
 break
 }
 
-input := strings.TrimSpace(scanner.Text())
+input := strings.TrimSpace(line)
 if input == "" {
 continue
 }
 
 if strings.HasPrefix(input, "/") {
-handleCommand(input, &history, availableModels, client, planner, executor, approval)
+handleCommand(input, &history, availableModels, client, orchestrator, planner, executor, approval, memService, sess)
+if sess.quit {
+break
+}
 continue
 }
 
@@ -103,38 +277,431 @@ fmt.Println()
 // This eliminates the double LLM call (was: router.Classify + agent.Execute)
 fmt.Print("🧠 Processing... ")
 startGen := time.Now()
+display := sess.newDisplay()
 
 request := &agent.Request{
-ID:      fmt.Sprintf("req-%d", time.Now().Unix()),
+ID:      fmt.Sprintf("req-%d", time.Now().UnixNano()),
 Query:   input,
 Context: buildContext(),
 Timeout: 5 * time.Minute,
-StreamCallback: func(token string) {
-fmt.Print(token)
-},
+Trace:   sess.trace,
+}
+if sess.streaming {
+request.StreamCallback = func(token string) {
+display.Write(token)
+}
 }
 
-// Single call through orchestrator (includes routing + execution)
-response, err := orchestrator.Execute(ctx, request)
+// Single call through orchestrator (includes routing + execution);
+// Ctrl-C cancels just this request
+reqCtx, done := interrupts.begin(ctx)
+response, err := orchestrator.Execute(reqCtx, request)
+cancelled := reqCtx.Err() == context.Canceled && ctx.Err() == nil
+done()
+if cancelled {
+fmt.Print("\n\n⏹ Request cancelled\n\n")
+continue
+}
 if err != nil {
+if model, ok := inference.MissingModel(err); ok {
+fmt.Println()
+if offerPull(ctx, client, model) {
+fmt.Print("Send your message again to continue.\n\n")
+}
+continue
+}
 fmt.Printf("\n❌ Error: %v\n\n", err)
 continue
 }
 
 genDuration := time.Since(startGen)
+if !sess.streaming {
+display.WriteAll(response.Answer)
+}
 
-// Show metrics
-fmt.Printf("\n\n⏱ %.2fs | 🚀 %.1f tok/s | 📝 %d tokens\n\n",
-genDuration.Seconds(),
-float64(response.TokensUsed)/genDuration.Seconds(),
-response.TokensUsed)
+// Show the answer's timing and token stats
+display.Finalize()
+fmt.Println()
 
-history = append(history, models.Message{
+if trace, ok := response.Metadata["routing"].(*agent.RoutingTrace); ok {
+printRoutingTrace(trace)
+if sess.tracePrompt {
+fmt.Printf("── Prompt ──\n%s\n\n", trace.Prompt)
+}
+}
+
+assistant := models.Message{
 Role:      "assistant",
 Content:   response.Answer,
 Timestamp: time.Now(),
+}
+// Turns with agents that have memory disabled stay out of the session summary
+if !orchestrator.Remembers(response.AgentName) {
+forget := map[string]interface{}{"forget": true}
+history[len(history)-1].Metadata = forget
+assistant.Metadata = forget
+}
+history = append(history, assistant)
+
+// Agents with memory disabled (e.g. SecAgent) don't have their Q&A stored
+if memService != nil && orchestrator.Remembers(response.AgentName) {
+stores.Add(1)
+go func(interaction *models.Interaction) {
+defer stores.Done()
+storeInteraction(ctx, memService, logger, interaction)
+}(&models.Interaction{
+ID:            request.ID,
+UserQuery:     input,
+AgentResponse: response.Answer,
+Timestamp:     startGen,
+Duration:      genDuration.Seconds(),
 })
 }
+
+history = fitHistory(ctx, history, client, sess.window, logger)
+}
+
+stores.Wait()
+// Shutting down on Ctrl-C skips the summary, which needs another model call
+if ctx.Err() == nil {
+endSession(history, memService, sess)
+}
+if sess.quit {
+fmt.Println("Goodbye! 👋")
+}
+}
+
+// readLine reads the next input line, returning false at EOF or once ctx is
+// cancelled. A read still blocked on stdin is abandoned on cancellation.
+func readLine(ctx context.Context, scanner *bufio.Scanner) (string, bool) {
+type result struct {
+line string
+ok   bool
+}
+lines := make(chan result, 1)
+go func() {
+ok := scanner.Scan()
+lines <- result{scanner.Text(), ok}
+}()
+
+select {
+case r := <-lines:
+return r.line, r.ok
+case <-ctx.Done():
+return "", false
+}
+}
+
+// sessionSummaryTimeout bounds summarizing and storing a session on exit
+const sessionSummaryTimeout = time.Minute
+
+// endSession stores a summary of the session as one episodic memory, so
+// later sessions can recall what was worked on
+func endSession(history []models.Message, memService memory.Service, sess *session) {
+if memService == nil {
+return
+}
+ctx, cancel := context.WithTimeout(context.Background(), sessionSummaryTimeout)
+defer cancel()
+
+fmt.Print("\n💾 Saving session summary... ")
+stored, err := memory.StoreSessionSummary(ctx, memService, sess.summarizer, history, sess.started)
+switch {
+case err != nil:
+fmt.Printf("\n⚠️ Session summary not saved: %v\n", err)
+case stored:
+fmt.Println("✓")
+default:
+fmt.Println("skipped (short session)")
+}
+}
+
+// handleCompactCommand replaces all but the latest messages with a summary
+// on demand; Ctrl-C cancels it and leaves history unchanged
+func handleCompactCommand(history *[]models.Message, sess *session) {
+ctx, done := sess.interrupts.begin(context.Background())
+defer done()
+
+fmt.Print("\n🗜  Summarizing history... ")
+compacted, compaction, err := sess.window.Compact(ctx, *history)
+if err != nil {
+fmt.Printf("\n❌ Compaction failed: %v\n\n", err)
+return
+}
+if compaction == nil {
+fmt.Printf("\nNothing to compact beyond the last %d messages\n\n", sess.window.KeepRecent)
+return
+}
+
+*history = compacted
+fmt.Printf("\n✓ Summarized %d messages, kept the last %d (~%d tokens saved, ~%d left)\n\n",
+compaction.Summarized, compaction.Kept, compaction.Saved(), compaction.TokensAfter)
+}
+
+// historyShare is the fraction of the context window the CLI history may use
+// before its oldest turns are summarized
+const historyShare = 4
+
+// fitHistory summarizes the oldest turns once history outgrows its share of
+// the context window, noting the compaction in the output
+func fitHistory(ctx context.Context, history []models.Message, client *inference.Client, window *memory.HistoryWindow, logger *slog.Logger) []models.Message {
+budget := client.Config().ContextSize / historyShare
+compacted, compaction, err := window.Fit(ctx, history, budget)
+if err != nil {
+if logger != nil {
+logger.Warn("history compaction failed", "error", err)
+}
+return history
+}
+if compaction != nil {
+fmt.Printf("🗜  Compacted %d earlier messages into a summary\n\n", compaction.Summarized)
+}
+return compacted
+}
+
+// offerPull asks whether to download a model that isn't installed and pulls
+// it on confirmation, returning true once the model is available
+func offerPull(ctx context.Context, client *inference.Client, model string) bool {
+fmt.Printf("⚠️ Model '%s' is not installed. Pull it now? [y/N]: ", model)
+reader := bufio.NewReader(os.Stdin)
+response, _ := reader.ReadString('\n')
+response = strings.TrimSpace(strings.ToLower(response))
+if response != "y" && response != "yes" {
+fmt.Printf("Run 'ollama pull %s' to install it later.\n\n", model)
+return false
+}
+
+fmt.Printf("⬇️  Pulling %s (this may take a while)...\n", model)
+if err := client.PullModel(ctx, model); err != nil {
+fmt.Printf("❌ Pull failed: %v\n\n", err)
+return false
+}
+
+fmt.Printf("✓ Model %s installed\n\n", model)
+return true
+}
+
+// warmupModel preloads the model, showing a spinner on a terminal while it
+// loads. A failure only means the first query loads the model instead.
+func warmupModel(ctx context.Context, client *inference.Client, model string) {
+stop := make(chan struct{})
+stopped := make(chan struct{})
+go func() {
+defer close(stopped)
+if !isTerminal(os.Stdout) {
+<-stop
+return
+}
+frames := []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+ticker := time.NewTicker(100 * time.Millisecond)
+defer ticker.Stop()
+for i := 0; ; i++ {
+fmt.Printf("\r%c Loading %s...", frames[i%len(frames)], model)
+select {
+case <-stop:
+fmt.Print("\r\033[K")
+return
+case <-ticker.C:
+}
+}
+}()
+
+loadTime, err := client.Warmup(ctx)
+close(stop)
+<-stopped
+
+switch {
+case err != nil:
+fmt.Printf("⚠️ Could not preload the model; the first query will load it: %v\n", err)
+case loadTime > time.Second:
+fmt.Printf("✓ Model loaded in %.1fs (a one-time cost while it stays in memory)\n", loadTime.Seconds())
+default:
+fmt.Println("✓ Model already loaded")
+}
+}
+
+// setupMemory connects to the memory backends, returning nil when they are
+// unreachable so the session continues without persistent memory. Warnings
+// raised later, while a request is printing, are queued on notices.
+func setupMemory(client *inference.Client, config *memory.Config, logger *slog.Logger, notices chan<- string) memory.Service {
+config.Logger = logger
+config.OnEmbeddingFallback = func(err error) {
+notify(notices, fmt.Sprintf("Embedding service failed (%v); memory uses word hashing and skips storing new memories until it recovers", err))
+}
+
+svc, err := memory.NewMemoryService(config, client)
+if err != nil {
+fmt.Printf("⚠️ Memory disabled: %v\n", err)
+fmt.Print("   Start Redis and Dgraph, or run with --no-memory to skip\n\n")
+return nil
+}
+
+fmt.Println("✓ Memory enabled")
+printDegradedStores(svc)
+fmt.Println()
+return svc
+}
+
+// noticeBuffer is how many queued notices are kept; more are dropped
+const noticeBuffer = 8
+
+// notify queues a notice without blocking the caller
+func notify(notices chan<- string, notice string) {
+select {
+case notices <- notice:
+default:
+}
+}
+
+// printNotices shows the warnings queued since the last prompt
+func (s *session) printNotices() {
+for {
+select {
+case notice := <-s.notices:
+fmt.Printf("⚠️  %s\n\n", notice)
+default:
+return
+}
+}
+}
+
+// printDegradedStores lists memory backends that failed to initialize
+func printDegradedStores(memService memory.Service) {
+for _, status := range memService.Status() {
+if !status.Available {
+fmt.Printf("   ⚠️ %s memory unavailable (%s): %s\n", status.Store, status.Backend, status.Error)
+}
+}
+}
+
+// storeInteraction persists a completed exchange in the background; shutting
+// down cancels it through ctx
+func storeInteraction(ctx context.Context, memService memory.Service, logger *slog.Logger, interaction *models.Interaction) {
+ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+defer cancel()
+
+if err := memService.Store(ctx, interaction); err != nil && logger != nil {
+logger.Error("storing interaction failed", "interaction", interaction.ID, "error", err)
+}
+}
+
+// serveMetrics exposes the collector at /metrics on addr in the background.
+// It listens before returning, so a bad or busy address is reported here.
+func serveMetrics(addr string, collector *metrics.Collector, logger *slog.Logger) error {
+listener, err := net.Listen("tcp", addr)
+if err != nil {
+return fmt.Errorf("could not listen on %s: %w", addr, err)
+}
+
+mux := http.NewServeMux()
+mux.Handle("/metrics", metrics.Handler(collector))
+
+go func() {
+if err := http.Serve(listener, mux); err != nil {
+fmt.Printf("⚠️ Metrics server stopped: %v\n", err)
+if logger != nil {
+logger.Error("metrics server stopped", "addr", addr, "error", err)
+}
+}
+}()
+
+fmt.Printf("✓ Metrics served on %s/metrics\n", listener.Addr())
+return nil
+}
+
+// setupTracing installs a tracer provider that exports spans to a file; with
+// no path the global no-op provider stays in place
+func setupTracing(path string) (func(), error) {
+if path == "" {
+return func() {}, nil
+}
+
+file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+if err != nil {
+return nil, fmt.Errorf("could not open trace file: %w", err)
+}
+
+exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+if err != nil {
+file.Close()
+return nil, fmt.Errorf("could not create trace exporter: %w", err)
+}
+
+provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+otel.SetTracerProvider(provider)
+
+return func() {
+ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+defer cancel()
+_ = provider.Shutdown(ctx)
+file.Close()
+}, nil
+}
+
+// setupLogger opens the structured log file; with no path it returns a nil
+// logger, which every component treats as discard
+func setupLogger(path, level string) (*slog.Logger, func(), error) {
+if path == "" {
+return nil, func() {}, nil
+}
+
+var minLevel slog.Level
+if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+return nil, nil, fmt.Errorf("invalid --log-level %q: use debug, info, warn or error", level)
+}
+
+file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+if err != nil {
+return nil, nil, fmt.Errorf("could not open log file: %w", err)
+}
+
+logger := slog.New(slog.NewJSONHandler(file, &slog.HandlerOptions{Level: minLevel}))
+return logger, func() { file.Close() }, nil
+}
+
+// interruptHandler implements two-level Ctrl-C: the first press cancels the
+// in-flight request, and a second press within interruptWindow exits
+type interruptHandler struct {
+mu         sync.Mutex
+cancel     context.CancelFunc
+lastSignal time.Time
+}
+
+// begin derives a cancelable context for a request; call done when it finishes
+func (h *interruptHandler) begin(parent context.Context) (context.Context, func()) {
+ctx, cancel := context.WithCancel(parent)
+
+h.mu.Lock()
+h.cancel = cancel
+h.mu.Unlock()
+
+return ctx, func() {
+h.mu.Lock()
+h.cancel = nil
+h.mu.Unlock()
+cancel()
+}
+}
+
+// interrupt handles a SIGINT and reports whether the program should exit
+func (h *interruptHandler) interrupt() bool {
+h.mu.Lock()
+defer h.mu.Unlock()
+
+now := time.Now()
+if now.Sub(h.lastSignal) < interruptWindow {
+return true
+}
+h.lastSignal = now
+
+if h.cancel != nil {
+h.cancel()
+h.cancel = nil
+return false
+}
+
+fmt.Print("\n(Press Ctrl-C again to exit)\nYou: ")
+return false
 }
 
 func buildContext() *agent.Context {
@@ -148,7 +715,7 @@ MaxExecutionTime: 5 * time.Minute,
 }
 }
 
-func handleCommand(cmd string, history *[]models.Message, modelsList []string, client *inference.Client, planner *agent.Planner, executor *agent.Executor, approval *agent.ApprovalWorkflow) {
+func handleCommand(cmd string, history *[]models.Message, modelsList []string, client *inference.Client, orchestrator *agent.AgentOrchestrator, planner *agent.Planner, executor *agent.Executor, approval *agent.ApprovalWorkflow, memService memory.Service, sess *session) {
 parts := strings.Fields(cmd)
 if len(parts) == 0 {
 return
@@ -156,15 +723,53 @@ return
 
 switch parts[0] {
 case "/help":
-fmt.Println("\nCommands: /help /models /history /stats /clear /exit")
-fmt.Println("Agent Routing: Quantum Router (LLM-based)\n")
+fmt.Println("\nCommands: /help /agents /trace /simulate /stream /config /models /history /compact /stats /memory /plan /template /diff /export /execute /checkpoints /rollback /clear /exit")
+fmt.Print("Agent Routing: Quantum Router (LLM-based)\n\n")
+case "/agents":
+printAgents(orchestrator)
+case "/trace":
+if len(parts) > 1 && parts[1] == "prompt" {
+sess.tracePrompt = !sess.tracePrompt
+if sess.tracePrompt {
+sess.trace = true
+}
+fmt.Printf("✓ Prompt trace %s\n\n", onOff(sess.tracePrompt))
+return
+}
+sess.trace = !sess.trace
+if sess.trace {
+fmt.Print("✓ Routing trace on\n\n")
+} else {
+sess.tracePrompt = false
+fmt.Print("✓ Routing trace off\n\n")
+}
+case "/stream":
+handleStreamCommand(cmd, sess)
+case "/config":
+handleConfigCommand(cmd, client, modelsList, sess)
+case "/simulate":
+handleSimulateCommand(cmd, orchestrator)
 case "/plan":
 handlePlanCommand(cmd, client, planner)
+case "/template":
+handleTemplateCommand(cmd, planner)
+case "/diff":
+handleDiffCommand(cmd, planner, approval)
+case "/export":
+handleExportCommand(cmd, approval)
 case "/execute":
-handleExecuteCommand(cmd, client, planner, executor, approval)
+handleExecuteCommand(cmd, client, planner, executor, approval, sess.interrupts)
+case "/checkpoints":
+handleCheckpointsCommand(cmd, executor)
+case "/rollback":
+handleRollbackCommand(cmd, executor)
+case "/memory":
+handleMemoryCommand(cmd, memService)
+case "/compact":
+handleCompactCommand(history, sess)
 case "/clear", "/new":
 *history = []models.Message{}
-fmt.Println("✓ Conversation cleared\n")
+fmt.Print("✓ Conversation cleared\n\n")
 case "/models":
 fmt.Println("\nAvailable models:")
 for _, m := range modelsList {
@@ -173,7 +778,7 @@ fmt.Printf("  • %s\n", m)
 fmt.Println()
 case "/history":
 if len(*history) == 0 {
-fmt.Println("\nNo history\n")
+fmt.Print("\nNo history\n\n")
 return
 }
 fmt.Println("\n=== History ===")
@@ -182,11 +787,275 @@ fmt.Printf("%d. %s: %s\n", i+1, msg.Role, truncate(msg.Content, 60))
 }
 fmt.Println()
 case "/stats":
-fmt.Printf("\nMessages: %d\n\n", len(*history))
+fmt.Printf("\nMessages: %d (~%d tokens)\n", len(*history), memory.EstimateTokens(*history))
+if stats, ok := client.CacheStats(); ok {
+lookups := stats.Hits + stats.Misses
+hitRate := 0.0
+if lookups > 0 {
+hitRate = float64(stats.Hits) / float64(lookups) * 100
+}
+fmt.Printf("Response cache: %d hits, %d misses (%.0f%% hit rate), %d cached\n", stats.Hits, stats.Misses, hitRate, stats.Size)
+}
+fmt.Println()
 case "/exit", "/quit":
-fmt.Println("Goodbye! 👋")
-os.Exit(0)
+// The main loop ends the session so deferred closes still run
+sess.quit = true
+}
+}
+
+// session holds the effective startup settings and the toggles that can be
+// changed mid-session
+type session struct {
+trace       bool
+tracePrompt bool // Also show the full prompt sent to the agent
+streaming   bool
+streamDelay time.Duration // Typewriter delay between display updates
+tty         bool          // Colors and the typewriter effect need a terminal
+memory      bool
+settings    *appconfig.Config
+interrupts  *interruptHandler // Ctrl-C cancels the in-flight request or plan
+window      *memory.HistoryWindow // Summarizes old turns to keep history in budget
+summarizer  memory.Summarizer
+notices     <-chan string // Warnings raised mid-request, shown before the next prompt
+started     time.Time
+quit        bool // Set by /exit to end the main loop
+}
+
+// defaultStreamDelay is the typewriter delay used on a terminal
+const defaultStreamDelay = 10 * time.Millisecond
+
+// maxStreamDelay bounds /stream speed so output can't stall
+const maxStreamDelay = time.Second
+
+// newDisplay creates the stream display for one response
+func (s *session) newDisplay() *inference.StreamDisplay {
+display := inference.NewStreamDisplay(os.Stdout, s.tty)
+display.SetUpdateDelay(s.streamDelay)
+return display
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+info, err := f.Stat()
+if err != nil {
+return false
+}
+return info.Mode()&os.ModeCharDevice != 0
+}
+
+// handleStreamCommand toggles streaming or sets the typewriter speed
+func handleStreamCommand(cmd string, sess *session) {
+parts := strings.Fields(cmd)
+switch {
+case len(parts) == 1:
+fmt.Printf("\nStreaming %s, typewriter delay %s\n", onOff(sess.streaming), sess.streamDelay)
+fmt.Print("Usage: /stream on | /stream off | /stream speed <ms>\n\n")
+case len(parts) == 2 && parts[1] == "on":
+sess.streaming = true
+fmt.Print("✓ Streaming on\n\n")
+case len(parts) == 2 && parts[1] == "off":
+sess.streaming = false
+fmt.Print("✓ Streaming off; responses print when complete\n\n")
+case len(parts) == 3 && parts[1] == "speed":
+ms, err := strconv.Atoi(parts[2])
+delay := time.Duration(ms) * time.Millisecond
+if err != nil || ms < 0 || delay > maxStreamDelay {
+fmt.Printf("❌ speed must be between 0 and %d ms\n\n", maxStreamDelay.Milliseconds())
+return
+}
+if !sess.tty {
+fmt.Print("⚠️ Output is not a terminal; the typewriter effect stays off\n\n")
+return
+}
+sess.streamDelay = delay
+fmt.Printf("✓ Typewriter delay set to %s\n\n", delay)
+default:
+fmt.Print("\nUsage: /stream on | /stream off | /stream speed <ms>\n\n")
+}
+}
+
+// onOff renders a toggle for display
+func onOff(on bool) string {
+if on {
+return "on"
+}
+return "off"
+}
+
+// maxContextSize bounds /config set context_size to what local models support
+const maxContextSize = 1 << 20
+
+// handleConfigCommand prints the effective settings or changes a mutable one
+// on the live client
+func handleConfigCommand(cmd string, client *inference.Client, modelsList []string, sess *session) {
+parts := strings.Fields(cmd)
+if len(parts) == 1 {
+printConfig(client, sess)
+return
 }
+if parts[1] != "set" || len(parts) != 4 {
+fmt.Println("\nUsage: /config | /config set <key> <value>")
+fmt.Print("Settable: model, temperature, context_size, streaming\n\n")
+return
+}
+
+key, value := parts[2], parts[3]
+switch key {
+case "model":
+if len(modelsList) > 0 && !containsString(modelsList, value) {
+fmt.Printf("❌ Model '%s' is not installed (see /models, or run 'ollama pull %s')\n\n", value, value)
+return
+}
+client.SetModel(value)
+case "temperature":
+temperature, err := strconv.ParseFloat(value, 64)
+if err != nil || temperature < 0 || temperature > 2 {
+fmt.Print("❌ temperature must be a number between 0 and 2\n\n")
+return
+}
+client.SetTemperature(temperature)
+case "context_size":
+size, err := strconv.Atoi(value)
+if err != nil || size <= 0 || size > maxContextSize {
+fmt.Printf("❌ context_size must be a whole number between 1 and %d\n\n", maxContextSize)
+return
+}
+client.SetContextSize(size)
+case "streaming":
+switch strings.ToLower(value) {
+case "on", "true", "yes":
+sess.streaming = true
+case "off", "false", "no":
+sess.streaming = false
+default:
+fmt.Print("❌ streaming must be on or off\n\n")
+return
+}
+case "ollama_url", "timeout", "memory", "config_file":
+fmt.Printf("❌ %s is read-only; change it in the config file and restart\n\n", key)
+return
+default:
+fmt.Printf("❌ Unknown setting '%s' (see /config)\n\n", key)
+return
+}
+
+fmt.Printf("✓ %s set to %s\n\n", key, value)
+}
+
+// printConfig lists the effective settings, marking those fixed at startup
+func printConfig(client *inference.Client, sess *session) {
+current := client.Config()
+configFile := sess.settings.Path
+if configFile == "" {
+configFile = "none (defaults)"
+}
+
+fmt.Println("\n=== Config ===")
+fmt.Printf("  model          %s\n", current.Model)
+fmt.Printf("  temperature    %.2f\n", current.Temperature)
+fmt.Printf("  context_size   %d\n", current.ContextSize)
+fmt.Printf("  streaming      %s\n", onOff(sess.streaming))
+fmt.Printf("  stream_delay   %s (set with /stream speed)\n", sess.streamDelay)
+fmt.Printf("  ollama_url     %s (read-only)\n", current.OllamaURL)
+fmt.Printf("  timeout        %s (read-only)\n", current.Timeout)
+fmt.Printf("  memory         %s (read-only)\n", onOff(sess.memory))
+fmt.Printf("  config_file    %s (read-only)\n", configFile)
+fmt.Print("\nChange with: /config set <key> <value>\n\n")
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+for _, v := range values {
+if v == s {
+return true
+}
+}
+return false
+}
+
+// printRoutingTrace shows which agent handled a query and why
+func printRoutingTrace(trace *agent.RoutingTrace) {
+fmt.Printf("🔀 Routed to %s (confidence %.2f)", trace.PrimaryAgent, trace.Confidence)
+if trace.SecondaryAgent != "" {
+fmt.Printf(" | secondary: %s", trace.SecondaryAgent)
+}
+fmt.Println()
+if trace.Reasoning != "" {
+fmt.Printf("   Reasoning: %s\n", trace.Reasoning)
+}
+fmt.Println()
+}
+
+// handleSimulateCommand shows how a query would be routed and the prompt each
+// selected agent would receive, without generating a response
+func handleSimulateCommand(cmd string, orchestrator *agent.AgentOrchestrator) {
+query := strings.TrimSpace(strings.TrimPrefix(cmd, "/simulate"))
+if query == "" {
+fmt.Println("\nUsage: /simulate <query>")
+fmt.Print("Example: /simulate Why is my SQL query slow?\n\n")
+return
+}
+
+request := &agent.Request{
+ID:      fmt.Sprintf("sim-%d", time.Now().Unix()),
+Query:   query,
+Context: buildContext(),
+}
+
+sim, err := orchestrator.Simulate(context.Background(), request)
+if err != nil {
+fmt.Printf("❌ Simulation failed: %v\n\n", err)
+return
+}
+
+fmt.Println()
+printRoutingTrace(sim.Routing)
+if len(sim.Memories) > 0 {
+fmt.Printf("🧠 %d memories would be injected\n\n", len(sim.Memories))
+}
+for _, p := range sim.Prompts {
+fmt.Printf("── Prompt for %s ──\n%s\n\n", p.AgentName, p.Prompt)
+}
+}
+
+// printAgents lists the registered agents and the tools each can use
+func printAgents(orchestrator *agent.AgentOrchestrator) {
+agents := orchestrator.GetAgents()
+sort.Slice(agents, func(i, j int) bool { return agents[i].Name() < agents[j].Name() })
+if len(agents) == 0 {
+fmt.Print("\nNo agents registered\n\n")
+return
+}
+
+fmt.Println("\n=== Agents ===")
+for _, a := range agents {
+fmt.Printf("\n• %s (%s)\n", a.Name(), a.Type())
+if config := a.Config(); config != nil && !config.MemoryEnabled {
+fmt.Println("    Memory: off")
+} else if config != nil && config.MaxMemoryItems > 0 {
+fmt.Printf("    Memory: up to %d items\n", config.MaxMemoryItems)
+}
+tools := a.GetTools()
+if len(tools) == 0 {
+fmt.Println("    No tools")
+continue
+}
+for _, tool := range tools {
+var flags []string
+if tool.IsDestructive() {
+flags = append(flags, "destructive")
+}
+if tool.RequiresApproval() {
+flags = append(flags, "requires approval")
+}
+label := ""
+if len(flags) > 0 {
+label = fmt.Sprintf(" [%s]", strings.Join(flags, ", "))
+}
+fmt.Printf("    - %s%s: %s\n", tool.Name(), label, tool.Description())
+}
+}
+fmt.Println()
 }
 
 func printBanner() {
@@ -210,14 +1079,14 @@ func handlePlanCommand(cmd string, client *inference.Client, planner *agent.Plan
 parts := strings.SplitN(cmd, " ", 2)
 if len(parts) < 2 {
 fmt.Println("\nUsage: /plan <task description>")
-fmt.Println("Example: /plan Add user authentication with JWT\n")
+fmt.Print("Example: /plan Add user authentication with JWT\n\n")
 return
 }
 
 query := strings.TrimSpace(parts[1])
 
 fmt.Println("\n🧠 Analyzing task complexity...")
-fmt.Println("📋 Generating execution plan...\n")
+fmt.Print("📋 Generating execution plan...\n\n")
 
 ctx := context.Background()
 req := &agent.PlanGenerationRequest{
@@ -232,6 +1101,11 @@ fmt.Printf("❌ Failed to generate plan: %v\n\n", err)
 return
 }
 
+savePlan(planner, plan)
+}
+
+// savePlan writes a new plan's markdown and execution state and prints its summary
+func savePlan(planner *agent.Planner, plan *agent.ExecutionPlan) {
 // Create plans directory
 homeDir, _ := os.UserHomeDir()
 plansDir := fmt.Sprintf("%s/.quantumflow/plans", homeDir)
@@ -269,11 +1143,263 @@ fmt.Printf("▶️  Execute with: /execute %s\n", plan.ID)
 fmt.Println()
 }
 
-func handleExecuteCommand(cmd string, client *inference.Client, planner *agent.Planner, executor *agent.Executor, approval *agent.ApprovalWorkflow) {
+// handleTemplateCommand lists plan templates or creates a plan from one,
+// optionally refined by the model with a description after "--"
+func handleTemplateCommand(cmd string, planner *agent.Planner) {
+args := strings.TrimSpace(strings.TrimPrefix(cmd, "/template"))
+if args == "" {
+fmt.Println("\nPlan templates:")
+for _, t := range planner.Templates() {
+params := make([]string, 0, len(t.Defaults))
+for k, v := range t.Defaults {
+params = append(params, fmt.Sprintf("%s=%s", k, v))
+}
+sort.Strings(params)
+fmt.Printf("  • %-14s %s\n", t.Name, t.Description)
+if len(params) > 0 {
+fmt.Printf("    %s\n", strings.Join(params, " "))
+}
+}
+fmt.Println("\nUsage: /template <name> [key=value ...] [-- <refinement>]")
+fmt.Print("Example: /template rest-api name=todo resource=task -- add JWT auth\n\n")
+return
+}
+
+fields, refinement := splitRefinement(args)
+if len(fields) == 0 {
+fmt.Print("\nUsage: /template <name> [key=value ...] [-- <refinement>]\n\n")
+return
+}
+params := make(map[string]string)
+for _, field := range fields[1:] {
+key, value, ok := strings.Cut(field, "=")
+if !ok || key == "" {
+fmt.Printf("❌ Invalid parameter %q, expected key=value\n\n", field)
+return
+}
+params[key] = value
+}
+
+plan, err := planner.FromTemplate(fields[0], params)
+if err != nil {
+fmt.Printf("❌ %v\n\n", err)
+return
+}
+
+if refinement != "" {
+fmt.Print("\n📋 Refining template plan...\n\n")
+refined, err := planner.Refine(context.Background(), plan, refinement)
+if err != nil {
+fmt.Printf("⚠️  %v; using the template as is\n", err)
+} else {
+plan = refined
+}
+}
+
+fmt.Println()
+savePlan(planner, plan)
+}
+
+// splitRefinement separates template arguments from the refinement after a
+// standalone "--", so a value or refinement containing "--" stays intact
+func splitRefinement(args string) ([]string, string) {
+var fields []string
+rest := args
+for {
+rest = strings.TrimLeft(rest, " \t")
+if rest == "" {
+return fields, ""
+}
+end := strings.IndexAny(rest, " \t")
+if end < 0 {
+end = len(rest)
+}
+field := rest[:end]
+rest = rest[end:]
+if field == "--" {
+return fields, strings.TrimSpace(rest)
+}
+fields = append(fields, field)
+}
+}
+
+// resetPlanState clears execution progress so a plan runs from the first phase
+func resetPlanState(plan *agent.ExecutionPlan) {
+plan.State.Reset()
+// Forget previously created files so they are generated again
+plan.Manifest = nil
+// Reset tasks
+for i := range plan.Phases {
+plan.Phases[i].Status = agent.PhaseStatusPending
+plan.Phases[i].StartedAt = nil
+plan.Phases[i].CompletedAt = nil
+for j := range plan.Phases[i].Tasks {
+plan.Phases[i].Tasks[j].Completed = false
+plan.Phases[i].Tasks[j].Result = ""
+plan.Phases[i].Tasks[j].Error = ""
+}
+}
+}
+
+// handleDiffCommand shows what changed between two saved plans, e.g. a plan
+// and its regenerated version
+func handleDiffCommand(cmd string, planner *agent.Planner, approval *agent.ApprovalWorkflow) {
+parts := strings.Fields(cmd)
+args := parts[1:]
+showGit := false
+if len(args) > 0 && args[len(args)-1] == "--git" {
+showGit = true
+args = args[:len(args)-1]
+}
+
+switch {
+case len(args) <= 1:
+showFileChanges(args, showGit, approval)
+return
+case len(args) != 2 || showGit:
+fmt.Println("\nUsage: /diff [plan-id] [--git]       Files written by a plan (default the last executed)")
+fmt.Println("       /diff <old-plan-id> <new-plan-id>   Phases and tasks that changed between two plans")
+fmt.Print("Example: /diff plan_20260117_140530 plan_20260117_142210\n\n")
+return
+}
+
+var plans [2]*agent.ExecutionPlan
+for i, planID := range args {
+plan, err := approval.LoadPlanState(planID)
+if err != nil {
+fmt.Printf("❌ Could not load plan %s: %v\n\n", planID, err)
+return
+}
+plans[i] = plan
+}
+
+diff := planner.Diff(plans[0], plans[1])
+fmt.Printf("\n%s\n", planner.FormatDiffAsMarkdown(diff))
+}
+
+// handleExportCommand writes a markdown report of a plan, by default the last
+// executed one, combining the plan, its execution state, created files and
+// agent responses
+func handleExportCommand(cmd string, approval *agent.ApprovalWorkflow) {
+parts := strings.Fields(cmd)
+if len(parts) > 2 {
+fmt.Println("\nUsage: /export [plan-id]   Write a markdown report of a plan (default the last executed)")
+fmt.Print("Example: /export plan_20260117_140530\n\n")
+return
+}
+
+var planID string
+if len(parts) == 2 {
+planID = parts[1]
+} else {
+id, err := approval.LastExecutedPlanID()
+if err != nil {
+fmt.Printf("\n❌ %v\n", err)
+fmt.Print("Tip: Use /export <plan-id> to report on a plan that hasn't run\n\n")
+return
+}
+planID = id
+}
+
+plan, err := approval.LoadPlanState(planID)
+if err != nil {
+fmt.Printf("❌ Could not load plan %s: %v\n\n", planID, err)
+return
+}
+
+path, err := approval.ExportReport(plan)
+if err != nil {
+fmt.Printf("❌ Could not export plan %s: %v\n\n", planID, err)
+return
+}
+fmt.Printf("\n📄 Report for %s written to %s\n\n", plan.Title, path)
+}
+
+// showFileChanges lists the files a plan wrote, by phase, with their sizes;
+// with showGit the full git diff of those files follows the summary
+func showFileChanges(args []string, showGit bool, approval *agent.ApprovalWorkflow) {
+var planID string
+if len(args) == 1 {
+planID = args[0]
+} else {
+id, err := approval.LastExecutedPlanID()
+if err != nil {
+fmt.Printf("\n❌ %v\n", err)
+fmt.Print("Tip: Use /execute to run a plan first\n\n")
+return
+}
+planID = id
+}
+
+plan, err := approval.LoadPlanState(planID)
+if err != nil {
+fmt.Printf("❌ Could not load plan %s: %v\n\n", planID, err)
+return
+}
+if plan.Manifest == nil || len(plan.Manifest.CreatedFiles) == 0 {
+fmt.Printf("\n📭 Plan %s hasn't written any files\n\n", plan.ID)
+return
+}
+
+fmt.Printf("\n📝 Files written by %s (%s)", plan.Title, plan.ID)
+if plan.Manifest.BaseDir != "" && plan.Manifest.BaseDir != "." {
+fmt.Printf(" in %s", plan.Manifest.BaseDir)
+}
+fmt.Println(":")
+
+var total int64
+phase := ""
+changes := plan.Manifest.Changes()
+for _, change := range changes {
+if change.Phase != phase {
+phase = change.Phase
+fmt.Printf("\n  %s\n", phase)
+}
+switch {
+case change.Missing:
+fmt.Printf("    - %-40s (deleted)\n", change.Path)
+default:
+total += change.CurrentSize
+note := ""
+if change.CurrentSize != change.Size {
+note = fmt.Sprintf(" (was %s)", formatSize(change.Size))
+}
+if change.Problem != "" {
+note += " ⚠️  " + change.Problem
+}
+fmt.Printf("    + %-40s %8s%s\n", change.Path, formatSize(change.CurrentSize), note)
+}
+}
+fmt.Printf("\n  %d files, %s\n", len(changes), formatSize(total))
+
+gitChanges, err := plan.Manifest.GitChanges(context.Background(), showGit)
+switch {
+case err != nil:
+if showGit {
+fmt.Printf("\n⚠️  %v\n", err)
+}
+case gitChanges != "":
+fmt.Printf("\n🔀 Git:\n%s", gitChanges)
+}
+fmt.Println()
+}
+
+// formatSize formats a byte count for display
+func formatSize(bytes int64) string {
+switch {
+case bytes >= 1<<20:
+return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+case bytes >= 1<<10:
+return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+}
+return fmt.Sprintf("%d B", bytes)
+}
+
+func handleExecuteCommand(cmd string, client *inference.Client, planner *agent.Planner, executor *agent.Executor, approval *agent.ApprovalWorkflow, interrupts *interruptHandler) {
 parts := strings.Fields(cmd)
 if len(parts) < 2 {
 fmt.Println("\nUsage: /execute <plan-id>")
-fmt.Println("Example: /execute plan_20260117_140530\n")
+fmt.Print("Example: /execute plan_20260117_140530\n\n")
 return
 }
 
@@ -285,12 +1411,13 @@ fmt.Printf("\n📂 Loading plan: %s...\n", planID)
 plan, err := approval.LoadPlanState(planID)
 if err != nil {
 fmt.Printf("❌ Could not load plan: %v\n", err)
-fmt.Println("\nTip: Use /plan to generate a new plan first\n")
+fmt.Print("\nTip: Use /plan to generate a new plan first\n\n")
 return
 }
 
-// Check if plan was already completed or failed
-if plan.State.Status == agent.ExecutionStatusCompleted || plan.State.Status == agent.ExecutionStatusFailed {
+// Check if plan was already completed, failed or interrupted
+switch plan.State.Status {
+case agent.ExecutionStatusCompleted:
 fmt.Printf("\n⚠️  This plan has already finished with status: %s\n", plan.State.Status)
 fmt.Print("Restart execution from beginning? [y/N]: ")
 reader := bufio.NewReader(os.Stdin)
@@ -298,24 +1425,28 @@ response, _ := reader.ReadString('\n')
 response = strings.TrimSpace(strings.ToLower(response))
 
 if response == "y" || response == "yes" {
-// Reset state
-plan.State.Status = agent.ExecutionStatusPending
-plan.State.CurrentPhase = 0
-plan.State.CompletedPhases = []int{}
-plan.State.FailedPhases = []int{}
-plan.State.StartedAt = nil
-plan.State.CompletedAt = nil
-// Reset tasks
-for i := range plan.Phases {
-plan.Phases[i].Status = agent.PhaseStatusPending
-for j := range plan.Phases[i].Tasks {
-plan.Phases[i].Tasks[j].Completed = false
-plan.Phases[i].Tasks[j].Result = ""
-}
-}
+resetPlanState(plan)
 fmt.Println("🔄 Plan state reset.")
 } else {
-fmt.Println("❌ Validation cancelled.")
+fmt.Println("❌ Execution cancelled.")
+return
+}
+case agent.ExecutionStatusFailed, agent.ExecutionStatusRunning, agent.ExecutionStatusCancelled:
+fmt.Printf("\n⚠️  This plan stopped at phase %d/%d (status: %s)\n", plan.State.CurrentPhase+1, len(plan.Phases), plan.State.Status)
+fmt.Print("[r]esume where it stopped, [s]tart over, or cancel? [r/s/N]: ")
+reader := bufio.NewReader(os.Stdin)
+response, _ := reader.ReadString('\n')
+response = strings.TrimSpace(strings.ToLower(response))
+
+switch response {
+case "r", "resume":
+plan.State.SetStatus(agent.ExecutionStatusPending)
+fmt.Println("⏩ Resuming; completed tasks will be skipped.")
+case "s", "start over":
+resetPlanState(plan)
+fmt.Println("🔄 Plan state reset.")
+default:
+fmt.Println("❌ Execution cancelled.")
 return
 }
 }
@@ -328,7 +1459,7 @@ return
 }
 
 if !approved {
-fmt.Println("\n❌ Execution cancelled\n")
+fmt.Print("\n❌ Execution cancelled\n\n")
 return
 }
 
@@ -337,9 +1468,16 @@ if err := approval.SavePlanState(plan); err != nil {
 fmt.Printf("⚠️  Could not save plan state: %v\n", err)
 }
 
-// Execute plan
-ctx := context.Background()
-if err := executor.Execute(ctx, plan); err != nil {
+// Execute plan; Ctrl-C stops it after saving state
+ctx, done := interrupts.begin(context.Background())
+err = executor.Execute(ctx, plan)
+done()
+if errors.Is(err, agent.ErrExecutionCancelled) {
+fmt.Printf("\n⏹ %v\n", err)
+fmt.Printf("Resume with: /execute %s\n\n", plan.ID)
+return
+}
+if err != nil {
 fmt.Printf("\n❌ Execution failed: %v\n\n", err)
 
 // Save failed state
@@ -352,5 +1490,168 @@ if err := approval.SavePlanState(plan); err != nil {
 fmt.Printf("⚠️  Could not save final state: %v\n", err)
 }
 
-fmt.Println("✅ Plan execution completed successfully!\n")
+fmt.Print("✅ Plan execution completed successfully!\n\n")
+}
+
+func handleCheckpointsCommand(cmd string, executor *agent.Executor) {
+parts := strings.Fields(cmd)
+if len(parts) < 2 {
+fmt.Print("\nUsage: /checkpoints <plan-id>\n\n")
+return
+}
+
+checkpoints, err := executor.ListCheckpoints(parts[1])
+if err != nil {
+fmt.Printf("❌ Could not list checkpoints: %v\n\n", err)
+return
+}
+if len(checkpoints) == 0 {
+fmt.Printf("\nNo checkpoints for plan %s\n\n", parts[1])
+return
+}
+
+fmt.Printf("\n=== Checkpoints for %s ===\n", parts[1])
+for _, cp := range checkpoints {
+git := "no git snapshot"
+if head := cp.Metadata["git_head"]; head != "" {
+git = "git " + truncate(head, 12)
+if cp.GitStash != "" {
+git += " + uncommitted changes"
+}
+}
+fmt.Printf("  • %s | before phase %d | %s | %s\n", cp.ID, cp.PhaseIndex+1, cp.Timestamp.Format("2006-01-02 15:04:05"), git)
+}
+fmt.Print("\nRestore with: /rollback <checkpoint-id>\n\n")
+}
+
+func handleRollbackCommand(cmd string, executor *agent.Executor) {
+parts := strings.Fields(cmd)
+if len(parts) < 2 {
+fmt.Print("\nUsage: /rollback <checkpoint-id>\n\n")
+return
+}
+
+fmt.Printf("\n⚠️  This restores tracked files to checkpoint %s and deletes files the plan created after it.\n", parts[1])
+fmt.Print("Continue? [y/N]: ")
+reader := bufio.NewReader(os.Stdin)
+response, _ := reader.ReadString('\n')
+response = strings.TrimSpace(strings.ToLower(response))
+if response != "y" && response != "yes" {
+fmt.Print("❌ Rollback cancelled\n\n")
+return
+}
+
+plan, err := executor.Rollback(context.Background(), parts[1])
+if err != nil {
+fmt.Printf("❌ Rollback failed: %v\n\n", err)
+return
+}
+
+fmt.Printf("✓ Rolled back to phase %d of %s\n", plan.State.CurrentPhase+1, plan.ID)
+fmt.Printf("▶️  Continue with: /execute %s\n\n", plan.ID)
+}
+
+func handleMemoryCommand(cmd string, memService memory.Service) {
+if memService == nil {
+fmt.Print("\n⚠️  Memory is not available in this session\n\n")
+return
+}
+
+parts := strings.SplitN(cmd, " ", 3)
+if len(parts) < 2 {
+fmt.Println("\nUsage: /memory stats | /memory search <query> | /memory pin <id> | /memory unpin <id> | /memory clear")
+fmt.Print("Example: /memory search kubernetes deployment\n\n")
+return
+}
+
+ctx := context.Background()
+
+switch parts[1] {
+case "stats":
+stats, err := memService.GetStats(ctx)
+if err != nil {
+fmt.Printf("❌ Could not load memory stats: %v\n\n", err)
+return
+}
+fmt.Println("\n=== Memory ===")
+for _, status := range memService.Status() {
+state := "✓"
+if !status.Available {
+state = "✗"
+}
+fmt.Printf("%s %-10s (%s)\n", state, status.Store, status.Backend)
+}
+fmt.Printf("Episodic memories: %d\n", stats.EpisodicCount)
+fmt.Printf("Avg retrieval: %.0fms\n", stats.AvgRetrievalMs)
+if stats.EmbeddingFallback != "" {
+fmt.Printf("⚠️  Embeddings degraded to word hashing: %s\n", stats.EmbeddingFallback)
+}
+if !stats.LastCompaction.IsZero() {
+fmt.Printf("Last compaction: %s\n", stats.LastCompaction.Format(time.RFC1123))
+}
+fmt.Printf("Uptime: %s\n\n", stats.Uptime.Round(time.Second))
+case "search":
+if len(parts) < 3 || strings.TrimSpace(parts[2]) == "" {
+fmt.Print("\nUsage: /memory search <query>\n\n")
+return
+}
+memories, err := memService.Retrieve(ctx, strings.TrimSpace(parts[2]), 5)
+if err != nil {
+fmt.Printf("❌ Memory search failed: %v\n\n", err)
+return
+}
+if len(memories) == 0 {
+fmt.Print("\nNo matching memories\n\n")
+return
+}
+fmt.Println("\n=== Memories ===")
+for i, m := range memories {
+pin := ""
+if m.Pinned {
+pin = " 📌"
+}
+fmt.Printf("%d. [%s %.2f]%s %s\n", i+1, m.Type, m.Score, pin, truncate(strings.ReplaceAll(m.Content, "\n", " "), 80))
+if m.Type == models.MemoryTypeEpisodic {
+fmt.Printf("   id: %s\n", memory.ShortID(m.ID))
+}
+}
+fmt.Println()
+case "pin", "unpin":
+if len(parts) < 3 || strings.TrimSpace(parts[2]) == "" {
+fmt.Printf("\nUsage: /memory %s <id>  (ids are shown by /memory search)\n\n", parts[1])
+return
+}
+id := strings.TrimSpace(parts[2])
+if parts[1] == "pin" {
+if err := memService.Pin(ctx, id); err != nil {
+fmt.Printf("❌ Could not pin memory: %v\n\n", err)
+return
+}
+fmt.Printf("📌 Pinned %s: it won't expire or be compacted\n\n", id)
+return
+}
+if err := memService.Unpin(ctx, id); err != nil {
+fmt.Printf("❌ Could not unpin memory: %v\n\n", err)
+return
+}
+fmt.Printf("✓ Unpinned %s\n\n", id)
+case "clear":
+fmt.Print("\nDelete all episodic memories? [y/N]: ")
+reader := bufio.NewReader(os.Stdin)
+response, _ := reader.ReadString('\n')
+response = strings.TrimSpace(strings.ToLower(response))
+if response != "y" && response != "yes" {
+fmt.Print("❌ Cancelled\n\n")
+return
+}
+removed, err := memService.ClearEpisodic(ctx)
+if err != nil {
+fmt.Printf("❌ %v\n\n", err)
+return
+}
+fmt.Printf("✓ Removed %d episodic memories\n\n", removed)
+default:
+fmt.Printf("\nUnknown memory command: %s\n", parts[1])
+fmt.Print("Usage: /memory stats | /memory search <query> | /memory pin <id> | /memory unpin <id> | /memory clear\n\n")
+}
 }
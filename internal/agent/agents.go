@@ -2,7 +2,10 @@ package agent
 
 import (
 "context"
+"errors"
 "fmt"
+"regexp"
+"strconv"
 "strings"
 "time"
 
@@ -10,15 +13,41 @@ import (
 "github.com/quantumflow/quantumflow/internal/models"
 )
 
+// Default system prompts, used when AgentConfig.SystemPrompt is empty
+const (
+defaultDataSystemPrompt  = "You are a data analysis expert. Provide SQL queries and data insights."
+defaultInfraSystemPrompt = "You are an infrastructure expert. Help with deployment and infra tasks."
+defaultSecSystemPrompt   = "You are a security expert. Analyze and provide security recommendations."
+)
+
+// generateOptions carries a request's temperature and token overrides to the client;
+// zero values fall back to the client configuration
+func generateOptions(request *Request) inference.GenerateOptions {
+return inference.GenerateOptions{
+Temperature: request.Temperature,
+MaxTokens:   request.MaxTokens,
+}
+}
+
+// systemPrompt returns the operator-configured system prompt, falling back to the agent's default
+func systemPrompt(config *AgentConfig, fallback string) string {
+if config != nil {
+if custom := strings.TrimSpace(config.SystemPrompt); custom != "" {
+return custom
+}
+}
+return fallback
+}
+
 // DataAgent specializes in data analysis and SQL tasks
 type DataAgent struct {
 name   string
-client *inference.Client
+client inference.Generator
 tools  []Tool
 config *AgentConfig
 }
 
-func NewDataAgent(client *inference.Client, config *AgentConfig) *DataAgent {
+func NewDataAgent(client inference.Generator, config *AgentConfig) *DataAgent {
 if config == nil {
 config = &AgentConfig{
 Name:           "DataAgent",
@@ -36,28 +65,56 @@ return &DataAgent{
 name:   config.Name,
 client: client,
 config: config,
-tools: []Tool{
+tools: configuredTools(config, []Tool{
 &SQLGeneratorTool{},
 &DataAnalysisTool{},
 &SchemaInspectorTool{},
-},
+}),
+}
+}
+
+// SetDatabase connects the data tools to a database: the schema inspector and
+// data analysis tools read from it, and the database tool, which requires
+// approval, can also write
+func (a *DataAgent) SetDatabase(db *DatabaseTool) {
+for i, tool := range a.tools {
+switch tool.(type) {
+case *DataAnalysisTool:
+a.tools[i] = &DataAnalysisTool{db: db}
+case *SchemaInspectorTool:
+a.tools[i] = &SchemaInspectorTool{db: db}
+case *DatabaseTool:
+a.tools[i] = db
+db = nil
 }
 }
+if db != nil {
+a.tools = append(a.tools, db)
+}
+}
+
+// AddTool adds a custom tool the agent exposes alongside its built-in ones
+func (a *DataAgent) AddTool(tool Tool) error {
+tools, err := addTool(a.tools, tool)
+a.tools = tools
+return err
+}
 
 func (a *DataAgent) Name() string           { return a.name }
 func (a *DataAgent) Type() models.AgentType { return models.AgentTypeData }
 func (a *DataAgent) GetTools() []Tool       { return a.tools }
+func (a *DataAgent) Config() *AgentConfig    { return a.config }
 
 func (a *DataAgent) Execute(ctx context.Context, request *Request) (*Response, error) {
 start := time.Now()
-prompt := a.buildPrompt(request)
+prompt := a.BuildPrompt(request)
 
 var fullResponse string
 
 if request.StreamCallback != nil {
 // Streaming mode with efficient string building
 var responseBuilder strings.Builder
-tokenChan, err := a.client.Generate(ctx, prompt, true)
+tokenChan, err := a.client.GenerateWithOptions(ctx, prompt, true, generateOptions(request))
 if err != nil {
 return nil, fmt.Errorf("generation failed: %w", err)
 }
@@ -69,20 +126,22 @@ request.StreamCallback(token)
 fullResponse = responseBuilder.String()
 } else {
 // Synchronous mode
-result, err := a.client.GenerateSync(ctx, prompt)
+result, err := a.client.GenerateSyncWithOptions(ctx, prompt, generateOptions(request))
 if err != nil {
 return nil, fmt.Errorf("generation failed: %w", err)
 }
 fullResponse = result.Response
 }
 
+answer, confidence := deriveConfidence(request, fullResponse)
+
 return &Response{
 AgentName:  a.name,
 AgentType:  a.Type(),
-Answer:     fullResponse,
-Confidence: 0.85,
+Answer:     answer,
+Confidence: confidence,
 Duration:   time.Since(start),
-TokensUsed: countTokens(fullResponse),
+TokensUsed: countTokens(answer),
 }, nil
 }
 
@@ -99,9 +158,9 @@ matches++
 return float64(matches) / float64(len(keywords)), nil
 }
 
-func (a *DataAgent) buildPrompt(request *Request) string {
+func (a *DataAgent) BuildPrompt(request *Request) string {
 var prompt strings.Builder
-prompt.WriteString("You are a data analysis expert. Provide SQL queries and data insights.\n\n")
+prompt.WriteString(systemPrompt(a.config, defaultDataSystemPrompt) + "\n\n")
 
 if len(request.Memories) > 0 {
 prompt.WriteString("Context:\n")
@@ -110,6 +169,7 @@ prompt.WriteString(fmt.Sprintf("- %s\n", truncate(mem.Content, 100)))
 }
 }
 
+prompt.WriteString(confidenceInstruction(request))
 prompt.WriteString(fmt.Sprintf("\nQuery: %s\n\nResponse:", request.Query))
 return prompt.String()
 }
@@ -117,17 +177,19 @@ return prompt.String()
 // InfraAgent specializes in infrastructure tasks
 type InfraAgent struct {
 name   string
-client *inference.Client
+client inference.Generator
 tools  []Tool
 config *AgentConfig
 }
 
-func NewInfraAgent(client *inference.Client, config *AgentConfig) *InfraAgent {
+func NewInfraAgent(client inference.Generator, config *AgentConfig) *InfraAgent {
 if config == nil {
 config = &AgentConfig{
-Name:        "InfraAgent",
-Type:        models.AgentTypeInfra,
-Temperature: 0.5,
+Name:           "InfraAgent",
+Type:           models.AgentTypeInfra,
+Temperature:    0.5,
+MemoryEnabled:  true,
+MaxMemoryItems: 10,
 }
 }
 
@@ -135,27 +197,50 @@ return &InfraAgent{
 name:   config.Name,
 client: client,
 config: config,
-tools: []Tool{
+tools: configuredTools(config, []Tool{
 &DockerTool{},
 &KubectlTool{},
 &TerraformTool{},
-},
+}),
 }
 }
 
 func (a *InfraAgent) Name() string           { return a.name }
 func (a *InfraAgent) Type() models.AgentType { return models.AgentTypeInfra }
 func (a *InfraAgent) GetTools() []Tool       { return a.tools }
+func (a *InfraAgent) Config() *AgentConfig    { return a.config }
+
+// AddTool adds a custom tool the agent exposes alongside its built-in ones
+func (a *InfraAgent) AddTool(tool Tool) error {
+tools, err := addTool(a.tools, tool)
+a.tools = tools
+return err
+}
+
+// SetDryRun makes the docker, kubectl and terraform tools preview commands
+// instead of changing anything
+func (a *InfraAgent) SetDryRun(dryRun bool) {
+for _, tool := range a.tools {
+switch t := tool.(type) {
+case *DockerTool:
+t.DryRun = dryRun
+case *KubectlTool:
+t.DryRun = dryRun
+case *TerraformTool:
+t.DryRun = dryRun
+}
+}
+}
 
 func (a *InfraAgent) Execute(ctx context.Context, request *Request) (*Response, error) {
 start := time.Now()
-prompt := fmt.Sprintf("You are an infrastructure expert. Help with deployment and infra tasks.\n\nQuery: %s\n\nResponse:", request.Query)
+prompt := a.BuildPrompt(request)
 
 var fullResponse string
 
 if request.StreamCallback != nil {
 var responseBuilder strings.Builder
-tokenChan, err := a.client.Generate(ctx, prompt, true)
+tokenChan, err := a.client.GenerateWithOptions(ctx, prompt, true, generateOptions(request))
 if err != nil {
 return nil, err
 }
@@ -166,20 +251,22 @@ request.StreamCallback(token)
 }
 fullResponse = responseBuilder.String()
 } else {
-result, err := a.client.GenerateSync(ctx, prompt)
+result, err := a.client.GenerateSyncWithOptions(ctx, prompt, generateOptions(request))
 if err != nil {
 return nil, err
 }
 fullResponse = result.Response
 }
 
+answer, confidence := deriveConfidence(request, fullResponse)
+
 return &Response{
 AgentName:  a.name,
 AgentType:  a.Type(),
-Answer:     fullResponse,
-Confidence: 0.8,
+Answer:     answer,
+Confidence: confidence,
 Duration:   time.Since(start),
-TokensUsed: countTokens(fullResponse),
+TokensUsed: countTokens(answer),
 }, nil
 }
 
@@ -196,19 +283,36 @@ matches++
 return float64(matches) / float64(len(keywords)), nil
 }
 
+func (a *InfraAgent) BuildPrompt(request *Request) string {
+var prompt strings.Builder
+prompt.WriteString(systemPrompt(a.config, defaultInfraSystemPrompt) + "\n\n")
+
+if len(request.Memories) > 0 {
+prompt.WriteString("Context:\n")
+for _, mem := range request.Memories {
+prompt.WriteString(fmt.Sprintf("- %s\n", truncate(mem.Content, 100)))
+}
+}
+
+prompt.WriteString(confidenceInstruction(request))
+prompt.WriteString(fmt.Sprintf("\nQuery: %s\n\nResponse:", request.Query))
+return prompt.String()
+}
+
 // SecAgent specializes in security tasks
 type SecAgent struct {
 name   string
-client *inference.Client
+client inference.Generator
 tools  []Tool
 config *AgentConfig
 }
 
-func NewSecAgent(client *inference.Client, config *AgentConfig) *SecAgent {
+func NewSecAgent(client inference.Generator, config *AgentConfig) *SecAgent {
 if config == nil {
 config = &AgentConfig{
-Name: "SecAgent",
-Type: models.AgentTypeSec,
+Name:          "SecAgent",
+Type:          models.AgentTypeSec,
+MemoryEnabled: false, // Security Q&A can include findings that shouldn't be kept
 }
 }
 
@@ -216,27 +320,35 @@ return &SecAgent{
 name:   config.Name,
 client: client,
 config: config,
-tools: []Tool{
+tools: configuredTools(config, []Tool{
 &VulnerabilityScannerTool{},
 &OWASPCheckerTool{},
 &SecurityAuditTool{},
-},
+}),
 }
 }
 
+// AddTool adds a custom tool the agent exposes alongside its built-in ones
+func (a *SecAgent) AddTool(tool Tool) error {
+tools, err := addTool(a.tools, tool)
+a.tools = tools
+return err
+}
+
 func (a *SecAgent) Name() string           { return a.name }
 func (a *SecAgent) Type() models.AgentType { return models.AgentTypeSec }
 func (a *SecAgent) GetTools() []Tool       { return a.tools }
+func (a *SecAgent) Config() *AgentConfig    { return a.config }
 
 func (a *SecAgent) Execute(ctx context.Context, request *Request) (*Response, error) {
 start := time.Now()
-prompt := fmt.Sprintf("You are a security expert. Analyze and provide security recommendations.\n\nQuery: %s\n\nResponse:", request.Query)
+prompt := a.BuildPrompt(request)
 
 var fullResponse string
 
 if request.StreamCallback != nil {
 var responseBuilder strings.Builder
-tokenChan, err := a.client.Generate(ctx, prompt, true)
+tokenChan, err := a.client.GenerateWithOptions(ctx, prompt, true, generateOptions(request))
 if err != nil {
 return nil, err
 }
@@ -247,20 +359,22 @@ request.StreamCallback(token)
 }
 fullResponse = responseBuilder.String()
 } else {
-result, err := a.client.GenerateSync(ctx, prompt)
+result, err := a.client.GenerateSyncWithOptions(ctx, prompt, generateOptions(request))
 if err != nil {
 return nil, err
 }
 fullResponse = result.Response
 }
 
+answer, confidence := deriveConfidence(request, fullResponse)
+
 return &Response{
 AgentName:  a.name,
 AgentType:  a.Type(),
-Answer:     fullResponse,
-Confidence: 0.9,
+Answer:     answer,
+Confidence: confidence,
 Duration:   time.Since(start),
-TokensUsed: countTokens(fullResponse),
+TokensUsed: countTokens(answer),
 }, nil
 }
 
@@ -277,6 +391,10 @@ matches++
 return float64(matches) / float64(len(keywords)), nil
 }
 
+func (a *SecAgent) BuildPrompt(request *Request) string {
+return fmt.Sprintf("%s\n%s\nQuery: %s\n\nResponse:", systemPrompt(a.config, defaultSecSystemPrompt), confidenceInstruction(request), request.Query)
+}
+
 // Tools
 type SQLGeneratorTool struct{}
 func (t *SQLGeneratorTool) Name() string { return "sql_generator" }
@@ -288,77 +406,186 @@ return fmt.Sprintf("-- Generated SQL for: %s\nSELECT * FROM table;", description
 func (t *SQLGeneratorTool) IsDestructive() bool { return false }
 func (t *SQLGeneratorTool) RequiresApproval() bool { return false }
 
-type DataAnalysisTool struct{}
+// DataAnalysisTool runs read-only queries against the configured database
+type DataAnalysisTool struct {
+db *DatabaseTool
+}
 func (t *DataAnalysisTool) Name() string { return "data_analysis" }
-func (t *DataAnalysisTool) Description() string { return "Analyze datasets and provide statistical insights" }
+func (t *DataAnalysisTool) Description() string { return "Run a read-only SELECT against the database and return the rows" }
 func (t *DataAnalysisTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-return "Data analysis results: Mean=X, Median=Y", nil
+if t.db == nil {
+return "", ErrNoDatabase
+}
+query, _ := params["query"].(string)
+result, err := t.db.Query(ctx, query)
+if errors.Is(err, ErrNotReadOnly) {
+return "", fmt.Errorf("%w; data_analysis only reads, use the database tool for writes", err)
+}
+return result, err
 }
 func (t *DataAnalysisTool) IsDestructive() bool { return false }
 func (t *DataAnalysisTool) RequiresApproval() bool { return false }
 
-type SchemaInspectorTool struct{}
+// SchemaInspectorTool describes the configured database's tables, columns and foreign keys
+type SchemaInspectorTool struct {
+db *DatabaseTool
+}
 func (t *SchemaInspectorTool) Name() string { return "schema_inspector" }
 func (t *SchemaInspectorTool) Description() string { return "Inspect database schema and relationships" }
 func (t *SchemaInspectorTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+if t.db == nil {
+return "", ErrNoDatabase
+}
 table, _ := params["table"].(string)
-return fmt.Sprintf("Schema for %s: columns, types, constraints", table), nil
+tables, err := t.db.Schema(ctx, table)
+if err != nil {
+return "", err
 }
-func (t *SchemaInspectorTool) IsDestructive() bool { return false }
-func (t *SchemaInspectorTool) RequiresApproval() bool { return false }
-
-type DockerTool struct{}
-func (t *DockerTool) Name() string { return "docker" }
-func (t *DockerTool) Description() string { return "Docker container operations" }
-func (t *DockerTool) IsDestructive() bool { return true }
-func (t *DockerTool) RequiresApproval() bool { return true }
-func (t *DockerTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-return "Docker command executed (dry run)", nil
+if len(tables) == 0 {
+if table != "" {
+return "", fmt.Errorf("table %q not found", table)
 }
-
-type KubectlTool struct{}
-func (t *KubectlTool) Name() string { return "kubectl" }
-func (t *KubectlTool) Description() string { return "Kubernetes operations" }
-func (t *KubectlTool) IsDestructive() bool { return true }
-func (t *KubectlTool) RequiresApproval() bool { return true }
-func (t *KubectlTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-return "Kubectl command executed (dry run)", nil
+return "The database has no tables", nil
 }
-
-type TerraformTool struct{}
-func (t *TerraformTool) Name() string { return "terraform" }
-func (t *TerraformTool) Description() string { return "Infrastructure as Code operations" }
-func (t *TerraformTool) IsDestructive() bool { return true }
-func (t *TerraformTool) RequiresApproval() bool { return true }
-func (t *TerraformTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-return "Terraform plan generated", nil
+return FormatSchema(tables), nil
 }
+func (t *SchemaInspectorTool) IsDestructive() bool { return false }
+func (t *SchemaInspectorTool) RequiresApproval() bool { return false }
 
+// VulnerabilityScannerTool scans a project's dependencies with govulncheck,
+// npm audit or pip-audit, chosen from its manifest
 type VulnerabilityScannerTool struct{}
 func (t *VulnerabilityScannerTool) Name() string { return "vuln_scanner" }
-func (t *VulnerabilityScannerTool) Description() string { return "Scan for vulnerabilities" }
+func (t *VulnerabilityScannerTool) Description() string { return "Scan dependencies for known vulnerabilities (govulncheck, npm audit, pip-audit)" }
 func (t *VulnerabilityScannerTool) IsDestructive() bool { return false }
 func (t *VulnerabilityScannerTool) RequiresApproval() bool { return false }
 func (t *VulnerabilityScannerTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-return "Vulnerability scan complete: 0 critical, 2 medium, 5 low", nil
+dir, _ := params["dir"].(string)
+if dir == "" {
+dir = "."
 }
 
+report, err := scanDependencies(ctx, dir)
+if errors.Is(err, errNoScanner) {
+return fmt.Sprintf("[placeholder, not a real scan: %v] %s", err, placeholderScan), nil
+}
+if err != nil {
+return "", err
+}
+return report.Format(), nil
+}
+
+// OWASPCheckerTool audits a project's files for hardcoded secrets, insecure
+// code and misconfiguration, grouped by OWASP Top 10 category
 type OWASPCheckerTool struct{}
 func (t *OWASPCheckerTool) Name() string { return "owasp_checker" }
-func (t *OWASPCheckerTool) Description() string { return "Check against OWASP Top 10" }
+func (t *OWASPCheckerTool) Description() string { return "Check project files against the OWASP Top 10 (secrets, eval, weak crypto, plain http, security headers)" }
 func (t *OWASPCheckerTool) IsDestructive() bool { return false }
 func (t *OWASPCheckerTool) RequiresApproval() bool { return false }
 func (t *OWASPCheckerTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-return "OWASP check passed: No critical issues", nil
+dir, _ := params["dir"].(string)
+if dir == "" {
+dir = "."
 }
 
+findings, err := auditProject(ctx, dir)
+if err != nil {
+return "", err
+}
+if len(findings) == 0 {
+return fmt.Sprintf("OWASP check of %s: no issues found", dir), nil
+}
+return fmt.Sprintf("OWASP check of %s: %d issues\n%s", dir, len(findings), formatAuditFindings(findings, true)), nil
+}
+
+// SecurityAuditTool combines the file audit with a dependency scan
 type SecurityAuditTool struct{}
 func (t *SecurityAuditTool) Name() string { return "security_audit" }
-func (t *SecurityAuditTool) Description() string { return "Comprehensive security audit" }
+func (t *SecurityAuditTool) Description() string { return "Audit project files and dependencies, reporting findings with file:line references" }
 func (t *SecurityAuditTool) IsDestructive() bool { return false }
 func (t *SecurityAuditTool) RequiresApproval() bool { return false }
 func (t *SecurityAuditTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-return "Security audit report generated", nil
+dir, _ := params["dir"].(string)
+if dir == "" {
+dir = "."
+}
+
+findings, err := auditProject(ctx, dir)
+if err != nil {
+return "", err
+}
+
+var report strings.Builder
+report.WriteString(fmt.Sprintf("Security audit of %s\n\nCode and configuration: ", dir))
+if len(findings) == 0 {
+report.WriteString("no issues found\n")
+} else {
+report.WriteString(fmt.Sprintf("%d issues\n%s\n", len(findings), formatAuditFindings(findings, false)))
+}
+
+report.WriteString("\nDependencies (" + owaspVulnComponents + "): ")
+scan, err := scanDependencies(ctx, dir)
+switch {
+case errors.Is(err, errNoScanner):
+report.WriteString(fmt.Sprintf("not scanned (%v)", err))
+case err != nil:
+report.WriteString(fmt.Sprintf("scan failed: %v", err))
+default:
+report.WriteString(scan.Format())
+}
+return report.String(), nil
+}
+
+// confidenceTailPattern matches a trailing {"confidence": 0.x} self-assessment
+var confidenceTailPattern = regexp.MustCompile(`(?s)\s*\{\s*"confidence"\s*:\s*([0-9]*\.?[0-9]+)\s*\}\s*$`)
+
+// defaultConfidence is used when neither routing nor the model supplied a score
+const defaultConfidence = 0.5
+
+// confidenceInstruction asks the model for a self-assessed confidence tail.
+// Streaming responses are shown to the user token by token, so the tail is
+// only requested for synchronous generation.
+func confidenceInstruction(request *Request) string {
+if request.StreamCallback != nil {
+return ""
+}
+return "\nAfter your answer, on its own final line, rate how confident you are in it as JSON: {\"confidence\": <0.0-1.0>}\n"
+}
+
+// deriveConfidence strips any self-assessed confidence tail from the answer and
+// combines it with the routing confidence into a single score in [0, 1]
+func deriveConfidence(request *Request, answer string) (string, float64) {
+selfAssessed := -1.0
+if m := confidenceTailPattern.FindStringSubmatchIndex(answer); m != nil {
+if v, err := strconv.ParseFloat(answer[m[2]:m[3]], 64); err == nil {
+selfAssessed = clampConfidence(v)
+answer = strings.TrimSpace(answer[:m[0]])
+}
+}
+
+routing := clampConfidence(request.RoutingConfidence)
+
+switch {
+case selfAssessed >= 0 && routing > 0:
+return answer, (selfAssessed + routing) / 2
+case selfAssessed >= 0:
+return answer, selfAssessed
+case routing > 0:
+return answer, routing
+default:
+return answer, defaultConfidence
+}
+}
+
+// clampConfidence limits a confidence score to [0, 1]
+func clampConfidence(v float64) float64 {
+if v < 0 {
+return 0
+}
+if v > 1 {
+return 1
+}
+return v
 }
 
 func countTokens(text string) int {
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestBuildPrompt tests that each agent's prompt carries the system instruction, memories and query
+func TestBuildPrompt(t *testing.T) {
+	request := &Request{
+		Query:    "How should I index the orders table?",
+		Memories: []*models.Memory{{Content: "Orders are queried by customer_id"}},
+	}
+
+	agents := []Agent{
+		NewCodeAgent(nil, nil),
+		NewDataAgent(nil, nil),
+		NewInfraAgent(nil, nil),
+		NewSecAgent(nil, nil),
+	}
+	for _, agent := range agents {
+		prompt := agent.BuildPrompt(request)
+		if !strings.HasSuffix(prompt, "Query: "+request.Query+"\n\nResponse:") {
+			t.Errorf("%s prompt does not end with the query:\n%s", agent.Name(), prompt)
+		}
+		if !strings.Contains(prompt, `{"confidence"`) {
+			t.Errorf("%s prompt missing confidence instruction", agent.Name())
+		}
+	}
+
+	for _, agent := range agents[1:3] {
+		if prompt := agent.BuildPrompt(request); !strings.Contains(prompt, "Orders are queried by customer_id") {
+			t.Errorf("%s prompt missing memories:\n%s", agent.Name(), prompt)
+		}
+	}
+
+	custom := NewSecAgent(nil, &AgentConfig{Name: "SecAgent", Type: models.AgentTypeSec, SystemPrompt: "Follow ACME security policy."})
+	if prompt := custom.BuildPrompt(request); !strings.HasPrefix(prompt, "Follow ACME security policy.") {
+		t.Errorf("Expected custom system prompt first, got:\n%s", prompt)
+	}
+}
+
+// TestCodeAgentExecute tests a full CodeAgent.Execute against a mock model,
+// synchronously and streaming
+func TestCodeAgentExecute(t *testing.T) {
+	mock := inference.NewMockGenerator(`Use a map for O(1) lookups. {"confidence": 0.8}`)
+	agent := NewCodeAgent(mock, nil)
+
+	request := &Request{Query: "How do I speed up this loop?", RoutingConfidence: 0.6, Temperature: 0.2}
+	response, err := agent.Execute(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if response.Answer != "Use a map for O(1) lookups." || math.Abs(response.Confidence-0.7) > 1e-9 {
+		t.Errorf("Unexpected response: %q at %.2f", response.Answer, response.Confidence)
+	}
+
+	calls := mock.Calls()
+	if len(calls) != 1 || !strings.Contains(calls[0].Prompt, request.Query) || calls[0].Options.Temperature != 0.2 {
+		t.Fatalf("Expected one call with the query and temperature, got %+v", calls)
+	}
+
+	var streamed strings.Builder
+	request.StreamCallback = func(token string) { streamed.WriteString(token) }
+	response, err = agent.Execute(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Streaming Execute failed: %v", err)
+	}
+	if !strings.HasPrefix(streamed.String(), "Use a map") || response.Metadata["streaming"] != true {
+		t.Errorf("Expected the answer streamed, got %q", streamed.String())
+	}
+
+	mock.Err = errors.New("model offline")
+	if _, err := agent.Execute(context.Background(), request); err == nil || !strings.Contains(err.Error(), "model offline") {
+		t.Errorf("Expected the model error, got %v", err)
+	}
+}
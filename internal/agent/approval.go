@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -21,66 +23,125 @@ func NewApprovalWorkflow(planner *Planner) *ApprovalWorkflow {
 	}
 }
 
-// RequestApproval displays a plan and requests user approval
+// RequestApproval displays a plan and requests user approval. Choosing edit
+// opens an interactive editor for reordering and deleting phases and rewording
+// tasks; an edited plan is saved and shown again for approval.
 func (a *ApprovalWorkflow) RequestApproval(ctx context.Context, plan *ExecutionPlan) (bool, error) {
-	// Display plan
-	fmt.Println("\n" + strings.Repeat("═", 60))
-	fmt.Printf("📋 PLAN REVIEW: %s\n", plan.Title)
-	fmt.Println(strings.Repeat("═", 60))
-	
-	markdown := a.planner.FormatAsMarkdown(plan)
-	fmt.Println(markdown)
-	
-	fmt.Println(strings.Repeat("═", 60))
-	fmt.Println("\n⚠️  This plan will be executed automatically.")
-	fmt.Println("Please review carefully before approving.\n")
-	
-	// Prompt for approval
-	fmt.Print("Approve execution? [y/N/e(dit)]: ")
+	reader := bufio.NewReader(os.Stdin)
 	
+	for {
+		// Display plan
+		fmt.Println("\n" + strings.Repeat("═", 60))
+		fmt.Printf("📋 PLAN REVIEW: %s\n", plan.Title)
+		fmt.Println(strings.Repeat("═", 60))
+		
+		markdown := a.planner.FormatAsMarkdown(plan)
+		fmt.Println(markdown)
+		
+		fmt.Println(strings.Repeat("═", 60))
+		fmt.Println("\n⚠️  This plan will be executed automatically.")
+		fmt.Print("Please review carefully before approving.\n\n")
+		
+		// Prompt for approval
+		fmt.Print("Approve execution? [y/N/e(dit)]: ")
+		
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return false, err
+		}
+		
+		response = strings.TrimSpace(strings.ToLower(response))
+		
+		switch response {
+		case "y", "yes":
+			plan.State.SetStatus(ExecutionStatusApproved)
+			return true, nil
+		case "e", "edit":
+			changed, err := editPlan(reader, plan)
+			if err != nil {
+				return false, err
+			}
+			if !changed {
+				continue
+			}
+			if len(plan.Phases) == 0 {
+				fmt.Print("\n⚠️  Every phase was deleted; nothing to execute.\n\n")
+				plan.State.SetStatus(ExecutionStatusCancelled)
+				return false, a.SavePlanState(plan)
+			}
+			if err := a.SavePlanState(plan); err != nil {
+				return false, fmt.Errorf("could not save edited plan: %w", err)
+			}
+			fmt.Println("💾 Edited plan saved.")
+		default:
+			plan.State.SetStatus(ExecutionStatusCancelled)
+			return false, nil
+		}
+	}
+}
+
+// RequestToolApproval asks the user to confirm a tool invocation that is
+// destructive or requires approval. detail describes what will run.
+func (a *ApprovalWorkflow) RequestToolApproval(ctx context.Context, tool Tool, detail string) (bool, error) {
+	label := "requires approval"
+	if tool.IsDestructive() {
+		label = "destructive"
+	}
+
+	fmt.Printf("\n⚠️  Tool %s (%s) wants to run:\n    %s\n", tool.Name(), label, detail)
+	fmt.Print("Allow? [y/N]: ")
+
 	reader := bufio.NewReader(os.Stdin)
 	response, err := reader.ReadString('\n')
 	if err != nil {
 		return false, err
 	}
-	
+
 	response = strings.TrimSpace(strings.ToLower(response))
-	
-	switch response {
-	case "y", "yes":
-		plan.State.Status = ExecutionStatusApproved
-		return true, nil
-	case "e", "edit":
-		// Future: Open plan in $EDITOR
-		fmt.Println("\n⚠️  Plan editing not yet implemented (coming soon!)")
-		fmt.Println("For now, you can manually edit the plan file and re-run.\n")
-		return false, nil
-	default:
-		plan.State.Status = ExecutionStatusCancelled
-		return false, nil
-	}
+	return response == "y" || response == "yes", nil
 }
 
-// SavePlanState saves plan state to disk for resumption
-func (a *ApprovalWorkflow) SavePlanState(plan *ExecutionPlan) error {
+// planStateDir returns the directory holding saved plan state
+func planStateDir() string {
 	homeDir, _ := os.UserHomeDir()
-	stateDir := fmt.Sprintf("%s/.quantumflow/state", homeDir)
+	return filepath.Join(homeDir, ".quantumflow", "state")
+}
+
+// manifestPath returns where a plan's project manifest is saved
+func manifestPath(planID string) string {
+	return filepath.Join(planStateDir(), planID+".manifest.json")
+}
+
+// SavePlanState saves plan state, and the project manifest if there is one,
+// to disk for resumption
+func (a *ApprovalWorkflow) SavePlanState(plan *ExecutionPlan) error {
+	stateDir := planStateDir()
 	os.MkdirAll(stateDir, 0755)
 	
-	stateFile := fmt.Sprintf("%s/%s.json", stateDir, plan.ID)
+	stateFile := filepath.Join(stateDir, plan.ID+".json")
 	
 	data, err := json.MarshalIndent(plan, "", "  ")
 	if err != nil {
 		return err
 	}
 	
-	return os.WriteFile(stateFile, data, 0644)
+	if err := os.WriteFile(stateFile, data, 0644); err != nil {
+		return err
+	}
+	
+	if plan.Manifest != nil {
+		if err := plan.Manifest.Save(manifestPath(plan.ID)); err != nil {
+			return fmt.Errorf("failed to save manifest: %w", err)
+		}
+	}
+	
+	return nil
 }
 
-// LoadPlanState loads a saved plan state
+// LoadPlanState loads a saved plan state along with its project manifest,
+// so files created before a restart are not generated again
 func (a *ApprovalWorkflow) LoadPlanState(planID string) (*ExecutionPlan, error) {
-	homeDir, _ := os.UserHomeDir()
-	stateFile := fmt.Sprintf("%s/.quantumflow/state/%s.json", homeDir, planID)
+	stateFile := filepath.Join(planStateDir(), planID+".json")
 	
 	data, err := os.ReadFile(stateFile)
 	if err != nil {
@@ -92,5 +153,12 @@ func (a *ApprovalWorkflow) LoadPlanState(planID string) (*ExecutionPlan, error)
 		return nil, err
 	}
 	
+	// Plans that never started have no manifest yet
+	manifest, err := LoadManifest(manifestPath(planID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load manifest: %w", err)
+	}
+	plan.Manifest = manifest
+	
 	return &plan, nil
 }
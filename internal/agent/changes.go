@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoExecutedPlans is returned when no plan has written any files yet
+var ErrNoExecutedPlans = errors.New("no executed plans found")
+
+// FileChange is a file a plan wrote, checked against the disk now
+type FileChange struct {
+	FileEntry
+	Missing     bool  // Deleted since the plan wrote it
+	CurrentSize int64 // Size on disk now
+}
+
+// Changes lists the files the manifest records, in the order they were
+// written, with their current state on disk
+func (m *ProjectManifest) Changes() []FileChange {
+	changes := make([]FileChange, len(m.CreatedFiles))
+	for i, f := range m.CreatedFiles {
+		changes[i] = FileChange{FileEntry: f}
+		info, err := os.Stat(m.Path(f.Path))
+		if err != nil {
+			changes[i].Missing = true
+			continue
+		}
+		changes[i].CurrentSize = info.Size()
+	}
+	return changes
+}
+
+// LastExecutedPlanID returns the plan whose manifest was saved most recently,
+// which is the last plan to execute
+func (a *ApprovalWorkflow) LastExecutedPlanID() (string, error) {
+	matches, err := filepath.Glob(filepath.Join(planStateDir(), "*.manifest.json"))
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	var latestMod int64
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if mod := info.ModTime().UnixNano(); latest == "" || mod > latestMod {
+			latest, latestMod = path, mod
+		}
+	}
+	if latest == "" {
+		return "", ErrNoExecutedPlans
+	}
+	return strings.TrimSuffix(filepath.Base(latest), ".manifest.json"), nil
+}
+
+// GitChanges describes how the manifest's files differ from the git HEAD of
+// the project directory: a status and diffstat, or the full diff when full is
+// set. It fails when the project isn't in a git repository.
+func (m *ProjectManifest) GitChanges(ctx context.Context, full bool) (string, error) {
+	dir := m.BaseDir
+	if dir == "" {
+		dir = "."
+	}
+	if _, err := gitOutput(dir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return "", fmt.Errorf("%s is not in a git repository", dir)
+	}
+
+	paths := make([]string, 0, len(m.CreatedFiles))
+	for _, f := range m.CreatedFiles {
+		paths = append(paths, f.Path)
+	}
+	if len(paths) == 0 {
+		return "", nil
+	}
+
+	// New files are untracked, so only status shows them
+	var args [][]string
+	if full {
+		args = [][]string{{"diff", "HEAD"}}
+	} else {
+		args = [][]string{{"status", "--short"}, {"diff", "--stat", "HEAD"}}
+	}
+
+	var output strings.Builder
+	for _, gitArgs := range args {
+		cmd := exec.CommandContext(ctx, "git", append(append(gitArgs, "--"), paths...)...)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %w", gitArgs[0], err)
+		}
+		if text := strings.TrimRight(string(out), "\n"); text != "" {
+			output.WriteString(text + "\n")
+		}
+	}
+	return output.String(), nil
+}
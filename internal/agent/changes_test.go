@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLastExecutedPlanChanges(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	approval := NewApprovalWorkflow(nil)
+
+	if _, err := approval.LastExecutedPlanID(); err != ErrNoExecutedPlans {
+		t.Errorf("Expected ErrNoExecutedPlans, got %v", err)
+	}
+
+	for _, id := range []string{"plan_old", "plan_new"} {
+		plan := &ExecutionPlan{ID: id, Manifest: NewProjectManifest(id, dir)}
+		if err := os.WriteFile(filepath.Join(dir, id+".txt"), []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		plan.Manifest.AddFile(id+".txt", "Setup", "")
+		plan.Manifest.AddFile("gone.txt", "Setup", "")
+		if err := approval.SavePlanState(plan); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond) // Distinct modification times
+	}
+
+	id, err := approval.LastExecutedPlanID()
+	if err != nil || id != "plan_new" {
+		t.Fatalf("Expected plan_new, got %q, %v", id, err)
+	}
+
+	plan, err := approval.LoadPlanState(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := plan.Manifest.Changes()
+	if len(changes) != 2 || changes[0].Missing || changes[0].CurrentSize != 5 || !changes[1].Missing {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+}
+
+func TestGitChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	manifest := NewProjectManifest("git", dir)
+	if _, err := manifest.GitChanges(context.Background(), false); err == nil {
+		t.Error("Expected an error outside a git repository")
+	}
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+	git("init", "-q")
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	git("add", ".")
+	git("commit", "-qm", "init")
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0644)
+	manifest.AddFile("main.go", "Build", "")
+	manifest.AddFile("new.go", "Build", "")
+
+	summary, err := manifest.GitChanges(context.Background(), false)
+	if err != nil {
+		t.Fatalf("GitChanges failed: %v", err)
+	}
+	if !strings.Contains(summary, "?? new.go") || !strings.Contains(summary, "main.go | 2 ++") {
+		t.Errorf("Expected status and diffstat, got:\n%s", summary)
+	}
+
+	diff, err := manifest.GitChanges(context.Background(), true)
+	if err != nil || !strings.Contains(diff, "+func main() {}") {
+		t.Errorf("Expected the full diff, got %v:\n%s", err, diff)
+	}
+}
@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Checkpoint metadata keys
+const (
+	checkpointGitHead      = "git_head"
+	checkpointCreatedFiles = "created_files"
+)
+
+// checkpointDir returns the directory holding a plan's saved checkpoints
+func checkpointDir(planID string) string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".quantumflow", "checkpoints", planID)
+}
+
+// createCheckpoint records the project state before a phase runs: the git
+// HEAD, a snapshot of uncommitted changes, and the files created so far.
+// A phase resumed partway through keeps the checkpoint from its first attempt.
+func (e *Executor) createCheckpoint(plan *ExecutionPlan, phaseIndex int) (*Checkpoint, error) {
+	id := fmt.Sprintf("checkpoint-%s-phase-%d", plan.ID, phaseIndex)
+
+	for _, task := range plan.Phases[phaseIndex].Tasks {
+		if task.Completed {
+			if existing, err := readCheckpoint(filepath.Join(checkpointDir(plan.ID), id+".json")); err == nil {
+				return existing, nil
+			}
+			break
+		}
+	}
+
+	checkpoint := &Checkpoint{
+		ID:         id,
+		PlanID:     plan.ID,
+		PhaseIndex: phaseIndex,
+		Timestamp:  time.Now(),
+		Metadata:   make(map[string]string),
+	}
+
+	// Git snapshot; outside a repository only the manifest is tracked.
+	// stash create records uncommitted changes without touching the working
+	// tree and prints nothing when there are none.
+	dir := e.projectDir(plan)
+	if head, err := gitOutput(dir, "rev-parse", "HEAD"); err == nil {
+		if stash, err := gitOutput(dir, "stash", "create"); err == nil {
+			checkpoint.Metadata[checkpointGitHead] = head
+			checkpoint.GitStash = stash
+		}
+	}
+
+	if plan.Manifest != nil {
+		paths := make([]string, len(plan.Manifest.CreatedFiles))
+		for i, f := range plan.Manifest.CreatedFiles {
+			paths[i] = f.Path
+		}
+		checkpoint.Metadata[checkpointCreatedFiles] = strings.Join(paths, "\n")
+	}
+
+	if err := saveCheckpoint(checkpoint); err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// ListCheckpoints returns the saved checkpoints for a plan, oldest phase first
+func (e *Executor) ListCheckpoints(planID string) ([]*Checkpoint, error) {
+	dir := checkpointDir(planID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var checkpoints []*Checkpoint
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		checkpoint, err := readCheckpoint(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue // Skip unreadable checkpoints
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].PhaseIndex < checkpoints[j].PhaseIndex
+	})
+
+	return checkpoints, nil
+}
+
+// Rollback restores the project and plan to a checkpoint with restoreCheckpoint,
+// and the plan resumes from the checkpoint's phase on the next /execute.
+func (e *Executor) Rollback(ctx context.Context, checkpointID string) (*ExecutionPlan, error) {
+	checkpoint, err := loadCheckpoint(checkpointID)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint %s not found: %w", checkpointID, err)
+	}
+
+	if e.approval == nil {
+		return nil, fmt.Errorf("no plan store available")
+	}
+	plan, err := e.approval.LoadPlanState(checkpoint.PlanID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load plan %s: %w", checkpoint.PlanID, err)
+	}
+
+	if err := e.restoreCheckpoint(ctx, plan, checkpoint); err != nil {
+		return nil, err
+	}
+	if err := e.approval.SavePlanState(plan); err != nil {
+		return nil, fmt.Errorf("could not save plan state: %w", err)
+	}
+
+	return plan, nil
+}
+
+// restoreCheckpoint undoes the plan's file changes since a checkpoint. Only
+// files in the plan's manifest are touched: those in the git snapshot are
+// restored from it, and those created after the checkpoint are otherwise
+// removed. Other work in the project directory is left alone.
+func (e *Executor) restoreCheckpoint(ctx context.Context, plan *ExecutionPlan, checkpoint *Checkpoint) error {
+	snapshot := checkpoint.GitStash
+	if snapshot == "" {
+		// The working tree was clean, so HEAD is the snapshot
+		snapshot = checkpoint.Metadata[checkpointGitHead]
+	}
+
+	if plan.Manifest != nil {
+		keep := make(map[string]bool)
+		for _, path := range strings.Split(checkpoint.Metadata[checkpointCreatedFiles], "\n") {
+			if path != "" {
+				keep[path] = true
+			}
+		}
+
+		dir := e.projectDir(plan)
+		var restore []string
+		kept := plan.Manifest.CreatedFiles[:0]
+		for _, f := range plan.Manifest.CreatedFiles {
+			tracked := snapshot != "" && gitTracked(ctx, dir, snapshot, f.Path)
+			if tracked {
+				restore = append(restore, f.Path)
+			}
+			if keep[f.Path] {
+				kept = append(kept, f)
+				continue
+			}
+			if tracked {
+				continue
+			}
+			if err := os.Remove(plan.Manifest.Path(f.Path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				fmt.Printf("⚠️  Could not remove %s: %v\n", f.Path, err)
+				kept = append(kept, f)
+			}
+		}
+		plan.Manifest.CreatedFiles = kept
+
+		if len(restore) > 0 {
+			cmd := exec.CommandContext(ctx, "git", append([]string{"checkout", snapshot, "--"}, restore...)...)
+			cmd.Dir = dir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("git restore failed: %w: %s", err, strings.TrimSpace(string(output)))
+			}
+		}
+	}
+
+	rewindPlan(plan, checkpoint.PhaseIndex)
+	plan.State.SetCheckpoint(checkpoint.ID)
+	return nil
+}
+
+// gitTracked reports whether path, relative to dir, exists in a git snapshot
+func gitTracked(ctx context.Context, dir, snapshot, path string) bool {
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "-e", snapshot+":./"+filepath.ToSlash(path))
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+// rewindPlan marks every phase from phaseIndex onward as not yet run
+func rewindPlan(plan *ExecutionPlan, phaseIndex int) {
+	plan.State.Rewind(phaseIndex)
+
+	for i := phaseIndex; i < len(plan.Phases); i++ {
+		plan.Phases[i].Status = PhaseStatusPending
+		plan.Phases[i].StartedAt = nil
+		plan.Phases[i].CompletedAt = nil
+		for j := range plan.Phases[i].Tasks {
+			plan.Phases[i].Tasks[j].Completed = false
+			plan.Phases[i].Tasks[j].Result = ""
+			plan.Phases[i].Tasks[j].Error = ""
+		}
+	}
+}
+
+// phasesBefore keeps the phase indexes lower than limit
+func phasesBefore(phases []int, limit int) []int {
+	kept := []int{}
+	for _, p := range phases {
+		if p < limit {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// saveCheckpoint writes a checkpoint to its plan's checkpoint directory
+func saveCheckpoint(checkpoint *Checkpoint) error {
+	dir := checkpointDir(checkpoint.PlanID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, checkpoint.ID+".json"), data, 0644)
+}
+
+// loadCheckpoint finds a checkpoint by ID across all plans
+func loadCheckpoint(id string) (*Checkpoint, error) {
+	if id == "" || strings.ContainsAny(id, `/\*?[`) {
+		return nil, fmt.Errorf("invalid checkpoint ID %q", id)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(checkpointDir("*"), id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, os.ErrNotExist
+	}
+
+	return readCheckpoint(matches[0])
+}
+
+// readCheckpoint decodes a checkpoint file
+func readCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	if checkpoint.Metadata == nil {
+		checkpoint.Metadata = make(map[string]string)
+	}
+
+	return &checkpoint, nil
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
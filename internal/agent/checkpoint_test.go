@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestRestoreCheckpoint tests that rolling back restores and removes only
+// the plan's files, leaving other uncommitted work alone
+func TestRestoreCheckpoint(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("main.go", "package main\n")
+	write("notes.txt", "committed\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	write("main.go", "package main // uncommitted\n")
+
+	plan := &ExecutionPlan{
+		ID:       "plan_rollback",
+		Phases:   []Phase{{ID: "phase-1", Name: "Build"}},
+		Manifest: NewProjectManifest("rollback", dir),
+	}
+	plan.Manifest.AddFile("main.go", "Setup", "")
+
+	executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+	checkpoint, err := executor.createCheckpoint(plan, 0)
+	if err != nil {
+		t.Fatalf("createCheckpoint failed: %v", err)
+	}
+
+	// The phase rewrites main.go and adds a file; the user edits notes.txt
+	write("main.go", "package broken\n")
+	write("extra.go", "package main\n")
+	plan.Manifest.AddFile("extra.go", "Build", "")
+	write("notes.txt", "user edit\n")
+	plan.Phases[0].Status = PhaseStatusFailed
+
+	if err := executor.restoreCheckpoint(context.Background(), plan, checkpoint); err != nil {
+		t.Fatalf("restoreCheckpoint failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(data) != "package main // uncommitted\n" {
+		t.Errorf("Expected main.go restored with its uncommitted change, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extra.go")); !os.IsNotExist(err) {
+		t.Errorf("Expected extra.go removed, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "notes.txt")); string(data) != "user edit\n" {
+		t.Errorf("Expected notes.txt left alone, got %q", data)
+	}
+	if len(plan.Manifest.CreatedFiles) != 1 || plan.Phases[0].Status != PhaseStatusPending {
+		t.Errorf("Expected manifest and phase rewound, got %+v, %s", plan.Manifest.CreatedFiles, plan.Phases[0].Status)
+	}
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -13,12 +14,12 @@ import (
 
 // QuantumRouter uses LLM-based reasoning to route queries to appropriate agents
 type QuantumRouter struct {
-	client *inference.Client
+	client inference.Generator
 	cache  *RoutingCache
 }
 
 // NewQuantumRouter creates a new LLM-based router with caching
-func NewQuantumRouter(client *inference.Client) *QuantumRouter {
+func NewQuantumRouter(client inference.Generator) *QuantumRouter {
 	return &QuantumRouter{
 		client: client,
 		cache:  NewRoutingCache(5 * time.Minute), // 5 minute TTL
@@ -36,46 +37,93 @@ ToolsNeeded    []string `json:"tools_needed,omitempty"`
 
 // Classify uses LLM to intelligently route queries
 func (r *QuantumRouter) Classify(ctx context.Context, query string) (models.AgentType, float64, error) {
+	route, err := r.decide(ctx, query)
+	if err != nil {
+		return "", 0, err
+	}
+	return route.AgentType, route.Confidence, nil
+}
+
+// decide returns the routing decision for a query, asking the LLM only on a cache miss
+func (r *QuantumRouter) decide(ctx context.Context, query string) (*CachedRoute, error) {
 	// Check cache first (avoids LLM call for repeated/similar queries)
 	if cached, ok := r.cache.Get(query); ok {
-		return cached.AgentType, cached.Confidence, nil
+		return cached, nil
 	}
 
 	prompt := r.buildRoutingPrompt(query)
 
-	result, err := r.client.GenerateSync(ctx, prompt)
+	result, err := r.client.GenerateSyncWithOptions(ctx, prompt, deterministic)
 	if err != nil {
-		return "", 0, fmt.Errorf("routing failed: %w", err)
+		return nil, fmt.Errorf("routing failed: %w", err)
 	}
 
 var decision RoutingDecision
-if err := r.parseRoutingResponse(result.Response, &decision); err != nil {
-return "", 0, fmt.Errorf("failed to parse routing decision: %w", err)
+err = r.parseRoutingResponse(result.Response, &decision)
+if errors.Is(err, ErrMalformedJSON) {
+// A truncated decision gets one retry with a minimal prompt
+result, err = r.client.GenerateSyncWithOptions(ctx, r.buildShortRoutingPrompt(query), deterministic)
+if err != nil {
+return nil, fmt.Errorf("routing failed: %w", err)
+}
+err = r.parseRoutingResponse(result.Response, &decision)
+}
+if err != nil {
+return nil, fmt.Errorf("failed to parse routing decision: %w", err)
 }
 
 // Normalize agent type from LLM response (includes fallback)
-agentType := normalizeAgentType(decision.PrimaryAgent)
+route := &CachedRoute{
+AgentType:  normalizeAgentType(decision.PrimaryAgent),
+Confidence: decision.Confidence,
+Reasoning:  decision.Reasoning,
+}
+if decision.SecondaryAgent != "" {
+if secondary := normalizeAgentType(decision.SecondaryAgent); secondary != route.AgentType {
+route.SecondaryAgent = secondary
+}
+}
 
 // Cache the result for future queries
-r.cache.Set(query, agentType, decision.Confidence)
+r.cache.Set(query, route)
+
+return route, nil
+}
 
-return agentType, decision.Confidence, nil
+// Explain returns the most recent routing decision for a query, if one is cached
+func (r *QuantumRouter) Explain(query string) (*CachedRoute, bool) {
+return r.cache.Get(query)
 }
 
 // ClassifyMulti returns top-k agent classifications
 func (r *QuantumRouter) ClassifyMulti(ctx context.Context, query string, k int) ([]Classification, error) {
-agentType, confidence, err := r.Classify(ctx, query)
+route, err := r.decide(ctx, query)
 if err != nil {
 return nil, err
 }
 
-return []Classification{
+reasoning := route.Reasoning
+if reasoning == "" {
+reasoning = fmt.Sprintf("Selected %s via LLM routing", route.AgentType)
+}
+
+classifications := []Classification{
 {
-AgentType:  agentType,
-Confidence: confidence,
-Reasoning:  fmt.Sprintf("Selected %s via LLM routing", agentType),
+AgentType:  route.AgentType,
+Confidence: route.Confidence,
+Reasoning:  reasoning,
 },
-}, nil
+}
+// The router only scores its primary pick; the secondary gets the remainder
+if k > 1 && route.SecondaryAgent != "" {
+classifications = append(classifications, Classification{
+AgentType:  route.SecondaryAgent,
+Confidence: 1 - route.Confidence,
+Reasoning:  fmt.Sprintf("Secondary choice after %s", route.AgentType),
+})
+}
+
+return classifications, nil
 }
 
 // buildRoutingPrompt creates the prompt for LLM-based routing
@@ -101,39 +149,31 @@ Respond with ONLY a JSON object:
 {
   "primary_agent": "code|data|infra|sec",
   "confidence": 0.0-1.0,
-  "reasoning": "brief explanation"
+  "reasoning": "brief explanation",
+  "secondary_agent": "code|data|infra|sec (optional, next best fit)"
 }
 
 JSON Response:`, query)
 }
 
-// parseRoutingResponse extracts the routing decision from LLM output
-func (r *QuantumRouter) parseRoutingResponse(response string, decision *RoutingDecision) error {
-response = strings.TrimSpace(response)
-
-// Remove markdown code blocks
-if strings.HasPrefix(response, "```json") {
-response = strings.TrimPrefix(response, "```json")
-response = strings.TrimSuffix(response, "```")
-response = strings.TrimSpace(response)
-} else if strings.HasPrefix(response, "```") {
-response = strings.TrimPrefix(response, "```")
-response = strings.TrimSuffix(response, "```")
-response = strings.TrimSpace(response)
+// buildShortRoutingPrompt is a minimal routing prompt for retrying after a
+// truncated response
+func (r *QuantumRouter) buildShortRoutingPrompt(query string) string {
+return fmt.Sprintf(`Pick the agent for this query: code, data, infra or sec.
+Query: %s
+Respond with ONLY: {"primary_agent":"code","confidence":0.8,"reasoning":"a few words"}
+JSON:`, query)
 }
 
-// Find JSON object
-start := strings.Index(response, "{")
-end := strings.LastIndex(response, "}")
-
-if start == -1 || end == -1 {
-return fmt.Errorf("no JSON object found in response")
+// parseRoutingResponse extracts the routing decision from LLM output
+func (r *QuantumRouter) parseRoutingResponse(response string, decision *RoutingDecision) error {
+jsonStr, _, err := extractJSON(response)
+if err != nil {
+return err
 }
 
-jsonStr := response[start : end+1]
-
 if err := json.Unmarshal([]byte(jsonStr), decision); err != nil {
-return fmt.Errorf("JSON parse error: %w", err)
+return fmt.Errorf("%w: %w", ErrMalformedJSON, err)
 }
 
 // Clamp confidence
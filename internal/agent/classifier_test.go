@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestParseRoutingResponse tests parsing raw model output into a decision,
+// including confidence clamping
+func TestParseRoutingResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		agent      string
+		confidence float64
+		err        error
+	}{
+		{"clean", `{"primary_agent": "data", "confidence": 0.9, "reasoning": "SQL"}`, "data", 0.9, nil},
+		{"fenced", "```json\n{\"primary_agent\": \"infra\", \"confidence\": 0.8}\n```", "infra", 0.8, nil},
+		{"bare fence", "```\n{\"primary_agent\": \"sec\", \"confidence\": 0.7}\n```", "sec", 0.7, nil},
+		{"prose around", `Sure! Here is my decision: {"primary_agent": "code", "confidence": 0.6} Hope that helps.`, "code", 0.6, nil},
+		{"confidence above one", `{"primary_agent": "data", "confidence": 1.7}`, "data", 1, nil},
+		{"negative confidence", `{"primary_agent": "data", "confidence": -0.2}`, "data", 0, nil},
+		{"truncated", `{"primary_agent": "infra", "confidence": 0.9, "reasoning": "docker compo`, "infra", 0.9, nil},
+		{"no json", "I would pick the data agent.", "", 0, ErrNoJSON},
+		{"string confidence", `{"primary_agent": "data", "confidence": "high"}`, "", 0, ErrMalformedJSON},
+	}
+
+	router := NewQuantumRouter(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decision RoutingDecision
+			err := router.parseRoutingResponse(tt.response, &decision)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Expected %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if decision.PrimaryAgent != tt.agent || decision.Confidence != tt.confidence {
+				t.Errorf("Expected %s at %.1f, got %s at %.1f", tt.agent, tt.confidence, decision.PrimaryAgent, decision.Confidence)
+			}
+		})
+	}
+}
+
+// TestNormalizeAgentType tests agent name normalization and the fallback to
+// the code agent
+func TestNormalizeAgentType(t *testing.T) {
+	tests := map[string]models.AgentType{
+		"code":       models.AgentTypeCode,
+		" Data ":     models.AgentTypeData,
+		"InfraAgent": models.AgentTypeInfra,
+		"security":   models.AgentTypeSec,
+		"SEC":        models.AgentTypeSec,
+		"general":    models.AgentTypeCode,
+		"none":       models.AgentTypeCode,
+		"":           models.AgentTypeCode,
+	}
+	for input, want := range tests {
+		if got := normalizeAgentType(input); got != want {
+			t.Errorf("normalizeAgentType(%q) = %s, want %s", input, got, want)
+		}
+	}
+}
+
+// TestClassify tests routing raw model output end to end through a mock model
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []string
+		agent      models.AgentType
+		confidence float64
+		calls      int
+		wantErr    bool
+	}{
+		{"clean", []string{`{"primary_agent": "data", "confidence": 0.9}`}, models.AgentTypeData, 0.9, 1, false},
+		{"fenced", []string{"```json\n{\"primary_agent\": \"sec\", \"confidence\": 0.75}\n```"}, models.AgentTypeSec, 0.75, 1, false},
+		{"prose", []string{`Routing to infra: {"primary_agent": "infra", "confidence": 0.8}`}, models.AgentTypeInfra, 0.8, 1, false},
+		{"general falls back to code", []string{`{"primary_agent": "general", "confidence": 0.4}`}, models.AgentTypeCode, 0.4, 1, false},
+		{"none falls back to code", []string{`{"primary_agent": "none", "confidence": 0.2}`}, models.AgentTypeCode, 0.2, 1, false},
+		{"clamped", []string{`{"primary_agent": "data", "confidence": 5}`}, models.AgentTypeData, 1, 1, false},
+		{"truncated is repaired", []string{`{"primary_agent": "data", "confidence": 0.7, "reasoning": "quer`}, models.AgentTypeData, 0.7, 1, false},
+		{"malformed is retried", []string{`{"primary_agent": "data", "confidence": "high"}`, `{"primary_agent": "data", "confidence": 0.6}`}, models.AgentTypeData, 0.6, 2, false},
+		{"no json fails", []string{"The data agent, probably."}, "", 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := inference.NewMockGenerator(tt.responses...)
+			router := NewQuantumRouter(mock)
+
+			agent, confidence, err := router.Classify(context.Background(), "route me")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got %s", agent)
+				}
+			} else if err != nil || agent != tt.agent || confidence != tt.confidence {
+				t.Fatalf("Expected %s at %.2f, got %s at %.2f, %v", tt.agent, tt.confidence, agent, confidence, err)
+			}
+
+			calls := mock.Calls()
+			if len(calls) != tt.calls {
+				t.Fatalf("Expected %d model calls, got %d", tt.calls, len(calls))
+			}
+			if tt.calls == 2 && !strings.Contains(calls[1].Prompt, "Pick the agent") {
+				t.Errorf("Expected the retry to use the short prompt, got %q", calls[1].Prompt)
+			}
+		})
+	}
+}
+
+// TestClassifyMultiCaches tests the secondary agent, that a secondary equal
+// to the primary is dropped, and that repeated queries hit the cache
+func TestClassifyMultiCaches(t *testing.T) {
+	mock := inference.NewMockGenerator(`{"primary_agent": "data", "confidence": 0.7, "secondary_agent": "code"}`)
+	router := NewQuantumRouter(mock)
+	ctx := context.Background()
+
+	classifications, err := router.ClassifyMulti(ctx, "index the orders table", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(classifications) != 2 || classifications[1].AgentType != models.AgentTypeCode || classifications[1].Confidence < 0.29 || classifications[1].Confidence > 0.31 {
+		t.Errorf("Unexpected classifications: %+v", classifications)
+	}
+	if _, _, err := router.Classify(ctx, "index the orders table"); err != nil || len(mock.Calls()) != 1 {
+		t.Errorf("Expected the repeated query served from the cache, got %d calls, %v", len(mock.Calls()), err)
+	}
+
+	same := NewQuantumRouter(inference.NewMockGenerator(`{"primary_agent": "sec", "confidence": 0.9, "secondary_agent": "security"}`))
+	if route, err := same.decide(ctx, "audit auth"); err != nil || route.SecondaryAgent != "" {
+		t.Errorf("Expected a secondary equal to the primary dropped, got %+v, %v", route, err)
+	}
+}
+
+// TestRoutingIsDeterministic tests that routing through a client at the
+// default temperature samples at 0, so the response cache answers repeats
+func TestRoutingIsDeterministic(t *testing.T) {
+	var temperatures []float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req inference.GenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		temperatures = append(temperatures, req.Options["temperature"].(float64))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"response": `{"primary_agent": "data", "confidence": 0.9}`,
+			"done":     true,
+		})
+	}))
+	defer server.Close()
+
+	config := inference.DefaultConfig()
+	config.OllamaURL = server.URL
+	config.CacheTTL = time.Minute
+	client := inference.NewClient(config)
+
+	// Separate routers, so the second isn't answered by the routing cache
+	for i := 0; i < 2; i++ {
+		agent, _, err := NewQuantumRouter(client).Classify(context.Background(), "top customers by revenue")
+		if err != nil || agent != models.AgentTypeData {
+			t.Fatalf("Expected the data agent, got %s, %v", agent, err)
+		}
+	}
+	if len(temperatures) != 1 || temperatures[0] != 0 {
+		t.Errorf("Expected one model call at temperature 0, got %v", temperatures)
+	}
+}
@@ -13,14 +13,17 @@ import (
 "github.com/quantumflow/quantumflow/internal/models"
 )
 
+// defaultCodeSystemPrompt is used when AgentConfig.SystemPrompt is empty
+const defaultCodeSystemPrompt = "You are a code expert assistant. Provide accurate, well-structured code solutions."
+
 type CodeAgent struct {
 name   string
-client *inference.Client
+client inference.Generator
 tools  []Tool
 config *AgentConfig
 }
 
-func NewCodeAgent(client *inference.Client, config *AgentConfig) *CodeAgent {
+func NewCodeAgent(client inference.Generator, config *AgentConfig) *CodeAgent {
 if config == nil {
 config = &AgentConfig{
 Name:           "CodeAgent",
@@ -38,28 +41,37 @@ return &CodeAgent{
 name:   config.Name,
 client: client,
 config: config,
-tools: []Tool{
+tools: configuredTools(config, []Tool{
 &ASTParserTool{},
 &CodeSearchTool{},
 &LintTool{},
-},
+&RunTestsTool{},
+}),
 }
 }
 
+// AddTool adds a custom tool the agent exposes alongside its built-in ones
+func (a *CodeAgent) AddTool(tool Tool) error {
+tools, err := addTool(a.tools, tool)
+a.tools = tools
+return err
+}
+
 func (a *CodeAgent) Name() string           { return a.name }
 func (a *CodeAgent) Type() models.AgentType { return models.AgentTypeCode }
 func (a *CodeAgent) GetTools() []Tool       { return a.tools }
+func (a *CodeAgent) Config() *AgentConfig    { return a.config }
 
 func (a *CodeAgent) Execute(ctx context.Context, request *Request) (*Response, error) {
 start := time.Now()
-prompt := a.buildPrompt(request)
+prompt := a.BuildPrompt(request)
 
 var fullResponse string
 
 if request.StreamCallback != nil {
 // Streaming mode with efficient string building
 var responseBuilder strings.Builder
-tokenChan, err := a.client.Generate(ctx, prompt, true)
+tokenChan, err := a.client.GenerateWithOptions(ctx, prompt, true, generateOptions(request))
 if err != nil {
 return nil, fmt.Errorf("generation failed: %w", err)
 }
@@ -71,21 +83,23 @@ request.StreamCallback(token)
 fullResponse = responseBuilder.String()
 } else {
 // Synchronous mode
-result, err := a.client.GenerateSync(ctx, prompt)
+result, err := a.client.GenerateSyncWithOptions(ctx, prompt, generateOptions(request))
 if err != nil {
 return nil, fmt.Errorf("generation failed: %w", err)
 }
 fullResponse = result.Response
 }
 
+answer, confidence := deriveConfidence(request, fullResponse)
+
 return &Response{
 AgentName:  a.name,
 AgentType:  a.Type(),
-Answer:     fullResponse,
+Answer:     answer,
 ToolCalls:  []models.ToolCall{},
-Confidence: 0.9,
+Confidence: confidence,
 Duration:   time.Since(start),
-TokensUsed: countTokens(fullResponse),
+TokensUsed: countTokens(answer),
 Metadata: map[string]interface{}{
 "streaming": request.StreamCallback != nil,
 },
@@ -109,9 +123,9 @@ matchCount++
 return float64(matchCount) / float64(len(codeKeywords)), nil
 }
 
-func (a *CodeAgent) buildPrompt(request *Request) string {
+func (a *CodeAgent) BuildPrompt(request *Request) string {
 var prompt strings.Builder
-prompt.WriteString("You are a code expert assistant. Provide accurate, well-structured code solutions.\n\n")
+prompt.WriteString(systemPrompt(a.config, defaultCodeSystemPrompt) + "\n\n")
 
 if request.Context != nil {
 if request.Context.CurrentDir != "" {
@@ -131,6 +145,7 @@ prompt.WriteString(fmt.Sprintf("- %s\n", truncate(mem.Content, 100)))
 prompt.WriteString("\n")
 }
 
+prompt.WriteString(confidenceInstruction(request))
 prompt.WriteString(fmt.Sprintf("Query: %s\n\nResponse:", request.Query))
 return prompt.String()
 }
@@ -198,3 +213,42 @@ return fmt.Sprintf("Found %d issues:\n%s", len(issues), strings.Join(issues, "\n
 }
 func (t *LintTool) IsDestructive() bool     { return false }
 func (t *LintTool) RequiresApproval() bool { return false }
+
+// RunTestsTool runs a project's test suite and linter, chosen from its
+// manifest (go.mod, package.json, requirements.txt or pyproject.toml)
+type RunTestsTool struct{}
+
+func (t *RunTestsTool) Name() string { return "run_tests" }
+func (t *RunTestsTool) Description() string { return "Run the project's tests and linter" }
+func (t *RunTestsTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+dir, _ := params["dir"].(string)
+if dir == "" {
+dir = "."
+}
+
+checks, ok := runChecks(ctx, dir, nil, defaultCheckTimeout)
+if !ok {
+return "", fmt.Errorf("no go.mod, package.json, requirements.txt or pyproject.toml in %s", dir)
+}
+
+var report strings.Builder
+for _, check := range []*CheckResult{checks.Tests, checks.Lint} {
+status := "passed"
+if check.Skipped {
+status = "skipped"
+} else if !check.Passed {
+status = "failed"
+}
+report.WriteString(fmt.Sprintf("$ %s: %s\n", check.Command, status))
+if check.Output != "" && !check.Passed {
+report.WriteString(check.Output + "\n")
+}
+}
+
+if !checks.TestsPassed() {
+return report.String(), fmt.Errorf("tests failed")
+}
+return report.String(), nil
+}
+func (t *RunTestsTool) IsDestructive() bool     { return false }
+func (t *RunTestsTool) RequiresApproval() bool { return false }
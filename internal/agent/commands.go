@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCommandTimeout bounds each generated shell command
+const defaultCommandTimeout = 2 * time.Minute
+
+// maxCommandOutput is how much of a command's output is kept in the task
+// result, from the end
+const maxCommandOutput = 2000
+
+// commandWaitDelay is how long to wait for output after a command is killed,
+// in case a child it started still holds the output pipes
+const commandWaitDelay = 5 * time.Second
+
+// CommandResult is the outcome of one generated shell command
+type CommandResult struct {
+	Command  string
+	Output   string // Combined stdout and stderr, truncated from the start
+	TimedOut bool
+	Err      error
+}
+
+// longRunningPatterns match commands that start servers or watchers, which
+// never exit on their own
+var longRunningPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(npm|yarn|pnpm)\s+(start|run\s+(dev|start|serve|watch))\b`),
+	regexp.MustCompile(`\b(yarn|pnpm)\s+(dev|serve|watch)\b`),
+	regexp.MustCompile(`\b(nodemon|uvicorn|gunicorn|http-server|live-server)\b`),
+	regexp.MustCompile(`\bnode\s+\S*(server|app)\.js\b`),
+	regexp.MustCompile(`\bpython3?\s+(-m\s+http\.server|\S*(app|server)\.py\b|\S*manage\.py\s+runserver)`),
+	regexp.MustCompile(`\bflask\s+run\b`),
+	regexp.MustCompile(`\brails\s+(s|server)\b`),
+	regexp.MustCompile(`\bdocker[- ]compose\s+up\b`),
+	regexp.MustCompile(`\btail\s+-f\b`),
+	regexp.MustCompile(`^watch\s`),
+}
+
+// detachedPattern matches flags that make a long-running command return
+var detachedPattern = regexp.MustCompile(`\s(-d|--detach)\b`)
+
+// isLongRunningCommand reports whether a command is likely a server or
+// watcher, or backgrounds itself with a trailing &
+func isLongRunningCommand(command string) bool {
+	command = strings.TrimSpace(command)
+	if strings.HasSuffix(command, "&") && !strings.HasSuffix(command, "&&") {
+		return true
+	}
+	for _, pattern := range longRunningPatterns {
+		if pattern.MatchString(command) {
+			return !detachedPattern.MatchString(command)
+		}
+	}
+	return false
+}
+
+// runCommand runs a shell command in dir, streaming its output to the
+// terminal while capturing it. On timeout the command's whole process group
+// is killed, so children it started don't outlive it.
+func runCommand(ctx context.Context, command, dir string, env []string, timeout time.Duration) CommandResult {
+	result := runProcess(ctx, []string{"bash", "-c", command}, dir, env, timeout, true)
+	result.Command = command
+	result.Output = tail(result.Output, maxCommandOutput)
+	return result
+}
+
+// runProcess runs args in dir with env (nil inherits ours), bounded by
+// timeout, killing its process group when it ends early. Its combined output
+// is captured in full and, with echo, also streamed to the terminal.
+func runProcess(ctx context.Context, args []string, dir string, env []string, timeout time.Duration, echo bool) CommandResult {
+	result := CommandResult{Command: strings.Join(args, " ")}
+
+	cmdCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		cmdCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// exec copies stdout and stderr on separate goroutines, so the shared
+	// capture buffer is locked
+	output := &lockedBuffer{}
+	cmd := exec.CommandContext(cmdCtx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	if echo {
+		cmd.Stdout = io.MultiWriter(os.Stdout, output)
+		cmd.Stderr = io.MultiWriter(os.Stderr, output)
+	} else {
+		cmd.Stdout = output
+		cmd.Stderr = output
+	}
+	cmd.WaitDelay = commandWaitDelay
+	setProcessGroup(cmd)
+
+	result.Err = cmd.Run()
+	result.Output = strings.TrimSpace(output.String())
+	if timeout > 0 && errors.Is(cmdCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		result.TimedOut = true
+		result.Err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return result
+}
+
+// lockedBuffer is a bytes.Buffer that is safe for concurrent writes
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// formatCommandResults describes commands and their output for a task result
+func formatCommandResults(results []CommandResult) string {
+	var summary strings.Builder
+	summary.WriteString("COMMAND OUTPUT:\n")
+	for _, result := range results {
+		summary.WriteString("$ " + result.Command + "\n")
+		if result.Output != "" {
+			summary.WriteString(result.Output + "\n")
+		}
+		if result.Err != nil {
+			summary.WriteString(fmt.Sprintf("(failed: %v)\n", result.Err))
+		}
+	}
+	return strings.TrimRight(summary.String(), "\n")
+}
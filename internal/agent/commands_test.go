@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsLongRunningCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"npm start", true},
+		{"npm run dev", true},
+		{"yarn dev", true},
+		{"python app.py", true},
+		{"python3 manage.py runserver", true},
+		{"uvicorn main:app --reload", true},
+		{"node server.js", true},
+		{"docker compose up", true},
+		{"docker-compose up -d", false},
+		{"./server &", true},
+		{"npm install && npm test", false},
+		{"npm run build", false},
+		{"python -m pytest", false},
+		{"python scripts/seed.py", false},
+		{"go build ./...", false},
+	}
+
+	for _, tt := range tests {
+		if got := isLongRunningCommand(tt.command); got != tt.want {
+			t.Errorf("isLongRunningCommand(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestRunCommandCapturesOutput(t *testing.T) {
+	result := runCommand(context.Background(), "echo out; echo err >&2; exit 3", t.TempDir(), nil, time.Minute)
+	if result.Err == nil || result.TimedOut {
+		t.Errorf("Expected a non-timeout failure, got %+v", result)
+	}
+	if !strings.Contains(result.Output, "out") || !strings.Contains(result.Output, "err") {
+		t.Errorf("Expected stdout and stderr captured, got %q", result.Output)
+	}
+}
+
+// TestRunCommandTimeout tests that a command that outlives its timeout is
+// killed together with the children it started
+func TestRunCommandTimeout(t *testing.T) {
+	started := time.Now()
+	result := runCommand(context.Background(), "sleep 30 & sleep 30", t.TempDir(), nil, 200*time.Millisecond)
+	if !result.TimedOut || result.Err == nil {
+		t.Errorf("Expected the command to time out, got %+v", result)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("Expected the process group to be killed promptly, took %s", elapsed)
+	}
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrToolDenied is returned when a tool is blocked by constraints or declined by the user
+	ErrToolDenied = errors.New("tool execution denied")
+
+	// ErrToolCallLimit is returned when a request exceeds Constraints.MaxToolCalls
+	ErrToolCallLimit = errors.New("tool call limit reached")
+
+	// ErrExecutionTimeLimit is returned when a request exceeds Constraints.MaxExecutionTime
+	ErrExecutionTimeLimit = errors.New("execution time limit exceeded")
+
+	// ErrOutputLimit is returned when generated files exceed Constraints.MaxTotalBytes
+	ErrOutputLimit = errors.New("generated output limit exceeded")
+)
+
+// Default limits on generated file output, guarding against runaway generation
+const (
+	defaultMaxFileBytes  = 1 << 20
+	defaultMaxTotalBytes = 10 << 20
+)
+
+// checkToolConstraints applies the DeniedTools and AllowedTools lists
+func checkToolConstraints(constraints *Constraints, name string) error {
+	if constraints == nil {
+		return nil
+	}
+
+	for _, denied := range constraints.DeniedTools {
+		if denied == name {
+			return fmt.Errorf("%w: %s is in the denied tools list", ErrToolDenied, name)
+		}
+	}
+
+	if len(constraints.AllowedTools) > 0 {
+		for _, allowed := range constraints.AllowedTools {
+			if allowed == name {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s is not in the allowed tools list", ErrToolDenied, name)
+	}
+
+	return nil
+}
+
+// toolBudget enforces Constraints.MaxToolCalls and MaxExecutionTime across
+// the tool invocations of a single request. Zero limits are unlimited.
+type toolBudget struct {
+	maxCalls int
+	maxTime  time.Duration
+	calls    int
+	elapsed  time.Duration
+}
+
+// newToolBudget creates a budget from request constraints, which may be nil
+func newToolBudget(constraints *Constraints) *toolBudget {
+	if constraints == nil {
+		return &toolBudget{}
+	}
+	return &toolBudget{
+		maxCalls: constraints.MaxToolCalls,
+		maxTime:  constraints.MaxExecutionTime,
+	}
+}
+
+// reserve records a tool invocation, failing once either limit has been reached
+func (b *toolBudget) reserve(name string) error {
+	if err := b.checkTime(); err != nil {
+		return err
+	}
+	if b.maxCalls > 0 && b.calls >= b.maxCalls {
+		return fmt.Errorf("%w: %s would exceed the maximum of %d tool calls", ErrToolCallLimit, name, b.maxCalls)
+	}
+	b.calls++
+	return nil
+}
+
+// checkTime fails once cumulative execution time reaches the limit
+func (b *toolBudget) checkTime() error {
+	if b.maxTime > 0 && b.elapsed >= b.maxTime {
+		return fmt.Errorf("%w: spent %s of %s", ErrExecutionTimeLimit, b.elapsed.Round(time.Second), b.maxTime)
+	}
+	return nil
+}
+
+// track adds time spent executing a tool or command
+func (b *toolBudget) track(d time.Duration) {
+	b.elapsed += d
+}
+
+// context bounds a single execution by the remaining time budget
+func (b *toolBudget) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.maxTime <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, b.maxTime-b.elapsed)
+}
+
+// outputBudget enforces Constraints.MaxFileBytes and MaxTotalBytes across the
+// files written during a plan phase
+type outputBudget struct {
+	maxFile  int64
+	maxTotal int64
+	written  int64
+}
+
+// newOutputBudget creates a budget from request constraints, which may be nil
+func newOutputBudget(constraints *Constraints) *outputBudget {
+	b := &outputBudget{maxFile: defaultMaxFileBytes, maxTotal: defaultMaxTotalBytes}
+	if constraints != nil {
+		if constraints.MaxFileBytes > 0 {
+			b.maxFile = constraints.MaxFileBytes
+		}
+		if constraints.MaxTotalBytes > 0 {
+			b.maxTotal = constraints.MaxTotalBytes
+		}
+	}
+	return b
+}
+
+// fileTooLarge reports whether a single file exceeds the per-file limit
+func (b *outputBudget) fileTooLarge(size int64) bool {
+	return size > b.maxFile
+}
+
+// reserve records a file write, failing once the phase total would be exceeded
+func (b *outputBudget) reserve(name string, size int64) error {
+	if b.written+size > b.maxTotal {
+		return fmt.Errorf("%w: writing %s would bring this phase to %d bytes (limit %d)", ErrOutputLimit, name, b.written+size, b.maxTotal)
+	}
+	b.written += size
+	return nil
+}
+
+// isBudgetError reports whether err means a request limit was hit and execution should stop
+func isBudgetError(err error) bool {
+	return errors.Is(err, ErrToolCallLimit) || errors.Is(err, ErrExecutionTimeLimit) || errors.Is(err, ErrOutputLimit)
+}
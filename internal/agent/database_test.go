@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestDatabase creates a sqlite database with two related tables
+func openTestDatabase(t *testing.T) *DatabaseTool {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := NewDatabaseTool(ctx, "sqlite://"+filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("NewDatabaseTool failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, stmt := range []string{
+		"CREATE TABLE orgs (id INTEGER PRIMARY KEY, name TEXT NOT NULL)",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL, org_id INTEGER REFERENCES orgs(id), bio TEXT)",
+		"INSERT INTO orgs (id, name) VALUES (1, 'Acme')",
+		"INSERT INTO users (email, org_id) VALUES ('a@acme.io', 1), ('b@acme.io', 1)",
+	} {
+		if _, err := db.Execute(ctx, map[string]interface{}{"query": stmt}); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+// TestSchemaInspector tests introspecting tables, columns and foreign keys
+func TestSchemaInspector(t *testing.T) {
+	db := openTestDatabase(t)
+	tool := &SchemaInspectorTool{db: db}
+	ctx := context.Background()
+
+	schema, err := tool.Execute(ctx, map[string]interface{}{"table": "users"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := "Table users\n" +
+		"  id INTEGER PRIMARY KEY NOT NULL\n" +
+		"  email TEXT NOT NULL\n" +
+		"  org_id INTEGER -> orgs.id\n" +
+		"  bio TEXT\n"
+	if schema != want {
+		t.Errorf("Unexpected schema:\n%s\nwant:\n%s", schema, want)
+	}
+
+	all, err := tool.Execute(ctx, map[string]interface{}{})
+	if err != nil || !strings.HasPrefix(all, "Table orgs\n") || !strings.Contains(all, "\nTable users\n") {
+		t.Errorf("Expected every table, got %q, %v", all, err)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"table": "missing"}); err == nil {
+		t.Error("Expected an unknown table to be reported")
+	}
+	if _, err := (&SchemaInspectorTool{}).Execute(ctx, nil); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("Expected ErrNoDatabase without a database, got %v", err)
+	}
+}
+
+// TestDataAnalysisReadOnly tests that the analysis tool returns rows and
+// refuses statements that write
+func TestDataAnalysisReadOnly(t *testing.T) {
+	db := openTestDatabase(t)
+	tool := &DataAnalysisTool{db: db}
+	ctx := context.Background()
+
+	rows, err := tool.Execute(ctx, map[string]interface{}{
+		"query": "-- users per org\nSELECT o.name, COUNT(*) AS users, NULL AS note FROM users u JOIN orgs o ON o.id = u.org_id GROUP BY o.name",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if want := "name | users | note\nAcme | 2 | NULL\n(1 row(s))"; rows != want {
+		t.Errorf("Unexpected rows:\n%s\nwant:\n%s", rows, want)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM users",
+		"SELECT 1; DROP TABLE users",
+		"/* SELECT */ UPDATE users SET bio = 'x'",
+	} {
+		if _, err := tool.Execute(ctx, map[string]interface{}{"query": query}); !errors.Is(err, ErrNotReadOnly) {
+			t.Errorf("Expected %q to be rejected, got %v", query, err)
+		}
+	}
+
+	// A write hidden in a CTE passes the keyword check but not the read-only connection
+	if _, err := tool.Execute(ctx, map[string]interface{}{"query": "WITH gone AS (SELECT 1) DELETE FROM users"}); err == nil {
+		t.Error("Expected a write inside WITH to fail")
+	}
+	if count, err := db.Query(ctx, "SELECT COUNT(*) FROM users"); err != nil || !strings.Contains(count, "\n2\n") {
+		t.Errorf("Expected users to be untouched, got %q, %v", count, err)
+	}
+
+	// The connection is writable again for the approved database tool
+	if !db.RequiresApproval() {
+		t.Error("Expected the database tool to require approval")
+	}
+	result, err := db.Execute(ctx, map[string]interface{}{"query": "UPDATE users SET bio = 'hi'"})
+	if err != nil || result != "Statement executed: 2 row(s) affected" {
+		t.Errorf("Expected the update to run, got %q, %v", result, err)
+	}
+}
+
+// TestParseDSN tests mapping database URLs to drivers
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn, driver, source string
+	}{
+		{"sqlite://data/app.db", "sqlite3", "data/app.db"},
+		{"sqlite:app.db", "sqlite3", "app.db"},
+		{"postgres://u:p@localhost:5432/app?sslmode=disable", "postgres", "postgres://u:p@localhost:5432/app?sslmode=disable"},
+		{"mysql://u:p@tcp(localhost:3306)/app", "mysql", "u:p@tcp(localhost:3306)/app"},
+	}
+	for _, tt := range tests {
+		driver, source, err := ParseDSN(tt.dsn)
+		if err != nil || driver != tt.driver || source != tt.source {
+			t.Errorf("ParseDSN(%q) = %q, %q, %v; want %q, %q", tt.dsn, driver, source, err, tt.driver, tt.source)
+		}
+	}
+
+	for _, dsn := range []string{"app.db", "oracle://host/db"} {
+		if _, _, err := ParseDSN(dsn); err == nil {
+			t.Errorf("Expected %q to be rejected", dsn)
+		}
+	}
+}
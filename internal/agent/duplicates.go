@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrDuplicateFile is returned under DuplicateError when a phase writes a
+// file an earlier phase already created
+var ErrDuplicateFile = errors.New("file already written by the plan")
+
+// DuplicatePolicy decides what happens when a phase writes a file the plan
+// has already created
+type DuplicatePolicy string
+
+const (
+	DuplicateSkip      DuplicatePolicy = "skip"      // Keep the first version
+	DuplicateOverwrite DuplicatePolicy = "overwrite" // Keep the newest version
+	DuplicateMerge     DuplicatePolicy = "merge"     // Append lines the first version lacks
+	DuplicateError     DuplicatePolicy = "error"     // Fail the task
+)
+
+// ParseDuplicatePolicy validates a duplicate policy name
+func ParseDuplicatePolicy(policy string) (DuplicatePolicy, error) {
+	switch DuplicatePolicy(policy) {
+	case DuplicateSkip, DuplicateOverwrite, DuplicateMerge, DuplicateError:
+		return DuplicatePolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid duplicate policy %q (want skip, overwrite, merge or error)", policy)
+}
+
+// FileResolution records how a later write to an already created file was
+// handled, so the manifest explains which version is on disk
+type FileResolution struct {
+	Phase  string          `json:"phase"` // The phase whose write was resolved
+	Policy DuplicatePolicy `json:"policy"`
+	At     time.Time       `json:"at"`
+}
+
+// Entry returns the manifest entry for a created file
+func (m *ProjectManifest) Entry(path string) (*FileEntry, bool) {
+	for i := range m.CreatedFiles {
+		if m.CreatedFiles[i].Path == path {
+			return &m.CreatedFiles[i], true
+		}
+	}
+	return nil, false
+}
+
+// RecordResolution notes how a duplicate write to a created file was handled,
+// refreshing its size when the file changed
+func (m *ProjectManifest) RecordResolution(path, phase string, policy DuplicatePolicy) {
+	entry, ok := m.Entry(path)
+	if !ok {
+		return
+	}
+	entry.Resolutions = append(entry.Resolutions, FileResolution{Phase: phase, Policy: policy, At: time.Now()})
+	if info, err := os.Stat(m.Path(path)); err == nil {
+		entry.Size = info.Size()
+	}
+}
+
+// mergeLines appends the lines of update that existing lacks, keeping
+// existing as is. It suits line lists like requirements.txt or .gitignore.
+func mergeLines(existing, update string) string {
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(existing, "\n") {
+		seen[strings.TrimSpace(line)] = true
+	}
+
+	merged := strings.TrimRight(existing, "\n")
+	for _, line := range strings.Split(update, "\n") {
+		key := strings.TrimSpace(line)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged += "\n" + line
+	}
+	if strings.HasSuffix(existing, "\n") {
+		merged += "\n"
+	}
+	return merged
+}
+
+// resolveDuplicate applies the duplicate policy to a write of an already
+// created file, returning the content to write; write is false when the
+// existing version is kept
+func (e *Executor) resolveDuplicate(plan *ExecutionPlan, path, phaseName, content string) (string, bool, error) {
+	entry, _ := plan.Manifest.Entry(path)
+
+	switch e.duplicates {
+	case DuplicateOverwrite:
+		fmt.Printf("♻️  Overwriting %s (first written by %s)\n", path, entry.Phase)
+		return content, true, nil
+	case DuplicateMerge:
+		existing, err := os.ReadFile(plan.Manifest.Path(path))
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s for merging: %w", path, err)
+		}
+		fmt.Printf("🔀 Merging into %s (first written by %s)\n", path, entry.Phase)
+		return mergeLines(string(existing), content), true, nil
+	case DuplicateError:
+		return "", false, fmt.Errorf("%w: %s (by phase %q)", ErrDuplicateFile, path, entry.Phase)
+	default:
+		fmt.Printf("⚠️  Skipping already created file: %s\n", path)
+		plan.Manifest.RecordResolution(path, phaseName, DuplicateSkip)
+		return "", false, nil
+	}
+}
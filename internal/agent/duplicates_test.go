@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDuplicatePolicies tests each policy for a file written by two phases
+func TestDuplicatePolicies(t *testing.T) {
+	first := "```text requirements.txt\nflask\nrequests\n```\n"
+	second := "```text requirements.txt\nrequests\npytest\n```\n"
+
+	tests := []struct {
+		policy  DuplicatePolicy
+		want    string
+		written int
+		err     error
+	}{
+		{DuplicateSkip, "flask\nrequests", 0, nil},
+		{DuplicateOverwrite, "requests\npytest", 1, nil},
+		{DuplicateMerge, "flask\nrequests\npytest", 1, nil},
+		{DuplicateError, "flask\nrequests", 0, ErrDuplicateFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			dir := t.TempDir()
+			executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+			executor.SetDuplicatePolicy(tt.policy)
+			plan := &ExecutionPlan{ID: "plan_dup", Manifest: NewProjectManifest("dup", dir)}
+
+			if _, err := executor.processFileBlocks(first, plan, "Setup", newOutputBudget(nil)); err != nil {
+				t.Fatal(err)
+			}
+			written, err := executor.processFileBlocks(second, plan, "Tests", newOutputBudget(nil))
+			if !errors.Is(err, tt.err) || len(written) != tt.written {
+				t.Fatalf("Got %v, %v; want %d written, error %v", written, err, tt.written, tt.err)
+			}
+
+			content, _ := os.ReadFile(filepath.Join(dir, "requirements.txt"))
+			if string(content) != tt.want {
+				t.Errorf("Got content %q, want %q", content, tt.want)
+			}
+
+			entry, _ := plan.Manifest.Entry("requirements.txt")
+			if entry.Phase != "Setup" || entry.Size != int64(len(tt.want)) {
+				t.Errorf("Expected the creating phase and current size kept, got %+v", entry)
+			}
+			if tt.err == nil && (len(entry.Resolutions) != 1 || entry.Resolutions[0].Phase != "Tests" || entry.Resolutions[0].Policy != tt.policy) {
+				t.Errorf("Expected the resolution recorded, got %+v", entry.Resolutions)
+			}
+		})
+	}
+}
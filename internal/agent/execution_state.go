@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Begin marks execution as running. StartedAt and CurrentPhase are kept when
+// resuming a plan.
+func (s *ExecutionState) Begin(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Status = ExecutionStatusRunning
+	if s.StartedAt == nil {
+		s.StartedAt = &now
+	}
+	if s.CurrentPhase < 0 {
+		s.CurrentPhase = 0
+	}
+}
+
+// MarkCompleted records a finished phase and advances past it
+func (s *ExecutionState) MarkCompleted(phase int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.Contains(s.CompletedPhases, phase) {
+		s.CompletedPhases = append(s.CompletedPhases, phase)
+	}
+	if phase+1 > s.CurrentPhase {
+		s.CurrentPhase = phase + 1
+	}
+}
+
+// MarkFailed records a failed phase and fails the execution
+func (s *ExecutionState) MarkFailed(phase int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Status = ExecutionStatusFailed
+	if !slices.Contains(s.FailedPhases, phase) {
+		s.FailedPhases = append(s.FailedPhases, phase)
+	}
+}
+
+// Finish marks execution as completed
+func (s *ExecutionState) Finish(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Status = ExecutionStatusCompleted
+	s.CompletedAt = &now
+}
+
+// SetStatus changes the execution status
+func (s *ExecutionState) SetStatus(status ExecutionStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Status = status
+}
+
+// SetCheckpoint records the most recent checkpoint
+func (s *ExecutionState) SetCheckpoint(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.LastCheckpoint = id
+}
+
+// IsCompleted reports whether a phase has completed
+func (s *ExecutionState) IsCompleted(phase int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Contains(s.CompletedPhases, phase)
+}
+
+// Rewind makes phase the next to run, forgetting results from it onward
+func (s *ExecutionState) Rewind(phase int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Status = ExecutionStatusPending
+	s.CurrentPhase = phase
+	s.CompletedAt = nil
+	s.CompletedPhases = phasesBefore(s.CompletedPhases, phase)
+	s.FailedPhases = phasesBefore(s.FailedPhases, phase)
+}
+
+// Reset clears all progress so the plan runs from the first phase
+func (s *ExecutionState) Reset() {
+	s.Rewind(0)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.StartedAt = nil
+}
+
+// Snapshot returns a copy of the state that is safe to read while the plan executes
+func (s *ExecutionState) Snapshot() ExecutionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return ExecutionState{
+		Status:          s.Status,
+		CurrentPhase:    s.CurrentPhase,
+		CompletedPhases: slices.Clone(s.CompletedPhases),
+		FailedPhases:    slices.Clone(s.FailedPhases),
+		LastCheckpoint:  s.LastCheckpoint,
+		StartedAt:       s.StartedAt,
+		CompletedAt:     s.CompletedAt,
+	}
+}
+
+// MarshalJSON encodes the state under its lock, so saving plan state doesn't
+// race with phases updating it
+func (s *ExecutionState) MarshalJSON() ([]byte, error) {
+	snapshot := s.Snapshot()
+	// The alias drops this method, avoiding recursion
+	type plain ExecutionState
+	return json.Marshal((*plain)(&snapshot))
+}
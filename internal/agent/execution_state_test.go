@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestExecutionStateConcurrentUpdates tests that phases finishing concurrently
+// are all recorded; run with -race to check synchronization
+func TestExecutionStateConcurrentUpdates(t *testing.T) {
+	plan := &ExecutionPlan{ID: "plan_test"}
+	plan.State.Begin(time.Now())
+
+	const phases = 50
+	var wg sync.WaitGroup
+	for i := 0; i < phases; i++ {
+		wg.Add(2)
+		go func(phase int) {
+			defer wg.Done()
+			if phase%5 == 0 {
+				plan.State.MarkFailed(phase)
+			} else {
+				plan.State.MarkCompleted(phase)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := json.Marshal(plan); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	state := plan.State.Snapshot()
+	if len(state.CompletedPhases) != phases*4/5 || len(state.FailedPhases) != phases/5 {
+		t.Errorf("Expected %d completed and %d failed phases, got %d and %d",
+			phases*4/5, phases/5, len(state.CompletedPhases), len(state.FailedPhases))
+	}
+	if state.CurrentPhase != phases || state.Status != ExecutionStatusFailed {
+		t.Errorf("Unexpected current phase %d / status %s", state.CurrentPhase, state.Status)
+	}
+}
+
+// TestExecutionStateRewind tests that rewinding forgets later phases and that
+// the state survives a JSON round trip
+func TestExecutionStateRewind(t *testing.T) {
+	var state ExecutionState
+	state.Begin(time.Now())
+	for i := 0; i < 3; i++ {
+		state.MarkCompleted(i)
+	}
+	state.MarkFailed(3)
+
+	state.Rewind(2)
+	if state.IsCompleted(2) || !state.IsCompleted(1) || state.CurrentPhase != 2 || len(state.FailedPhases) != 0 {
+		t.Errorf("Unexpected state after rewind: %+v", state.Snapshot())
+	}
+
+	data, err := json.Marshal(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ExecutionState
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Status != ExecutionStatusPending || decoded.CurrentPhase != 2 || len(decoded.CompletedPhases) != 2 {
+		t.Errorf("Unexpected decoded state: %s", data)
+	}
+
+	state.Reset()
+	if state.StartedAt != nil || state.CurrentPhase != 0 || len(state.CompletedPhases) != 0 {
+		t.Errorf("Expected reset state, got %+v", state.Snapshot())
+	}
+}
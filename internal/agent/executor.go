@@ -2,46 +2,151 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
-"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+	"github.com/quantumflow/quantumflow/internal/tracing"
 )
 
+// ErrExecutionCancelled is returned when a plan's context is cancelled, e.g.
+// by Ctrl-C; the plan is saved as cancelled and can be resumed
+var ErrExecutionCancelled = errors.New("execution cancelled")
+
 // Executor executes multi-phase plans with checkpoint support
 type Executor struct {
-	orchestrator *AgentOrchestrator
-	checkpoints  map[string]*Checkpoint
+	orchestrator       *AgentOrchestrator
+	approval           *ApprovalWorkflow
+	constraints        *Constraints
+	outputDir          string // Root for generated files and commands; empty uses the working directory
+	sandbox            bool   // Confine generated commands to the project directory
+	commandTimeout     time.Duration
+	duplicates         DuplicatePolicy
+	verify             VerifyMode
+	format             bool
+	regenerateAttempts int
+	logger             *slog.Logger
 }
 
-// NewExecutor creates a new plan executor
-func NewExecutor(orchestrator *AgentOrchestrator) *Executor {
+// NewExecutor creates a new plan executor. Tools that are destructive or
+// require approval are confirmed through the approval workflow before running.
+func NewExecutor(orchestrator *AgentOrchestrator, approval *ApprovalWorkflow) *Executor {
 	return &Executor{
-		orchestrator: orchestrator,
-		checkpoints:  make(map[string]*Checkpoint),
+		orchestrator:       orchestrator,
+		approval:           approval,
+		verify:             VerifyWarn,
+		format:             true,
+		regenerateAttempts: defaultRegenerateAttempts,
+		commandTimeout:     defaultCommandTimeout,
+		duplicates:         DuplicateSkip,
+		logger:             orchestrator.logger,
+	}
+}
+
+// SetConstraints sets the tool allow/deny lists and dry-run mode applied to plan execution
+func (e *Executor) SetConstraints(constraints *Constraints) {
+	e.constraints = constraints
+}
+
+// SetVerifyMode sets whether the project's tests and linter run after each
+// phase that writes files, and whether failing tests fail the phase
+func (e *Executor) SetVerifyMode(mode VerifyMode) {
+	e.verify = mode
+}
+
+// SetOutputDir roots new plans' generated files and commands under dir, which
+// must be an existing writable directory. Resumed plans keep the directory
+// recorded in their manifest.
+func (e *Executor) SetOutputDir(dir string) error {
+	abs, err := validateOutputDir(dir)
+	if err != nil {
+		return err
+	}
+	e.outputDir = abs
+	return nil
+}
+
+// validateOutputDir checks that dir exists and is writable, returning its absolute path
+func validateOutputDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("output directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("output directory %s is not a directory", abs)
+	}
+	probe, err := os.CreateTemp(abs, ".quantumflow-write-test-*")
+	if err != nil {
+		return "", fmt.Errorf("output directory %s is not writable: %w", abs, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return abs, nil
+}
+
+// SetSandbox confines generated commands to the project directory: commands
+// that cd outside it are skipped, and commands run with a minimal environment
+// that leaves out API keys and other credentials
+func (e *Executor) SetSandbox(enabled bool) {
+	e.sandbox = enabled
+}
+
+// SetCommandTimeout bounds each generated shell command; a command that runs
+// longer is killed along with any processes it started. Zero disables the limit.
+func (e *Executor) SetCommandTimeout(timeout time.Duration) {
+	e.commandTimeout = timeout
+}
+
+// SetDuplicatePolicy sets how a phase's write to a file the plan already
+// created is handled
+func (e *Executor) SetDuplicatePolicy(policy DuplicatePolicy) {
+	e.duplicates = policy
+}
+
+// projectDir is the directory a plan's files and commands are rooted in
+func (e *Executor) projectDir(plan *ExecutionPlan) string {
+	if plan.Manifest != nil && plan.Manifest.BaseDir != "" {
+		return plan.Manifest.BaseDir
+	}
+	if e.outputDir != "" {
+		return e.outputDir
 	}
+	return "."
+}
+
+// SetFormatFiles sets whether generated files are run through gofmt, prettier
+// or black after they are written
+func (e *Executor) SetFormatFiles(enabled bool) {
+	e.format = enabled
+}
+
+// SetRegenerateAttempts sets how often an agent is asked to fix generated Go
+// files that don't parse before the task fails (0 fails immediately)
+func (e *Executor) SetRegenerateAttempts(attempts int) {
+	e.regenerateAttempts = attempts
 }
 
 // Execute runs an execution plan phase by phase
 func (e *Executor) Execute(ctx context.Context, plan *ExecutionPlan) error {
 	// Update plan state - Only set StartedAt if not already set (resuming)
-	now := time.Now()
-	plan.State.Status = ExecutionStatusRunning
-	if plan.State.StartedAt == nil {
-		plan.State.StartedAt = &now
-	}
-	
-	// If starting fresh, reset current phase
-	if plan.State.CurrentPhase < 0 {
-		plan.State.CurrentPhase = 0
-	}
+	plan.State.Begin(time.Now())
 	
 	// Initialize project manifest for tracking created files
 	if plan.Manifest == nil {
-		plan.Manifest = NewProjectManifest(plan.Title, ".")
+		plan.Manifest = NewProjectManifest(plan.Title, e.projectDir(plan))
 		if plan.FileStructure != nil {
 			plan.Manifest.SetExpectedStructure(plan.FileStructure)
 		}
@@ -54,6 +159,9 @@ func (e *Executor) Execute(ctx context.Context, plan *ExecutionPlan) error {
 	
 	fmt.Printf("\n🚀 Starting execution of: %s\n", plan.Title)
 	fmt.Printf("Total phases: %d\n", len(plan.Phases))
+	if dir := e.projectDir(plan); dir != "." {
+		fmt.Printf("📂 Output directory: %s\n", dir)
+	}
 	if len(plan.FileStructure) > 0 {
 		fmt.Printf("📁 Project structure: %d directories\n", len(plan.FileStructure))
 	}
@@ -63,16 +171,23 @@ func (e *Executor) Execute(ctx context.Context, plan *ExecutionPlan) error {
 	for i := plan.State.CurrentPhase; i < len(plan.Phases); i++ {
 		phase := &plan.Phases[i]
 		
+		select {
+		case <-ctx.Done():
+			return e.cancelExecution(plan, i, ctx.Err())
+		default:
+		}
+		
 		// Check if phase has dependencies
 		if !e.areDependenciesMet(plan, phase) {
 			return fmt.Errorf("dependencies not met for phase %s", phase.Name)
 		}
 		
 		// Create checkpoint before phase
-		checkpoint, err := e.createCheckpoint(plan.ID, i)
+		checkpoint, err := e.createCheckpoint(plan, i)
 		if err != nil {
 			return fmt.Errorf("failed to create checkpoint: %w", err)
 		}
+		plan.State.SetCheckpoint(checkpoint.ID)
 		
 		// Execute phase
 		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
@@ -80,46 +195,99 @@ func (e *Executor) Execute(ctx context.Context, plan *ExecutionPlan) error {
 		fmt.Printf("🤖 Agent: %s | ⏱️  Estimated: %s\n", phase.Agent, phase.EstimatedTime)
 		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 		
+		// A resumed phase keeps its original start time
+		if phase.StartedAt == nil {
+			started := time.Now()
+			phase.StartedAt = &started
+		}
+		
 		if err := e.executePhase(ctx, plan, phase); err != nil {
+			if ctx.Err() != nil {
+				// Interrupted rather than failed; the phase resumes with its
+				// incomplete tasks
+				phase.Status = PhaseStatusPending
+				return e.cancelExecution(plan, i, ctx.Err())
+			}
+			
 			// Phase failed - rollback
 			fmt.Printf("\n❌ Phase %d failed: %v\n", i+1, err)
+			e.logger.Error("plan phase failed", "plan", plan.ID, "phase", phase.ID, "agent", phase.Agent, "error", err)
 			fmt.Println("🔄 Rolling back to checkpoint...")
-			
-			if rollbackErr := e.rollback(checkpoint); rollbackErr != nil {
-				return fmt.Errorf("phase failed and rollback failed: %w", rollbackErr)
+			if rollbackErr := e.restoreCheckpoint(ctx, plan, checkpoint); rollbackErr != nil {
+				fmt.Printf("⚠️  Rollback failed: %v\n", rollbackErr)
+				fmt.Printf("↩️  Undo this phase with: /rollback %s\n", checkpoint.ID)
+				e.logger.Error("rolling back failed phase failed", "plan", plan.ID, "checkpoint", checkpoint.ID, "error", rollbackErr)
 			}
 			
-			plan.State.Status = ExecutionStatusFailed
-			plan.State.FailedPhases = append(plan.State.FailedPhases, i)
+			plan.State.MarkFailed(i)
+			phase.Status = PhaseStatusFailed
+			failed := time.Now()
+			phase.CompletedAt = &failed
+			e.saveState(plan)
 			return fmt.Errorf("phase %d failed: %w", i+1, err)
 		}
 		
 		// Phase succeeded
-		plan.State.CompletedPhases = append(plan.State.CompletedPhases, i)
-		plan.State.CurrentPhase = i + 1
+		plan.State.MarkCompleted(i)
 		phase.Status = PhaseStatusCompleted
+		completed := time.Now()
+		phase.CompletedAt = &completed
+		e.saveState(plan)
 		
 		fmt.Printf("\n✅ Phase %d complete!\n\n", i+1)
 	}
 	
 	// All phases completed
-	now = time.Now()
-	plan.State.Status = ExecutionStatusCompleted
-	plan.State.CompletedAt = &now
+	plan.State.Finish(time.Now())
+
+	e.learnWorkflow(ctx, plan)
 	
 	duration := time.Since(*plan.State.StartedAt).Round(time.Second)
 	
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Printf("🎉 Execution complete! (%s)\n", plan.Title)
 	fmt.Printf("⏱️  Duration: %s\n", duration)
+	if plan.Manifest != nil {
+		if flagged := plan.Manifest.ProblemFiles(); len(flagged) > 0 {
+			fmt.Printf("⚠️  %d generated file(s) look broken:\n", len(flagged))
+			for _, f := range flagged {
+				fmt.Printf("   • %s: %s\n", f.Path, f.Problem)
+			}
+		}
+	}
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Println()
 	
 	return nil
 }
 
-// executePhase executes a single phase using the appropriate agent
+// cancelExecution stops a plan whose context was cancelled before or during
+// phase index, saving its state so it can be resumed
+func (e *Executor) cancelExecution(plan *ExecutionPlan, index int, cause error) error {
+	fmt.Printf("\n⏹ Execution cancelled at phase %d/%d\n", index+1, len(plan.Phases))
+	e.logger.Info("plan execution cancelled", "plan", plan.ID, "phase", index+1, "cause", cause)
+	
+	plan.State.SetStatus(ExecutionStatusCancelled)
+	e.saveState(plan)
+	return fmt.Errorf("%w at phase %d: %w", ErrExecutionCancelled, index+1, cause)
+}
+
+// executePhase executes a single phase using the appropriate agent. The
+// phase's incomplete tasks go to the agent in one request, so a phase costs one
+// model call; completed tasks are skipped, so a resumed phase only does the
+// remaining work.
 func (e *Executor) executePhase(ctx context.Context, plan *ExecutionPlan, phase *Phase) error {
+	ctx, span := tracer().Start(ctx, "executor.Phase", trace.WithAttributes(
+		attribute.String("plan.id", plan.ID),
+		attribute.String("phase.id", phase.ID),
+		attribute.String("phase.agent", string(phase.Agent)),
+		attribute.Int("phase.tasks", len(phase.Tasks)),
+	))
+	return tracing.EndSpan(span, e.runPhase(ctx, plan, phase))
+}
+
+// runPhase does the work of executePhase
+func (e *Executor) runPhase(ctx context.Context, plan *ExecutionPlan, phase *Phase) error {
 	phase.Status = PhaseStatusInProgress
 	
 	// Get the agent for this phase
@@ -136,33 +304,91 @@ func (e *Executor) executePhase(ctx context.Context, plan *ExecutionPlan, phase
 		return fmt.Errorf("agent %s not found", phase.Agent)
 	}
 	
-	// Build query from tasks with project context
-	query := e.buildPhaseQuery(plan, phase)
+	// A phase without tasks is carried out from its name alone
+	var pending []*Task
+	for i := range phase.Tasks {
+		if !phase.Tasks[i].Completed {
+			pending = append(pending, &phase.Tasks[i])
+		}
+	}
+	if len(phase.Tasks) > 0 && len(pending) == 0 {
+		return e.verifyPhase(ctx, plan, phase)
+	}
+	if len(pending) < len(phase.Tasks) {
+		fmt.Printf("⏩ Resuming phase: %d of %d tasks already complete\n", len(phase.Tasks)-len(pending), len(phase.Tasks))
+	}
+	
+	if len(phase.Tasks) > 0 {
+		fmt.Printf("Executing tasks:\n")
+		for i, task := range phase.Tasks {
+			marker := " "
+			if task.Completed {
+				marker = "✓"
+			}
+			fmt.Printf("  %s %d. %s\n", marker, i+1, task.Description)
+		}
+		fmt.Println()
+	}
+	
+	// Tool calls and tool commands share one budget per phase, as do generated files
+	budget := newToolBudget(e.constraints)
+	output := newOutputBudget(e.constraints)
 	
-	// Execute with the agent
-	fmt.Printf("Executing tasks:\n")
-	for i, task := range phase.Tasks {
-		fmt.Printf("  %d. %s\n", i+1, task.Description)
+	answer, err := e.executeTasks(ctx, plan, phase, pending, targetAgent, budget, output)
+	if err != nil {
+		for _, task := range pending {
+			task.Error = err.Error()
+		}
+		e.saveState(plan)
+		return err
 	}
-	fmt.Println()
+	
+	for _, task := range pending {
+		task.Completed = true
+		task.Result = answer
+		task.Error = ""
+	}
+	e.saveState(plan)
+	
+	return e.verifyPhase(ctx, plan, phase)
+}
+
+// executeTasks runs the pending tasks of a phase through the agent and applies
+// the files, tool calls and commands in its response
+func (e *Executor) executeTasks(ctx context.Context, plan *ExecutionPlan, phase *Phase, pending []*Task, targetAgent Agent, budget *toolBudget, output *outputBudget) (string, error) {
+	// Build query from the tasks with project context
+	query := e.buildPhaseQuery(plan, phase, pending)
 	
 	request := &Request{
 		ID:      fmt.Sprintf("%s-phase-%s", plan.ID, phase.ID),
 		Query:   query,
-		Context: &Context{},
+		Context: &Context{Constraints: e.constraints},
 		Timeout: 10 * time.Minute, // Generous timeout for phases
 	}
 	
 	response, err := targetAgent.Execute(ctx, request)
 	if err != nil {
-		return err
+		return "", err
 	}
 	
-	// Process agent response - Scan for file blocks and write them
-	filesCreated, err := e.processFileBlocks(response.Answer, plan, phase.Name)
-	if err != nil {
-		fmt.Printf("⚠️ Warning: Failed to write some files: %v\n", err)
+	// Run any structured tool calls through the approval gate
+	for i := range response.ToolCalls {
+		call := &response.ToolCalls[i]
+		result, err := e.executeTool(ctx, targetAgent, call, budget)
+		if err != nil {
+			if isBudgetError(err) {
+				return "", err
+			}
+			call.Error = err.Error()
+			fmt.Printf("⚠️ Tool %s not run: %v\n", call.Name, err)
+			e.logger.Warn("tool call not run", "tool", call.Name, "error", err)
+			continue
+		}
+		call.Result = result
 	}
+
+	// Process agent response - Scan for file blocks and write them
+	filesCreated, err := e.processFileBlocks(response.Answer, plan, phase.Name, output)
 	
 	if len(filesCreated) > 0 {
 		fmt.Println("\n💾 Files Created/Updated:")
@@ -171,115 +397,206 @@ func (e *Executor) executePhase(ctx context.Context, plan *ExecutionPlan, phase
 		}
 	}
 	
+	if err != nil {
+		if isBudgetError(err) || errors.Is(err, ErrDuplicateFile) {
+			return "", err
+		}
+		fmt.Printf("⚠️ Warning: Failed to write some files: %v\n", err)
+		e.logger.Warn("writing generated files failed", "plan", plan.ID, "phase", phase.ID, "error", err)
+	}
+	
+	// Catch broken Go before later phases build on it
+	if err := e.validateGoFiles(ctx, plan, phase, targetAgent, filesCreated, output); err != nil {
+		return "", err
+	}
+	e.formatFiles(ctx, plan, filesCreated)
+	
 	// Process agent response - Scan for command blocks and execute them
-	commandsExecuted, err := e.processCommandBlocks(response.Answer)
+	commandsExecuted, err := e.processCommandBlocks(ctx, targetAgent, response.Answer, e.projectDir(plan), budget)
 	if err != nil {
+		if isBudgetError(err) {
+			return "", err
+		}
 		fmt.Printf("⚠️ Warning: Failed to execute some commands: %v\n", err)
+		e.logger.Warn("running generated commands failed", "plan", plan.ID, "phase", phase.ID, "error", err)
 	}
 	
 	if len(commandsExecuted) > 0 {
 		fmt.Println("\n⚡ Commands Executed:")
 		for _, cmd := range commandsExecuted {
-			fmt.Printf("  • %s\n", cmd)
+			status := "✓"
+			if cmd.Err != nil {
+				status = "✗"
+			}
+			fmt.Printf("  %s %s\n", status, cmd.Command)
 		}
 	}
 	
-	// Mark all tasks as completed
-	for i := range phase.Tasks {
-		phase.Tasks[i].Completed = true
-		phase.Tasks[i].Result = response.Answer
-	}
-	
-	fmt.Printf("\n📝 Agent Response:\n%s\n", truncateResponse(response.Answer, 500))
+	fmt.Printf("\n📝 Agent Response:\n%s\n\n", truncateResponse(response.Answer, 500))
 	
-	return nil
+	// Later phases and resumed runs see what the commands printed
+	if len(commandsExecuted) > 0 {
+		return response.Answer + "\n\n" + formatCommandResults(commandsExecuted), nil
+	}
+	return response.Answer, nil
+}
+
+// saveState persists plan progress so an interrupted execution can resume
+func (e *Executor) saveState(plan *ExecutionPlan) {
+	if e.approval == nil {
+		return
+	}
+	plan.UpdatedAt = time.Now()
+	if err := e.approval.SavePlanState(plan); err != nil {
+		fmt.Printf("⚠️ Warning: Could not save plan state: %v\n", err)
+		e.logger.Error("saving plan state failed", "plan", plan.ID, "error", err)
+	}
 }
 
 // processFileBlocks identifies code blocks with potential filenames and writes them to disk
-func (e *Executor) processFileBlocks(response string, plan *ExecutionPlan, phaseName string) ([]string, error) {
+func (e *Executor) processFileBlocks(response string, plan *ExecutionPlan, phaseName string, output *outputBudget) ([]string, error) {
 	var filesCreated []string
 	
-	// Multiple regex patterns to match different code block formats:
-	// Pattern 1: ```language path/to/file.ext  (standard format)
-	// Pattern 2: ```language filename="path/to/file.ext"  (quoted format)
-	patterns := []string{
-		"(?s)```(\\w+)\\s+([\\w./-]+)\\s*\n(.*?)```",           // ```python path/file.py\ncode\n```
-		"(?s)```(\\w+)\\s*\n#\\s*([\\w./-]+)\\s*\n(.*?)```",    // ```python\n# path/file.py\ncode\n```
+	// Fenced blocks name their file on the fence line (```python path/to/file.py)
+	// or in a leading comment (# path/to/file.py); see parseCodeBlocks
+	blocks := parseCodeBlocks(response)
+	baseDir := e.projectDir(plan)
+	inferred := make(map[string]bool)
+	var unnamed []string
+	
+	// Unnamed blocks belong with the files this phase names and has written
+	var phaseFiles []string
+	for _, block := range blocks {
+		if block.Path != "" {
+			phaseFiles = append(phaseFiles, filepath.ToSlash(filepath.Clean(block.Path)))
+		}
+	}
+	if plan.Manifest != nil {
+		for _, f := range plan.Manifest.CreatedFiles {
+			if f.Phase == phaseName {
+				phaseFiles = append(phaseFiles, filepath.ToSlash(f.Path))
+			}
+		}
 	}
+	inferDir := phaseDir(phaseFiles, projectRootDir(plan.FileStructure))
 	
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindAllStringSubmatch(response, -1)
+	for _, block := range blocks {
+		filename := block.Path
+		content := block.Content
 		
-		for _, match := range matches {
-			if len(match) < 4 {
+		// Unnamed blocks get a conventional file name for their language in
+		// the phase's directory, unless that file already exists
+		if filename == "" {
+			if nonFileLanguages[strings.ToLower(block.Lang)] || content == "" {
 				continue
 			}
-			
-			// lang := match[1]  // language (python, go, etc)
-			filename := strings.TrimSpace(match[2])
-			content := strings.TrimSpace(match[3])
-			
-			// Skip if no content
-			if content == "" {
-				fmt.Printf("⚠️  Skipping empty file: %s\n", filename)
+			path, ok := defaultFilePath(block.Lang, inferDir)
+			if !ok || inferred[path] || (plan.Manifest != nil && plan.Manifest.FileExists(path)) {
+				unnamed = append(unnamed, block.Lang)
 				continue
 			}
-			
-			// Ensure file is in current directory or relative subdirectory
-			cleanPath := filepath.Clean(filename)
-			if strings.HasPrefix(cleanPath, "..") || strings.HasPrefix(cleanPath, "/") {
-				fmt.Printf("⚠️  Skipping unsafe file path: %s\n", filename)
-				continue
-			}
-			
-			// Check if file was already created in a previous phase
-			if plan.Manifest != nil && plan.Manifest.FileExists(cleanPath) {
-				fmt.Printf("⚠️  Skipping already created file: %s\n", cleanPath)
+			if _, err := os.Stat(filepath.Join(baseDir, path)); err == nil {
+				unnamed = append(unnamed, block.Lang)
 				continue
 			}
-			
-			// Create directory if needed
-			dir := filepath.Dir(cleanPath)
-			if dir != "." && dir != "" {
-				if err := os.MkdirAll(dir, 0755); err != nil {
-					return filesCreated, fmt.Errorf("failed to create directory for %s: %w", filename, err)
-				}
+			inferred[path] = true
+			fmt.Printf("📄 Code block has no file name; writing %s block to %s\n", blockLanguage(block.Lang), path)
+			filename = path
+		}
+		
+		// Skip if no content
+		if content == "" {
+			fmt.Printf("⚠️  Skipping empty file: %s\n", filename)
+			continue
+		}
+		
+		// Ensure file resolves inside the project directory
+		cleanPath, ok := projectPath(baseDir, filename)
+		if !ok || cleanPath == "." {
+			fmt.Printf("⚠️  Skipping unsafe file path: %s\n", filename)
+			continue
+		}
+		
+		// A file created earlier in the plan is resolved by the duplicate policy
+		duplicate := plan.Manifest != nil && plan.Manifest.FileExists(cleanPath)
+		if duplicate {
+			resolved, write, err := e.resolveDuplicate(plan, cleanPath, phaseName, content)
+			if err != nil {
+				return filesCreated, err
 			}
-			
-			// Write file
-			if err := os.WriteFile(cleanPath, []byte(content), 0644); err != nil {
-				return filesCreated, fmt.Errorf("failed to write %s: %w", filename, err)
+			if !write {
+				continue
 			}
-			
-			// Track file in manifest
-			if plan.Manifest != nil {
-				plan.Manifest.AddFile(cleanPath, phaseName, "")
+			content = resolved
+		}
+		
+		// Guard against runaway generation filling the disk
+		size := int64(len(content))
+		if output.fileTooLarge(size) {
+			fmt.Printf("⚠️  Skipping oversize file: %s (%d bytes, limit %d)\n", cleanPath, size, output.maxFile)
+			continue
+		}
+		if err := output.reserve(cleanPath, size); err != nil {
+			return filesCreated, err
+		}
+		
+		// Create directory if needed
+		dir := filepath.Dir(cleanPath)
+		if dir != "." && dir != "" {
+			if err := os.MkdirAll(filepath.Join(baseDir, dir), 0755); err != nil {
+				return filesCreated, fmt.Errorf("failed to create directory for %s: %w", filename, err)
 			}
-			
-			filesCreated = append(filesCreated, cleanPath)
 		}
+		
+		// Write file
+		if err := os.WriteFile(filepath.Join(baseDir, cleanPath), []byte(content), 0644); err != nil {
+			return filesCreated, fmt.Errorf("failed to write %s: %w", filename, err)
+		}
+		
+		// Track file in manifest
+		if duplicate {
+			plan.Manifest.RecordResolution(cleanPath, phaseName, e.duplicates)
+		} else if plan.Manifest != nil {
+			plan.Manifest.AddFile(cleanPath, phaseName, "")
+		}
+		
+		filesCreated = append(filesCreated, cleanPath)
+	}
+	
+	if len(unnamed) > 0 {
+		langs := make([]string, len(unnamed))
+		for i, lang := range unnamed {
+			langs[i] = blockLanguage(lang)
+		}
+		fmt.Printf("⚠️  Skipped %d code block(s) without a file name (%s)\n", len(unnamed), strings.Join(langs, ", "))
 	}
 	
 	return filesCreated, nil
 }
 
-// processCommandBlocks identifies shell command blocks and executes them
-func (e *Executor) processCommandBlocks(response string) ([]string, error) {
-	var commandsExecuted []string
-	
-	// Regex matches: ```bash or ```sh
-	// followed by content
-	// followed by ```
-	re := regexp.MustCompile("(?m)^```(bash|sh|shell)\\s*\\n([\\s\\S]*?)^```")
-	matches := re.FindAllStringSubmatch(response, -1)
-	
-	for _, match := range matches {
-		if len(match) < 3 {
+// blockLanguage names a code block's language for display
+func blockLanguage(lang string) string {
+	if lang == "" {
+		return "untagged"
+	}
+	return lang
+}
+
+// processCommandBlocks identifies shell command blocks and executes them in dir,
+// each bounded by the command timeout. Commands that invoke one of the agent's
+// tools (e.g. docker, kubectl) go through the same constraint and approval gate
+// as structured tool calls; servers and watchers are skipped.
+func (e *Executor) processCommandBlocks(ctx context.Context, agent Agent, response, dir string, budget *toolBudget) ([]CommandResult, error) {
+	var commandsExecuted []CommandResult
+	
+	// Top-level ```bash, ```sh or ```shell blocks without a file path; blocks
+	// nested inside generated files (e.g. README examples) are never run
+	for _, block := range parseCodeBlocks(response) {
+		if block.Path != "" || (block.Lang != "bash" && block.Lang != "sh" && block.Lang != "shell") {
 			continue
 		}
 		
-		script := strings.TrimSpace(match[2])
+		script := block.Content
 		if script == "" {
 			continue
 		}
@@ -299,24 +616,166 @@ func (e *Executor) processCommandBlocks(response string) ([]string, error) {
 				continue
 			}
 			
-			fmt.Printf("running: %s\n", cmdStr)
+			if isLongRunningCommand(cmdStr) {
+				fmt.Printf("⚠️  Skipping long-running command (start it yourself once the plan is done): %s\n", cmdStr)
+				continue
+			}
 			
-			// Execute command
-			cmd := exec.Command("bash", "-c", cmdStr)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
+			if e.sandbox && escapesSandbox(cmdStr, dir) {
+				fmt.Printf("⚠️  Skipping command that leaves the project directory: %s\n", cmdStr)
+				continue
+			}
 			
-			if err := cmd.Run(); err != nil {
-				return commandsExecuted, fmt.Errorf("failed to execute '%s': %w", cmdStr, err)
+			if err := budget.checkTime(); err != nil {
+				return commandsExecuted, err
+			}
+
+			if tool := commandTool(agent, cmdStr); tool != nil {
+				if err := e.authorizeTool(ctx, tool, cmdStr); err != nil {
+					fmt.Printf("⚠️  Skipping command: %v\n", err)
+					continue
+				}
+				if err := budget.reserve(tool.Name()); err != nil {
+					return commandsExecuted, err
+				}
 			}
+
+			if e.constraints != nil && e.constraints.DryRun {
+				fmt.Printf("[dry run] would run: %s\n", cmdStr)
+				continue
+			}
+
+			fmt.Printf("running: %s\n", cmdStr)
 			
-			commandsExecuted = append(commandsExecuted, cmdStr)
+			// Execute command, bounded by the remaining execution time
+			cmdCtx, cancel := budget.context(ctx)
+			var env []string
+			if e.sandbox {
+				env = sandboxEnv(dir)
+			}
+			
+			started := time.Now()
+			result := runCommand(cmdCtx, cmdStr, dir, env, e.commandTimeout)
+			budget.track(time.Since(started))
+			cancel()
+			commandsExecuted = append(commandsExecuted, result)
+			if result.Err != nil {
+				if timeErr := budget.checkTime(); timeErr != nil {
+					return commandsExecuted, timeErr
+				}
+				return commandsExecuted, fmt.Errorf("failed to execute '%s': %w", cmdStr, result.Err)
+			}
 		}
 	}
 	
 	return commandsExecuted, nil
 }
 
+// ExecuteTool runs one of an agent's tools after checking constraints and,
+// for destructive tools, obtaining user approval. In dry-run mode the tool
+// is described but not executed.
+func (e *Executor) ExecuteTool(ctx context.Context, agent Agent, call *models.ToolCall) (string, error) {
+	return e.executeTool(ctx, agent, call, newToolBudget(e.constraints))
+}
+
+// executeTool runs a tool call, charging it against the request's tool budget
+func (e *Executor) executeTool(ctx context.Context, agent Agent, call *models.ToolCall, budget *toolBudget) (string, error) {
+	var tool Tool
+	for _, t := range agent.GetTools() {
+		if t.Name() == call.Name {
+			tool = t
+			break
+		}
+	}
+	if tool == nil {
+		return "", fmt.Errorf("agent %s has no tool %q", agent.Name(), call.Name)
+	}
+
+	detail := call.Name
+	if len(call.Parameters) > 0 {
+		params, _ := json.Marshal(call.Parameters)
+		detail = fmt.Sprintf("%s %s", call.Name, params)
+	}
+
+	if err := e.authorizeTool(ctx, tool, detail); err != nil {
+		return "", err
+	}
+
+	if err := budget.reserve(tool.Name()); err != nil {
+		return "", err
+	}
+
+	if e.constraints != nil && e.constraints.DryRun {
+		return fmt.Sprintf("[dry run] would run: %s", detail), nil
+	}
+
+	toolCtx, cancel := budget.context(ctx)
+	defer cancel()
+
+	started := time.Now()
+	result, err := tool.Execute(toolCtx, call.Parameters)
+	budget.track(time.Since(started))
+	if err != nil {
+		if timeErr := budget.checkTime(); timeErr != nil {
+			return "", timeErr
+		}
+		return "", err
+	}
+
+	return result, nil
+}
+
+// authorizeTool enforces the allow/deny lists and asks for approval when a
+// tool is destructive or requires it
+func (e *Executor) authorizeTool(ctx context.Context, tool Tool, detail string) error {
+	if err := checkToolConstraints(e.constraints, tool.Name()); err != nil {
+		return err
+	}
+
+	if !tool.RequiresApproval() && !tool.IsDestructive() {
+		return nil
+	}
+
+	// Nothing runs in dry-run mode, so there is nothing to approve
+	if e.constraints != nil && e.constraints.DryRun {
+		return nil
+	}
+
+	if e.approval == nil {
+		return fmt.Errorf("%w: %s requires approval but no approval workflow is configured", ErrToolDenied, tool.Name())
+	}
+
+	approved, err := e.approval.RequestToolApproval(ctx, tool, detail)
+	if err != nil {
+		return fmt.Errorf("approval failed: %w", err)
+	}
+	if !approved {
+		return fmt.Errorf("%w: %s was not approved", ErrToolDenied, tool.Name())
+	}
+
+	return nil
+}
+
+// commandTool returns the agent tool a shell command invokes, matched by
+// the command's executable name (e.g. "kubectl apply" -> kubectl)
+func commandTool(agent Agent, cmd string) Tool {
+	fields := strings.Fields(cmd)
+	if len(fields) > 0 && fields[0] == "sudo" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	executable := filepath.Base(fields[0])
+	for _, tool := range agent.GetTools() {
+		if tool.Name() == executable {
+			return tool
+		}
+	}
+	return nil
+}
+
 // isDangerousCommand checks for obviously dangerous commands
 func isDangerousCommand(cmd string) bool {
 	dangerous := []string{"rm -rf /", "rm -rf ~", ":(){ :|:& };:"}
@@ -328,8 +787,42 @@ func isDangerousCommand(cmd string) bool {
 	return false
 }
 
-// buildPhaseQuery creates a comprehensive query for the phase with project context
-func (e *Executor) buildPhaseQuery(plan *ExecutionPlan, phase *Phase) string {
+// learnWorkflow feeds a successful plan back into procedural memory: patterns
+// the plan was seeded with are credited, otherwise the plan becomes a new pattern
+func (e *Executor) learnWorkflow(ctx context.Context, plan *ExecutionPlan) {
+	memoryService := e.orchestrator.memory
+	if memoryService == nil {
+		return
+	}
+
+	if len(plan.Patterns) > 0 {
+		for _, id := range plan.Patterns {
+			if err := memoryService.RecordPatternUse(ctx, id); err != nil {
+				e.logger.Warn("updating workflow pattern failed", "pattern", id, "error", err)
+			}
+		}
+		return
+	}
+
+	pattern := &models.WorkflowPattern{
+		Name:        plan.Title,
+		Steps:       make([]models.WorkflowStep, len(plan.Phases)),
+		SuccessRate: 1.0,
+	}
+	for i, phase := range plan.Phases {
+		pattern.Steps[i] = models.WorkflowStep{
+			Action: phase.Name,
+			Tool:   string(phase.Agent),
+		}
+	}
+
+	if err := memoryService.RecordWorkflow(ctx, pattern); err != nil {
+		e.logger.Warn("saving workflow pattern failed", "plan", plan.ID, "error", err)
+	}
+}
+
+// buildPhaseQuery creates a comprehensive query for the pending tasks of the phase with project context
+func (e *Executor) buildPhaseQuery(plan *ExecutionPlan, phase *Phase, pending []*Task) string {
 	var query strings.Builder
 	
 	query.WriteString(fmt.Sprintf("Phase: %s\n\n", phase.Name))
@@ -349,10 +842,32 @@ func (e *Executor) buildPhaseQuery(plan *ExecutionPlan, phase *Phase) string {
 		}
 	}
 	
-	query.WriteString("Please complete the following tasks:\n\n")
+	if failures := previousCheckFailures(plan, phase); failures != "" {
+		query.WriteString(failures)
+		query.WriteString("\n")
+	}
+	
+	var done []string
+	for _, t := range phase.Tasks {
+		if t.Completed {
+			done = append(done, t.Description)
+		}
+	}
+	if len(done) > 0 {
+		query.WriteString("Already completed in this phase:\n")
+		for _, d := range done {
+			query.WriteString(fmt.Sprintf("- %s\n", d))
+		}
+		query.WriteString("\n")
+	}
 	
-	for i, task := range phase.Tasks {
-		query.WriteString(fmt.Sprintf("%d. %s\n", i+1, task.Description))
+	if len(pending) == 0 {
+		query.WriteString("Please complete this phase.\n")
+	} else {
+		query.WriteString("Please complete the following tasks:\n\n")
+		for i, task := range pending {
+			query.WriteString(fmt.Sprintf("%d. %s\n", i+1, task.Description))
+		}
 	}
 	
 	query.WriteString(fmt.Sprintf("\n\nSuccess Criteria: %s\n", phase.SuccessCriteria))
@@ -386,13 +901,21 @@ func (e *Executor) initProjectStructure(plan *ExecutionPlan) error {
 	}
 	
 	fmt.Println("📂 Creating project structure...")
+	baseDir := e.projectDir(plan)
 	for dir := range plan.FileStructure {
 		if dir == "." || dir == "" {
 			continue
 		}
-		// Remove trailing slash if present
-		cleanDir := strings.TrimSuffix(dir, "/")
-		if err := os.MkdirAll(cleanDir, 0755); err != nil {
+		// Directories are named by the model, so keep them inside the project
+		cleanDir, ok := projectPath(baseDir, dir)
+		if !ok {
+			fmt.Printf("⚠️  Skipping unsafe directory: %s\n", dir)
+			continue
+		}
+		if cleanDir == "." {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(baseDir, cleanDir), 0755); err != nil {
 			return fmt.Errorf("failed to create %s: %w", cleanDir, err)
 		}
 		fmt.Printf("  ✓ %s/\n", cleanDir)
@@ -400,6 +923,21 @@ func (e *Executor) initProjectStructure(plan *ExecutionPlan) error {
 	return nil
 }
 
+// projectPath resolves a model-supplied path against the project directory
+// and returns it relative to baseDir; ok is false when it resolves outside
+func projectPath(baseDir, path string) (string, bool) {
+	root, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", false
+	}
+	resolved := resolvePath(root, path)
+	if outside(root, resolved) {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, resolved)
+	return rel, err == nil
+}
+
 // areDependenciesMet checks if all dependencies for a phase are satisfied
 // areDependenciesMet checks if all dependencies for a phase are satisfied
 func (e *Executor) areDependenciesMet(plan *ExecutionPlan, phase *Phase) bool {
@@ -409,7 +947,7 @@ func (e *Executor) areDependenciesMet(plan *ExecutionPlan, phase *Phase) bool {
 	
 	for _, depID := range phase.Dependencies {
 		depCompleted := false
-		for _, completedIdx := range plan.State.CompletedPhases {
+		for _, completedIdx := range plan.State.Snapshot().CompletedPhases {
 			completedPhase := plan.Phases[completedIdx]
 			// Check if completed phase matches dependency by ID OR Name
 			if completedPhase.ID == depID || completedPhase.Name == depID {
@@ -425,31 +963,6 @@ func (e *Executor) areDependenciesMet(plan *ExecutionPlan, phase *Phase) bool {
 	return true
 }
 
-// createCheckpoint creates a checkpoint before executing a phase
-func (e *Executor) createCheckpoint(planID string, phaseIndex int) (*Checkpoint, error) {
-	checkpoint := &Checkpoint{
-		ID:         fmt.Sprintf("checkpoint-%s-phase-%d", planID, phaseIndex),
-		PlanID:     planID,
-		PhaseIndex: phaseIndex,
-		Timestamp:  time.Now(),
-		Metadata:   make(map[string]string),
-	}
-	
-	// For now, just store in memory
-	// In Week 3, we'll add Git-based snapshots
-	e.checkpoints[checkpoint.ID] = checkpoint
-	
-	return checkpoint, nil
-}
-
-// rollback restores state to a checkpoint
-func (e *Executor) rollback(checkpoint *Checkpoint) error {
-	// For now, just log
-	// In Week 3, we'll implement Git restore
-	fmt.Printf("Rollback to checkpoint: %s\n", checkpoint.ID)
-	return nil
-}
-
 // truncateResponse truncates a response for display
 func truncateResponse(s string, maxLen int) string {
 	if len(s) <= maxLen {
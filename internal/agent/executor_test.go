@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestOutputDirRootsFileWrites tests that generated files land under the
+// output directory and the manifest records it as BaseDir
+func TestOutputDirRootsFileWrites(t *testing.T) {
+	t.Chdir(t.TempDir())
+	out := t.TempDir()
+
+	executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+	if err := executor.SetOutputDir(filepath.Join(out, "missing")); err == nil {
+		t.Error("Expected a missing output directory to be rejected")
+	}
+	if err := executor.SetOutputDir(out); err != nil {
+		t.Fatalf("SetOutputDir failed: %v", err)
+	}
+
+	plan := &ExecutionPlan{ID: "plan_out", Title: "Out"}
+	plan.Manifest = NewProjectManifest(plan.Title, executor.projectDir(plan))
+	if plan.Manifest.BaseDir != out {
+		t.Errorf("Expected manifest base %s, got %s", out, plan.Manifest.BaseDir)
+	}
+
+	response := "```python app/main.py\nprint('hi')\n```\n"
+	files, err := executor.processFileBlocks(response, plan, "Build", newOutputBudget(nil))
+	if err != nil || len(files) != 1 || files[0] != filepath.Join("app", "main.py") {
+		t.Fatalf("Unexpected result: %v, %v", files, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "app", "main.py")); err != nil {
+		t.Errorf("Expected file under the output directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("app", "main.py")); !os.IsNotExist(err) {
+		t.Error("Expected nothing written to the working directory")
+	}
+	if entry := plan.Manifest.CreatedFiles[0]; entry.Size == 0 {
+		t.Errorf("Expected manifest to stat the file under BaseDir, got %+v", entry)
+	}
+}
+
+// cancellingAgent cancels the plan's context while running its first task,
+// as Ctrl-C would
+type cancellingAgent struct {
+	scriptedAgent
+	cancel context.CancelFunc
+}
+
+func (a *cancellingAgent) Execute(ctx context.Context, request *Request) (*Response, error) {
+	a.queries = append(a.queries, request.Query)
+	a.cancel()
+	return nil, ctx.Err()
+}
+
+// TestExecuteStopsWhenCancelled tests that a cancelled plan is saved as
+// cancelled, with the interrupted phase left to resume, and runs no more phases
+func TestExecuteStopsWhenCancelled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agent := &cancellingAgent{cancel: cancel}
+	orchestrator := NewAgentOrchestrator(nil, nil, nil)
+	orchestrator.RegisterAgent(agent)
+	approval := NewApprovalWorkflow(nil)
+	executor := NewExecutor(orchestrator, approval)
+
+	plan := &ExecutionPlan{ID: "plan_cancel", Title: "Cancel", Phases: []Phase{
+		{ID: "phase-1", Name: "First", Agent: models.AgentTypeCode, Tasks: []Task{{Description: "one"}}},
+		{ID: "phase-2", Name: "Second", Agent: models.AgentTypeCode, Tasks: []Task{{Description: "two"}}},
+	}}
+
+	err := executor.Execute(ctx, plan)
+	if !errors.Is(err, ErrExecutionCancelled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got %v", err)
+	}
+	if len(agent.queries) != 1 {
+		t.Errorf("Expected only the first task to run, got %d queries", len(agent.queries))
+	}
+
+	saved, err := approval.LoadPlanState(plan.ID)
+	if err != nil {
+		t.Fatalf("Expected state to be saved: %v", err)
+	}
+	if saved.State.Status != ExecutionStatusCancelled || saved.State.CurrentPhase != 0 || len(saved.State.FailedPhases) != 0 {
+		t.Errorf("Expected a cancelled plan at phase 1, got %+v", saved.State.Snapshot())
+	}
+	if saved.Phases[0].Status != PhaseStatusPending {
+		t.Errorf("Expected the interrupted phase to be pending, got %s", saved.Phases[0].Status)
+	}
+
+	// A context cancelled before execution runs nothing
+	agent.queries = nil
+	if err := executor.Execute(ctx, plan); !errors.Is(err, ErrExecutionCancelled) {
+		t.Fatalf("Expected a cancellation error, got %v", err)
+	}
+	if len(agent.queries) != 0 {
+		t.Errorf("Expected no tasks to run, got %v", agent.queries)
+	}
+}
+
+// TestProjectPathsStayInProject tests that model-named files and directories
+// resolving outside the project are skipped
+func TestProjectPathsStayInProject(t *testing.T) {
+	parent := t.TempDir()
+	out := filepath.Join(parent, "project")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+	if err := executor.SetOutputDir(out); err != nil {
+		t.Fatalf("SetOutputDir failed: %v", err)
+	}
+
+	plan := &ExecutionPlan{ID: "plan_paths", Title: "Paths", FileStructure: map[string][]string{
+		"src/":           nil,
+		"../sibling":     nil,
+		"src/../../up":   nil,
+		parent + "/abs":  nil,
+		out + "/inside/": nil,
+	}}
+	if err := executor.initProjectStructure(plan); err != nil {
+		t.Fatalf("initProjectStructure failed: %v", err)
+	}
+	for _, dir := range []string{"src", "inside"} {
+		if _, err := os.Stat(filepath.Join(out, dir)); err != nil {
+			t.Errorf("Expected %s created in the project: %v", dir, err)
+		}
+	}
+	for _, dir := range []string{"sibling", "up", "abs"} {
+		if _, err := os.Stat(filepath.Join(parent, dir)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be created outside the project", dir)
+		}
+	}
+
+	response := "```python src/../../escaped.py\nprint('out')\n```\n" +
+		"```python " + parent + "/abs.py\nprint('out')\n```\n" +
+		"```python src/../..hidden.py\nprint('in')\n```\n"
+	files, err := executor.processFileBlocks(response, plan, "Build", newOutputBudget(nil))
+	if err != nil {
+		t.Fatalf("processFileBlocks failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "..hidden.py" {
+		t.Errorf("Expected only the in-project file written, got %v", files)
+	}
+	for _, name := range []string{"escaped.py", "abs.py"} {
+		if _, err := os.Stat(filepath.Join(parent, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be written outside the project", name)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// filePathPattern matches the file paths models put on a code fence
+var filePathPattern = regexp.MustCompile(`^[\w./-]+$`)
+
+// codeBlock is a fenced code block extracted from a model response
+type codeBlock struct {
+	Lang    string
+	Path    string
+	Content string
+}
+
+// parseCodeBlocks extracts the top-level fenced code blocks from text.
+//
+// A block closes on a bare fence of the same character that is at least as
+// long as the opening fence. Fences carrying an info string (```bash) inside a
+// block open a nested block instead, so a README that shows code examples is
+// extracted whole; a longer outer fence (````) works as in CommonMark.
+// Unterminated blocks, usually from truncated output, are dropped.
+func parseCodeBlocks(text string) []codeBlock {
+	lines := strings.Split(text, "\n")
+	var blocks []codeBlock
+
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := parseFence(lines[i])
+		if !ok {
+			continue
+		}
+
+		depth := 0
+		closed := false
+		var body []string
+		for j := i + 1; j < len(lines); j++ {
+			inner, innerInfo, isFence := parseFence(lines[j])
+			if isFence && inner[0] == fence[0] && len(inner) >= len(fence) {
+				if innerInfo != "" {
+					depth++
+				} else if depth > 0 {
+					depth--
+				} else {
+					i = j
+					closed = true
+					break
+				}
+			}
+			body = append(body, lines[j])
+		}
+
+		if !closed {
+			break
+		}
+		blocks = append(blocks, newCodeBlock(info, body))
+	}
+
+	return blocks
+}
+
+// parseFence reports whether a line is a code fence, returning the fence
+// characters and the trimmed info string after them
+func parseFence(line string) (fence, info string, ok bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", "", false
+	}
+
+	n := 0
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	if n < 3 {
+		return "", "", false
+	}
+
+	info = strings.TrimSpace(trimmed[n:])
+	// A backtick in the info string means inline code, not a fence
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+
+	return trimmed[:n], info, true
+}
+
+// newCodeBlock builds a code block from a fence info string and body lines.
+// The file path comes from the info string (```python app/main.py or
+// filename="app/main.py"), or from a leading "# path" / "// path" comment,
+// which is then dropped from the content.
+func newCodeBlock(info string, body []string) codeBlock {
+	block := codeBlock{}
+
+	fields := strings.Fields(info)
+	if len(fields) > 0 {
+		block.Lang = fields[0]
+	}
+	for i, field := range fields[1:] {
+		if key, value, found := strings.Cut(field, "="); found {
+			if key == "filename" || key == "file" || key == "path" {
+				block.Path = strings.Trim(value, `"'`)
+				break
+			}
+			continue
+		}
+		if i == 0 && filePathPattern.MatchString(field) {
+			block.Path = field
+			break
+		}
+	}
+
+	if block.Path == "" && len(body) > 0 {
+		if path, ok := commentPath(body[0]); ok {
+			block.Path = path
+			body = body[1:]
+		}
+	}
+
+	block.Content = strings.TrimSpace(strings.Join(body, "\n"))
+	return block
+}
+
+// commentPath extracts a file path from a "# path/file.ext" or "// path/file.ext" line
+func commentPath(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"#", "//"} {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		candidate := strings.TrimSpace(strings.TrimPrefix(line, prefix))
+		if filePathPattern.MatchString(candidate) && strings.ContainsAny(candidate, "./") {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// defaultFileNames maps a code block language to the file it most likely is
+// when the model omits a path
+var defaultFileNames = map[string]string{
+	"python":     "main.py",
+	"py":         "main.py",
+	"go":         "main.go",
+	"javascript": "index.js",
+	"js":         "index.js",
+	"typescript": "index.ts",
+	"ts":         "index.ts",
+	"rust":       "main.rs",
+	"java":       "Main.java",
+	"ruby":       "main.rb",
+	"php":        "index.php",
+	"html":       "index.html",
+	"css":        "styles.css",
+	"sql":        "schema.sql",
+	"dockerfile": "Dockerfile",
+	"docker":     "Dockerfile",
+	"makefile":   "Makefile",
+	"yaml":       "config.yaml",
+	"yml":        "config.yaml",
+	"toml":       "config.toml",
+}
+
+// nonFileLanguages are block languages that are never meant to become files
+var nonFileLanguages = map[string]bool{
+	"bash": true, "sh": true, "shell": true, "console": true,
+	"text": true, "txt": true, "plaintext": true, "output": true,
+}
+
+// defaultFilePath infers where an unnamed code block should be written: a
+// conventional file name for its language inside dir. It returns false for
+// languages with no sensible default.
+func defaultFilePath(lang, dir string) (string, bool) {
+	name, ok := defaultFileNames[strings.ToLower(lang)]
+	if !ok {
+		return "", false
+	}
+	if dir == "" {
+		return name, true
+	}
+	return path.Join(dir, name), true
+}
+
+// phaseDir returns the directory a phase is working in: the deepest directory
+// shared by the files it names, or projectRoot when it names none
+func phaseDir(files []string, projectRoot string) string {
+	if len(files) == 0 {
+		return projectRoot
+	}
+
+	common := strings.Split(path.Dir(files[0]), "/")
+	for _, file := range files[1:] {
+		parts := strings.Split(path.Dir(file), "/")
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+
+	dir := path.Join(common...)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// projectRootDir returns the single top-level directory of a planned file
+// structure, or "" when there is none or more than one
+func projectRootDir(structure map[string][]string) string {
+	roots := make(map[string]bool)
+	for dir := range structure {
+		root := strings.SplitN(strings.Trim(dir, "/"), "/", 2)[0]
+		if root != "" && root != "." {
+			roots[root] = true
+		}
+	}
+	if len(roots) != 1 {
+		return ""
+	}
+	for root := range roots {
+		return root
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseCodeBlocksNested tests that a markdown file containing its own code fences is extracted intact
+func TestParseCodeBlocksNested(t *testing.T) {
+	response := strings.Join([]string{
+		"Here is the README:",
+		"",
+		"```markdown app/README.md",
+		"# App",
+		"",
+		"Install with:",
+		"",
+		"```bash",
+		"pip install -r requirements.txt",
+		"```",
+		"",
+		"Then run:",
+		"",
+		"```python",
+		"import app",
+		"```",
+		"```",
+		"",
+		"```python app/main.py",
+		"print('hi')",
+		"```",
+	}, "\n")
+
+	blocks := parseCodeBlocks(response)
+	if len(blocks) != 2 {
+		t.Fatalf("Expected 2 top-level blocks, got %d: %+v", len(blocks), blocks)
+	}
+
+	readme := blocks[0]
+	if readme.Lang != "markdown" || readme.Path != "app/README.md" {
+		t.Errorf("Unexpected README block header: %q %q", readme.Lang, readme.Path)
+	}
+	if !strings.Contains(readme.Content, "```bash\npip install -r requirements.txt\n```") {
+		t.Errorf("Expected nested bash example to be kept, got:\n%s", readme.Content)
+	}
+	if !strings.HasSuffix(readme.Content, "```python\nimport app\n```") {
+		t.Errorf("Expected README to end with its python example, got:\n%s", readme.Content)
+	}
+
+	if blocks[1].Path != "app/main.py" || blocks[1].Content != "print('hi')" {
+		t.Errorf("Unexpected second block: %+v", blocks[1])
+	}
+}
+
+// TestParseCodeBlocksLongerFence tests that a four-backtick fence is only closed by a fence at least as long
+func TestParseCodeBlocksLongerFence(t *testing.T) {
+	response := "````md docs/guide.md\nExample:\n```\nplain block\n```\n````\n"
+
+	blocks := parseCodeBlocks(response)
+	if len(blocks) != 1 {
+		t.Fatalf("Expected 1 block, got %d", len(blocks))
+	}
+	if blocks[0].Content != "Example:\n```\nplain block\n```" {
+		t.Errorf("Unexpected content:\n%s", blocks[0].Content)
+	}
+}
+
+// TestParseCodeBlocksPaths tests the supported ways of naming a file
+func TestParseCodeBlocksPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		path     string
+		content  string
+	}{
+		{"fence line", "```go cmd/main.go\npackage main\n```", "cmd/main.go", "package main"},
+		{"filename attribute", "```python filename=\"src/api.py\"\nx = 1\n```", "src/api.py", "x = 1"},
+		{"hash comment", "```python\n# src/models.py\nx = 1\n```", "src/models.py", "x = 1"},
+		{"slash comment", "```js\n// web/index.js\nlet x = 1\n```", "web/index.js", "let x = 1"},
+		{"no path", "```python\n# Setup\nx = 1\n```", "", "# Setup\nx = 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := parseCodeBlocks(tt.response)
+			if len(blocks) != 1 {
+				t.Fatalf("Expected 1 block, got %d", len(blocks))
+			}
+			if blocks[0].Path != tt.path || blocks[0].Content != tt.content {
+				t.Errorf("Expected %q / %q, got %q / %q", tt.path, tt.content, blocks[0].Path, blocks[0].Content)
+			}
+		})
+	}
+}
+
+// TestParseCodeBlocksUnterminated tests that truncated output does not produce a partial file
+func TestParseCodeBlocksUnterminated(t *testing.T) {
+	blocks := parseCodeBlocks("```python app.py\nprint('a')\n```\n```python b.py\nprint(")
+	if len(blocks) != 1 || blocks[0].Path != "app.py" {
+		t.Errorf("Expected only the terminated block, got %+v", blocks)
+	}
+}
+
+// TestDefaultFilePath tests file name inference for code blocks without a path
+func TestDefaultFilePath(t *testing.T) {
+	root := projectRootDir(map[string][]string{"todo_api/": {"main.py"}, "todo_api/src/": {"api.py"}})
+	if root != "todo_api" {
+		t.Fatalf("Expected project root todo_api, got %q", root)
+	}
+
+	if path, ok := defaultFilePath("Python", root); !ok || path != "todo_api/main.py" {
+		t.Errorf("Expected todo_api/main.py, got %q (%v)", path, ok)
+	}
+	if path, ok := defaultFilePath("go", ""); !ok || path != "main.go" {
+		t.Errorf("Expected main.go, got %q (%v)", path, ok)
+	}
+	if _, ok := defaultFilePath("brainfuck", root); ok {
+		t.Error("Expected no default for an unknown language")
+	}
+
+	if root := projectRootDir(map[string][]string{"api/": nil, "web/": nil}); root != "" {
+		t.Errorf("Expected no single root for multiple top-level dirs, got %q", root)
+	}
+
+	if dir := phaseDir([]string{"todo_api/web/app.js", "todo_api/web/css/site.css"}, root); dir != "todo_api/web" {
+		t.Errorf("Expected the phase's directory todo_api/web, got %q", dir)
+	}
+	if dir := phaseDir([]string{"README.md", "todo_api/main.py"}, root); dir != "" {
+		t.Errorf("Expected the top level for files across the tree, got %q", dir)
+	}
+	if dir := phaseDir(nil, root); dir != root {
+		t.Errorf("Expected the project root without phase files, got %q", dir)
+	}
+}
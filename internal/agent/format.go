@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// formatTimeout bounds each external formatter run
+const formatTimeout = 30 * time.Second
+
+// externalFormatters rewrite files in place, by extension. Go is formatted
+// in-process with go/format, the library behind gofmt.
+var externalFormatters = map[string][]string{
+	".js":  {"prettier", "--write"},
+	".jsx": {"prettier", "--write"},
+	".ts":  {"prettier", "--write"},
+	".tsx": {"prettier", "--write"},
+	".mjs": {"prettier", "--write"},
+	".py":  {"black", "--quiet"},
+}
+
+// FormatResult is the outcome of formatting one generated file
+type FormatResult struct {
+	Path      string
+	Formatter string
+	Changed   bool
+	Err       error // The file could not be parsed or formatted
+}
+
+// formatFile formats a written file with the formatter for its type. ok is
+// false when there is no formatter for the file or it isn't installed.
+func formatFile(ctx context.Context, path string) (FormatResult, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".go" {
+		return formatGoFile(path), true
+	}
+
+	args, ok := externalFormatters[ext]
+	if !ok {
+		return FormatResult{}, false
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return FormatResult{}, false
+	}
+
+	result := FormatResult{Path: path, Formatter: args[0]}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return result, true
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, formatTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(cmdCtx, args[0], append(args[1:], path)...).CombinedOutput()
+	if err != nil {
+		result.Err = fmt.Errorf("%s: %s", args[0], firstLine(string(output), err))
+		return result, true
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return result, true
+	}
+	result.Changed = !bytes.Equal(before, after)
+	return result, true
+}
+
+// formatGoFile rewrites a Go file in gofmt style
+func formatGoFile(path string) FormatResult {
+	result := FormatResult{Path: path, Formatter: "gofmt"}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	formatted, err := format.Source(source)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if bytes.Equal(source, formatted) {
+		return result
+	}
+
+	result.Changed = true
+	result.Err = os.WriteFile(path, formatted, 0644)
+	return result
+}
+
+// firstLine returns the first non-empty line of a tool's output, or err
+func firstLine(output string, err error) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return err.Error()
+}
+
+// formatFiles formats files written by a task, flagging files that fail to
+// parse in the manifest so broken generations are visible
+func (e *Executor) formatFiles(ctx context.Context, plan *ExecutionPlan, files []string) []FormatResult {
+	if !e.format {
+		return nil
+	}
+
+	baseDir := e.projectDir(plan)
+	var results []FormatResult
+	for _, path := range files {
+		result, ok := formatFile(ctx, filepath.Join(baseDir, path))
+		if !ok {
+			continue
+		}
+		result.Path = path
+		results = append(results, result)
+
+		switch {
+		case result.Err != nil:
+			fmt.Printf("⚠️  %s failed on %s: %v\n", result.Formatter, path, result.Err)
+			e.logger.Warn("generated file failed to format", "plan", plan.ID, "file", path, "formatter", result.Formatter, "error", result.Err)
+			if plan.Manifest != nil {
+				plan.Manifest.FlagFile(path, result.Err.Error())
+			}
+		case result.Changed:
+			fmt.Printf("🎨 Reformatted %s (%s)\n", path, result.Formatter)
+		}
+	}
+	return results
+}
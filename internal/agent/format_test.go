@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFormatFilesGo tests that Go files are reformatted and unparsable ones flagged
+func TestFormatFilesGo(t *testing.T) {
+	dir := t.TempDir()
+	messy, broken, notes := "messy.go", "broken.go", "notes.txt"
+	files := map[string]string{
+		messy:  "package main\nfunc main(){\nx:=1\n_=x}\n",
+		broken: "```go\npackage main\n```\n",
+		notes:  "not code",
+	}
+	plan := &ExecutionPlan{ID: "plan_format", Manifest: NewProjectManifest("format", dir)}
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		plan.Manifest.AddFile(path, "Build", "")
+	}
+
+	executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+	results := executor.formatFiles(context.Background(), plan, []string{messy, broken, notes})
+	if len(results) != 2 {
+		t.Fatalf("Expected results for the two Go files only, got %+v", results)
+	}
+
+	formatted, _ := os.ReadFile(filepath.Join(dir, messy))
+	if string(formatted) != "package main\n\nfunc main() {\n\tx := 1\n\t_ = x\n}\n" {
+		t.Errorf("Unexpected formatting:\n%s", formatted)
+	}
+	if !results[0].Changed || results[0].Err != nil {
+		t.Errorf("Expected messy.go to be reformatted, got %+v", results[0])
+	}
+
+	flagged := plan.Manifest.ProblemFiles()
+	if len(flagged) != 1 || flagged[0].Path != broken {
+		t.Errorf("Expected broken.go to be flagged, got %+v", flagged)
+	}
+
+	executor.SetFormatFiles(false)
+	if results := executor.formatFiles(context.Background(), plan, []string{messy}); results != nil {
+		t.Error("Expected no formatting when disabled")
+	}
+}
@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrBinaryNotFound is returned when an infrastructure tool's CLI isn't installed
+var ErrBinaryNotFound = errors.New("binary not found")
+
+// ErrNoDryRun is returned in dry-run mode for subcommands that can't be
+// previewed without making changes
+var ErrNoDryRun = errors.New("subcommand has no dry run")
+
+// terraformReadOnly are terraform subcommands that change nothing
+var terraformReadOnly = map[string]bool{
+	"plan": true, "show": true, "validate": true, "output": true, "version": true,
+	"providers": true, "graph": true, "state list": true, "state show": true,
+}
+
+// kubectlReadOnly are kubectl verbs that change nothing
+var kubectlReadOnly = map[string]bool{
+	"get": true, "describe": true, "logs": true, "top": true, "explain": true, "version": true,
+	"api-resources": true, "api-versions": true, "cluster-info": true, "diff": true,
+}
+
+// kubectlReadOnlySubcommands are the read-only subcommands of kubectl verbs
+// that otherwise change things, like auth reconcile and config set
+var kubectlReadOnlySubcommands = map[string]map[string]bool{
+	"auth":   {"can-i": true, "whoami": true},
+	"config": {"view": true},
+}
+
+// kubectlDryRunnable are kubectl verbs that accept --dry-run
+var kubectlDryRunnable = map[string]bool{
+	"apply": true, "create": true, "delete": true, "patch": true, "replace": true, "scale": true,
+	"set": true, "label": true, "annotate": true, "run": true, "expose": true, "autoscale": true,
+	"cordon": true, "uncordon": true, "drain": true, "taint": true,
+}
+
+// dockerReadOnly are docker subcommands that change nothing
+var dockerReadOnly = map[string]bool{
+	"ps": true, "images": true, "inspect": true, "logs": true, "version": true, "info": true,
+	"history": true, "top": true, "stats": true, "diff": true, "port": true, "search": true,
+}
+
+// cliTool runs one infrastructure CLI with arguments from a tool call. In
+// dry-run mode the arguments are rewritten by preview so nothing changes;
+// preview returns nil args when the command should only be described.
+type cliTool struct {
+	binary  string
+	dryRun  bool
+	preview func(args []string) ([]string, error)
+}
+
+// run executes the binary with the call's "args" in its "dir". A call with
+// "dry_run": true is previewed even when the tool isn't in dry-run mode.
+func (t *cliTool) run(ctx context.Context, params map[string]interface{}) (string, error) {
+	args, err := toolArgs(params)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := exec.LookPath(t.binary)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s is not installed or not on PATH", ErrBinaryNotFound, t.binary)
+	}
+
+	if dryRun, _ := params["dry_run"].(bool); dryRun || t.dryRun {
+		previewArgs, err := t.preview(args)
+		if err != nil {
+			return "", err
+		}
+		if previewArgs == nil {
+			return fmt.Sprintf("[dry run] %s has no dry run for this command; would run: %s %s", t.binary, t.binary, strings.Join(args, " ")), nil
+		}
+		args = previewArgs
+	}
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir, _ = params["dir"].(string)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = commandWaitDelay
+
+	err = cmd.Run()
+	result := tail(strings.TrimSpace(output.String()), maxCommandOutput)
+	if err != nil {
+		if result == "" {
+			return "", fmt.Errorf("%s %s failed: %w", t.binary, strings.Join(args, " "), err)
+		}
+		return result, fmt.Errorf("%s %s failed: %w\n%s", t.binary, strings.Join(args, " "), err, result)
+	}
+	return result, nil
+}
+
+// toolArgs reads a tool call's "args", either a list or a space-separated string
+func toolArgs(params map[string]interface{}) ([]string, error) {
+	var args []string
+	switch v := params["args"].(type) {
+	case string:
+		args = strings.Fields(v)
+	case []string:
+		args = v
+	case []interface{}:
+		for _, arg := range v {
+			args = append(args, fmt.Sprint(arg))
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing args parameter")
+	}
+	return args, nil
+}
+
+// withoutFlag returns args with every occurrence of flag removed
+func withoutFlag(args []string, flag string) []string {
+	kept := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg != flag && !strings.HasPrefix(arg, flag+"=") {
+			kept = append(kept, arg)
+		}
+	}
+	return kept
+}
+
+// cliFlags are the flags a CLI accepts before its subcommand: value flags
+// take the next argument unless written as --flag=value, boolean flags don't
+type cliFlags struct {
+	value   map[string]bool
+	boolean map[string]bool
+}
+
+// terraformFlags are terraform's global flags
+var terraformFlags = cliFlags{
+	value:   map[string]bool{"-chdir": true},
+	boolean: map[string]bool{"-help": true, "-version": true},
+}
+
+// kubectlFlags are kubectl's global flags and the common flags models put
+// before the verb or between a verb and its subcommand
+var kubectlFlags = cliFlags{
+	value: map[string]bool{
+		"-n": true, "--namespace": true, "--context": true, "--cluster": true, "--user": true,
+		"--kubeconfig": true, "-s": true, "--server": true, "--token": true, "--as": true,
+		"--as-group": true, "--as-uid": true, "--request-timeout": true, "--cache-dir": true,
+		"--certificate-authority": true, "--client-certificate": true, "--client-key": true,
+		"--tls-server-name": true, "-v": true, "--v": true, "-f": true, "--filename": true,
+		"-l": true, "--selector": true, "-o": true, "--output": true, "-c": true, "--container": true,
+	},
+	boolean: map[string]bool{
+		"--insecure-skip-tls-verify": true, "--match-server-version": true,
+		"--warnings-as-errors": true, "--disable-compression": true, "-A": true, "--all-namespaces": true,
+	},
+}
+
+// dockerFlags are docker's global flags
+var dockerFlags = cliFlags{
+	value: map[string]bool{
+		"-c": true, "--context": true, "-H": true, "--host": true, "-l": true, "--log-level": true,
+		"--config": true, "--tlscacert": true, "--tlscert": true, "--tlskey": true,
+	},
+	boolean: map[string]bool{"-D": true, "--debug": true, "--tls": true, "--tlsverify": true},
+}
+
+// firstCommand returns the first argument that isn't a flag or a flag's
+// value, and its index. An unknown flag might take the next argument, so
+// the command can't be told apart from a value and "", -1 is returned.
+func firstCommand(args []string, flags cliFlags) (string, int) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case !strings.HasPrefix(arg, "-"):
+			return arg, i
+		case strings.Contains(arg, "="):
+		case flags.value[arg]:
+			i++ // Skip the flag's value
+		case !flags.boolean[arg]:
+			return "", -1
+		}
+	}
+	return "", -1
+}
+
+// previewTerraform maps apply to plan and destroy to plan -destroy
+func previewTerraform(args []string) ([]string, error) {
+	sub, i := firstCommand(args, terraformFlags)
+	if sub == "state" && i+1 < len(args) {
+		sub += " " + args[i+1]
+	}
+
+	switch {
+	case terraformReadOnly[sub]:
+		return args, nil
+	case sub == "apply" || sub == "destroy":
+		preview := append(append([]string{}, args[:i]...), "plan")
+		if sub == "destroy" {
+			preview = append(preview, "-destroy")
+		}
+		return append(preview, withoutFlag(args[i+1:], "-auto-approve")...), nil
+	default:
+		return nil, fmt.Errorf("%w: terraform %s", ErrNoDryRun, sub)
+	}
+}
+
+// previewKubectl adds --dry-run=client to verbs that change the cluster
+func previewKubectl(args []string) ([]string, error) {
+	verb, i := firstCommand(args, kubectlFlags)
+	sub := ""
+	if i >= 0 {
+		sub, _ = firstCommand(args[i+1:], kubectlFlags)
+	}
+	switch {
+	case kubectlReadOnly[verb] || kubectlReadOnlySubcommands[verb][sub]:
+		return args, nil
+	case kubectlDryRunnable[verb]:
+		return append(withoutFlag(args, "--dry-run"), "--dry-run=client"), nil
+	default:
+		return nil, fmt.Errorf("%w: kubectl %s", ErrNoDryRun, verb)
+	}
+}
+
+// previewDocker runs read-only commands and docker compose with --dry-run;
+// docker itself has no dry run, so anything else is only described
+func previewDocker(args []string) ([]string, error) {
+	sub, i := firstCommand(args, dockerFlags)
+	switch {
+	case dockerReadOnly[sub]:
+		return args, nil
+	case sub == "compose":
+		return append(append(append([]string{}, args[:i+1]...), "--dry-run"), withoutFlag(args[i+1:], "--dry-run")...), nil
+	default:
+		return nil, nil
+	}
+}
+
+// DockerTool runs the docker CLI
+type DockerTool struct {
+	DryRun bool // Run only read-only commands and compose --dry-run
+}
+
+func (t *DockerTool) Name() string { return "docker" }
+func (t *DockerTool) Description() string {
+	return "Run docker commands (args: docker arguments, dir, dry_run)"
+}
+func (t *DockerTool) IsDestructive() bool    { return true }
+func (t *DockerTool) RequiresApproval() bool { return true }
+func (t *DockerTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return (&cliTool{binary: "docker", dryRun: t.DryRun, preview: previewDocker}).run(ctx, params)
+}
+
+// KubectlTool runs the kubectl CLI
+type KubectlTool struct {
+	DryRun bool // Add --dry-run=client to commands that change the cluster
+}
+
+func (t *KubectlTool) Name() string { return "kubectl" }
+func (t *KubectlTool) Description() string {
+	return "Run kubectl commands (args: kubectl arguments, dir, dry_run)"
+}
+func (t *KubectlTool) IsDestructive() bool    { return true }
+func (t *KubectlTool) RequiresApproval() bool { return true }
+func (t *KubectlTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return (&cliTool{binary: "kubectl", dryRun: t.DryRun, preview: previewKubectl}).run(ctx, params)
+}
+
+// TerraformTool runs the terraform CLI
+type TerraformTool struct {
+	DryRun bool // Run terraform plan in place of apply and destroy
+}
+
+func (t *TerraformTool) Name() string { return "terraform" }
+func (t *TerraformTool) Description() string {
+	return "Run terraform commands (args: terraform arguments, dir, dry_run)"
+}
+func (t *TerraformTool) IsDestructive() bool    { return true }
+func (t *TerraformTool) RequiresApproval() bool { return true }
+func (t *TerraformTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return (&cliTool{binary: "terraform", dryRun: t.DryRun, preview: previewTerraform}).run(ctx, params)
+}
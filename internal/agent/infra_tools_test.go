@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeBinaries puts scripts that echo their arguments on PATH; a script exits
+// 1 when its first argument is "fail"
+func fakeBinaries(t *testing.T, names ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	for _, name := range names {
+		script := "#!/bin/sh\necho " + name + " \"$@\"\n[ \"$1\" != fail ]\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+// TestInfraToolsRunBinaries tests real execution, failures and missing binaries
+func TestInfraToolsRunBinaries(t *testing.T) {
+	fakeBinaries(t, "kubectl")
+	ctx := context.Background()
+
+	out, err := (&KubectlTool{}).Execute(ctx, map[string]interface{}{"args": []interface{}{"apply", "-f", "app.yaml"}})
+	if err != nil || out != "kubectl apply -f app.yaml" {
+		t.Errorf("Expected kubectl to run, got %q, %v", out, err)
+	}
+
+	out, err = (&KubectlTool{}).Execute(ctx, map[string]interface{}{"args": "fail now"})
+	if err == nil || out != "kubectl fail now" || !strings.Contains(err.Error(), "kubectl fail now") {
+		t.Errorf("Expected the failure and its output, got %q, %v", out, err)
+	}
+
+	if _, err := (&TerraformTool{}).Execute(ctx, map[string]interface{}{"args": "plan"}); !errors.Is(err, ErrBinaryNotFound) {
+		t.Errorf("Expected ErrBinaryNotFound for a missing terraform, got %v", err)
+	}
+	if _, err := (&KubectlTool{}).Execute(ctx, map[string]interface{}{}); err == nil {
+		t.Error("Expected missing args to be rejected")
+	}
+}
+
+// TestInfraToolsDryRun tests the dry-run mapping for each tool
+func TestInfraToolsDryRun(t *testing.T) {
+	fakeBinaries(t, "docker", "kubectl", "terraform")
+	ctx := context.Background()
+
+	tests := []struct {
+		tool Tool
+		args string
+		want string
+	}{
+		{&TerraformTool{DryRun: true}, "apply -auto-approve -var env=prod", "terraform plan -var env=prod"},
+		{&TerraformTool{DryRun: true}, "-chdir=infra destroy", "terraform -chdir=infra plan -destroy"},
+		{&TerraformTool{DryRun: true}, "state list", "terraform state list"},
+		{&KubectlTool{DryRun: true}, "delete pod web --dry-run=none", "kubectl delete pod web --dry-run=client"},
+		{&KubectlTool{DryRun: true}, "get pods", "kubectl get pods"},
+		{&KubectlTool{DryRun: true}, "auth can-i create pods", "kubectl auth can-i create pods"},
+		{&KubectlTool{DryRun: true}, "--context=prod config view", "kubectl --context=prod config view"},
+		{&KubectlTool{DryRun: true}, "-n prod delete pod x", "kubectl -n prod delete pod x --dry-run=client"},
+		{&KubectlTool{DryRun: true}, "-n get delete pod x", "kubectl -n get delete pod x --dry-run=client"},
+		{&KubectlTool{DryRun: true}, "--context prod auth can-i get pods", "kubectl --context prod auth can-i get pods"},
+		{&TerraformTool{DryRun: true}, "-chdir infra apply", "terraform -chdir infra plan"},
+		{&DockerTool{DryRun: true}, "compose up -d", "docker compose --dry-run up -d"},
+		{&DockerTool{DryRun: true}, "ps -a", "docker ps -a"},
+		{&DockerTool{DryRun: true}, "rm -f web", "[dry run] docker has no dry run for this command; would run: docker rm -f web"},
+		{&DockerTool{DryRun: true}, "--context prod ps -a", "docker --context prod ps -a"},
+		// A flag value that names a read-only command doesn't make the command read-only
+		{&DockerTool{DryRun: true}, "--context ps rm -f c", "[dry run] docker has no dry run for this command; would run: docker --context ps rm -f c"},
+		// Unknown flags might take a value, so the command is only described
+		{&DockerTool{DryRun: true}, "--mystery ps rm -f c", "[dry run] docker has no dry run for this command; would run: docker --mystery ps rm -f c"},
+	}
+	for _, tt := range tests {
+		out, err := tt.tool.Execute(ctx, map[string]interface{}{"args": tt.args})
+		if err != nil || out != tt.want {
+			t.Errorf("%s %s: expected %q, got %q, %v", tt.tool.Name(), tt.args, tt.want, out, err)
+		}
+	}
+
+	// A call can ask for a dry run itself
+	out, err := (&TerraformTool{}).Execute(ctx, map[string]interface{}{"args": "apply", "dry_run": true})
+	if err != nil || out != "terraform plan" {
+		t.Errorf("Expected a per-call dry run, got %q, %v", out, err)
+	}
+
+	for _, tt := range []struct {
+		tool Tool
+		args string
+	}{
+		{&TerraformTool{DryRun: true}, "import aws_instance.web i-123"},
+		{&KubectlTool{DryRun: true}, "exec -it web -- sh"},
+		{&KubectlTool{DryRun: true}, "auth reconcile -f rbac.yaml"},
+		{&KubectlTool{DryRun: true}, "config set-context prod"},
+		{&KubectlTool{DryRun: true}, "-n get exec web -- sh"},
+		{&KubectlTool{DryRun: true}, "--mystery get exec web -- sh"},
+	} {
+		if _, err := tt.tool.Execute(ctx, map[string]interface{}{"args": tt.args}); !errors.Is(err, ErrNoDryRun) {
+			t.Errorf("%s %s: expected ErrNoDryRun, got %v", tt.tool.Name(), tt.args, err)
+		}
+	}
+}
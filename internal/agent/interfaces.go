@@ -2,6 +2,7 @@ package agent
 
 import (
 "context"
+"log/slog"
 "time"
 
 "github.com/quantumflow/quantumflow/internal/models"
@@ -14,6 +15,13 @@ Type() models.AgentType
 Execute(ctx context.Context, request *Request) (*Response, error)
 CanHandle(ctx context.Context, query string) (float64, error)
 GetTools() []Tool
+
+// Config returns the agent's configuration
+Config() *AgentConfig
+
+// BuildPrompt returns the full prompt Execute sends for a request: system
+// instruction, context, memories and query
+BuildPrompt(request *Request) string
 }
 
 // Request represents a request sent to an agent
@@ -26,8 +34,16 @@ MaxTokens   int
 Temperature float64
 Timeout     time.Duration
 
+// RoutingConfidence is the classifier's confidence that this agent fits the query
+// (0 when the agent was invoked directly, e.g. by the plan executor)
+RoutingConfidence float64
+
 // StreamCallback is called for each token during streaming generation
 StreamCallback func(token string)
+
+// Trace records the routing decision in Response.Metadata["routing"]
+// as a *RoutingTrace
+Trace bool
 }
 
 // Response represents an agent's response
@@ -61,6 +77,11 @@ AllowedTools     []string
 DeniedTools      []string
 MaxExecutionTime time.Duration
 DryRun           bool
+
+// MaxFileBytes skips generated files larger than this, and MaxTotalBytes
+// aborts a plan phase once its generated files exceed it (0 uses defaults)
+MaxFileBytes  int64
+MaxTotalBytes int64
 }
 
 // Tool represents a capability available to agents
@@ -113,9 +134,13 @@ ModelName       string
 ContextSize     int
 Temperature     float64
 MaxConcurrency  int
-Tools           []Tool
-MemoryEnabled   bool
-MaxMemoryItems  int
+Tools           []Tool // Replaces the agent's built-in tools when non-nil
+MemoryEnabled   bool // Inject memories into prompts and store the agent's Q&A
+MaxMemoryItems  int  // Most memories injected per request; 0 uses the retrieval default
+
+// SystemPrompt overrides the agent's built-in persona and instructions
+// (e.g. org-specific coding standards); empty uses the default
+SystemPrompt string
 }
 
 // OrchestratorConfig holds orchestrator configuration
@@ -126,6 +151,24 @@ ConflictResolution  bool
 SummaryPropagation  bool
 MaxAgentsPerQuery   int
 DefaultTimeout      time.Duration
+
+// MinConfidence is the classifier confidence below which the orchestrator
+// re-routes by asking every agent's CanHandle (0 disables the fallback)
+MinConfidence float64
+
+// Logger receives routing decisions and failures the orchestrator and plan
+// executor recover from (nil discards them)
+Logger *slog.Logger
+
+// Observer is notified of every agent execution, e.g. to export metrics
+// (nil disables it)
+Observer RequestObserver
+}
+
+// RequestObserver receives the start and outcome of each agent execution
+type RequestObserver interface {
+AgentStarted(agent string)
+AgentFinished(agent string, duration time.Duration, err error)
 }
 
 // DefaultOrchestratorConfig returns default configuration
@@ -137,5 +180,6 @@ ConflictResolution: true,
 SummaryPropagation: true,
 MaxAgentsPerQuery:  1,
 DefaultTimeout:     5 * time.Minute,
+MinConfidence:      0.4,
 }
 }
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrNoJSON is returned when a model response contains no JSON object
+var ErrNoJSON = errors.New("no JSON object found in response")
+
+// ErrMalformedJSON is returned when a response's JSON can't be parsed, even
+// after repair
+var ErrMalformedJSON = errors.New("JSON parse error")
+
+// extractJSON finds the JSON object in a model response, stripping markdown
+// fences. A truncated object, e.g. one cut off by the context limit, is
+// repaired; repaired reports whether that was needed.
+func extractJSON(response string) (jsonStr string, repaired bool, err error) {
+	response = strings.TrimSpace(response)
+
+	// Remove markdown code blocks if present
+	if strings.HasPrefix(response, "```json") {
+		response = strings.TrimPrefix(response, "```json")
+		response = strings.TrimSuffix(response, "```")
+		response = strings.TrimSpace(response)
+	} else if strings.HasPrefix(response, "```") {
+		response = strings.TrimPrefix(response, "```")
+		response = strings.TrimSuffix(response, "```")
+		response = strings.TrimSpace(response)
+	}
+
+	start := strings.Index(response, "{")
+	if start == -1 {
+		return "", false, ErrNoJSON
+	}
+	if end := strings.LastIndex(response, "}"); end > start {
+		if candidate := response[start : end+1]; json.Valid([]byte(candidate)) {
+			return candidate, false, nil
+		}
+	}
+
+	// Truncated output runs to the end of the response
+	if fixed, ok := repairJSON(response[start:]); ok {
+		return fixed, true, nil
+	}
+	return "", false, ErrMalformedJSON
+}
+
+// repairJSON closes a truncated JSON document: an unterminated string is
+// closed, and if the result still doesn't parse, the document is cut back to
+// the last complete member. Open objects and arrays are then closed.
+func repairJSON(s string) (string, bool) {
+	// cut is a point where the document can end once its containers are closed
+	type cut struct {
+		at      int
+		closers string
+	}
+
+	var (
+		stack    []byte
+		cuts     []cut
+		inString bool
+		escaped  bool
+	)
+	closers := func() string {
+		var b strings.Builder
+		for i := len(stack) - 1; i >= 0; i-- {
+			b.WriteByte(stack[i])
+		}
+		return b.String()
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			if c == '{' {
+				stack = append(stack, '}')
+			} else {
+				stack = append(stack, ']')
+			}
+			cuts = append(cuts, cut{i + 1, closers()})
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return "", false
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				// Anything after the outermost object is prose
+				doc := s[:i+1]
+				return doc, json.Valid([]byte(doc))
+			}
+			cuts = append(cuts, cut{i + 1, closers()})
+		case ',':
+			cuts = append(cuts, cut{i, closers()})
+		}
+	}
+
+	// Close a string cut off mid-value
+	tail := strings.TrimRight(s, " \t\r\n")
+	if inString && !escaped {
+		if doc := tail + `"` + closers(); json.Valid([]byte(doc)) {
+			return doc, true
+		}
+	}
+	if doc := tail + closers(); json.Valid([]byte(doc)) {
+		return doc, true
+	}
+
+	// Drop the incomplete member, latest cut first
+	for i := len(cuts) - 1; i >= 0; i-- {
+		doc := strings.TrimRight(s[:cuts[i].at], " \t\r\n") + cuts[i].closers
+		if json.Valid([]byte(doc)) {
+			return doc, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+)
+
+// stubOllama serves canned /api/generate responses in order, recording the
+// requests it receives
+func stubOllama(t *testing.T, responses ...string) (*inference.Client, *[]inference.GenerateRequest) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests []inference.GenerateRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var req inference.GenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+
+		response := ""
+		if len(requests) <= len(responses) {
+			response = responses[len(requests)-1]
+		}
+		json.NewEncoder(w).Encode(inference.GenerateResponse{Response: response, Done: true})
+	}))
+	t.Cleanup(server.Close)
+
+	config := inference.DefaultConfig()
+	config.OllamaURL = server.URL
+	return inference.NewClient(config), &requests
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		repaired bool
+		err      error
+	}{
+		{"complete", `Sure: {"a": 1} hope that helps`, `{"a": 1}`, false, nil},
+		{"fenced", "```json\n{\"a\": [1, 2]}\n```", `{"a": [1, 2]}`, false, nil},
+		{"cut mid string", `{"title": "Todo", "phases": [{"name": "Setu`, `{"title": "Todo", "phases": [{"name": "Setu"}]}`, true, nil},
+		{"cut after comma", `{"a": 1, "b": [1, 2,`, `{"a": 1, "b": [1, 2]}`, true, nil},
+		{"cut mid key", `{"a": {"x": 1}, "b`, `{"a": {"x": 1}}`, true, nil},
+		{"cut after colon", `{"a": 1, "b":`, `{"a": 1}`, true, nil},
+		{"cut mid number", `{"a": [1, 23`, `{"a": [1, 23]}`, true, nil},
+		{"escaped quote", `{"a": "say \"hi`, `{"a": "say \"hi"}`, true, nil},
+		{"no json", "I can't do that", "", false, ErrNoJSON},
+		{"unrepairable", `{"a": 1,}`, "", false, ErrMalformedJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, repaired, err := extractJSON(tt.response)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want || repaired != tt.repaired {
+				t.Errorf("Got %q (repaired %v), want %q (repaired %v)", got, repaired, tt.want, tt.repaired)
+			}
+		})
+	}
+}
+
+// TestGeneratePhasesRetriesShorter tests that an unusable plan response is
+// retried once with the short prompt
+func TestGeneratePhasesRetriesShorter(t *testing.T) {
+	client, requests := stubOllama(t,
+		`{"title": "Todo", "phases": [{"name": "Setup", "tasks": [{"description": 42}]}]}`,
+		`{"title": "Todo", "phases": [{"name": "Setup", "agent": "code", "tasks": [{"description": "Init"}]}]}`,
+	)
+	planner := NewPlanner(client)
+	req := &PlanGenerationRequest{Query: "todo app", Preferences: DefaultPlanPreferences()}
+
+	plan, err := planner.generatePhasesCompact(context.Background(), req, nil, nil)
+	if err != nil {
+		t.Fatalf("generatePhasesCompact failed: %v", err)
+	}
+	if len(*requests) != 2 || (*requests)[1].Prompt != shortPhasesPrompt(req, "") {
+		t.Errorf("Expected a retry with the short prompt, got %d requests", len(*requests))
+	}
+	if len(plan.Phases) != 1 || plan.Phases[0].Tasks[0].Description != "Init" {
+		t.Errorf("Unexpected plan: %+v", plan.Phases)
+	}
+}
+
+func TestRoutingRepairsTruncatedDecision(t *testing.T) {
+	client, requests := stubOllama(t, `{"primary_agent": "infra", "confidence": 0.9, "reasoning": "docker compo`)
+	router := NewQuantumRouter(client)
+
+	agentType, confidence, err := router.Classify(context.Background(), "write a compose file")
+	if err != nil || agentType != "infra" || confidence != 0.9 {
+		t.Errorf("Unexpected route %s %.1f: %v", agentType, confidence, err)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("Expected the repaired decision to need no retry, got %d requests", len(*requests))
+	}
+}
+
+// TestGenerateJSONReprompts tests that a response without JSON is asked for
+// again with the output constrained to JSON
+func TestGenerateJSONReprompts(t *testing.T) {
+	client, requests := stubOllama(t, "Here is your file structure!", `{"dirs": {"app/": ["main.py"]}}`)
+	planner := NewPlanner(client)
+
+	dirs, err := planner.generateFileStructure(context.Background(), "todo app")
+	if err != nil {
+		t.Fatalf("generateFileStructure failed: %v", err)
+	}
+	if len(dirs["app/"]) != 1 {
+		t.Errorf("Unexpected structure: %v", dirs)
+	}
+	if len(*requests) != 2 || (*requests)[0].Format != "" || (*requests)[1].Format != "json" {
+		t.Errorf("Expected one reprompt constrained to JSON, got %+v", *requests)
+	}
+
+	client, requests = stubOllama(t, "no", "still no")
+	planner = NewPlanner(client)
+	if _, err := planner.generateFileStructure(context.Background(), "todo app"); !errors.Is(err, ErrNoJSON) {
+		t.Errorf("Expected ErrNoJSON after the reprompt, got %v", err)
+	}
+	if len(*requests) != 1+maxJSONReprompts {
+		t.Errorf("Expected %d requests, got %d", 1+maxJSONReprompts, len(*requests))
+	}
+}
@@ -3,14 +3,24 @@ package agent
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/logging"
 	"github.com/quantumflow/quantumflow/internal/memory"
 	"github.com/quantumflow/quantumflow/internal/models"
+	"github.com/quantumflow/quantumflow/internal/tracing"
 )
 
+// summaryMaxTokens bounds each per-agent summary when merging parallel responses
+const summaryMaxTokens = 256
+
 // AgentOrchestrator manages multiple agents and routes queries
 type AgentOrchestrator struct {
 	agents     map[models.AgentType]Agent
@@ -19,6 +29,7 @@ type AgentOrchestrator struct {
 	propagator SummaryPropagator
 	memory     memory.Service
 	config     *OrchestratorConfig
+	logger     *slog.Logger
 	mu         sync.RWMutex
 }
 
@@ -26,19 +37,23 @@ type AgentOrchestrator struct {
 func NewAgentOrchestrator(
 	config *OrchestratorConfig,
 	memoryService memory.Service,
-	inferenceClient *inference.Client,
+	inferenceClient inference.Generator,
 ) *AgentOrchestrator {
 	if config == nil {
 		config = DefaultOrchestratorConfig()
 	}
 
+	propagator := NewQwenSummaryPropagator(inferenceClient)
+	logger := logging.OrDiscard(config.Logger)
+
 	orchestrator := &AgentOrchestrator{
 		agents:     make(map[models.AgentType]Agent),
 		classifier: NewQuantumRouter(inferenceClient),
-		resolver:   NewSimpleConflictResolver(),
-		propagator: NewQwenSummaryPropagator(inferenceClient),
+		resolver:   NewLLMConflictResolver(propagator, logger),
+		propagator: propagator,
 		memory:     memoryService,
 		config:     config,
+		logger:     logger,
 	}
 
 	return orchestrator
@@ -74,12 +89,67 @@ func (o *AgentOrchestrator) GetAgents() []Agent {
 	return agents
 }
 
+// RoutingTrace explains why a query was sent to the agent(s) that handled it
+type RoutingTrace struct {
+	PrimaryAgent   models.AgentType
+	SecondaryAgent models.AgentType
+	Confidence     float64
+	Reasoning      string
+
+	// Prompt is the full prompt sent to the primary agent
+	Prompt string
+}
+
+// routingExplainer is implemented by classifiers that can report the
+// reasoning behind a decision they have already made
+type routingExplainer interface {
+	Explain(query string) (*CachedRoute, bool)
+}
+
 // Route determines which agent(s) should handle a query
 func (o *AgentOrchestrator) Route(ctx context.Context, query string, context *Context) ([]Agent, error) {
+	agents, _, err := o.route(ctx, query)
+	return agents, err
+}
+
+// traceRouting describes the routing decision that selected agents
+func (o *AgentOrchestrator) traceRouting(query string, agents []Agent, confidence float64) *RoutingTrace {
+	trace := &RoutingTrace{
+		PrimaryAgent: agents[0].Type(),
+		Confidence:   confidence,
+	}
+	if len(agents) > 1 {
+		trace.SecondaryAgent = agents[1].Type()
+	}
+
+	if explainer, ok := o.classifier.(routingExplainer); ok {
+		if route, ok := explainer.Explain(query); ok {
+			if route.AgentType == trace.PrimaryAgent {
+				trace.Reasoning = route.Reasoning
+				if trace.SecondaryAgent == "" {
+					trace.SecondaryAgent = route.SecondaryAgent
+				}
+			} else {
+				trace.Reasoning = fmt.Sprintf("Classifier chose %s with low confidence (%.2f); re-routed to the agent that best matched the query",
+					route.AgentType, route.Confidence)
+			}
+		}
+	}
+
+	return trace
+}
+
+// route classifies a query and returns the matching agent(s) along with the
+// classifier's confidence that each one fits
+func (o *AgentOrchestrator) route(ctx context.Context, query string) ([]Agent, []float64, error) {
+	if o.config.ParallelExecution && o.config.MaxAgentsPerQuery > 1 {
+		return o.routeMulti(ctx, query)
+	}
+
 	// Classify query
 	agentType, confidence, err := o.classifier.Classify(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("classification failed: %w", err)
+		return nil, nil, fmt.Errorf("classification failed: %w", err)
 	}
 
 	// Check if we have an agent for this type
@@ -88,15 +158,113 @@ func (o *AgentOrchestrator) Route(ctx context.Context, query string, context *Co
 	o.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("no agent registered for type %s (confidence: %.2f)", agentType, confidence)
+		return nil, nil, fmt.Errorf("no agent registered for type %s (confidence: %.2f)", agentType, confidence)
 	}
 
 	// For now, return single agent (non-parallel execution)
-	return []Agent{agent}, nil
+	agent, confidence = o.confirm(ctx, query, agent, confidence, nil)
+	return []Agent{agent}, []float64{confidence}, nil
+}
+
+// confirm returns the agent to use for a classification. Rather than commit
+// to a low-confidence classification it asks the agents not in exclude
+// whether one claims the query. The keyword score that picks the fallback
+// isn't on the classifier's scale, so a re-routed agent gets no routing
+// confidence and its answer is scored on self-assessment alone.
+func (o *AgentOrchestrator) confirm(ctx context.Context, query string, agent Agent, confidence float64, exclude map[Agent]bool) (Agent, float64) {
+	if confidence >= o.config.MinConfidence {
+		return agent, confidence
+	}
+
+	fallback, score := o.bestCanHandle(ctx, query, agent, exclude)
+	if fallback == agent {
+		return agent, confidence
+	}
+	o.logger.Info("re-routed low-confidence classification",
+		"classified", agent.Name(), "confidence", confidence, "agent", fallback.Name(), "score", score)
+	return fallback, 0
+}
+
+// bestCanHandle asks every registered agent not in exclude whether it can
+// handle a query and returns the highest scorer. The classifier's pick wins
+// ties, so it is kept when no agent scores above zero.
+func (o *AgentOrchestrator) bestCanHandle(ctx context.Context, query string, classified Agent, exclude map[Agent]bool) (Agent, float64) {
+	best := classified
+	bestScore, err := classified.CanHandle(ctx, query)
+	if err != nil {
+		bestScore = 0
+	}
+
+	for _, agent := range o.GetAgents() {
+		if agent == classified || exclude[agent] {
+			continue
+		}
+		score, err := agent.CanHandle(ctx, query)
+		if err != nil {
+			continue
+		}
+		if score > bestScore || (score == bestScore && best != classified && agent.Name() < best.Name()) {
+			best, bestScore = agent, score
+		}
+	}
+
+	return best, bestScore
+}
+
+// routeMulti selects up to MaxAgentsPerQuery distinct agents for parallel
+// execution, each confirmed and scored like a single-agent route
+func (o *AgentOrchestrator) routeMulti(ctx context.Context, query string) ([]Agent, []float64, error) {
+	classifications, err := o.classifier.ClassifyMulti(ctx, query, o.config.MaxAgentsPerQuery)
+	if err != nil {
+		return nil, nil, fmt.Errorf("classification failed: %w", err)
+	}
+
+	var agents []Agent
+	var confidences []float64
+	seen := make(map[Agent]bool)
+	for _, c := range classifications {
+		o.mu.RLock()
+		agent, exists := o.agents[c.AgentType]
+		o.mu.RUnlock()
+		if !exists || seen[agent] {
+			continue
+		}
+
+		agent, confidence := o.confirm(ctx, query, agent, c.Confidence, seen)
+		seen[agent] = true
+		agents = append(agents, agent)
+		confidences = append(confidences, confidence)
+		if len(agents) >= o.config.MaxAgentsPerQuery {
+			break
+		}
+	}
+
+	if len(agents) == 0 {
+		return nil, nil, fmt.Errorf("no agent registered for classified types")
+	}
+
+	return agents, confidences, nil
 }
 
 // Execute runs a query through the appropriate agent(s)
 func (o *AgentOrchestrator) Execute(ctx context.Context, request *Request) (*Response, error) {
+	ctx, span := tracer().Start(ctx, "orchestrator.Execute", trace.WithAttributes(
+		attribute.String("request.id", request.ID),
+		attribute.Int("request.query_chars", len(request.Query)),
+	))
+	response, err := o.execute(ctx, request)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("response.agent", response.AgentName),
+			attribute.Int("response.tokens", response.TokensUsed),
+		)
+	}
+	return response, tracing.EndSpan(span, err)
+}
+
+// execute runs a request through memory retrieval, routing, the selected
+// agents and response merging
+func (o *AgentOrchestrator) execute(ctx context.Context, request *Request) (*Response, error) {
 	start := time.Now()
 
 	// Set default timeout if not specified
@@ -104,20 +272,30 @@ func (o *AgentOrchestrator) Execute(ctx context.Context, request *Request) (*Res
 		request.Timeout = o.config.DefaultTimeout
 	}
 
-	// Create execution context with timeout
-	execCtx, cancel := context.WithTimeout(ctx, request.Timeout)
-	defer cancel()
-
-	// Retrieve relevant memories if needed
-	if o.memory != nil && request.Memories == nil {
-		memories, err := o.memory.Retrieve(execCtx, request.Query, 5)
-		if err == nil {
-			request.Memories = memories
+	// Create execution context with timeout, capped by the request's
+	// MaxExecutionTime constraint
+	timeout := request.Timeout
+	limitedByConstraint := false
+	if request.Context != nil && request.Context.Constraints != nil {
+		if max := request.Context.Constraints.MaxExecutionTime; max > 0 && max < timeout {
+			timeout = max
+			limitedByConstraint = true
 		}
 	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
 	// Route to appropriate agent(s)
-	agents, err := o.Route(execCtx, request.Query, request.Context)
+	routeCtx, routeSpan := tracer().Start(execCtx, "orchestrator.Classify")
+	agents, confidences, err := o.route(routeCtx, request.Query)
+	if err == nil && len(agents) > 0 {
+		routeSpan.SetAttributes(
+			attribute.String("routing.agent", agents[0].Name()),
+			attribute.Int("routing.agents", len(agents)),
+			attribute.Float64("routing.confidence", confidences[0]),
+		)
+	}
+	tracing.EndSpan(routeSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("routing failed: %w", err)
 	}
@@ -125,16 +303,23 @@ func (o *AgentOrchestrator) Execute(ctx context.Context, request *Request) (*Res
 	if len(agents) == 0 {
 		return nil, fmt.Errorf("no agents available to handle query")
 	}
+	o.logger.Debug("routed query", "request", request.ID, "agent", agents[0].Name(), "agents", len(agents), "confidence", confidences[0])
+
+	o.retrieveMemories(execCtx, request, agents)
 
 	// Execute with agent(s)
 	var responses []*Response
 
 	if o.config.ParallelExecution && len(agents) > 1 {
 		// Parallel execution (future enhancement)
-		responses, err = o.executeParallel(execCtx, agents, request)
+		responses, err = o.executeParallel(execCtx, agents, confidences, request)
 	} else {
 		// Sequential execution (current implementation)
-		responses, err = o.executeSequential(execCtx, agents, request)
+		responses, err = o.executeSequential(execCtx, agents, confidences, request)
+	}
+
+	if limitedByConstraint && execCtx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("%w: request ran longer than %s", ErrExecutionTimeLimit, timeout)
 	}
 
 	if err != nil {
@@ -151,23 +336,127 @@ func (o *AgentOrchestrator) Execute(ctx context.Context, request *Request) (*Res
 		if err != nil {
 			return nil, fmt.Errorf("conflict resolution failed: %w", err)
 		}
+	} else if o.config.SummaryPropagation {
+		// Merge every agent's contribution into one answer
+		finalResponse = o.combineResponses(execCtx, responses)
 	} else {
-		// Combine responses
-		finalResponse = responses[0] // Use first response for now
+		finalResponse = responses[0]
 	}
 
 	// Add execution metadata
 	finalResponse.Duration = time.Since(start)
+	if request.Trace {
+		if finalResponse.Metadata == nil {
+			finalResponse.Metadata = make(map[string]interface{})
+		}
+		trace := o.traceRouting(request.Query, agents, confidences[0])
+		trace.Prompt = agents[0].BuildPrompt(agentRequest(agents[0], request, confidences[0]))
+		finalResponse.Metadata["routing"] = trace
+	}
 
 	return finalResponse, nil
 }
 
+// defaultMemoryItems is how many memories are retrieved for agents that
+// don't set MaxMemoryItems
+const defaultMemoryItems = 5
+
+// retrieveMemories fills in the request's relevant memories if the caller
+// didn't supply any, as many as the agents with memory enabled take; retrieval
+// failures only cost the context
+func (o *AgentOrchestrator) retrieveMemories(ctx context.Context, request *Request, agents []Agent) {
+	if o.memory == nil || request.Memories != nil {
+		return
+	}
+
+	limit := 0
+	for _, agent := range agents {
+		if config := agent.Config(); config != nil && config.MemoryEnabled {
+			limit = max(limit, memoryLimit(config))
+		}
+	}
+	if limit == 0 {
+		return
+	}
+
+	memories, err := o.memory.Retrieve(ctx, request.Query, limit)
+	if err != nil {
+		o.logger.Warn("memory retrieval failed", "request", request.ID, "error", err)
+		return
+	}
+	request.Memories = memories
+}
+
+// memoryLimit is how many memories an agent with memory enabled takes
+func memoryLimit(config *AgentConfig) int {
+	if config.MaxMemoryItems > 0 {
+		return config.MaxMemoryItems
+	}
+	return defaultMemoryItems
+}
+
+// agentRequest returns the request as an agent sees it: with the routing
+// confidence for that agent, and without memories if the agent has memory
+// disabled, otherwise with at most MaxMemoryItems
+func agentRequest(agent Agent, request *Request, confidence float64) *Request {
+	req := *request
+	req.RoutingConfidence = confidence
+
+	config := agent.Config()
+	if config == nil || len(req.Memories) == 0 {
+		return &req
+	}
+	if !config.MemoryEnabled {
+		req.Memories = nil
+	} else if limit := memoryLimit(config); len(req.Memories) > limit {
+		req.Memories = req.Memories[:limit]
+	}
+	return &req
+}
+
+// Remembers reports whether interactions answered by the named agent should
+// be stored in memory
+func (o *AgentOrchestrator) Remembers(agentName string) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	for _, agent := range o.agents {
+		if agent.Name() == agentName {
+			config := agent.Config()
+			return config == nil || config.MemoryEnabled
+		}
+	}
+	return true
+}
+
+// combineResponses summarizes each agent response and combines the summaries
+// into a single answer; on failure it falls back to the first response
+func (o *AgentOrchestrator) combineResponses(ctx context.Context, responses []*Response) *Response {
+	summaries := make([]string, 0, len(responses))
+	for _, resp := range responses {
+		summary, err := o.propagator.Summarize(ctx, resp, summaryMaxTokens)
+		if err != nil {
+			o.logger.Warn("summarizing agent response failed", "agent", resp.AgentName, "error", err)
+			return responses[0]
+		}
+		summaries = append(summaries, fmt.Sprintf("%s: %s", resp.AgentName, strings.TrimSpace(summary)))
+	}
+
+	combined, err := o.propagator.Combine(ctx, summaries)
+	if err != nil {
+		o.logger.Warn("combining agent summaries failed", "error", err)
+		return responses[0]
+	}
+
+	return mergeResponses(responses, combined)
+}
+
 // executeSequential runs agents one at a time
-func (o *AgentOrchestrator) executeSequential(ctx context.Context, agents []Agent, request *Request) ([]*Response, error) {
+func (o *AgentOrchestrator) executeSequential(ctx context.Context, agents []Agent, confidences []float64, request *Request) ([]*Response, error) {
 	responses := make([]*Response, 0, len(agents))
 
-	for _, agent := range agents {
-		response, err := agent.Execute(ctx, request)
+	for i, agent := range agents {
+		response, err := o.executeAgent(ctx, agent, agentRequest(agent, request, confidences[i]))
 		if err != nil {
 			return nil, fmt.Errorf("agent %s failed: %w", agent.Name(), err)
 		}
@@ -177,8 +466,28 @@ func (o *AgentOrchestrator) executeSequential(ctx context.Context, agents []Agen
 	return responses, nil
 }
 
+// executeAgent runs one agent in its own span, reporting it to the configured observer
+func (o *AgentOrchestrator) executeAgent(ctx context.Context, agent Agent, request *Request) (*Response, error) {
+	ctx, span := tracer().Start(ctx, "agent.Execute", trace.WithAttributes(
+		attribute.String("agent.name", agent.Name()),
+		attribute.String("agent.type", string(agent.Type())),
+	))
+
+	observer := o.config.Observer
+	if observer == nil {
+		response, err := agent.Execute(ctx, request)
+		return response, tracing.EndSpan(span, err)
+	}
+
+	start := time.Now()
+	observer.AgentStarted(agent.Name())
+	response, err := agent.Execute(ctx, request)
+	observer.AgentFinished(agent.Name(), time.Since(start), err)
+	return response, tracing.EndSpan(span, err)
+}
+
 // executeParallel runs agents concurrently using goroutines
-func (o *AgentOrchestrator) executeParallel(ctx context.Context, agents []Agent, request *Request) ([]*Response, error) {
+func (o *AgentOrchestrator) executeParallel(ctx context.Context, agents []Agent, confidences []float64, request *Request) ([]*Response, error) {
 	var wg sync.WaitGroup
 	responses := make([]*Response, len(agents))
 	errors := make([]error, len(agents))
@@ -187,7 +496,7 @@ func (o *AgentOrchestrator) executeParallel(ctx context.Context, agents []Agent,
 		wg.Add(1)
 		go func(idx int, a Agent) {
 			defer wg.Done()
-			resp, err := a.Execute(ctx, request)
+			resp, err := o.executeAgent(ctx, a, agentRequest(a, request, confidences[idx]))
 			responses[idx] = resp
 			errors[idx] = err
 		}(i, agent)
@@ -265,13 +574,95 @@ func (r *SimpleConflictResolver) Resolve(ctx context.Context, responses []*Respo
 	return bestResponse, nil
 }
 
+// LLMConflictResolver reconciles contradictory agent responses into a single
+// answer using the model, falling back to SimpleConflictResolver on failure
+type LLMConflictResolver struct {
+	propagator *QwenSummaryPropagator
+	fallback   *SimpleConflictResolver
+	logger     *slog.Logger
+}
+
+// NewLLMConflictResolver creates a resolver that synthesizes answers via the
+// propagator; a nil logger discards the warnings logged on fallback
+func NewLLMConflictResolver(propagator *QwenSummaryPropagator, logger *slog.Logger) *LLMConflictResolver {
+	return &LLMConflictResolver{
+		propagator: propagator,
+		fallback:   NewSimpleConflictResolver(),
+		logger:     logging.OrDiscard(logger),
+	}
+}
+
+// DetectConflict checks if responses contradict each other
+func (r *LLMConflictResolver) DetectConflict(responses []*Response) bool {
+	return r.fallback.DetectConflict(responses)
+}
+
+// Resolve synthesizes one answer from all responses, noting where agents disagree
+func (r *LLMConflictResolver) Resolve(ctx context.Context, responses []*Response) (*Response, error) {
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no responses to resolve")
+	}
+	if len(responses) == 1 || r.propagator == nil || r.propagator.client == nil {
+		return r.fallback.Resolve(ctx, responses)
+	}
+
+	answers := make([]string, len(responses))
+	for i, resp := range responses {
+		answers[i] = fmt.Sprintf("[%s, confidence %.2f]\n%s", resp.AgentName, resp.Confidence, resp.Answer)
+	}
+
+	instruction := `The following answers from different specialist agents contradict each other.
+Reconcile them into a single, correct answer. Prefer the better-supported claims,
+and explicitly note any points where the agents disagree.`
+
+	synthesized, err := r.propagator.combine(ctx, instruction, "Reconciled answer:", answers)
+	if err != nil {
+		r.logger.Warn("reconciling conflicting answers failed; keeping the most confident", "agents", len(responses), "error", err)
+		return r.fallback.Resolve(ctx, responses)
+	}
+
+	return mergeResponses(responses, synthesized), nil
+}
+
+// mergeResponses builds a single response carrying the combined answer and the
+// aggregated bookkeeping of every contributing agent
+func mergeResponses(responses []*Response, answer string) *Response {
+	best := responses[0]
+	names := make([]string, 0, len(responses))
+	var toolCalls []models.ToolCall
+	totalConfidence := 0.0
+	tokens := countTokens(answer)
+
+	for _, resp := range responses {
+		if resp.Confidence > best.Confidence {
+			best = resp
+		}
+		names = append(names, resp.AgentName)
+		toolCalls = append(toolCalls, resp.ToolCalls...)
+		totalConfidence += resp.Confidence
+		tokens += resp.TokensUsed
+	}
+
+	return &Response{
+		AgentName:  strings.Join(names, "+"),
+		AgentType:  best.AgentType,
+		Answer:     answer,
+		ToolCalls:  toolCalls,
+		Confidence: totalConfidence / float64(len(responses)),
+		TokensUsed: tokens,
+		Metadata: map[string]interface{}{
+			"merged_from": names,
+		},
+	}
+}
+
 // QwenSummaryPropagator uses Qwen for summarization
 type QwenSummaryPropagator struct {
-	client *inference.Client
+	client inference.Generator
 }
 
 // NewQwenSummaryPropagator creates a new Qwen-based propagator
-func NewQwenSummaryPropagator(client *inference.Client) *QwenSummaryPropagator {
+func NewQwenSummaryPropagator(client inference.Generator) *QwenSummaryPropagator {
 	return &QwenSummaryPropagator{client: client}
 }
 
@@ -301,18 +692,23 @@ func (p *QwenSummaryPropagator) Combine(ctx context.Context, summaries []string)
 		return summaries[0], nil
 	}
 
-	prompt := fmt.Sprintf(`Combine the following summaries into a coherent response:
+	return p.combine(ctx, "Combine the following summaries into a coherent response:", "Combined summary:", summaries)
+}
+
+// combine asks the model to merge numbered texts according to an instruction
+func (p *QwenSummaryPropagator) combine(ctx context.Context, instruction, answerLabel string, texts []string) (string, error) {
+	prompt := fmt.Sprintf(`%s
 
 %s
 
-Combined summary:`, joinSummaries(summaries))
+%s`, instruction, joinSummaries(texts), answerLabel)
 
 	result, err := p.client.GenerateSync(ctx, prompt)
 	if err != nil {
 		return "", fmt.Errorf("combination failed: %w", err)
 	}
 
-	return result.Response, nil
+	return strings.TrimSpace(result.Response), nil
 }
 
 // joinSummaries formats summaries for combination
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestAgentMemorySettings tests that agents only see as many memories as
+// MaxMemoryItems allows, and none with memory disabled
+func TestAgentMemorySettings(t *testing.T) {
+	orchestrator := NewAgentOrchestrator(nil, nil, nil)
+	orchestrator.classifier = &fixedClassifier{agentType: models.AgentTypeCode, confidence: 0.9}
+	orchestrator.RegisterAgent(NewCodeAgent(nil, &AgentConfig{Name: "CodeAgent", MemoryEnabled: true, MaxMemoryItems: 2}))
+	orchestrator.RegisterAgent(NewSecAgent(nil, nil))
+
+	request := &Request{
+		Query: "Refactor the parser",
+		Memories: []*models.Memory{
+			{Content: "memory one"}, {Content: "memory two"}, {Content: "memory three"},
+		},
+	}
+
+	sim, err := orchestrator.Simulate(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	prompt := sim.Prompts[0].Prompt
+	if !strings.Contains(prompt, "memory two") || strings.Contains(prompt, "memory three") {
+		t.Errorf("Expected the first 2 memories in the prompt:\n%s", prompt)
+	}
+
+	var secAgent Agent
+	for _, agent := range orchestrator.GetAgents() {
+		if agent.Type() == models.AgentTypeSec {
+			secAgent = agent
+		}
+	}
+	if got := agentRequest(secAgent, request, 0.9); len(got.Memories) != 0 {
+		t.Errorf("Expected no memories for an agent with memory disabled, got %d", len(got.Memories))
+	}
+	if len(request.Memories) != 3 {
+		t.Error("Expected the shared request's memories left intact")
+	}
+
+	if !orchestrator.Remembers("CodeAgent") || orchestrator.Remembers("SecAgent") {
+		t.Error("Expected only CodeAgent interactions to be remembered")
+	}
+}
+
+// TestLowConfidenceReroute tests that a low-confidence classification goes to
+// the agent that best claims the query, without passing its keyword score off
+// as a routing confidence
+func TestLowConfidenceReroute(t *testing.T) {
+	orchestrator := NewAgentOrchestrator(nil, nil, nil)
+	orchestrator.classifier = &fixedClassifier{agentType: models.AgentTypeCode, confidence: 0.2}
+	orchestrator.RegisterAgent(NewCodeAgent(nil, nil))
+	orchestrator.RegisterAgent(NewDataAgent(nil, nil))
+
+	agents, confidences, err := orchestrator.route(context.Background(), "sql query on the users table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 1 || agents[0].Type() != models.AgentTypeData || confidences[0] != 0 {
+		t.Errorf("Expected the data agent with no routing confidence, got %v at %v", agents, confidences)
+	}
+
+	orchestrator.classifier = &fixedClassifier{agentType: models.AgentTypeCode, confidence: 0.8}
+	agents, confidences, _ = orchestrator.route(context.Background(), "sql query on the users table")
+	if agents[0].Type() != models.AgentTypeCode || confidences[0] != 0.8 {
+		t.Errorf("Expected a confident classification kept, got %s at %v", agents[0].Type(), confidences)
+	}
+}
+
+// multiClassifier returns a fixed list of classifications
+type multiClassifier []Classification
+
+func (c multiClassifier) Classify(ctx context.Context, query string) (models.AgentType, float64, error) {
+	return c[0].AgentType, c[0].Confidence, nil
+}
+
+func (c multiClassifier) ClassifyMulti(ctx context.Context, query string, k int) ([]Classification, error) {
+	return c, nil
+}
+
+// TestRouteMulti tests that every agent selected for parallel execution gets
+// its own confidence, and that low-confidence picks go through the same
+// fallback as single-agent routing without repeating an agent
+func TestRouteMulti(t *testing.T) {
+	orchestrator := NewAgentOrchestrator(nil, nil, nil)
+	orchestrator.config.ParallelExecution = true
+	orchestrator.config.MaxAgentsPerQuery = 2
+	orchestrator.classifier = multiClassifier{
+		{AgentType: models.AgentTypeCode, Confidence: 0.9},
+		{AgentType: models.AgentTypeSec, Confidence: 0.1},
+	}
+	for _, agent := range []Agent{NewCodeAgent(nil, nil), NewDataAgent(nil, nil), NewSecAgent(nil, nil)} {
+		orchestrator.RegisterAgent(agent)
+	}
+
+	agents, confidences, err := orchestrator.route(context.Background(), "sql query on the users table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 2 || agents[0].Type() != models.AgentTypeCode || agents[1].Type() != models.AgentTypeData {
+		t.Fatalf("Expected the code agent and the data agent re-routed from sec, got %v", agents)
+	}
+	if confidences[0] != 0.9 || confidences[1] != 0 {
+		t.Errorf("Expected per-agent confidences [0.9 0], got %v", confidences)
+	}
+
+	sim, err := orchestrator.Simulate(context.Background(), &Request{Query: "sql query on the users table"})
+	if err != nil || len(sim.Prompts) != 2 || sim.Routing.Confidence != 0.9 {
+		t.Errorf("Expected two prompts routed at 0.9, got %+v, %v", sim, err)
+	}
+}
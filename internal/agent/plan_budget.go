@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBudgetRetries is how many times the model is asked to shrink a plan
+const maxBudgetRetries = 2
+
+// estimatePattern matches one "<n>[-<m>] <unit>" component of an estimate such
+// as "5 min", "10-15 minutes" or "1h 30m"
+var estimatePattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)(?:\s*(?:-|to)\s*(\d+(?:\.\d+)?))?\s*(hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\b`)
+
+// destructiveTaskPattern matches tasks that delete data or drive the
+// destructive infra tools (docker, kubectl, terraform)
+var destructiveTaskPattern = regexp.MustCompile(`(?i)\b(delete[sd]?|deleting|drop(s|ped|ping)?|remove[sd]?|removing|rm|truncate[sd]?|destroy(s|ed|ing)?|overwrite[sn]?|overwriting|wipe[sd]?|docker|kubectl|terraform)\b`)
+
+// parseEstimatedTime converts a free-form phase estimate to a duration. Ranges
+// count at their upper bound; ok is false when no estimate can be read.
+func parseEstimatedTime(estimate string) (time.Duration, bool) {
+	matches := estimatePattern.FindAllStringSubmatch(estimate, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+
+	var total time.Duration
+	for _, m := range matches {
+		amount := m[1]
+		if m[2] != "" {
+			amount = m[2]
+		}
+		value, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		unit := time.Second
+		switch strings.ToLower(m[3])[0] {
+		case 'h':
+			unit = time.Hour
+		case 'm':
+			unit = time.Minute
+		}
+		total += time.Duration(value * float64(unit))
+	}
+	return total, true
+}
+
+// EstimatedDuration sums the phase estimates that can be parsed
+func (plan *ExecutionPlan) EstimatedDuration() time.Duration {
+	var total time.Duration
+	for _, phase := range plan.Phases {
+		if d, ok := parseEstimatedTime(phase.EstimatedTime); ok {
+			total += d
+		}
+	}
+	return total
+}
+
+// DestructiveOps counts tasks that delete or overwrite data or run destructive tools
+func (plan *ExecutionPlan) DestructiveOps() int {
+	count := 0
+	for _, phase := range plan.Phases {
+		for _, task := range phase.Tasks {
+			if destructiveTaskPattern.MatchString(task.Description) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// budgetViolations lists the preference limits a plan exceeds. Zero limits
+// are not enforced.
+func budgetViolations(plan *ExecutionPlan, prefs PlanPreferences) []string {
+	var violations []string
+	if prefs.MaxPhases > 0 && len(plan.Phases) > prefs.MaxPhases {
+		violations = append(violations, fmt.Sprintf("%d phases (max %d)", len(plan.Phases), prefs.MaxPhases))
+	}
+	if prefs.MaxEstimatedTime > 0 {
+		if total := plan.EstimatedDuration(); total > prefs.MaxEstimatedTime {
+			violations = append(violations, fmt.Sprintf("estimated %s (max %s)", total, prefs.MaxEstimatedTime))
+		}
+	}
+	if prefs.MaxDestructiveOps > 0 {
+		if ops := plan.DestructiveOps(); ops > prefs.MaxDestructiveOps {
+			violations = append(violations, fmt.Sprintf("%d destructive operations (max %d)", ops, prefs.MaxDestructiveOps))
+		}
+	}
+	return violations
+}
+
+// formatBudgetRules renders the preference limits as extra planning prompt rules
+func formatBudgetRules(prefs PlanPreferences) string {
+	var rules strings.Builder
+	if prefs.MaxPhases > 0 {
+		rules.WriteString(fmt.Sprintf("- At most %d phases\n", prefs.MaxPhases))
+	}
+	if prefs.MaxEstimatedTime > 0 {
+		rules.WriteString(fmt.Sprintf("- Total estimated_time at most %s\n", prefs.MaxEstimatedTime))
+	}
+	if prefs.MaxDestructiveOps > 0 {
+		rules.WriteString(fmt.Sprintf("- At most %d tasks that delete, drop or overwrite data or run docker/kubectl/terraform\n", prefs.MaxDestructiveOps))
+	}
+	if rules.Len() == 0 {
+		return ""
+	}
+	return "\nBudget:\n" + rules.String()
+}
+
+// enforceBudget asks the model to consolidate a plan until it fits the
+// preferences. After maxBudgetRetries, or if consolidating fails, the plan is
+// returned as it is with a warning, since the user reviews it before approving.
+func (p *Planner) enforceBudget(ctx context.Context, plan *ExecutionPlan, req *PlanGenerationRequest) *ExecutionPlan {
+	for attempt := 0; ; attempt++ {
+		violations := budgetViolations(plan, req.Preferences)
+		if len(violations) == 0 {
+			return plan
+		}
+		if attempt == maxBudgetRetries {
+			fmt.Printf("⚠️  Plan still over budget (%s); review it before approving\n", strings.Join(violations, ", "))
+			return plan
+		}
+
+		fmt.Printf("✂️  Plan over budget (%s), consolidating...\n", strings.Join(violations, ", "))
+
+		trimmed, err := p.consolidate(ctx, plan, req, violations)
+		if err != nil {
+			fmt.Printf("⚠️  Could not consolidate the plan (%v); review it before approving\n", err)
+			return plan
+		}
+		plan = trimmed
+	}
+}
+
+// consolidate asks the model to merge and cut phases so the plan fits the budget
+func (p *Planner) consolidate(ctx context.Context, plan *ExecutionPlan, req *PlanGenerationRequest, violations []string) (*ExecutionPlan, error) {
+	data, err := planJSON(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := fmt.Sprintf(`This plan for "%s" is too large: %s.
+Consolidate it: merge related phases, drop optional work and avoid destructive steps
+that are not required. Keep full file paths in tasks.
+%s
+Plan:
+%s
+
+Output the revised plan as JSON in the same format only.
+JSON:`, req.Query, strings.Join(violations, ", "), formatBudgetRules(req.Preferences), data)
+
+	result, err := p.client.GenerateSyncWithOptions(ctx, prompt, deterministic)
+	if err != nil {
+		return nil, err
+	}
+	return p.parsePlanResponse(result.Response, req.Query)
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestParseEstimatedTime tests the estimate formats the model produces
+func TestParseEstimatedTime(t *testing.T) {
+	tests := []struct {
+		estimate string
+		want     time.Duration
+		ok       bool
+	}{
+		{"5 min", 5 * time.Minute, true},
+		{"10m", 10 * time.Minute, true},
+		{"10-15 minutes", 15 * time.Minute, true},
+		{"1 hour 30 min", 90 * time.Minute, true},
+		{"2h", 2 * time.Hour, true},
+		{"1.5 hours", 90 * time.Minute, true},
+		{"quick", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseEstimatedTime(tt.estimate)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseEstimatedTime(%q) = %s, %v; want %s, %v", tt.estimate, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestBudgetViolations tests each preference limit and that zero limits are ignored
+func TestBudgetViolations(t *testing.T) {
+	plan := &ExecutionPlan{Phases: []Phase{
+		{Name: "Setup", Agent: models.AgentTypeCode, EstimatedTime: "2 hours", Tasks: []Task{
+			{Description: "Create login form in app/forms.py"},
+			{Description: "Write Dockerfile"},
+		}},
+		{Name: "Deploy", Agent: models.AgentTypeInfra, EstimatedTime: "90 min", Tasks: []Task{
+			{Description: "Drop the legacy users table"},
+			{Description: "Run terraform apply"},
+		}},
+	}}
+
+	if ops := plan.DestructiveOps(); ops != 2 {
+		t.Errorf("Expected 2 destructive ops, got %d", ops)
+	}
+	if total := plan.EstimatedDuration(); total != 3*time.Hour+30*time.Minute {
+		t.Errorf("Expected 3h30m estimate, got %s", total)
+	}
+
+	if v := budgetViolations(plan, PlanPreferences{}); len(v) != 0 {
+		t.Errorf("Expected zero limits to be ignored, got %v", v)
+	}
+
+	prefs := PlanPreferences{MaxPhases: 1, MaxEstimatedTime: time.Hour, MaxDestructiveOps: 1}
+	if v := budgetViolations(plan, prefs); len(v) != 3 {
+		t.Errorf("Expected 3 violations, got %v", v)
+	}
+
+	if v := budgetViolations(plan, DefaultPlanPreferences()); len(v) != 0 {
+		t.Errorf("Expected plan to fit default preferences, got %v", v)
+	}
+}
+
+// TestGenerateKeepsOverBudgetPlan tests that a plan the model can't bring
+// within an opted-in limit is returned rather than failing generation
+func TestGenerateKeepsOverBudgetPlan(t *testing.T) {
+	mock := inference.NewMockGenerator(
+		`{"dirs": {"app/": ["main.go"]}}`,
+		`{"title": "App", "phases": [
+			{"name": "Build", "agent": "code", "tasks": [{"description": "app/main.go"}]},
+			{"name": "Deploy", "agent": "infra", "tasks": [{"description": "Run kubectl apply"}]}]}`,
+	)
+
+	req := &PlanGenerationRequest{Query: "app", Preferences: PlanPreferences{MaxPhases: 1}}
+	plan, err := NewPlanner(mock).Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the over-budget plan to be returned, got %v", err)
+	}
+	if len(plan.Phases) != 2 {
+		t.Errorf("Expected the unconsolidated plan, got %d phases", len(plan.Phases))
+	}
+	if calls := mock.Calls(); len(calls) != 2+maxBudgetRetries {
+		t.Errorf("Expected %d consolidation attempts, got %d calls", maxBudgetRetries, len(calls))
+	}
+}
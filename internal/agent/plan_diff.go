@@ -0,0 +1,284 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// taskSimilarityThreshold is the word overlap above which two task
+// descriptions are treated as the same task, reworded
+const taskSimilarityThreshold = 0.5
+
+// PlanDiff describes how one execution plan differs from another
+type PlanDiff struct {
+	OldPlanID     string       `json:"old_plan_id"`
+	NewPlanID     string       `json:"new_plan_id"`
+	TitleChange   *FieldChange `json:"title_change,omitempty"`
+	AddedPhases   []Phase      `json:"added_phases,omitempty"`
+	RemovedPhases []Phase      `json:"removed_phases,omitempty"`
+	ChangedPhases []PhaseDiff  `json:"changed_phases,omitempty"`
+}
+
+// PhaseDiff describes the changes to a phase present in both plans
+type PhaseDiff struct {
+	Name         string        `json:"name"` // Name in the new plan
+	Fields       []FieldChange `json:"fields,omitempty"`
+	AddedTasks   []Task        `json:"added_tasks,omitempty"`
+	RemovedTasks []Task        `json:"removed_tasks,omitempty"`
+	ChangedTasks []TaskChange  `json:"changed_tasks,omitempty"`
+}
+
+// FieldChange records an old and new value of a plan or phase field
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// TaskChange pairs a task with its reworded counterpart in the new plan
+type TaskChange struct {
+	Old        Task    `json:"old"`
+	New        Task    `json:"new"`
+	Similarity float64 `json:"similarity"`
+}
+
+// IsEmpty reports whether the plans are equivalent
+func (d *PlanDiff) IsEmpty() bool {
+	return d.TitleChange == nil && len(d.AddedPhases) == 0 && len(d.RemovedPhases) == 0 && len(d.ChangedPhases) == 0
+}
+
+// Diff compares two plans. Phases are matched by name first and then by ID,
+// since phase IDs are positional and shift when a re-plan inserts a phase;
+// tasks are matched by exact description, then by description similarity.
+func (p *Planner) Diff(oldPlan, newPlan *ExecutionPlan) *PlanDiff {
+	diff := &PlanDiff{OldPlanID: oldPlan.ID, NewPlanID: newPlan.ID}
+	if oldPlan.Title != newPlan.Title {
+		diff.TitleChange = &FieldChange{Field: "title", Old: oldPlan.Title, New: newPlan.Title}
+	}
+
+	matched := matchPhases(oldPlan.Phases, newPlan.Phases)
+	oldMatched := make(map[int]bool)
+	for i := range newPlan.Phases {
+		oldIdx, ok := matched[i]
+		if !ok {
+			diff.AddedPhases = append(diff.AddedPhases, newPlan.Phases[i])
+			continue
+		}
+		oldMatched[oldIdx] = true
+		if phaseDiff := diffPhase(&oldPlan.Phases[oldIdx], &newPlan.Phases[i]); phaseDiff != nil {
+			diff.ChangedPhases = append(diff.ChangedPhases, *phaseDiff)
+		}
+	}
+	for i, phase := range oldPlan.Phases {
+		if !oldMatched[i] {
+			diff.RemovedPhases = append(diff.RemovedPhases, phase)
+		}
+	}
+
+	return diff
+}
+
+// matchPhases maps new phase indexes to old phase indexes, by name then by ID
+func matchPhases(oldPhases, newPhases []Phase) map[int]int {
+	matched := make(map[int]int)
+	used := make(map[int]bool)
+
+	match := func(key func(*Phase) string) {
+		for i := range newPhases {
+			if _, ok := matched[i]; ok {
+				continue
+			}
+			k := key(&newPhases[i])
+			if k == "" {
+				continue
+			}
+			for j := range oldPhases {
+				if !used[j] && key(&oldPhases[j]) == k {
+					matched[i] = j
+					used[j] = true
+					break
+				}
+			}
+		}
+	}
+
+	match(func(p *Phase) string { return strings.ToLower(strings.TrimSpace(p.Name)) })
+	match(func(p *Phase) string { return p.ID })
+
+	return matched
+}
+
+// diffPhase compares two matched phases, returning nil when they are equivalent
+func diffPhase(oldPhase, newPhase *Phase) *PhaseDiff {
+	diff := &PhaseDiff{Name: newPhase.Name}
+
+	fields := []FieldChange{
+		{"name", oldPhase.Name, newPhase.Name},
+		{"agent", string(oldPhase.Agent), string(newPhase.Agent)},
+		{"success_criteria", oldPhase.SuccessCriteria, newPhase.SuccessCriteria},
+		{"estimated_time", oldPhase.EstimatedTime, newPhase.EstimatedTime},
+		{"dependencies", strings.Join(oldPhase.Dependencies, ", "), strings.Join(newPhase.Dependencies, ", ")},
+	}
+	for _, field := range fields {
+		if field.Old != field.New {
+			diff.Fields = append(diff.Fields, field)
+		}
+	}
+
+	diff.AddedTasks, diff.RemovedTasks, diff.ChangedTasks = diffTasks(oldPhase.Tasks, newPhase.Tasks)
+
+	if len(diff.Fields) == 0 && len(diff.AddedTasks) == 0 && len(diff.RemovedTasks) == 0 && len(diff.ChangedTasks) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// diffTasks matches tasks by identical description, then pairs the most
+// similar remaining descriptions above taskSimilarityThreshold
+func diffTasks(oldTasks, newTasks []Task) (added, removed []Task, changed []TaskChange) {
+	oldUsed := make([]bool, len(oldTasks))
+	newUsed := make([]bool, len(newTasks))
+
+	for i, newTask := range newTasks {
+		for j, oldTask := range oldTasks {
+			if !oldUsed[j] && normalizeTask(oldTask.Description) == normalizeTask(newTask.Description) {
+				oldUsed[j], newUsed[i] = true, true
+				break
+			}
+		}
+	}
+
+	type candidate struct {
+		oldIdx, newIdx int
+		score          float64
+	}
+	var candidates []candidate
+	for i, newTask := range newTasks {
+		if newUsed[i] {
+			continue
+		}
+		for j, oldTask := range oldTasks {
+			if oldUsed[j] {
+				continue
+			}
+			if score := taskSimilarity(oldTask.Description, newTask.Description); score >= taskSimilarityThreshold {
+				candidates = append(candidates, candidate{j, i, score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].score > candidates[b].score })
+
+	pairs := make(map[int]candidate)
+	for _, c := range candidates {
+		if oldUsed[c.oldIdx] || newUsed[c.newIdx] {
+			continue
+		}
+		oldUsed[c.oldIdx], newUsed[c.newIdx] = true, true
+		pairs[c.newIdx] = c
+	}
+
+	// Report in new-plan order
+	for i, task := range newTasks {
+		if c, ok := pairs[i]; ok {
+			changed = append(changed, TaskChange{Old: oldTasks[c.oldIdx], New: task, Similarity: c.score})
+		} else if !newUsed[i] {
+			added = append(added, task)
+		}
+	}
+	for j, task := range oldTasks {
+		if !oldUsed[j] {
+			removed = append(removed, task)
+		}
+	}
+
+	return added, removed, changed
+}
+
+// normalizeTask folds case and whitespace so cosmetic edits don't count as changes
+func normalizeTask(description string) string {
+	return strings.Join(strings.Fields(strings.ToLower(description)), " ")
+}
+
+// taskSimilarity is the Jaccard overlap of the words in two descriptions
+func taskSimilarity(a, b string) float64 {
+	wordsA := strings.Fields(strings.ToLower(a))
+	wordsB := strings.Fields(strings.ToLower(b))
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	setA := make(map[string]bool, len(wordsA))
+	for _, w := range wordsA {
+		setA[strings.Trim(w, ".,;:()")] = true
+	}
+	setB := make(map[string]bool, len(wordsB))
+	for _, w := range wordsB {
+		setB[strings.Trim(w, ".,;:()")] = true
+	}
+
+	shared := 0
+	for w := range setB {
+		if setA[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+// FormatDiffAsMarkdown renders a plan diff for review before approval
+func (p *Planner) FormatDiffAsMarkdown(diff *PlanDiff) string {
+	var md strings.Builder
+
+	md.WriteString(fmt.Sprintf("# Plan Changes: %s → %s\n\n", diff.OldPlanID, diff.NewPlanID))
+
+	if diff.IsEmpty() {
+		md.WriteString("No changes.\n")
+		return md.String()
+	}
+
+	if diff.TitleChange != nil {
+		md.WriteString(fmt.Sprintf("**Title:** ~~%s~~ → %s\n\n", diff.TitleChange.Old, diff.TitleChange.New))
+	}
+
+	if len(diff.AddedPhases) > 0 {
+		md.WriteString("## Added Phases\n\n")
+		for _, phase := range diff.AddedPhases {
+			md.WriteString(fmt.Sprintf("- **%s** (%s, %d tasks)\n", phase.Name, phase.Agent, len(phase.Tasks)))
+			for _, task := range phase.Tasks {
+				md.WriteString(fmt.Sprintf("  - + %s\n", task.Description))
+			}
+		}
+		md.WriteString("\n")
+	}
+
+	if len(diff.RemovedPhases) > 0 {
+		md.WriteString("## Removed Phases\n\n")
+		for _, phase := range diff.RemovedPhases {
+			md.WriteString(fmt.Sprintf("- ~~%s~~ (%s, %d tasks)\n", phase.Name, phase.Agent, len(phase.Tasks)))
+		}
+		md.WriteString("\n")
+	}
+
+	if len(diff.ChangedPhases) > 0 {
+		md.WriteString("## Changed Phases\n\n")
+		for _, phase := range diff.ChangedPhases {
+			md.WriteString(fmt.Sprintf("### %s\n\n", phase.Name))
+			for _, field := range phase.Fields {
+				md.WriteString(fmt.Sprintf("- **%s:** ~~%s~~ → %s\n", field.Field, field.Old, field.New))
+			}
+			for _, task := range phase.AddedTasks {
+				md.WriteString(fmt.Sprintf("- + %s\n", task.Description))
+			}
+			for _, task := range phase.RemovedTasks {
+				md.WriteString(fmt.Sprintf("- − ~~%s~~\n", task.Description))
+			}
+			for _, change := range phase.ChangedTasks {
+				md.WriteString(fmt.Sprintf("- ~ ~~%s~~ → %s\n", change.Old.Description, change.New.Description))
+			}
+			md.WriteString("\n")
+		}
+	}
+
+	return md.String()
+}
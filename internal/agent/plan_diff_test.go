@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestPlanDiff tests phase matching by name and task matching by description similarity
+func TestPlanDiff(t *testing.T) {
+	oldPlan := &ExecutionPlan{
+		ID:    "plan_old",
+		Title: "Todo API",
+		Phases: []Phase{
+			{ID: "phase-1", Name: "Setup", Agent: models.AgentTypeCode, Tasks: []Task{
+				{Description: "Create project structure"},
+				{Description: "Install FastAPI and uvicorn dependencies"},
+			}},
+			{ID: "phase-2", Name: "Endpoints", Agent: models.AgentTypeCode, Tasks: []Task{
+				{Description: "Implement CRUD endpoints"},
+			}},
+			{ID: "phase-3", Name: "Docs", Agent: models.AgentTypeCode, Tasks: []Task{
+				{Description: "Write README"},
+			}},
+		},
+	}
+	newPlan := &ExecutionPlan{
+		ID:    "plan_new",
+		Title: "Todo API",
+		Phases: []Phase{
+			{ID: "phase-1", Name: "Setup", Agent: models.AgentTypeCode, Tasks: []Task{
+				{Description: "create project  structure"},
+				{Description: "Install FastAPI, SQLAlchemy and uvicorn dependencies"},
+				{Description: "Configure linting"},
+			}},
+			{ID: "phase-2", Name: "Database", Agent: models.AgentTypeData, Tasks: []Task{
+				{Description: "Design schema"},
+			}},
+			{ID: "phase-3", Name: "Endpoints", Agent: models.AgentTypeCode, Tasks: []Task{
+				{Description: "Implement CRUD endpoints"},
+			}},
+		},
+	}
+
+	planner := &Planner{}
+	diff := planner.Diff(oldPlan, newPlan)
+
+	if diff.TitleChange != nil {
+		t.Errorf("Expected no title change, got %+v", diff.TitleChange)
+	}
+	if len(diff.AddedPhases) != 1 || diff.AddedPhases[0].Name != "Database" {
+		t.Errorf("Expected Database to be added, got %+v", diff.AddedPhases)
+	}
+	if len(diff.RemovedPhases) != 1 || diff.RemovedPhases[0].Name != "Docs" {
+		t.Errorf("Expected Docs to be removed, got %+v", diff.RemovedPhases)
+	}
+
+	// Endpoints moved from phase-2 to phase-3 but is unchanged
+	if len(diff.ChangedPhases) != 1 {
+		t.Fatalf("Expected only Setup to change, got %+v", diff.ChangedPhases)
+	}
+	setup := diff.ChangedPhases[0]
+	if setup.Name != "Setup" || len(setup.Fields) != 0 {
+		t.Errorf("Unexpected Setup changes: %+v", setup)
+	}
+	if len(setup.ChangedTasks) != 1 || !strings.Contains(setup.ChangedTasks[0].New.Description, "SQLAlchemy") {
+		t.Errorf("Expected the reworded install task to be paired, got %+v", setup.ChangedTasks)
+	}
+	if len(setup.AddedTasks) != 1 || setup.AddedTasks[0].Description != "Configure linting" {
+		t.Errorf("Expected linting task to be added, got %+v", setup.AddedTasks)
+	}
+	if len(setup.RemovedTasks) != 0 {
+		t.Errorf("Expected no removed tasks, got %+v", setup.RemovedTasks)
+	}
+
+	md := planner.FormatDiffAsMarkdown(diff)
+	for _, want := range []string{"## Added Phases", "**Database**", "~~Docs~~", "### Setup", "+ Configure linting"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q:\n%s", want, md)
+		}
+	}
+
+	if same := planner.Diff(oldPlan, oldPlan); !same.IsEmpty() {
+		t.Errorf("Expected a plan to have no diff with itself, got %+v", same)
+	}
+}
@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrPhaseAlreadyRun is returned when editing a phase that has already run;
+// only phases from the current one onward can change
+var ErrPhaseAlreadyRun = errors.New("phase has already run")
+
+// checkEditablePhase validates a phase index for editing
+func (p *ExecutionPlan) checkEditablePhase(index int) error {
+	if index < 0 || index >= len(p.Phases) {
+		return fmt.Errorf("no phase %d (plan has %d)", index+1, len(p.Phases))
+	}
+	if index < p.State.Snapshot().CurrentPhase {
+		return fmt.Errorf("%w: phase %d", ErrPhaseAlreadyRun, index+1)
+	}
+	return nil
+}
+
+// MovePhase moves the phase at from to position to, shifting the phases in
+// between. Moves that would run a phase before one it depends on are rejected.
+func (p *ExecutionPlan) MovePhase(from, to int) error {
+	if err := p.checkEditablePhase(from); err != nil {
+		return err
+	}
+	if err := p.checkEditablePhase(to); err != nil {
+		return err
+	}
+
+	phases := make([]Phase, 0, len(p.Phases))
+	for i := range p.Phases {
+		if i != from {
+			phases = append(phases, p.Phases[i])
+		}
+	}
+	phases = append(phases[:to], append([]Phase{p.Phases[from]}, phases[to:]...)...)
+
+	if err := checkDependencyOrder(phases); err != nil {
+		return err
+	}
+	p.Phases = phases
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// RemovePhase deletes a phase, dropping it from other phases' dependencies
+func (p *ExecutionPlan) RemovePhase(index int) error {
+	if err := p.checkEditablePhase(index); err != nil {
+		return err
+	}
+
+	removed := p.Phases[index]
+	p.Phases = append(p.Phases[:index], p.Phases[index+1:]...)
+	for i := range p.Phases {
+		deps := p.Phases[i].Dependencies[:0]
+		for _, dep := range p.Phases[i].Dependencies {
+			if dep != removed.ID && dep != removed.Name {
+				deps = append(deps, dep)
+			}
+		}
+		p.Phases[i].Dependencies = deps
+	}
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// EditTask replaces a task's description
+func (p *ExecutionPlan) EditTask(phaseIndex, taskIndex int, description string) error {
+	if err := p.checkEditablePhase(phaseIndex); err != nil {
+		return err
+	}
+	phase := &p.Phases[phaseIndex]
+	if taskIndex < 0 || taskIndex >= len(phase.Tasks) {
+		return fmt.Errorf("no task %d in phase %d (it has %d)", taskIndex+1, phaseIndex+1, len(phase.Tasks))
+	}
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return fmt.Errorf("task description is empty")
+	}
+
+	phase.Tasks[taskIndex].Description = description
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// checkDependencyOrder reports a phase that comes before one it depends on
+func checkDependencyOrder(phases []Phase) error {
+	position := make(map[string]int)
+	for i, phase := range phases {
+		position[phase.ID] = i
+		position[phase.Name] = i
+	}
+	for i, phase := range phases {
+		for _, dep := range phase.Dependencies {
+			if j, ok := position[dep]; ok && j > i {
+				return fmt.Errorf("%q would run before %q, which it depends on", phase.Name, phases[j].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// printPlanOutline lists phases and tasks with the numbers edit commands use
+func printPlanOutline(plan *ExecutionPlan) {
+	current := plan.State.Snapshot().CurrentPhase
+	fmt.Println()
+	for i, phase := range plan.Phases {
+		marker := ""
+		if i < current {
+			marker = " (already run)"
+		}
+		fmt.Printf("%d. %s [%s]%s\n", i+1, phase.Name, phase.Agent, marker)
+		for j, task := range phase.Tasks {
+			fmt.Printf("   %d.%d %s\n", i+1, j+1, task.Description)
+		}
+	}
+	fmt.Println()
+}
+
+// planEditHelp describes the commands of the plan editor
+const planEditHelp = `Edit commands:
+  mv <phase> <position>        Move a phase
+  rm <phase>                   Delete a phase
+  task <phase>.<task> <text>   Replace a task's description
+  show                         List phases and tasks
+  done                         Finish editing and review the plan again`
+
+// editPlan runs the interactive plan editor until the user types done.
+// changed reports whether the plan was modified.
+func editPlan(reader *bufio.Reader, plan *ExecutionPlan) (changed bool, err error) {
+	fmt.Println("\n" + planEditHelp)
+	printPlanOutline(plan)
+
+	for {
+		fmt.Print("edit> ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return changed, err
+		}
+
+		done, edited, editErr := applyPlanEdit(plan, strings.TrimSpace(line))
+		if editErr != nil {
+			fmt.Printf("❌ %v\n", editErr)
+			continue
+		}
+		if done {
+			return changed, nil
+		}
+		if edited {
+			changed = true
+			printPlanOutline(plan)
+		}
+	}
+}
+
+// applyPlanEdit applies one editor command. done is set by "done"; edited
+// reports whether the plan changed.
+func applyPlanEdit(plan *ExecutionPlan, line string) (done, edited bool, err error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, false, nil
+	}
+
+	switch fields[0] {
+	case "done", "q":
+		return true, false, nil
+	case "show", "ls":
+		printPlanOutline(plan)
+		return false, false, nil
+	case "mv", "move":
+		if len(fields) != 3 {
+			return false, false, fmt.Errorf("usage: mv <phase> <position>")
+		}
+		from, err1 := strconv.Atoi(fields[1])
+		to, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			return false, false, fmt.Errorf("usage: mv <phase> <position>")
+		}
+		err = plan.MovePhase(from-1, to-1)
+		return false, err == nil, err
+	case "rm", "delete":
+		if len(fields) != 2 {
+			return false, false, fmt.Errorf("usage: rm <phase>")
+		}
+		index, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return false, false, fmt.Errorf("usage: rm <phase>")
+		}
+		err = plan.RemovePhase(index - 1)
+		return false, err == nil, err
+	case "task":
+		if len(fields) < 3 {
+			return false, false, fmt.Errorf("usage: task <phase>.<task> <description>")
+		}
+		phaseNum, taskNum, ok := strings.Cut(fields[1], ".")
+		phaseIndex, err1 := strconv.Atoi(phaseNum)
+		taskIndex, err2 := strconv.Atoi(taskNum)
+		if !ok || err1 != nil || err2 != nil {
+			return false, false, fmt.Errorf("usage: task <phase>.<task> <description>")
+		}
+		description := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(line, fields[0])), fields[1]))
+		err = plan.EditTask(phaseIndex-1, taskIndex-1, description)
+		return false, err == nil, err
+	case "help", "?":
+		fmt.Println(planEditHelp)
+		return false, false, nil
+	}
+	return false, false, fmt.Errorf("unknown edit command %q (type help)", fields[0])
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+)
+
+func editablePlan() *ExecutionPlan {
+	return &ExecutionPlan{
+		ID: "plan_edit",
+		Phases: []Phase{
+			{ID: "phase-1", Name: "Setup", Tasks: []Task{{ID: "t1", Description: "Init module"}}},
+			{ID: "phase-2", Name: "API", Dependencies: []string{"phase-1"}, Tasks: []Task{{ID: "t2", Description: "Add handlers"}}},
+			{ID: "phase-3", Name: "Docs", Tasks: []Task{{ID: "t3", Description: "Write README"}}},
+		},
+	}
+}
+
+func phaseIDs(plan *ExecutionPlan) []string {
+	ids := make([]string, len(plan.Phases))
+	for i, phase := range plan.Phases {
+		ids[i] = phase.ID
+	}
+	return ids
+}
+
+func TestApplyPlanEdit(t *testing.T) {
+	plan := editablePlan()
+
+	if _, edited, err := applyPlanEdit(plan, "mv 3 1"); err != nil || !edited {
+		t.Fatalf("mv failed: %v", err)
+	}
+	if got := phaseIDs(plan); got[0] != "phase-3" || got[1] != "phase-1" || got[2] != "phase-2" {
+		t.Errorf("Unexpected order after mv: %v", got)
+	}
+
+	// API depends on Setup, so it can't move ahead of it
+	if _, edited, err := applyPlanEdit(plan, "mv 3 1"); err == nil || edited {
+		t.Error("Expected moving a phase before its dependency to fail")
+	}
+
+	if _, _, err := applyPlanEdit(plan, "task 3.1 Add handlers with  validation"); err != nil {
+		t.Fatalf("task failed: %v", err)
+	}
+	if got := plan.Phases[2].Tasks[0].Description; got != "Add handlers with  validation" {
+		t.Errorf("Unexpected description %q", got)
+	}
+
+	if _, _, err := applyPlanEdit(plan, "rm 2"); err != nil {
+		t.Fatalf("rm failed: %v", err)
+	}
+	if len(plan.Phases) != 2 || len(plan.Phases[1].Dependencies) != 0 {
+		t.Errorf("Expected Setup removed along with the dependency on it, got %+v", plan.Phases)
+	}
+
+	if done, _, err := applyPlanEdit(plan, "done"); !done || err != nil {
+		t.Error("Expected done to finish editing")
+	}
+	if _, _, err := applyPlanEdit(plan, "rm 9"); err == nil {
+		t.Error("Expected an out-of-range phase to fail")
+	}
+}
+
+// TestEditRejectsPhasesAlreadyRun tests that phases before the current one
+// can't change, so a resumed plan's progress stays valid
+func TestEditRejectsPhasesAlreadyRun(t *testing.T) {
+	plan := editablePlan()
+	plan.State.MarkCompleted(0)
+
+	for _, err := range []error{
+		plan.RemovePhase(0),
+		plan.MovePhase(2, 0),
+		plan.EditTask(0, 0, "Something else"),
+	} {
+		if !errors.Is(err, ErrPhaseAlreadyRun) {
+			t.Errorf("Expected ErrPhaseAlreadyRun, got %v", err)
+		}
+	}
+
+	if err := plan.MovePhase(2, 1); err != nil {
+		t.Errorf("Expected pending phases to move: %v", err)
+	}
+}
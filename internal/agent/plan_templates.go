@@ -0,0 +1,392 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// ErrUnknownTemplate is returned when no template is registered under a name
+var ErrUnknownTemplate = errors.New("unknown plan template")
+
+// templateParamPattern matches {{param}} placeholders in template text
+var templateParamPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// PlanTemplate is a known-good phase and file structure for a common project
+// type. Text fields may contain {{param}} placeholders filled from the params
+// passed to FromTemplate, falling back to Defaults.
+type PlanTemplate struct {
+	Name          string
+	Description   string
+	Title         string
+	PlanDesc      string
+	Defaults      map[string]string
+	FileStructure map[string][]string
+	Phases        []TemplatePhase
+}
+
+// TemplatePhase is a phase in a plan template
+type TemplatePhase struct {
+	Name            string
+	Agent           models.AgentType
+	Tasks           []string
+	SuccessCriteria string
+	EstimatedTime   string
+	Dependencies    []string
+}
+
+// builtinTemplates are available on every planner
+var builtinTemplates = []*PlanTemplate{
+	{
+		Name:        "rest-api",
+		Description: "CRUD REST service with tests and a container image",
+		Title:       "{{name}}: {{framework}} REST API for {{resource}}s",
+		PlanDesc:    "A {{framework}} service exposing create, read, update and delete endpoints for {{resource}}s, with tests and a Dockerfile.",
+		Defaults:    map[string]string{"name": "api", "framework": "FastAPI", "resource": "item"},
+		FileStructure: map[string][]string{
+			"{{name}}/":       {"requirements.txt", "Dockerfile", "README.md"},
+			"{{name}}/app/":   {"__init__.py", "main.py", "models.py", "schemas.py", "database.py"},
+			"{{name}}/tests/": {"test_{{resource}}s.py"},
+		},
+		Phases: []TemplatePhase{
+			{
+				Name:  "Project Setup",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Create {{name}}/requirements.txt with {{framework}} and its server dependencies",
+					"Create {{name}}/app/database.py with the database session setup",
+				},
+				SuccessCriteria: "Dependencies install and the app package imports",
+				EstimatedTime:   "10m",
+			},
+			{
+				Name:  "Models and Endpoints",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Define the {{resource}} model in {{name}}/app/models.py and request/response schemas in {{name}}/app/schemas.py",
+					"Implement CRUD endpoints for {{resource}}s in {{name}}/app/main.py",
+				},
+				SuccessCriteria: "All CRUD endpoints respond with the documented status codes",
+				EstimatedTime:   "20m",
+				Dependencies:    []string{"phase-1"},
+			},
+			{
+				Name:  "Tests",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Write endpoint tests in {{name}}/tests/test_{{resource}}s.py covering success and not-found cases",
+				},
+				SuccessCriteria: "Test suite passes",
+				EstimatedTime:   "15m",
+				Dependencies:    []string{"phase-2"},
+			},
+			{
+				Name:  "Packaging",
+				Agent: models.AgentTypeInfra,
+				Tasks: []string{
+					"Create {{name}}/Dockerfile that runs the service",
+					"Document setup and endpoints in {{name}}/README.md",
+				},
+				SuccessCriteria: "Container image builds and serves the API",
+				EstimatedTime:   "10m",
+				Dependencies:    []string{"phase-2"},
+			},
+		},
+	},
+	{
+		Name:        "cli-tool",
+		Description: "Command-line tool with subcommands, config and tests",
+		Title:       "{{name}}: {{language}} command-line tool",
+		PlanDesc:    "A {{language}} CLI with argument parsing, subcommands, tests and usage documentation.",
+		Defaults:    map[string]string{"name": "cli", "language": "Go"},
+		FileStructure: map[string][]string{
+			// File names depend on the language, so only the layout is fixed
+			"{{name}}/":                   {"README.md"},
+			"{{name}}/cmd/":               nil,
+			"{{name}}/internal/commands/": nil,
+			"{{name}}/internal/config/":   nil,
+		},
+		Phases: []TemplatePhase{
+			{
+				Name:  "Scaffold",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Initialize the {{language}} project in {{name}}/ with its dependency manifest",
+					"Create the entry point in {{name}}/cmd/ with argument parsing and --help output",
+				},
+				SuccessCriteria: "The tool builds and prints usage",
+				EstimatedTime:   "10m",
+			},
+			{
+				Name:  "Commands",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Implement the subcommands in {{name}}/internal/commands",
+					"Load settings from flags and a config file in {{name}}/internal/config",
+				},
+				SuccessCriteria: "Each subcommand runs and reports errors with a non-zero exit code",
+				EstimatedTime:   "20m",
+				Dependencies:    []string{"phase-1"},
+			},
+			{
+				Name:  "Tests and Docs",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Write tests for the subcommands and config loading",
+					"Document installation and usage in {{name}}/README.md",
+				},
+				SuccessCriteria: "Test suite passes and README examples work",
+				EstimatedTime:   "15m",
+				Dependencies:    []string{"phase-2"},
+			},
+		},
+	},
+	{
+		Name:        "data-pipeline",
+		Description: "Extract-transform-load job with validation and scheduling",
+		Title:       "{{name}}: {{source}} to {{destination}} pipeline",
+		PlanDesc:    "An ETL pipeline that extracts from {{source}}, validates and transforms records, and loads them into {{destination}}.",
+		Defaults:    map[string]string{"name": "pipeline", "source": "CSV files", "destination": "PostgreSQL"},
+		FileStructure: map[string][]string{
+			"{{name}}/":          {"requirements.txt", "README.md"},
+			"{{name}}/pipeline/": {"__init__.py", "extract.py", "transform.py", "load.py", "run.py"},
+			"{{name}}/sql/":      {"schema.sql"},
+			"{{name}}/tests/":    {"test_transform.py"},
+		},
+		Phases: []TemplatePhase{
+			{
+				Name:  "Schema",
+				Agent: models.AgentTypeData,
+				Tasks: []string{
+					"Design the {{destination}} target schema in {{name}}/sql/schema.sql",
+				},
+				SuccessCriteria: "Schema applies cleanly to an empty database",
+				EstimatedTime:   "10m",
+			},
+			{
+				Name:  "Extract and Transform",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Read records from {{source}} in {{name}}/pipeline/extract.py",
+					"Validate and normalize records in {{name}}/pipeline/transform.py, rejecting malformed rows",
+				},
+				SuccessCriteria: "Sample input produces clean records and a rejection report",
+				EstimatedTime:   "20m",
+				Dependencies:    []string{"phase-1"},
+			},
+			{
+				Name:  "Load and Run",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Batch insert records into {{destination}} in {{name}}/pipeline/load.py",
+					"Wire extract, transform and load together in {{name}}/pipeline/run.py",
+				},
+				SuccessCriteria: "A full run loads the sample data idempotently",
+				EstimatedTime:   "15m",
+				Dependencies:    []string{"phase-2"},
+			},
+			{
+				Name:  "Tests",
+				Agent: models.AgentTypeCode,
+				Tasks: []string{
+					"Write transform tests in {{name}}/tests/test_transform.py",
+				},
+				SuccessCriteria: "Test suite passes",
+				EstimatedTime:   "10m",
+				Dependencies:    []string{"phase-2"},
+			},
+		},
+	},
+}
+
+// RegisterTemplate adds a user template, replacing any template with the same name
+func (p *Planner) RegisterTemplate(template *PlanTemplate) error {
+	if template == nil || template.Name == "" {
+		return fmt.Errorf("template must have a name")
+	}
+	if len(template.Phases) == 0 {
+		return fmt.Errorf("template %s has no phases", template.Name)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.templates == nil {
+		p.templates = make(map[string]*PlanTemplate)
+	}
+	p.templates[template.Name] = template
+	return nil
+}
+
+// Templates returns the available templates sorted by name
+func (p *Planner) Templates() []*PlanTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	byName := make(map[string]*PlanTemplate)
+	for _, t := range builtinTemplates {
+		byName[t.Name] = t
+	}
+	for name, t := range p.templates {
+		byName[name] = t
+	}
+
+	templates := make([]*PlanTemplate, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// template finds a template by name, preferring user templates over built-ins
+func (p *Planner) template(name string) (*PlanTemplate, bool) {
+	p.mu.RLock()
+	t, ok := p.templates[name]
+	p.mu.RUnlock()
+	if ok {
+		return t, true
+	}
+
+	for _, t := range builtinTemplates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// FromTemplate builds a plan from a registered template without calling the
+// model. params fill the template's {{param}} placeholders; every placeholder
+// must be covered by params or the template's defaults.
+func (p *Planner) FromTemplate(name string, params map[string]string) (*ExecutionPlan, error) {
+	t, ok := p.template(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	values := make(map[string]string, len(t.Defaults)+len(params))
+	for k, v := range t.Defaults {
+		values[k] = v
+	}
+	for k, v := range params {
+		values[k] = v
+	}
+
+	var missing []string
+	fill := func(text string) string {
+		return templateParamPattern.ReplaceAllStringFunc(text, func(match string) string {
+			key := templateParamPattern.FindStringSubmatch(match)[1]
+			value, ok := values[key]
+			if !ok {
+				missing = append(missing, key)
+				return match
+			}
+			return value
+		})
+	}
+
+	plan := &ExecutionPlan{
+		ID:            generatePlanID(),
+		Title:         fill(t.Title),
+		Description:   fill(t.PlanDesc),
+		FileStructure: make(map[string][]string, len(t.FileStructure)),
+		Phases:        make([]Phase, len(t.Phases)),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		State:         ExecutionState{Status: ExecutionStatusPending},
+	}
+
+	for dir, files := range t.FileStructure {
+		filled := make([]string, len(files))
+		for i, f := range files {
+			filled[i] = fill(f)
+		}
+		plan.FileStructure[fill(dir)] = filled
+	}
+
+	for i, tp := range t.Phases {
+		tasks := make([]Task, len(tp.Tasks))
+		for j, description := range tp.Tasks {
+			tasks[j] = Task{
+				ID:          fmt.Sprintf("task-%d-%d", i+1, j+1),
+				Description: fill(description),
+			}
+		}
+		plan.Phases[i] = Phase{
+			ID:              fmt.Sprintf("phase-%d", i+1),
+			Name:            fill(tp.Name),
+			Agent:           tp.Agent,
+			Tasks:           tasks,
+			SuccessCriteria: fill(tp.SuccessCriteria),
+			EstimatedTime:   tp.EstimatedTime,
+			Dependencies:    append([]string(nil), tp.Dependencies...),
+			Status:          PhaseStatusPending,
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("template %s needs parameter(s): %s", name, strings.Join(uniqueStrings(missing), ", "))
+	}
+
+	return plan, nil
+}
+
+// Refine asks the model to adapt a template-derived plan to the user's
+// request, keeping its structure. The plan's ID and creation time are kept.
+func (p *Planner) Refine(ctx context.Context, plan *ExecutionPlan, request string) (*ExecutionPlan, error) {
+	data, err := planJSON(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := fmt.Sprintf(`Adapt this proven project plan to the request below.
+Keep the phase structure and file layout unless the request needs a change;
+adjust tasks, names and file paths to fit. Output the plan as JSON in the same format only.
+
+Request: %s
+
+Plan:
+%s
+
+JSON:`, request, data)
+
+	result, err := p.client.GenerateSyncWithOptions(ctx, prompt, deterministic)
+	if err != nil {
+		return nil, fmt.Errorf("refinement failed: %w", err)
+	}
+
+	refined, err := p.parsePlanResponse(result.Response, request)
+	if err != nil {
+		return nil, fmt.Errorf("refinement failed: %w", err)
+	}
+	if len(refined.Phases) == 0 {
+		return nil, fmt.Errorf("refinement failed: model returned no phases")
+	}
+
+	refined.ID = plan.ID
+	refined.CreatedAt = plan.CreatedAt
+	refined.UpdatedAt = time.Now()
+	refined.State = plan.State.Snapshot()
+	if len(refined.FileStructure) == 0 {
+		refined.FileStructure = plan.FileStructure
+	}
+	return refined, nil
+}
+
+// uniqueStrings removes adjacent duplicates from a sorted slice
+func uniqueStrings(sorted []string) []string {
+	var out []string
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestFromTemplate tests parameter substitution and defaults in a built-in template
+func TestFromTemplate(t *testing.T) {
+	planner := NewPlanner(nil)
+
+	plan, err := planner.FromTemplate("rest-api", map[string]string{"name": "todo", "resource": "task"})
+	if err != nil {
+		t.Fatalf("FromTemplate failed: %v", err)
+	}
+
+	if plan.Title != "todo: FastAPI REST API for tasks" {
+		t.Errorf("Unexpected title: %s", plan.Title)
+	}
+	if _, ok := plan.FileStructure["todo/tests/"]; !ok {
+		t.Errorf("Expected file structure to use the name parameter, got %v", plan.FileStructure)
+	}
+	cli, err := planner.FromTemplate("cli-tool", nil)
+	if err != nil {
+		t.Fatalf("FromTemplate failed: %v", err)
+	}
+	if files, ok := cli.FileStructure["cli/internal/commands/"]; !ok || len(files) != 0 {
+		t.Errorf("Expected commands listed as a directory, got %v", cli.FileStructure)
+	}
+	if plan.State.Status != ExecutionStatusPending || !strings.HasPrefix(plan.ID, "plan_") {
+		t.Errorf("Expected a pending plan with a generated ID, got %s / %s", plan.ID, plan.State.Status)
+	}
+	for i, phase := range plan.Phases {
+		if phase.ID == "" || phase.Status != PhaseStatusPending {
+			t.Errorf("Phase %d not initialized: %+v", i, phase)
+		}
+		for _, task := range phase.Tasks {
+			if strings.Contains(task.Description, "{{") {
+				t.Errorf("Unfilled placeholder in task: %s", task.Description)
+			}
+		}
+	}
+}
+
+// TestFromTemplateErrors tests unknown templates and unresolved placeholders
+func TestFromTemplateErrors(t *testing.T) {
+	planner := NewPlanner(nil)
+
+	if _, err := planner.FromTemplate("missing", nil); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("Expected ErrUnknownTemplate, got %v", err)
+	}
+
+	err := planner.RegisterTemplate(&PlanTemplate{
+		Name:  "library",
+		Title: "{{name}} library",
+		Phases: []TemplatePhase{
+			{Name: "Build", Agent: models.AgentTypeCode, Tasks: []string{"Write {{name}} in {{language}}"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterTemplate failed: %v", err)
+	}
+
+	_, err = planner.FromTemplate("library", map[string]string{"name": "strutil"})
+	if err == nil || !strings.Contains(err.Error(), "language") {
+		t.Errorf("Expected missing parameter error naming language, got %v", err)
+	}
+
+	plan, err := planner.FromTemplate("library", map[string]string{"name": "strutil", "language": "Go"})
+	if err != nil {
+		t.Fatalf("FromTemplate failed: %v", err)
+	}
+	if plan.Phases[0].Tasks[0].Description != "Write strutil in Go" {
+		t.Errorf("Unexpected task: %s", plan.Phases[0].Tasks[0].Description)
+	}
+}
+
+// TestTemplatesListing tests that user templates are listed alongside and override built-ins
+func TestTemplatesListing(t *testing.T) {
+	planner := NewPlanner(nil)
+	custom := &PlanTemplate{
+		Name:   "cli-tool",
+		Phases: []TemplatePhase{{Name: "Everything", Agent: models.AgentTypeCode}},
+	}
+	if err := planner.RegisterTemplate(custom); err != nil {
+		t.Fatal(err)
+	}
+	if err := planner.RegisterTemplate(&PlanTemplate{Name: "empty"}); err == nil {
+		t.Error("Expected a template without phases to be rejected")
+	}
+
+	var names []string
+	for _, tmpl := range planner.Templates() {
+		names = append(names, tmpl.Name)
+		if tmpl.Name == "cli-tool" && tmpl != custom {
+			t.Error("Expected the user template to override the built-in")
+		}
+	}
+	if strings.Join(names, ",") != "cli-tool,data-pipeline,rest-api" {
+		t.Errorf("Unexpected templates: %v", names)
+	}
+}
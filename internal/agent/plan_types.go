@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"sync"
 	"time"
 
 	"github.com/quantumflow/quantumflow/internal/models"
@@ -14,7 +15,8 @@ type ExecutionPlan struct {
 	FileStructure map[string][]string `json:"file_structure,omitempty"` // Expected: dir -> files
 	Phases        []Phase             `json:"phases"`
 	State         ExecutionState      `json:"state"`
-	Manifest      *ProjectManifest    `json:"-"` // Runtime tracking (not serialized)
+	Manifest      *ProjectManifest    `json:"-"` // Runtime tracking (saved separately as <id>.manifest.json)
+	Patterns      []string            `json:"patterns,omitempty"` // IDs of workflow patterns the plan was seeded with
 	CreatedAt     time.Time           `json:"created_at"`
 	UpdatedAt     time.Time           `json:"updated_at"`
 }
@@ -29,6 +31,9 @@ type Phase struct {
 	EstimatedTime   string            `json:"estimated_time"`
 	Dependencies    []string          `json:"dependencies"` // IDs of phases that must complete first
 	Status          PhaseStatus       `json:"status"`
+	Checks          *PhaseChecks      `json:"checks,omitempty"` // Test and lint results after the phase wrote files
+	StartedAt       *time.Time        `json:"started_at,omitempty"`
+	CompletedAt     *time.Time        `json:"completed_at,omitempty"` // When the phase finished or failed
 }
 
 // Task represents a specific task within a phase
@@ -40,7 +45,8 @@ type Task struct {
 	Error       string     `json:"error,omitempty"`
 }
 
-// ExecutionState tracks the current state of plan execution
+// ExecutionState tracks the current state of plan execution. Code that may
+// run concurrently with an executing plan must use its methods, which lock.
 type ExecutionState struct {
 	Status          ExecutionStatus `json:"status"`
 	CurrentPhase    int             `json:"current_phase"`
@@ -49,6 +55,8 @@ type ExecutionState struct {
 	LastCheckpoint  string          `json:"last_checkpoint,omitempty"`
 	StartedAt       *time.Time      `json:"started_at,omitempty"`
 	CompletedAt     *time.Time      `json:"completed_at,omitempty"`
+
+	mu sync.Mutex
 }
 
 // PhaseStatus represents the status of a phase
@@ -92,11 +100,15 @@ type PlanGenerationRequest struct {
 }
 
 // PlanPreferences allows customization of plan generation
+// Zero limits are not enforced, and a plan the model can't bring within the
+// limits is still returned with a warning.
 type PlanPreferences struct {
-	MaxPhases       int  `json:"max_phases"`
-	RequireApproval bool `json:"require_approval"`
-	AutoExecute     bool `json:"auto_execute"`
-	VerboseLogging  bool `json:"verbose_logging"`
+	MaxPhases         int           `json:"max_phases"`
+	MaxEstimatedTime  time.Duration `json:"max_estimated_time"`  // Sum of phase estimates
+	MaxDestructiveOps int           `json:"max_destructive_ops"` // Tasks that delete data or run destructive tools
+	RequireApproval   bool          `json:"require_approval"`
+	AutoExecute       bool          `json:"auto_execute"`
+	VerboseLogging    bool          `json:"verbose_logging"`
 }
 
 // DefaultPlanPreferences returns default plan preferences
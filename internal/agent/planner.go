@@ -3,47 +3,111 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/logging"
+	"github.com/quantumflow/quantumflow/internal/memory"
+	"github.com/quantumflow/quantumflow/internal/models"
+	"github.com/quantumflow/quantumflow/internal/tracing"
 )
 
+// maxJSONReprompts is how many times the planner re-asks a model that
+// answered without any JSON
+const maxJSONReprompts = 1
+
+// deterministic samples routing and planning calls at temperature 0, so a
+// repeated request gets the same answer and the response cache can serve it
+var deterministic = inference.GenerateOptions{Deterministic: true}
+
+// strictJSONInstruction is appended to a prompt when re-asking for JSON
+const strictJSONInstruction = "\n\nYour previous answer contained no JSON. Respond with ONLY the JSON object: no prose, no explanations, no markdown.\nJSON:"
+
+// maxSuggestedPatterns caps how many past workflows are offered to the planner
+const maxSuggestedPatterns = 3
+
 // Planner generates execution plans for complex queries
 type Planner struct {
-	client *inference.Client
+	client inference.Generator
+	memory memory.Service
+	logger *slog.Logger
+
+	mu        sync.RWMutex
+	templates map[string]*PlanTemplate // User templates, consulted before built-ins
 }
 
 // NewPlanner creates a new plan generator
-func NewPlanner(client *inference.Client) *Planner {
+func NewPlanner(client inference.Generator) *Planner {
 	return &Planner{
 		client: client,
+		logger: logging.OrDiscard(nil),
 	}
 }
 
+// SetLogger sets the logger for responses the planner could not use
+func (p *Planner) SetLogger(logger *slog.Logger) {
+	p.logger = logging.OrDiscard(logger)
+}
+
+// SetMemory enables reuse of successful workflow patterns when planning
+func (p *Planner) SetMemory(memoryService memory.Service) {
+	p.memory = memoryService
+}
+
 // Generate creates an execution plan using two-stage hierarchical planning
 // Stage 1: Generate file structure (minimal tokens)
 // Stage 2: Generate phases (compact prompt)
 func (p *Planner) Generate(ctx context.Context, req *PlanGenerationRequest) (*ExecutionPlan, error) {
+	ctx, span := tracer().Start(ctx, "planner.Generate")
+	plan, err := p.generate(ctx, req)
+	if err == nil {
+		span.SetAttributes(attribute.String("plan.id", plan.ID), attribute.Int("plan.phases", len(plan.Phases)))
+	}
+	return plan, tracing.EndSpan(span, err)
+}
+
+// generate runs both planning stages, each in its own span
+func (p *Planner) generate(ctx context.Context, req *PlanGenerationRequest) (*ExecutionPlan, error) {
 	// Stage 1: Generate file structure first (small, focused prompt ~2k tokens)
 	fmt.Println("📐 Stage 1: Generating file structure...")
-	fileStructure, err := p.generateFileStructure(ctx, req.Query)
+	stageCtx, stageSpan := tracer().Start(ctx, "planner.FileStructure")
+	fileStructure, err := p.generateFileStructure(stageCtx, req.Query)
+	tracing.EndSpan(stageSpan, err)
 	if err != nil {
 		// Fall back to empty structure if stage 1 fails
 		fmt.Printf("⚠️ File structure generation failed, continuing without: %v\n", err)
 		fileStructure = make(map[string][]string)
 	}
 	
+	// Seed stage 2 with workflows that worked for similar requests
+	patterns := p.suggestPatterns(ctx, req.Query)
+	if len(patterns) > 0 {
+		fmt.Printf("🧩 Reusing %d proven workflow pattern(s)\n", len(patterns))
+	}
+
 	// Stage 2: Generate phases with compact prompt (~3k tokens)
 	fmt.Println("📋 Stage 2: Generating execution phases...")
-	plan, err := p.generatePhasesCompact(ctx, req.Query, fileStructure)
+	stageCtx, stageSpan = tracer().Start(ctx, "planner.Phases")
+	plan, err := p.generatePhasesCompact(stageCtx, req, fileStructure, patterns)
+	tracing.EndSpan(stageSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("phase generation failed: %w", err)
 	}
 
+	plan = p.enforceBudget(ctx, plan, req)
+
 	// Set metadata
 	plan.ID = generatePlanID()
+	for _, pattern := range patterns {
+		plan.Patterns = append(plan.Patterns, pattern.ID)
+	}
 	plan.FileStructure = fileStructure
 	plan.CreatedAt = time.Now()
 	plan.UpdatedAt = time.Now()
@@ -68,24 +132,24 @@ Output ONLY valid JSON in this exact format:
 
 JSON:`, query)
 
-	result, err := p.client.GenerateSync(ctx, prompt)
+	response, err := p.generateJSON(ctx, "file_structure", prompt)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse response
-	response := strings.TrimSpace(result.Response)
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-	if start == -1 || end == -1 {
-		return nil, fmt.Errorf("no JSON in response")
+	jsonStr, repaired, err := extractJSON(response)
+	if err != nil {
+		return nil, err
+	}
+	if repaired {
+		fmt.Println("🩹 Repaired truncated file structure JSON")
 	}
 
 	var parsed struct {
 		Dirs map[string][]string `json:"dirs"`
 	}
-	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
-		return nil, err
+	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedJSON, err)
 	}
 
 	return parsed.Dirs, nil
@@ -93,7 +157,7 @@ JSON:`, query)
 
 // generatePhasesCompact creates phases using a minimal prompt
 // This is Stage 2 of hierarchical planning (~3k tokens)
-func (p *Planner) generatePhasesCompact(ctx context.Context, query string, fileStructure map[string][]string) (*ExecutionPlan, error) {
+func (p *Planner) generatePhasesCompact(ctx context.Context, req *PlanGenerationRequest, fileStructure map[string][]string, patterns []*models.WorkflowPattern) (*ExecutionPlan, error) {
 	// Count files for context
 	fileCount := 0
 	for _, files := range fileStructure {
@@ -101,17 +165,7 @@ func (p *Planner) generatePhasesCompact(ctx context.Context, query string, fileS
 	}
 
 	// Get project root from structure to guide phase tasks
-	var projectRoot string
-	for dir := range fileStructure {
-		parts := strings.Split(dir, "/")
-		if len(parts) > 0 && parts[0] != "" {
-			if projectRoot == "" {
-				projectRoot = parts[0]
-			} else if projectRoot != parts[0] {
-				// Detect multiple roots, but stick to first one found for prompt hint
-			}
-		}
-	}
+	root := projectRoot(fileStructure)
 
 	prompt := fmt.Sprintf(`Create build plan for: %s
 
@@ -124,15 +178,116 @@ Rules:
 1. Phases: 3-5 max
 2. Tasks MUST use full file paths starting with %s/
 3. Output JSON only
+%s%s
+JSON:`, req.Query, root, fileCount, root, formatBudgetRules(req.Preferences), formatPatternHints(patterns))
 
-JSON:`, query, projectRoot, fileCount, projectRoot)
+	response, err := p.generateJSON(ctx, "phases", prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := p.parsePlanResponse(response, req.Query)
+	if !errors.Is(err, ErrMalformedJSON) {
+		return plan, err
+	}
 
-	result, err := p.client.GenerateSync(ctx, prompt)
+	// Output too long for the model's limits is usually cut off; ask for less
+	fmt.Println("⚠️ Plan JSON was unusable; retrying with a shorter prompt...")
+	response, err = p.generateJSON(ctx, "phases", shortPhasesPrompt(req, root))
 	if err != nil {
 		return nil, err
 	}
+	return p.parsePlanResponse(response, req.Query)
+}
+
+// projectRoot is the top-level directory holding most of a file structure's
+// directories, ties going to the alphabetically first, so the same structure
+// always gives the same prompt
+func projectRoot(fileStructure map[string][]string) string {
+	counts := make(map[string]int)
+	for dir := range fileStructure {
+		if root, _, _ := strings.Cut(dir, "/"); root != "" {
+			counts[root]++
+		}
+	}
+
+	var best string
+	for root, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && root < best) {
+			best = root
+		}
+	}
+	return best
+}
+
+// generateJSON asks the model for a JSON response. A response without any
+// JSON is re-requested with a stricter instruction and the model constrained
+// to JSON output; if that fails too, the raw response is shown and logged.
+func (p *Planner) generateJSON(ctx context.Context, stage, prompt string) (string, error) {
+	result, err := p.client.GenerateSyncWithOptions(ctx, prompt, deterministic)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 1; attempt <= maxJSONReprompts; attempt++ {
+		if _, _, err := extractJSON(result.Response); !errors.Is(err, ErrNoJSON) {
+			return result.Response, nil
+		}
+		fmt.Printf("⚠️ Model answered without JSON; asking again (attempt %d/%d)...\n", attempt, maxJSONReprompts)
+		result, err = p.client.GenerateSyncWithOptions(ctx, prompt+strictJSONInstruction, inference.GenerateOptions{Format: "json", Deterministic: true})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if _, _, err := extractJSON(result.Response); errors.Is(err, ErrNoJSON) {
+		fmt.Printf("📄 Model response:\n%s\n", truncateResponse(strings.TrimSpace(result.Response), 500))
+		p.logger.Warn("planner response had no JSON", "stage", stage, "response", result.Response)
+		return "", fmt.Errorf("%s: %w", stage, ErrNoJSON)
+	}
+	return result.Response, nil
+}
+
+// shortPhasesPrompt is a minimal stage 2 prompt asking for a small plan, used
+// when the full prompt's response was truncated
+func shortPhasesPrompt(req *PlanGenerationRequest, projectRoot string) string {
+	return fmt.Sprintf(`Build plan for: %s
+Files go under %s/. At most 3 phases with 2 short tasks each.%s
+Output ONLY JSON: {"title":"...","description":"...","phases":[{"name":"...","agent":"code","tasks":[{"description":"..."}],"success_criteria":"...","estimated_time":"10 min"}]}
+JSON:`, req.Query, projectRoot, formatBudgetRules(req.Preferences))
+}
+
+// suggestPatterns looks up proven workflow patterns for a query; memory
+// failures only cost the hints, never the plan
+func (p *Planner) suggestPatterns(ctx context.Context, query string) []*models.WorkflowPattern {
+	if p.memory == nil {
+		return nil
+	}
 
-	return p.parsePlanResponse(result.Response, query)
+	patterns, err := p.memory.SuggestPatterns(ctx, query, maxSuggestedPatterns)
+	if err != nil {
+		fmt.Printf("⚠️ Could not load workflow patterns: %v\n", err)
+		return nil
+	}
+	return patterns
+}
+
+// formatPatternHints renders workflow patterns as suggested phases for the planning prompt
+func formatPatternHints(patterns []*models.WorkflowPattern) string {
+	if len(patterns) == 0 {
+		return ""
+	}
+
+	var hints strings.Builder
+	hints.WriteString("\nProven workflows for similar requests (reuse these phases where they fit):\n")
+	for _, pattern := range patterns {
+		steps := make([]string, len(pattern.Steps))
+		for i, step := range pattern.Steps {
+			steps[i] = fmt.Sprintf("%s (%s)", step.Action, step.Tool)
+		}
+		hints.WriteString(fmt.Sprintf("- %s: %s\n", pattern.Name, strings.Join(steps, " -> ")))
+	}
+	return hints.String()
 }
 
 // buildPlanningPrompt creates a combined prompt (fallback for larger models)
@@ -144,28 +299,13 @@ JSON:`, req.Query)
 
 // parsePlanResponse extracts the execution plan from LLM output
 func (p *Planner) parsePlanResponse(response string, query string) (*ExecutionPlan, error) {
-	response = strings.TrimSpace(response)
-	
-	// Remove markdown code blocks if present
-	if strings.HasPrefix(response, "```json") {
-		response = strings.TrimPrefix(response, "```json")
-		response = strings.TrimSuffix(response, "```")
-		response = strings.TrimSpace(response)
-	} else if strings.HasPrefix(response, "```") {
-		response = strings.TrimPrefix(response, "```")
-		response = strings.TrimSuffix(response, "```")
-		response = strings.TrimSpace(response)
-	}
-
-	// Find JSON object
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-	
-	if start == -1 || end == -1 {
-		return nil, fmt.Errorf("no JSON object found in response")
+	jsonStr, repaired, err := extractJSON(response)
+	if err != nil {
+		return nil, err
+	}
+	if repaired {
+		fmt.Println("🩹 Repaired truncated plan JSON")
 	}
-
-	jsonStr := response[start : end+1]
 	
 	// Parse into temporary structure
 	var rawPlan struct {
@@ -185,7 +325,7 @@ func (p *Planner) parsePlanResponse(response string, query string) (*ExecutionPl
 	}
 	
 	if err := json.Unmarshal([]byte(jsonStr), &rawPlan); err != nil {
-		return nil, fmt.Errorf("JSON parse error: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrMalformedJSON, err)
 	}
 
 	// Convert to ExecutionPlan
@@ -226,6 +366,44 @@ func (p *Planner) parsePlanResponse(response string, query string) (*ExecutionPl
 	return plan, nil
 }
 
+// planJSON renders a plan in the JSON format the model produces, so it can be
+// sent back for revision and parsed again with parsePlanResponse
+func planJSON(plan *ExecutionPlan) ([]byte, error) {
+	type rawTask struct {
+		Description string `json:"description"`
+	}
+	type rawPhase struct {
+		Name            string    `json:"name"`
+		Agent           string    `json:"agent"`
+		Tasks           []rawTask `json:"tasks"`
+		SuccessCriteria string    `json:"success_criteria"`
+		EstimatedTime   string    `json:"estimated_time"`
+		Dependencies    []string  `json:"dependencies,omitempty"`
+	}
+	raw := struct {
+		Title         string              `json:"title"`
+		Description   string              `json:"description"`
+		FileStructure map[string][]string `json:"file_structure,omitempty"`
+		Phases        []rawPhase          `json:"phases"`
+	}{Title: plan.Title, Description: plan.Description, FileStructure: plan.FileStructure}
+
+	for _, phase := range plan.Phases {
+		rp := rawPhase{
+			Name:            phase.Name,
+			Agent:           string(phase.Agent),
+			SuccessCriteria: phase.SuccessCriteria,
+			EstimatedTime:   phase.EstimatedTime,
+			Dependencies:    phase.Dependencies,
+		}
+		for _, task := range phase.Tasks {
+			rp.Tasks = append(rp.Tasks, rawTask{task.Description})
+		}
+		raw.Phases = append(raw.Phases, rp)
+	}
+
+	return json.MarshalIndent(raw, "", "  ")
+}
+
 // FormatAsMarkdown converts an execution plan to markdown format
 func (p *Planner) FormatAsMarkdown(plan *ExecutionPlan) string {
 	var md strings.Builder
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestParsePlanResponse tests turning raw model output into a plan: fences,
+// prose, missing fields, empty phases, agent casings and malformed JSON
+func TestParsePlanResponse(t *testing.T) {
+	const plan = `{"title": "Todo API", "description": "REST service", "phases": [
+		{"name": "Scaffold", "agent": "Code", "tasks": [{"description": "Create main.go"}, {"description": "Add routes"}], "success_criteria": "Builds", "estimated_time": "10 min"},
+		{"name": "Schema", "agent": "DATA", "tasks": [{"description": "Create todos table"}], "dependencies": ["phase-1"]},
+		{"name": "Deploy", "agent": "InfraAgent", "tasks": [], "dependencies": ["Scaffold", "phase-2"]},
+		{"name": "Audit", "agent": "security"},
+		{"name": "Chat", "agent": "general", "tasks": [{"description": "Say hi"}]}
+	]}`
+
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"bare", plan},
+		{"json fence", "```json\n" + plan + "\n```"},
+		{"plain fence", "```\n" + plan + "\n```"},
+		{"prose around", "Here is the plan:\n" + plan + "\nLet me know!"},
+	}
+
+	planner := NewPlanner(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := planner.parsePlanResponse(tt.response, "build a todo API")
+			if err != nil {
+				t.Fatalf("parsePlanResponse failed: %v", err)
+			}
+			if parsed.Title != "Todo API" || len(parsed.Phases) != 5 {
+				t.Fatalf("Unexpected plan: %q with %d phases", parsed.Title, len(parsed.Phases))
+			}
+
+			wantAgents := []models.AgentType{models.AgentTypeCode, models.AgentTypeData, models.AgentTypeInfra, models.AgentTypeSec, models.AgentTypeCode}
+			for i, phase := range parsed.Phases {
+				if phase.Agent != wantAgents[i] {
+					t.Errorf("Phase %d: expected agent %s, got %s", i+1, wantAgents[i], phase.Agent)
+				}
+				if phase.ID != "phase-"+string(rune('1'+i)) || phase.Status != PhaseStatusPending {
+					t.Errorf("Phase %d: unexpected ID %q or status %q", i+1, phase.ID, phase.Status)
+				}
+			}
+
+			first := parsed.Phases[0]
+			if len(first.Tasks) != 2 || first.Tasks[1].ID != "task-1-2" || first.Tasks[1].Description != "Add routes" || first.EstimatedTime != "10 min" {
+				t.Errorf("Unexpected first phase: %+v", first)
+			}
+			if deps := parsed.Phases[2].Dependencies; len(deps) != 2 || deps[0] != "Scaffold" || deps[1] != "phase-2" {
+				t.Errorf("Expected dependencies kept as written, got %v", deps)
+			}
+			if len(parsed.Phases[2].Tasks) != 0 || len(parsed.Phases[3].Tasks) != 0 || parsed.Phases[3].Dependencies != nil {
+				t.Errorf("Expected phases without tasks or dependencies to stay empty, got %+v", parsed.Phases[3])
+			}
+		})
+	}
+
+	// Missing fields are left empty rather than rejected
+	parsed, err := planner.parsePlanResponse(`{"phases": [{"name": "Only"}]}`, "q")
+	if err != nil || parsed.Title != "" || len(parsed.Phases) != 1 || parsed.Phases[0].Agent != models.AgentTypeCode {
+		t.Errorf("Expected a sparse plan to parse with defaults, got %+v, %v", parsed, err)
+	}
+	parsed, err = planner.parsePlanResponse(`{"title": "Empty"}`, "q")
+	if err != nil || len(parsed.Phases) != 0 {
+		t.Errorf("Expected a plan without phases, got %+v, %v", parsed, err)
+	}
+
+	if _, err := planner.parsePlanResponse(`{"title": "Bad", "phases": "not a list"}`, "q"); !errors.Is(err, ErrMalformedJSON) {
+		t.Errorf("Expected wrongly typed fields to be malformed, got %v", err)
+	}
+	if _, err := planner.parsePlanResponse(`{"title": "Bad", "phases": [{"name": "A", "tasks": [}]}`, "q"); !errors.Is(err, ErrMalformedJSON) {
+		t.Errorf("Expected broken JSON to be malformed, got %v", err)
+	}
+	if _, err := planner.parsePlanResponse("I can't plan that.", "q"); !errors.Is(err, ErrNoJSON) {
+		t.Errorf("Expected a response without JSON to fail, got %v", err)
+	}
+}
+
+// TestPlanJSONRoundTrip tests that a parsed plan rendered back to JSON parses
+// to the same phases
+func TestPlanJSONRoundTrip(t *testing.T) {
+	planner := NewPlanner(nil)
+	original, err := planner.parsePlanResponse(`{"title": "T", "description": "D", "phases": [
+		{"name": "A", "agent": "data", "tasks": [{"description": "one"}], "success_criteria": "ok", "estimated_time": "5 min"},
+		{"name": "B", "agent": "sec", "tasks": [{"description": "two"}], "dependencies": ["A"]}]}`, "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := planJSON(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := planner.parsePlanResponse(string(data), "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range original.Phases {
+		a, b := original.Phases[i], again.Phases[i]
+		if a.Name != b.Name || a.Agent != b.Agent || len(a.Tasks) != len(b.Tasks) || strings.Join(a.Dependencies, ",") != strings.Join(b.Dependencies, ",") {
+			t.Errorf("Phase %d changed in the round trip: %+v vs %+v", i+1, a, b)
+		}
+	}
+}
+
+// TestProjectRoot tests that the project root doesn't depend on map order
+func TestProjectRoot(t *testing.T) {
+	structure := map[string][]string{
+		"shop/":        {"main.go"},
+		"shop/api/":    {"routes.go"},
+		"docs/":        {"README.md"},
+		"admin/":       {"index.html"},
+		"admin/pages/": {"home.html"},
+	}
+	for i := 0; i < 20; i++ {
+		if root := projectRoot(structure); root != "admin" {
+			t.Fatalf("Expected the alphabetically first of the largest roots, got %q", root)
+		}
+	}
+	structure["shop/db/"] = []string{"schema.sql"}
+	if root := projectRoot(structure); root != "shop" {
+		t.Errorf("Expected the root with the most directories, got %q", root)
+	}
+	if root := projectRoot(nil); root != "" {
+		t.Errorf("Expected no root for an empty structure, got %q", root)
+	}
+}
+
+// TestPlannerGenerate tests both planning stages end to end through a mock model
+func TestPlannerGenerate(t *testing.T) {
+	mock := inference.NewMockGenerator(
+		`{"dirs": {"todo_api/": ["main.go"], "todo_api/store/": ["store.go"]}}`,
+		"```json\n"+`{"title": "Todo API", "description": "CRUD", "phases": [
+			{"name": "Scaffold", "agent": "code", "tasks": [{"description": "todo_api/main.go"}]},
+			{"name": "Storage", "agent": "Data", "tasks": [{"description": "todo_api/store/store.go"}], "dependencies": ["phase-1"]}]}`+"\n```",
+	)
+
+	plan, err := NewPlanner(mock).Generate(context.Background(), &PlanGenerationRequest{Query: "todo API"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	calls := mock.Calls()
+	if len(calls) != 2 || !strings.Contains(calls[1].Prompt, "Project Root: todo_api/") {
+		t.Fatalf("Expected a file structure call then a phases call for todo_api, got %+v", calls)
+	}
+	if plan.ID == "" || plan.Title != "Todo API" || len(plan.Phases) != 2 || plan.Phases[1].Agent != models.AgentTypeData {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+	if len(plan.FileStructure["todo_api/store/"]) != 1 || plan.State.Status != ExecutionStatusPending {
+		t.Errorf("Expected the stage 1 structure and a pending state, got %v, %s", plan.FileStructure, plan.State.Status)
+	}
+}
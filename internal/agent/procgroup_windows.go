@@ -0,0 +1,9 @@
+//go:build windows
+
+package agent
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows; a cancelled command's process is
+// killed, but not the processes it started
+func setProcessGroup(cmd *exec.Cmd) {}
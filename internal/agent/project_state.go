@@ -24,6 +24,10 @@ type FileEntry struct {
 	Purpose   string    `json:"purpose"`
 	Size      int64     `json:"size"`
 	CreatedAt time.Time `json:"created_at"`
+	Problem   string    `json:"problem,omitempty"` // Why the generated file looks broken, e.g. a parse error
+
+	// Resolutions records later phases' writes to the file under the duplicate policy
+	Resolutions []FileResolution `json:"resolutions,omitempty"`
 }
 
 // NewProjectManifest creates a new manifest for tracking project files
@@ -36,9 +40,17 @@ func NewProjectManifest(projectName, baseDir string) *ProjectManifest {
 	}
 }
 
+// Path resolves a manifest path, which is relative to BaseDir, on disk
+func (m *ProjectManifest) Path(rel string) string {
+	if m.BaseDir == "" {
+		return rel
+	}
+	return filepath.Join(m.BaseDir, rel)
+}
+
 // AddFile records a newly created file in the manifest
 func (m *ProjectManifest) AddFile(path, phase, purpose string) {
-	info, _ := os.Stat(path)
+	info, _ := os.Stat(m.Path(path))
 	var size int64
 	if info != nil {
 		size = info.Size()
@@ -60,6 +72,37 @@ func (m *ProjectManifest) AddFile(path, phase, purpose string) {
 	m.FileStructure[dir] = append(m.FileStructure[dir], filepath.Base(path))
 }
 
+// FlagFile records a problem with a created file
+func (m *ProjectManifest) FlagFile(path, problem string) {
+	for i := range m.CreatedFiles {
+		if m.CreatedFiles[i].Path == path {
+			m.CreatedFiles[i].Problem = problem
+		}
+	}
+}
+
+// RemoveFile forgets a created file so it can be written again
+func (m *ProjectManifest) RemoveFile(path string) {
+	kept := m.CreatedFiles[:0]
+	for _, f := range m.CreatedFiles {
+		if f.Path != path {
+			kept = append(kept, f)
+		}
+	}
+	m.CreatedFiles = kept
+}
+
+// ProblemFiles returns the created files flagged with a problem
+func (m *ProjectManifest) ProblemFiles() []FileEntry {
+	var flagged []FileEntry
+	for _, f := range m.CreatedFiles {
+		if f.Problem != "" {
+			flagged = append(flagged, f)
+		}
+	}
+	return flagged
+}
+
 // FileExists checks if a file was already created
 func (m *ProjectManifest) FileExists(path string) bool {
 	for _, f := range m.CreatedFiles {
@@ -111,7 +154,7 @@ func (m *ProjectManifest) InitializeDirectories() error {
 		if dir == "." || dir == "" {
 			continue
 		}
-		if err := os.MkdirAll(dir, 0755); err != nil {
+		if err := os.MkdirAll(m.Path(dir), 0755); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 	}
@@ -138,6 +181,9 @@ func LoadManifest(path string) (*ProjectManifest, error) {
 	if err := json.Unmarshal(data, &manifest); err != nil {
 		return nil, err
 	}
+	if manifest.FileStructure == nil {
+		manifest.FileStructure = make(map[string][]string)
+	}
 
 	return &manifest, nil
 }
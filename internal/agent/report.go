@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// reportDir returns the directory exported plan reports are written to
+func reportDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".quantumflow", "reports")
+}
+
+// ExportReport writes a markdown report of a plan and its results to the
+// reports directory, returning the report's path
+func (a *ApprovalWorkflow) ExportReport(plan *ExecutionPlan) (string, error) {
+	dir := reportDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+
+	path := filepath.Join(dir, plan.ID+".md")
+	if err := os.WriteFile(path, []byte(FormatReport(plan)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+// FormatReport renders a plan as a shareable markdown report: its execution
+// state, a phase summary with durations and checks, the files it created and
+// each phase's agent responses
+func FormatReport(plan *ExecutionPlan) string {
+	state := plan.State.Snapshot()
+	var md strings.Builder
+
+	fmt.Fprintf(&md, "# %s\n\n", plan.Title)
+	if plan.Description != "" {
+		fmt.Fprintf(&md, "%s\n\n", plan.Description)
+	}
+	fmt.Fprintf(&md, "**Plan ID:** %s  \n", plan.ID)
+	fmt.Fprintf(&md, "**Status:** %s  \n", state.Status)
+	if state.StartedAt != nil {
+		fmt.Fprintf(&md, "**Started:** %s  \n", state.StartedAt.Format("2006-01-02 15:04:05"))
+	}
+	if state.StartedAt != nil && state.CompletedAt != nil {
+		fmt.Fprintf(&md, "**Duration:** %s  \n", state.CompletedAt.Sub(*state.StartedAt).Round(time.Second))
+	}
+	fmt.Fprintf(&md, "**Phases:** %d completed, %d failed, %d total\n\n",
+		len(state.CompletedPhases), len(state.FailedPhases), len(plan.Phases))
+
+	md.WriteString("## Summary\n\n")
+	md.WriteString("| # | Phase | Agent | Status | Duration | Checks |\n")
+	md.WriteString("|---|-------|-------|--------|----------|--------|\n")
+	for i, phase := range plan.Phases {
+		fmt.Fprintf(&md, "| %d | %s | %s | %s | %s | %s |\n",
+			i+1, tableCell(phase.Name), phase.Agent, phaseStatus(phase), phaseDuration(phase), checksSummary(phase.Checks))
+	}
+	md.WriteString("\n")
+
+	if plan.Manifest != nil && len(plan.Manifest.CreatedFiles) > 0 {
+		md.WriteString("## Created Files\n\n")
+		if plan.Manifest.BaseDir != "" && plan.Manifest.BaseDir != "." {
+			fmt.Fprintf(&md, "In `%s`:\n\n", plan.Manifest.BaseDir)
+		}
+		md.WriteString("| File | Phase | Size | Notes |\n")
+		md.WriteString("|------|-------|------|-------|\n")
+		for _, f := range plan.Manifest.CreatedFiles {
+			notes := f.Problem
+			if len(f.Resolutions) > 0 {
+				if notes != "" {
+					notes += "; "
+				}
+				notes += fmt.Sprintf("%d later write(s) resolved", len(f.Resolutions))
+			}
+			fmt.Fprintf(&md, "| `%s` | %s | %d B | %s |\n", f.Path, tableCell(f.Phase), f.Size, tableCell(notes))
+		}
+		md.WriteString("\n")
+	}
+
+	md.WriteString("## Phases\n\n")
+	for i, phase := range plan.Phases {
+		fmt.Fprintf(&md, "### Phase %d: %s\n\n", i+1, phase.Name)
+		fmt.Fprintf(&md, "**Agent:** %s  \n", phase.Agent)
+		fmt.Fprintf(&md, "**Status:** %s\n\n", phaseStatus(phase))
+
+		for j, task := range phase.Tasks {
+			box := " "
+			if task.Completed {
+				box = "x"
+			}
+			fmt.Fprintf(&md, "- [%s] %s\n", box, task.Description)
+			if task.Error != "" {
+				fmt.Fprintf(&md, "  - ❌ %s\n", task.Error)
+			}
+			if task.Result != "" {
+				// The response is markdown itself, so it's folded rather than fenced
+				fmt.Fprintf(&md, "\n<details>\n<summary>Response to task %d.%d</summary>\n\n%s\n\n</details>\n\n",
+					i+1, j+1, strings.TrimSpace(task.Result))
+			}
+		}
+		md.WriteString("\n")
+
+		if phase.Checks != nil {
+			for _, check := range []struct {
+				name   string
+				result *CheckResult
+			}{{"Tests", phase.Checks.Tests}, {"Lint", phase.Checks.Lint}} {
+				if check.result != nil {
+					fmt.Fprintf(&md, "**%s:** `%s` %s  \n", check.name, check.result.Command, checkOutcome(check.result))
+				}
+			}
+			md.WriteString("\n")
+		}
+	}
+
+	return md.String()
+}
+
+// phaseStatus returns a phase's status, treating an unset one as pending
+func phaseStatus(phase Phase) PhaseStatus {
+	if phase.Status == "" {
+		return PhaseStatusPending
+	}
+	return phase.Status
+}
+
+// phaseDuration returns how long a finished phase ran, or "-"
+func phaseDuration(phase Phase) string {
+	if phase.StartedAt == nil || phase.CompletedAt == nil {
+		return "-"
+	}
+	return phase.CompletedAt.Sub(*phase.StartedAt).Round(time.Second).String()
+}
+
+// checksSummary describes a phase's test and lint results in a few words
+func checksSummary(checks *PhaseChecks) string {
+	if checks == nil {
+		return "-"
+	}
+	var parts []string
+	if checks.Tests != nil {
+		parts = append(parts, "tests "+checkOutcome(checks.Tests))
+	}
+	if checks.Lint != nil {
+		parts = append(parts, "lint "+checkOutcome(checks.Lint))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkOutcome describes a check result in one word
+func checkOutcome(result *CheckResult) string {
+	switch {
+	case result.Skipped:
+		return "skipped"
+	case result.Passed:
+		return "passed"
+	}
+	return "failed"
+}
+
+// tableCell escapes text for a markdown table cell
+func tableCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(text, "\n", " ")
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestExportReport tests that a report covers state, durations, files and responses
+func TestExportReport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	started := time.Date(2026, 1, 17, 14, 0, 0, 0, time.UTC)
+	finished := started.Add(90 * time.Second)
+	plan := &ExecutionPlan{
+		ID:    "plan_report",
+		Title: "Todo API",
+		Phases: []Phase{
+			{
+				ID: "phase-1", Name: "Setup", Agent: models.AgentTypeCode,
+				Status: PhaseStatusCompleted, StartedAt: &started, CompletedAt: &finished,
+				Tasks:  []Task{{Description: "Create app", Completed: true, Result: "## Done\nCreated `app.py`"}},
+				Checks: &PhaseChecks{Tests: &CheckResult{Command: "pytest", Passed: true}},
+			},
+			{
+				ID: "phase-2", Name: "Deploy | ship", Agent: models.AgentTypeInfra,
+				Status: PhaseStatusFailed,
+				Tasks:  []Task{{Description: "Write Dockerfile", Error: "agent timed out"}},
+			},
+		},
+		Manifest: &ProjectManifest{CreatedFiles: []FileEntry{{Path: "app.py", Phase: "Setup", Size: 120}}},
+	}
+	plan.State.Begin(started)
+	plan.State.MarkCompleted(0)
+	plan.State.MarkFailed(1)
+
+	path, err := NewApprovalWorkflow(nil).ExportReport(plan)
+	if err != nil {
+		t.Fatalf("ExportReport failed: %v", err)
+	}
+	if want := filepath.Join(home, ".quantumflow", "reports", "plan_report.md"); path != want {
+		t.Errorf("Expected report at %s, got %s", want, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Report not written: %v", err)
+	}
+	report := string(data)
+
+	for _, want := range []string{
+		"**Phases:** 1 completed, 1 failed, 2 total",
+		"| 1 | Setup | code | completed | 1m30s | tests passed |",
+		`| 2 | Deploy \| ship | infra | failed | - | - |`,
+		"| `app.py` | Setup | 120 B |  |",
+		"- [x] Create app",
+		"## Done\nCreated `app.py`",
+		"- [ ] Write Dockerfile\n  - ❌ agent timed out",
+		"**Tests:** `pytest` passed",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
@@ -10,9 +10,11 @@ import (
 
 // CachedRoute holds a cached routing decision
 type CachedRoute struct {
-	AgentType  models.AgentType
-	Confidence float64
-	CachedAt   time.Time
+	AgentType      models.AgentType
+	Confidence     float64
+	Reasoning      string
+	SecondaryAgent models.AgentType
+	CachedAt       time.Time
 }
 
 // RoutingCache provides TTL-based caching for routing decisions
@@ -48,15 +50,13 @@ func (c *RoutingCache) Get(query string) (*CachedRoute, bool) {
 }
 
 // Set stores a routing decision in cache
-func (c *RoutingCache) Set(query string, agentType models.AgentType, confidence float64) {
+func (c *RoutingCache) Set(query string, route *CachedRoute) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.cache[normalizeQuery(query)] = &CachedRoute{
-		AgentType:  agentType,
-		Confidence: confidence,
-		CachedAt:   time.Now(),
-	}
+	entry := *route
+	entry.CachedAt = time.Now()
+	c.cache[normalizeQuery(query)] = &entry
 }
 
 // cleanup removes expired entries periodically
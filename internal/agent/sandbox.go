@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sandboxEnvVars are passed through to sandboxed commands; everything else,
+// including API keys and cloud credentials, is dropped
+var sandboxEnvVars = []string{
+	"PATH", "HOME", "USER", "LANG", "LC_ALL", "TERM", "TMPDIR",
+	"GOPATH", "GOCACHE", "GOMODCACHE", "GOPROXY",
+	"NODE_PATH", "npm_config_cache",
+	"VIRTUAL_ENV", "PYTHONPATH",
+}
+
+// sandboxEnv builds the environment for a sandboxed command run in dir
+func sandboxEnv(dir string) []string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	env := []string{"PWD=" + dir}
+	for _, name := range sandboxEnvVars {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// commandSeparator splits a shell command into simple commands
+var commandSeparator = regexp.MustCompile(`[;&|()]+`)
+
+// redirectPrefix matches the redirection operator glued to a path, as in 2>/dev/null
+var redirectPrefix = regexp.MustCompile(`^\d*[<>]+`)
+
+// deviceFiles may be named by sandboxed commands even though they are outside
+// the project directory
+var deviceFiles = map[string]bool{
+	"/dev/null": true, "/dev/stdin": true, "/dev/stdout": true, "/dev/stderr": true,
+}
+
+// escapesSandbox reports whether a command changes directory outside dir or
+// names a path outside it, either absolute or through "..". A bare cd goes to
+// $HOME, so it escapes too, as does any argument the shell would expand
+// through a variable or command substitution, since its path is unknown here.
+func escapesSandbox(command, dir string) bool {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return true
+	}
+
+	current := root
+	for _, segment := range commandSeparator.Split(command, -1) {
+		fields := strings.Fields(segment)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "cd" || fields[0] == "pushd" {
+			target := ""
+			if len(fields) > 1 {
+				target = strings.Trim(fields[1], `"'`)
+			}
+			if target == "" || target == "-" || strings.HasPrefix(target, "~") || expands(target) {
+				return true
+			}
+			current = resolvePath(current, target)
+			if outside(root, current) {
+				return true
+			}
+			continue
+		}
+
+		for _, field := range fields {
+			if pathEscapes(root, current, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathEscapes reports whether a command argument names a path outside root,
+// resolving relative paths against current
+func pathEscapes(root, current, arg string) bool {
+	arg = redirectPrefix.ReplaceAllString(strings.Trim(arg, `"'`), "")
+	if _, value, ok := strings.Cut(arg, "="); ok {
+		arg = value // --prefix=/usr/local
+	}
+	arg = strings.Trim(arg, `"'`)
+
+	switch {
+	case strings.HasPrefix(arg, "~") || expands(arg):
+		return true
+	case deviceFiles[arg]:
+		return false
+	case filepath.IsAbs(arg) || hasParentRef(arg):
+		return outside(root, resolvePath(current, arg))
+	}
+	return false
+}
+
+// expands reports whether the shell would substitute part of an argument,
+// through $VAR, ${VAR}, $(...) or backticks
+func expands(arg string) bool {
+	return strings.ContainsAny(arg, "$`")
+}
+
+// hasParentRef reports whether a path has a ".." element
+func hasParentRef(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath resolves target against the current directory
+func resolvePath(current, target string) string {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(current, target)
+	}
+	return filepath.Clean(target)
+}
+
+// outside reports whether path is outside root
+func outside(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
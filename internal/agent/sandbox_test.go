@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscapesSandbox(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		command string
+		escapes bool
+	}{
+		{"go test ./...", false},
+		{"cd src && npm install", false},
+		{"cd src && cd .. && ls", false},
+		{"mkdir -p build; cd build", false},
+		{"cd /etc && ls", true},
+		{"cd .. && rm -rf project", true},
+		{"cd src/../.. && ls", true},
+		{"cd && ls", true},
+		{"cd ~/projects", true},
+		{"cd $HOME", true},
+		{"pushd /tmp", true},
+		{"echo cd src", false},
+		{"go build -o bin/app ./cmd/app 2>/dev/null", false},
+		{"cd src && cat ../go.mod", false},
+		{"rm -rf /home/user/.ssh", true},
+		{"cat ../secrets.env", true},
+		{"cd src && cp main.go ../../elsewhere", true},
+		{"echo key > ~/.bashrc", true},
+		{"echo key >/etc/profile", true},
+		{"npm install --prefix=/usr/local", true},
+		{"cat $HOME/.ssh/id_rsa", true},
+		{"cat ${HOME}/.aws/credentials", true},
+		{"cp `echo /etc/passwd` .", true},
+		{"cd `pwd`/..", true},
+		{"ls $(dirname /etc/hosts)", true},
+	}
+
+	for _, tt := range tests {
+		if got := escapesSandbox(tt.command, dir); got != tt.escapes {
+			t.Errorf("escapesSandbox(%q) = %v, want %v", tt.command, got, tt.escapes)
+		}
+	}
+}
+
+// TestSandboxedCommands tests that sandboxed commands run in the project
+// directory without the caller's credentials
+func TestSandboxedCommands(t *testing.T) {
+	t.Setenv("QUANTUMFLOW_TEST_SECRET", "hunter2")
+	dir := t.TempDir()
+
+	executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+	executor.SetSandbox(true)
+
+	response := "```bash\nenv > env.txt\ncd /etc && touch escaped.txt\n```\n"
+	executed, err := executor.processCommandBlocks(context.Background(), &scriptedAgent{}, response, dir, newToolBudget(nil))
+	if err != nil {
+		t.Fatalf("processCommandBlocks failed: %v", err)
+	}
+	if len(executed) != 1 || executed[0].Err != nil {
+		t.Fatalf("Expected only the in-project command to run, got %v", executed)
+	}
+
+	output, err := os.ReadFile(filepath.Join(dir, "env.txt"))
+	if err != nil {
+		t.Fatalf("Expected command to run in the project directory: %v", err)
+	}
+	if strings.Contains(string(output), "hunter2") {
+		t.Error("Expected the sandboxed environment to drop unlisted variables")
+	}
+}
@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxAuditFileSize is the largest file the audit reads
+const maxAuditFileSize = 1 << 20
+
+// maxReportedAuditFindings bounds the findings listed in an audit report
+const maxReportedAuditFindings = 100
+
+// auditSkipDirs are dependency, build and VCS directories the audit doesn't enter
+var auditSkipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "venv": true, ".venv": true,
+	"__pycache__": true, "dist": true, "build": true, ".next": true, "target": true,
+}
+
+// AuditFinding is one issue found in a project's files
+type AuditFinding struct {
+	Rule     string
+	Category string // OWASP Top 10 (2021) category
+	Severity string
+	File     string
+	Line     int
+	Message  string
+}
+
+// auditRule matches one insecure pattern on a single line
+type auditRule struct {
+	name     string
+	category string
+	severity string
+	pattern  *regexp.Regexp
+	message  string
+	exts     map[string]bool // Extensions the rule applies to; nil means all
+	secret   bool            // The match is a credential and is redacted
+}
+
+const (
+	owaspCrypto         = "A02 Cryptographic Failures"
+	owaspInjection      = "A03 Injection"
+	owaspMisconfig      = "A05 Security Misconfiguration"
+	owaspVulnComponents = "A06 Vulnerable and Outdated Components"
+	owaspAuth           = "A07 Identification and Authentication Failures"
+)
+
+var (
+	scriptExts = map[string]bool{".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".mjs": true, ".cjs": true, ".py": true, ".php": true, ".rb": true}
+	codeExts   = map[string]bool{".go": true, ".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".mjs": true, ".cjs": true, ".py": true, ".php": true, ".rb": true, ".java": true, ".cs": true}
+)
+
+// auditRules are checked against every line of every file the audit reads
+var auditRules = []auditRule{
+	{name: "aws-access-key", category: owaspAuth, severity: "critical", secret: true,
+		pattern: regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), message: "hardcoded AWS access key"},
+	{name: "github-token", category: owaspAuth, severity: "critical", secret: true,
+		pattern: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), message: "hardcoded GitHub token"},
+	{name: "slack-token", category: owaspAuth, severity: "critical", secret: true,
+		pattern: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), message: "hardcoded Slack token"},
+	{name: "api-secret-key", category: owaspAuth, severity: "critical", secret: true,
+		pattern: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`), message: "hardcoded API secret key"},
+	{name: "private-key", category: owaspAuth, severity: "critical",
+		pattern: regexp.MustCompile(`-----BEGIN (RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`), message: "private key committed to the project"},
+	{name: "hardcoded-credential", category: owaspAuth, severity: "high", secret: true,
+		pattern: regexp.MustCompile(`(?i)\b[a-z_]*(api[_-]?key|secret|token|passw(or)?d)[a-z_]*["']?\s*[:=]\s*["'][^"'\s]{8,}["']`),
+		message: "hardcoded credential"},
+	{name: "eval", category: owaspInjection, severity: "high", exts: scriptExts,
+		pattern: regexp.MustCompile(`(^|[^.\w])eval\s*\(`), message: "eval of dynamic code"},
+	{name: "weak-hash", category: owaspCrypto, severity: "medium", exts: codeExts,
+		pattern: regexp.MustCompile(`(?i)\bmd5\.(New|Sum)\b|hashlib\.(md5|sha1)\b|createHash\(\s*["'](md5|sha1)["']|\bsha1\.(New|Sum)\b|MessageDigest\.getInstance\(\s*"(MD5|SHA-1)"`),
+		message: "weak hash (MD5/SHA-1); use SHA-256 or a password hash like bcrypt"},
+	{name: "insecure-http", category: owaspCrypto, severity: "medium", exts: codeExts,
+		pattern: regexp.MustCompile(`http://[^\s"'<>()/]+`), message: "plain http:// endpoint; use https://"},
+	{name: "tls-verify-disabled", category: owaspCrypto, severity: "high", exts: codeExts,
+		pattern: regexp.MustCompile(`InsecureSkipVerify:\s*true|verify\s*=\s*False|rejectUnauthorized:\s*false`), message: "TLS certificate verification disabled"},
+}
+
+// localHostPattern matches http:// endpoints that are fine unencrypted
+var localHostPattern = regexp.MustCompile(`^http://(localhost|127\.0\.0\.1|0\.0\.0\.0|\[::1\]|[^/:]*\.(local|test|example)|example\.(com|org|net)|www\.w3\.org)([:/]|$)`)
+
+// placeholderValue matches credential assignments whose value is a template
+// or example rather than a real secret
+var placeholderValue = regexp.MustCompile(`(?i)[:=]\s*["'](\$\{|\{\{|<|%\(|your[_-]|changeme|xxxx|example)`)
+
+// securityHeaders are the headers a web server config is expected to set
+var securityHeaders = []string{"Strict-Transport-Security", "X-Content-Type-Options", "X-Frame-Options", "Content-Security-Policy"}
+
+// auditProject walks dir for hardcoded secrets, insecure code patterns and
+// web server configs missing security headers
+func auditProject(ctx context.Context, dir string) ([]AuditFinding, error) {
+	var findings []AuditFinding
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && auditSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || binaryExtension(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxAuditFileSize {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		fileFindings, err := auditFile(path, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		findings = append(findings, fileFindings...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("audit of %s failed: %w", dir, err)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityOrder[findings[i].Severity] < severityOrder[findings[j].Severity]
+	})
+	return findings, nil
+}
+
+// auditFile checks one file line by line; name is how findings refer to it
+func auditFile(path, name string) ([]AuditFinding, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(path))
+	var findings []AuditFinding
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxAuditFileSize)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if strings.IndexByte(line, 0) >= 0 {
+			return nil, nil // Binary file
+		}
+		lines = append(lines, line)
+		for _, rule := range auditRules {
+			if rule.exts != nil && !rule.exts[ext] {
+				continue
+			}
+			if finding, ok := matchRule(rule, line); ok {
+				finding.File, finding.Line = name, lineNum
+				findings = append(findings, finding)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	if ext == ".conf" || filepath.Base(path) == "nginx.conf" {
+		findings = append(findings, auditServerConfig(lines, name)...)
+	}
+	return findings, nil
+}
+
+// matchRule reports a rule's first match on a line
+func matchRule(rule auditRule, line string) (AuditFinding, bool) {
+	for _, match := range rule.pattern.FindAllString(line, -1) {
+		if rule.name == "insecure-http" && localHostPattern.MatchString(match) {
+			continue
+		}
+		if rule.name == "hardcoded-credential" && placeholderValue.MatchString(match) {
+			continue
+		}
+		message := rule.message
+		if rule.secret {
+			message += " (" + redact(match) + ")"
+		} else if rule.name == "insecure-http" {
+			message += " (" + match + ")"
+		}
+		return AuditFinding{Rule: rule.name, Category: rule.category, Severity: rule.severity, Message: message}, true
+	}
+	return AuditFinding{}, false
+}
+
+// auditServerConfig reports nginx server blocks that set none of some
+// security headers; the finding points at the block's opening line
+func auditServerConfig(lines []string, name string) []AuditFinding {
+	var findings []AuditFinding
+	for i, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "server {") && strings.TrimSpace(line) != "server{" {
+			continue
+		}
+
+		// The block runs to its matching brace
+		depth, end := 0, len(lines)
+		for j := i; j < len(lines); j++ {
+			depth += strings.Count(lines[j], "{") - strings.Count(lines[j], "}")
+			if depth == 0 {
+				end = j + 1
+				break
+			}
+		}
+		block := strings.ToLower(strings.Join(lines[i:end], "\n"))
+
+		var missing []string
+		for _, header := range securityHeaders {
+			if !strings.Contains(block, strings.ToLower(header)) {
+				missing = append(missing, header)
+			}
+		}
+		if len(missing) > 0 {
+			findings = append(findings, AuditFinding{
+				Rule: "missing-security-headers", Category: owaspMisconfig, Severity: "medium",
+				File: name, Line: i + 1,
+				Message: "server block doesn't set " + strings.Join(missing, ", "),
+			})
+		}
+	}
+	return findings
+}
+
+// redact keeps enough of a secret to find it, and hides the rest
+func redact(secret string) string {
+	if key, value, ok := strings.Cut(secret, "="); ok {
+		return strings.TrimSpace(key) + "=" + redact(strings.Trim(strings.TrimSpace(value), `"'`))
+	}
+	if key, value, ok := strings.Cut(secret, ":"); ok {
+		return strings.TrimSpace(key) + ": " + redact(strings.Trim(strings.TrimSpace(value), `"'`))
+	}
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:4] + "****"
+}
+
+// binaryExtension reports whether a path's extension marks a binary file
+func binaryExtension(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".ico", ".webp", ".pdf", ".zip", ".gz", ".tar",
+		".exe", ".dll", ".so", ".dylib", ".woff", ".woff2", ".ttf", ".db", ".sqlite", ".pyc", ".class", ".jar":
+		return true
+	}
+	return false
+}
+
+// formatAuditFindings lists findings as file:line references, optionally
+// grouped under their OWASP category
+func formatAuditFindings(findings []AuditFinding, byCategory bool) string {
+	if byCategory {
+		sort.SliceStable(findings, func(i, j int) bool { return findings[i].Category < findings[j].Category })
+	}
+
+	var report strings.Builder
+	category := ""
+	for i, f := range findings {
+		if i == maxReportedAuditFindings {
+			report.WriteString(fmt.Sprintf("... and %d more\n", len(findings)-i))
+			break
+		}
+		if byCategory && f.Category != category {
+			category = f.Category
+			report.WriteString(category + ":\n")
+		}
+		report.WriteString(fmt.Sprintf("- [%s] %s:%d %s\n", f.Severity, f.File, f.Line, f.Message))
+	}
+	return strings.TrimRight(report.String(), "\n")
+}
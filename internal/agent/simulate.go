@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// Simulation is what Execute would do with a request, short of generating
+type Simulation struct {
+	Routing  *RoutingTrace
+	Memories []*models.Memory
+	Prompts  []SimulatedPrompt // One per selected agent, in execution order
+}
+
+// SimulatedPrompt is the prompt an agent would be sent
+type SimulatedPrompt struct {
+	AgentName string
+	AgentType models.AgentType
+	Prompt    string
+}
+
+// Simulate routes a request and builds each selected agent's prompt without
+// calling generation. Memory retrieval and classification still run, since
+// both shape the prompt. The request itself is not modified.
+func (o *AgentOrchestrator) Simulate(ctx context.Context, request *Request) (*Simulation, error) {
+	req := *request
+
+	agents, confidences, err := o.route(ctx, req.Query)
+	if err != nil {
+		return nil, fmt.Errorf("routing failed: %w", err)
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agents available to handle query")
+	}
+	o.retrieveMemories(ctx, &req, agents)
+
+	sim := &Simulation{
+		Routing:  o.traceRouting(req.Query, agents, confidences[0]),
+		Memories: req.Memories,
+	}
+	for i, agent := range agents {
+		sim.Prompts = append(sim.Prompts, SimulatedPrompt{
+			AgentName: agent.Name(),
+			AgentType: agent.Type(),
+			Prompt:    agent.BuildPrompt(agentRequest(agent, &req, confidences[i])),
+		})
+	}
+
+	return sim, nil
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// fixedClassifier routes every query to one agent type
+type fixedClassifier struct {
+	agentType  models.AgentType
+	confidence float64
+}
+
+func (c *fixedClassifier) Classify(ctx context.Context, query string) (models.AgentType, float64, error) {
+	return c.agentType, c.confidence, nil
+}
+
+func (c *fixedClassifier) ClassifyMulti(ctx context.Context, query string, k int) ([]Classification, error) {
+	return []Classification{{AgentType: c.agentType, Confidence: c.confidence}}, nil
+}
+
+// TestSimulate tests that Simulate reports routing and the agent prompt without modifying the request
+func TestSimulate(t *testing.T) {
+	orchestrator := NewAgentOrchestrator(nil, nil, nil)
+	orchestrator.classifier = &fixedClassifier{agentType: models.AgentTypeCode, confidence: 0.9}
+	orchestrator.RegisterAgent(NewCodeAgent(nil, nil))
+	orchestrator.RegisterAgent(NewDataAgent(nil, nil))
+
+	request := &Request{
+		Query:    "Refactor the parser",
+		Context:  &Context{GitBranch: "feature/parser"},
+		Memories: []*models.Memory{{Content: "Parser lives in internal/parse"}},
+	}
+
+	sim, err := orchestrator.Simulate(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+
+	if sim.Routing.PrimaryAgent != models.AgentTypeCode || sim.Routing.Confidence != 0.9 {
+		t.Errorf("Unexpected routing: %+v", sim.Routing)
+	}
+	if len(sim.Prompts) != 1 || sim.Prompts[0].AgentType != models.AgentTypeCode {
+		t.Fatalf("Expected one code agent prompt, got %+v", sim.Prompts)
+	}
+
+	prompt := sim.Prompts[0].Prompt
+	for _, want := range []string{"Git Branch: feature/parser", "Parser lives in internal/parse", "Query: Refactor the parser"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if request.RoutingConfidence != 0 {
+		t.Error("Expected Simulate to leave the request unmodified")
+	}
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidGoSyntax is returned when generated Go files still don't parse
+// after the agent was asked to regenerate them
+var ErrInvalidGoSyntax = errors.New("generated Go code does not parse")
+
+// defaultRegenerateAttempts is how often an agent is asked to fix Go files
+// that fail to parse before the task fails
+const defaultRegenerateAttempts = 1
+
+// parseGoFiles runs generated .go files, relative to baseDir, through the AST
+// parser tool and returns the parse error for each file that fails
+func parseGoFiles(ctx context.Context, baseDir string, files []string) map[string]error {
+	parser := &ASTParserTool{}
+	invalid := make(map[string]error)
+	for _, path := range files {
+		if !strings.HasSuffix(path, ".go") {
+			continue
+		}
+		source, err := os.ReadFile(filepath.Join(baseDir, path))
+		if err != nil {
+			invalid[path] = err
+			continue
+		}
+		if _, err := parser.Execute(ctx, map[string]interface{}{"code": string(source)}); err != nil {
+			invalid[path] = err
+		}
+	}
+	return invalid
+}
+
+// validateGoFiles checks that the Go files a phase wrote parse, asking the
+// agent to regenerate broken files. Files that still don't parse are dropped
+// from the manifest, so re-running the phase can rewrite them.
+func (e *Executor) validateGoFiles(ctx context.Context, plan *ExecutionPlan, phase *Phase, agent Agent, files []string, output *outputBudget) error {
+	baseDir := e.projectDir(plan)
+	invalid := parseGoFiles(ctx, baseDir, files)
+
+	for attempt := 1; len(invalid) > 0 && attempt <= e.regenerateAttempts; attempt++ {
+		fmt.Printf("🔁 %d Go file(s) failed to parse; asking %s to regenerate (attempt %d/%d)\n",
+			len(invalid), agent.Name(), attempt, e.regenerateAttempts)
+		e.forgetFiles(plan, invalid)
+
+		request := &Request{
+			ID:      fmt.Sprintf("%s-%s-fix%d", plan.ID, phase.ID, attempt),
+			Query:   buildRegenerateQuery(baseDir, invalid),
+			Context: &Context{Constraints: e.constraints},
+			Timeout: 10 * time.Minute,
+		}
+		response, err := agent.Execute(ctx, request)
+		if err != nil {
+			return err
+		}
+		rewritten, err := e.processFileBlocks(response.Answer, plan, phase.Name, output)
+		if err != nil && isBudgetError(err) {
+			return err
+		}
+
+		// Files the agent didn't rewrite are still broken
+		retry := parseGoFiles(ctx, baseDir, rewritten)
+		for path, parseErr := range invalid {
+			if !slices.Contains(rewritten, path) {
+				retry[path] = parseErr
+			}
+		}
+		invalid = retry
+	}
+
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	e.forgetFiles(plan, invalid)
+	paths := make([]string, 0, len(invalid))
+	for path, err := range invalid {
+		paths = append(paths, fmt.Sprintf("%s (%v)", path, err))
+	}
+	sort.Strings(paths)
+	e.logger.Warn("generated Go files do not parse", "plan", plan.ID, "phase", phase.ID, "files", len(invalid))
+	return fmt.Errorf("%w: %s", ErrInvalidGoSyntax, strings.Join(paths, "; "))
+}
+
+// forgetFiles removes files from the manifest so they can be written again
+func (e *Executor) forgetFiles(plan *ExecutionPlan, files map[string]error) {
+	if plan.Manifest == nil {
+		return
+	}
+	for path := range files {
+		plan.Manifest.RemoveFile(path)
+	}
+}
+
+// buildRegenerateQuery asks for corrected versions of files that failed to parse
+func buildRegenerateQuery(baseDir string, invalid map[string]error) string {
+	paths := make([]string, 0, len(invalid))
+	for path := range invalid {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var query strings.Builder
+	query.WriteString("These Go files you generated do not parse:\n\n")
+	for _, path := range paths {
+		source, _ := os.ReadFile(filepath.Join(baseDir, path))
+		query.WriteString(fmt.Sprintf("%s: %v\n```go %s\n%s\n```\n\n", path, invalid[path], path, strings.TrimRight(string(source), "\n")))
+	}
+	query.WriteString("Output the complete corrected version of each file, one code block per file, " +
+		"with the file path on the same line as the language (```go path/to/file.go). " +
+		"Output only Go source inside each block, without nested markdown.\n")
+	return query.String()
+}
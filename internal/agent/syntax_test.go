@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// scriptedAgent answers each Execute call with the next canned response
+type scriptedAgent struct {
+	answers []string
+	queries []string
+}
+
+func (a *scriptedAgent) Name() string                        { return "ScriptedAgent" }
+func (a *scriptedAgent) Type() models.AgentType              { return models.AgentTypeCode }
+func (a *scriptedAgent) GetTools() []Tool                    { return nil }
+func (a *scriptedAgent) Config() *AgentConfig                { return &AgentConfig{} }
+func (a *scriptedAgent) BuildPrompt(request *Request) string { return request.Query }
+func (a *scriptedAgent) CanHandle(ctx context.Context, query string) (float64, error) {
+	return 1, nil
+}
+func (a *scriptedAgent) Execute(ctx context.Context, request *Request) (*Response, error) {
+	a.queries = append(a.queries, request.Query)
+	if len(a.answers) == 0 {
+		return &Response{}, nil
+	}
+	answer := a.answers[0]
+	a.answers = a.answers[1:]
+	return &Response{Answer: answer}, nil
+}
+
+// TestValidateGoFilesRegenerates tests that a Go file that fails to parse is
+// sent back to the agent and replaced by its corrected version
+func TestValidateGoFilesRegenerates(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &ExecutionPlan{ID: "plan_syntax", Manifest: NewProjectManifest("syntax", ".")}
+	plan.Manifest.AddFile("main.go", "Build", "")
+	phase := &Phase{ID: "phase-1", Name: "Build"}
+	agent := &scriptedAgent{answers: []string{"```go main.go\npackage main\n\nfunc main() {}\n```\n"}}
+
+	executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+	err := executor.validateGoFiles(context.Background(), plan, phase, agent, []string{"main.go"}, newOutputBudget(nil))
+	if err != nil {
+		t.Fatalf("Expected regenerated file to pass, got %v", err)
+	}
+
+	if len(agent.queries) != 1 || !strings.Contains(agent.queries[0], "main.go") {
+		t.Errorf("Expected one regeneration request naming main.go, got %q", agent.queries)
+	}
+	source, _ := os.ReadFile("main.go")
+	if strings.TrimSpace(string(source)) != "package main\n\nfunc main() {}" {
+		t.Errorf("Expected corrected file, got %q", source)
+	}
+	if !plan.Manifest.FileExists("main.go") {
+		t.Error("Expected regenerated file to be tracked in the manifest")
+	}
+}
+
+// TestValidateGoFilesFails tests that files still broken after regeneration
+// fail the task and are dropped from the manifest
+func TestValidateGoFilesFails(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main( {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &ExecutionPlan{ID: "plan_syntax", Manifest: NewProjectManifest("syntax", ".")}
+	plan.Manifest.AddFile("main.go", "Build", "")
+	agent := &scriptedAgent{answers: []string{"Sorry, I can't fix that."}}
+
+	executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+	err := executor.validateGoFiles(context.Background(), plan, &Phase{ID: "phase-1", Name: "Build"}, agent, []string{"main.go", "README.md"}, newOutputBudget(nil))
+	if !errors.Is(err, ErrInvalidGoSyntax) || !strings.Contains(err.Error(), "main.go") {
+		t.Fatalf("Expected ErrInvalidGoSyntax naming main.go, got %v", err)
+	}
+	if plan.Manifest.FileExists("main.go") {
+		t.Error("Expected broken file to be dropped from the manifest")
+	}
+
+	executor.SetRegenerateAttempts(0)
+	agent.queries = nil
+	plan.Manifest.AddFile("main.go", "Build", "")
+	if err := executor.validateGoFiles(context.Background(), plan, &Phase{ID: "phase-1", Name: "Build"}, agent, []string{"main.go"}, newOutputBudget(nil)); err == nil || len(agent.queries) != 0 {
+		t.Errorf("Expected immediate failure without regeneration, got %v after %d requests", err, len(agent.queries))
+	}
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// ErrDuplicateTool is returned when a tool is added to an agent or registry
+// that already has a tool of the same name
+var ErrDuplicateTool = errors.New("duplicate tool")
+
+// ToolAdder is implemented by agents that accept tools beyond their built-in
+// set; every built-in agent does
+type ToolAdder interface {
+	AddTool(tool Tool) error
+}
+
+// ToolRegistry collects custom tools for agents, keyed by agent type, so
+// code outside this package can extend agents without editing their
+// constructors. Tools registered for the empty agent type go to every agent.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[models.AgentType][]Tool
+}
+
+// NewToolRegistry creates an empty tool registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[models.AgentType][]Tool)}
+}
+
+// Register adds a tool for agents of agentType, or for every agent when
+// agentType is empty
+func (r *ToolRegistry) Register(agentType models.AgentType, tool Tool) error {
+	if tool == nil {
+		return fmt.Errorf("cannot register nil tool")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tools, err := addTool(r.tools[agentType], tool)
+	if err != nil {
+		return err
+	}
+	r.tools[agentType] = tools
+	return nil
+}
+
+// Tools returns the tools registered for agentType, including those
+// registered for every agent
+func (r *ToolRegistry) Tools(agentType models.AgentType) []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := append([]Tool{}, r.tools[""]...)
+	if agentType != "" {
+		tools = append(tools, r.tools[agentType]...)
+	}
+	return tools
+}
+
+// Install adds the registered tools to each agent that accepts them
+func (r *ToolRegistry) Install(agents ...Agent) error {
+	for _, agent := range agents {
+		adder, ok := agent.(ToolAdder)
+		if !ok {
+			continue
+		}
+		for _, tool := range r.Tools(agent.Type()) {
+			if err := adder.AddTool(tool); err != nil {
+				return fmt.Errorf("failed to add tool to %s: %w", agent.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// addTool appends tool unless one of the same name is already in tools
+func addTool(tools []Tool, tool Tool) ([]Tool, error) {
+	for _, existing := range tools {
+		if existing.Name() == tool.Name() {
+			return tools, fmt.Errorf("%w: %s", ErrDuplicateTool, tool.Name())
+		}
+	}
+	return append(tools, tool), nil
+}
+
+// configuredTools returns a copy of config.Tools when set, otherwise defaults
+func configuredTools(config *AgentConfig, defaults []Tool) []Tool {
+	if config.Tools != nil {
+		return append([]Tool{}, config.Tools...)
+	}
+	return defaults
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// namedTool is a custom tool with a fixed name
+type namedTool struct {
+	name string
+}
+
+func (t *namedTool) Name() string           { return t.name }
+func (t *namedTool) Description() string    { return "custom " + t.name }
+func (t *namedTool) IsDestructive() bool    { return false }
+func (t *namedTool) RequiresApproval() bool { return false }
+func (t *namedTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return t.name + " ran", nil
+}
+
+// toolNames lists an agent's tool names in order
+func toolNames(agent Agent) []string {
+	var names []string
+	for _, tool := range agent.GetTools() {
+		names = append(names, tool.Name())
+	}
+	return names
+}
+
+// TestToolRegistryInstall tests that registered tools reach the agents of
+// their type, and all agents for the empty type, and can be executed
+func TestToolRegistryInstall(t *testing.T) {
+	registry := NewToolRegistry()
+	if err := registry.Register("", &namedTool{name: "ticket"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(models.AgentTypeInfra, &namedTool{name: "helm"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(models.AgentTypeInfra, &namedTool{name: "helm"}); !errors.Is(err, ErrDuplicateTool) {
+		t.Errorf("Expected a duplicate registration to fail, got %v", err)
+	}
+
+	infra := NewInfraAgent(nil, nil)
+	code := NewCodeAgent(nil, nil)
+	if err := registry.Install(infra, code); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	if got := toolNames(infra); len(got) != 5 || got[3] != "ticket" || got[4] != "helm" {
+		t.Errorf("Expected the built-in infra tools plus ticket and helm, got %v", got)
+	}
+	if got := toolNames(code); len(got) != 5 || got[4] != "ticket" {
+		t.Errorf("Expected the built-in code tools plus ticket, got %v", got)
+	}
+
+	result, err := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil).ExecuteTool(context.Background(), infra, &models.ToolCall{Name: "helm"})
+	if err != nil || result != "helm ran" {
+		t.Errorf("Expected the custom tool to run, got %q, %v", result, err)
+	}
+
+	if err := code.AddTool(&namedTool{name: "lint"}); !errors.Is(err, ErrDuplicateTool) {
+		t.Errorf("Expected a tool clashing with a built-in to be rejected, got %v", err)
+	}
+}
+
+// TestAgentConfigTools tests that AgentConfig.Tools replaces the built-in tools
+func TestAgentConfigTools(t *testing.T) {
+	config := &AgentConfig{Name: "DataAgent", Type: models.AgentTypeData, Tools: []Tool{&namedTool{name: "warehouse"}, &DataAnalysisTool{}}}
+	data := NewDataAgent(nil, config)
+	if got := toolNames(data); len(got) != 2 || got[0] != "warehouse" {
+		t.Fatalf("Expected the configured tools, got %v", got)
+	}
+
+	// Connecting a database keeps custom tools
+	data.SetDatabase(&DatabaseTool{})
+	if got := toolNames(data); len(got) != 3 || got[0] != "warehouse" || got[2] != "database" {
+		t.Errorf("Expected the database tool appended to the configured tools, got %v", got)
+	}
+	if len(config.Tools) != 2 {
+		t.Errorf("Expected the config's tool list to be left alone, got %d tools", len(config.Tools))
+	}
+}
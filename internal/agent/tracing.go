@@ -0,0 +1,13 @@
+package agent
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer returns the orchestrator, agent and plan tracer from the global
+// OpenTelemetry provider, which is a no-op until a program installs one. It
+// is looked up per span so a provider installed (or replaced) later takes effect.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/quantumflow/quantumflow/internal/agent")
+}
@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrChecksFailed is returned when a phase's tests fail in strict verify mode
+var ErrChecksFailed = errors.New("phase checks failed")
+
+// defaultCheckTimeout bounds each test or lint run
+const defaultCheckTimeout = 5 * time.Minute
+
+// maxCheckOutput is how much of a check's output is kept, from the end,
+// since test runners print failures last
+const maxCheckOutput = 4000
+
+// VerifyMode controls the test and lint gate run after a phase writes files
+type VerifyMode string
+
+const (
+	VerifyOff    VerifyMode = "off"
+	VerifyWarn   VerifyMode = "warn"   // Record failures and feed them to the next phase
+	VerifyStrict VerifyMode = "strict" // Also fail the phase when tests fail
+)
+
+// ParseVerifyMode validates a verify mode name
+func ParseVerifyMode(mode string) (VerifyMode, error) {
+	switch VerifyMode(mode) {
+	case VerifyOff, VerifyWarn, VerifyStrict:
+		return VerifyMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid verify mode %q (want off, warn or strict)", mode)
+}
+
+// CheckResult is the outcome of one test or lint command
+type CheckResult struct {
+	Command string `json:"command"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"` // The tool isn't installed
+	Output  string `json:"output,omitempty"`
+}
+
+// PhaseChecks records the test and lint gate for a phase
+type PhaseChecks struct {
+	Stack string       `json:"stack"`
+	Dir   string       `json:"dir"`
+	Tests *CheckResult `json:"tests,omitempty"`
+	Lint  *CheckResult `json:"lint,omitempty"`
+}
+
+// Passed reports whether every check that ran passed
+func (c *PhaseChecks) Passed() bool {
+	return c.TestsPassed() && (c.Lint == nil || c.Lint.Passed || c.Lint.Skipped)
+}
+
+// TestsPassed reports whether the tests passed or could not be run
+func (c *PhaseChecks) TestsPassed() bool {
+	return c.Tests == nil || c.Tests.Passed || c.Tests.Skipped
+}
+
+// projectStack is how to test and lint one kind of project
+type projectStack struct {
+	name     string
+	manifest string // File whose presence identifies the stack
+	test     []string
+	lint     []string
+}
+
+// projectStacks are checked in order; the first manifest found wins
+var projectStacks = []projectStack{
+	{name: "go", manifest: "go.mod", test: []string{"go", "test", "./..."}, lint: []string{"go", "vet", "./..."}},
+	{name: "node", manifest: "package.json", test: []string{"npm", "test", "--silent"}, lint: []string{"npm", "run", "lint", "--if-present"}},
+	{name: "python", manifest: "requirements.txt", test: []string{"pytest", "-q"}, lint: []string{"ruff", "check", "."}},
+	{name: "python", manifest: "pyproject.toml", test: []string{"pytest", "-q"}, lint: []string{"ruff", "check", "."}},
+}
+
+// detectStack identifies a project's stack from the manifest files in dir
+func detectStack(dir string) (*projectStack, bool) {
+	for i := range projectStacks {
+		if _, err := os.Stat(filepath.Join(dir, projectStacks[i].manifest)); err == nil {
+			return &projectStacks[i], true
+		}
+	}
+	return nil, false
+}
+
+// runChecks runs the tests and linter of the project in dir with env (nil
+// inherits ours). ok is false when no supported stack is detected.
+func runChecks(ctx context.Context, dir string, env []string, timeout time.Duration) (*PhaseChecks, bool) {
+	stack, ok := detectStack(dir)
+	if !ok {
+		return nil, false
+	}
+
+	return &PhaseChecks{
+		Stack: stack.name,
+		Dir:   dir,
+		Tests: runCheck(ctx, dir, stack.test, env, timeout),
+		Lint:  runCheck(ctx, dir, stack.lint, env, timeout),
+	}, true
+}
+
+// runCheck runs one command in dir the way generated commands run, capturing
+// the tail of its output
+func runCheck(ctx context.Context, dir string, args, env []string, timeout time.Duration) *CheckResult {
+	result := &CheckResult{Command: strings.Join(args, " ")}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		result.Skipped = true
+		result.Output = fmt.Sprintf("%s not installed", args[0])
+		return result
+	}
+
+	run := runProcess(ctx, args, dir, env, timeout, false)
+	result.Passed = run.Err == nil
+	result.Output = tail(run.Output, maxCheckOutput)
+	if run.TimedOut {
+		result.Output = strings.TrimSpace(result.Output + fmt.Sprintf("\n(timed out after %s)", timeout))
+	}
+	return result
+}
+
+// tail keeps the last n bytes of s
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "..." + s[len(s)-n:]
+}
+
+// verifyPhase runs the project's tests and linter after a phase that wrote
+// files. Results are recorded on the phase; in strict mode failing tests fail
+// the phase.
+func (e *Executor) verifyPhase(ctx context.Context, plan *ExecutionPlan, phase *Phase) error {
+	if e.verify == "" || e.verify == VerifyOff || (e.constraints != nil && e.constraints.DryRun) {
+		return nil
+	}
+	if !phaseWroteFiles(plan, phase) {
+		return nil
+	}
+
+	dir := filepath.Join(e.projectDir(plan), projectRootDir(plan.FileStructure))
+	var env []string
+	if e.sandbox {
+		env = sandboxEnv(dir)
+	}
+	checks, ok := runChecks(ctx, dir, env, defaultCheckTimeout)
+	if !ok {
+		return nil
+	}
+	phase.Checks = checks
+
+	fmt.Printf("\n🧪 Checks (%s in %s):\n", checks.Stack, checks.Dir)
+	for _, check := range []*CheckResult{checks.Tests, checks.Lint} {
+		switch {
+		case check.Skipped:
+			fmt.Printf("  ⏭  %s (%s)\n", check.Command, check.Output)
+		case check.Passed:
+			fmt.Printf("  ✅ %s\n", check.Command)
+		default:
+			fmt.Printf("  ❌ %s\n%s\n", check.Command, indent(tail(check.Output, 800), "     "))
+		}
+	}
+	e.saveState(plan)
+
+	if checks.Passed() {
+		return nil
+	}
+	e.logger.Warn("phase checks failed", "plan", plan.ID, "phase", phase.ID,
+		"tests_passed", checks.TestsPassed(), "stack", checks.Stack)
+	if e.verify == VerifyStrict && !checks.TestsPassed() {
+		return fmt.Errorf("%w: %s", ErrChecksFailed, checks.Tests.Command)
+	}
+	return nil
+}
+
+// phaseWroteFiles reports whether the manifest records files from the phase
+func phaseWroteFiles(plan *ExecutionPlan, phase *Phase) bool {
+	if plan.Manifest == nil {
+		return false
+	}
+	for _, f := range plan.Manifest.CreatedFiles {
+		if f.Phase == phase.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// previousCheckFailures describes failing checks from the phase before this
+// one, so the agent can fix them
+func previousCheckFailures(plan *ExecutionPlan, phase *Phase) string {
+	var previous *Phase
+	for i := range plan.Phases {
+		if &plan.Phases[i] == phase {
+			break
+		}
+		if plan.Phases[i].Checks != nil {
+			previous = &plan.Phases[i]
+		}
+	}
+	if previous == nil || previous.Checks.Passed() {
+		return ""
+	}
+
+	var failures strings.Builder
+	failures.WriteString(fmt.Sprintf("CHECKS FAILING AFTER PHASE %q (fix these as part of this task):\n", previous.Name))
+	for _, check := range []*CheckResult{previous.Checks.Tests, previous.Checks.Lint} {
+		if check != nil && !check.Passed && !check.Skipped {
+			failures.WriteString(fmt.Sprintf("$ %s\n%s\n", check.Command, tail(check.Output, 1500)))
+		}
+	}
+	return failures.String()
+}
+
+// indent prefixes every line of s
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDetectStack tests stack detection from project manifest files
+func TestDetectStack(t *testing.T) {
+	tests := map[string]string{
+		"go.mod":           "go",
+		"package.json":     "node",
+		"requirements.txt": "python",
+		"pyproject.toml":   "python",
+	}
+	for manifest, want := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, manifest), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		stack, ok := detectStack(dir)
+		if !ok || stack.name != want {
+			t.Errorf("%s: expected stack %s, got %v", manifest, want, stack)
+		}
+	}
+
+	if _, ok := detectStack(t.TempDir()); ok {
+		t.Error("Expected no stack for an empty directory")
+	}
+}
+
+// TestVerifyPhaseFeedsFailuresForward tests that a failing Go test is recorded
+// on the phase, fails it in strict mode and is included in the next phase's query
+func TestVerifyPhaseFeedsFailuresForward(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	t.Chdir(t.TempDir())
+	dir := "calc"
+	files := map[string]string{
+		"go.mod":       "module calc\n\ngo 1.21\n",
+		"calc.go":      "package calc\n\nfunc Add(a, b int) int { return a - b }\n",
+		"calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(2, 2) != 4 {\n\t\tt.Fatal(\"Add(2, 2) != 4\")\n\t}\n}\n",
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan := &ExecutionPlan{
+		ID:            "plan_verify",
+		FileStructure: map[string][]string{dir + "/": {"calc.go"}},
+		Phases:        []Phase{{ID: "phase-1", Name: "Build"}, {ID: "phase-2", Name: "Fix"}},
+		Manifest:      NewProjectManifest("calc", "."),
+	}
+	plan.Manifest.AddFile(filepath.Join(dir, "calc.go"), "Build", "")
+
+	executor := NewExecutor(NewAgentOrchestrator(nil, nil, nil), nil)
+	executor.SetVerifyMode(VerifyStrict)
+
+	err := executor.verifyPhase(context.Background(), plan, &plan.Phases[0])
+	if err == nil {
+		t.Fatal("Expected strict mode to fail the phase")
+	}
+	checks := plan.Phases[0].Checks
+	if checks == nil || checks.Stack != "go" || checks.TestsPassed() {
+		t.Fatalf("Expected failing go tests to be recorded, got %+v", checks)
+	}
+
+	query := executor.buildPhaseQuery(plan, &plan.Phases[1], []*Task{{Description: "Fix Add"}})
+	if !strings.Contains(query, "Add(2, 2) != 4") {
+		t.Errorf("Expected next phase query to include the test failure:\n%s", query)
+	}
+}
@@ -0,0 +1,324 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// maxReportedFindings bounds the findings listed in a scan summary
+const maxReportedFindings = 50
+
+// placeholderScan is returned, labeled, when no scanner can run
+const placeholderScan = "Vulnerability scan complete: 0 critical, 2 medium, 5 low"
+
+// severityOrder ranks severities for sorting and summaries
+var severityOrder = map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3, "info": 4, "unknown": 5}
+
+// Finding is one vulnerable dependency reported by a scanner
+type Finding struct {
+	ID       string // Advisory ID or URL, e.g. GO-2024-2687 or GHSA-...
+	Package  string
+	Version  string // Installed version, or the affected range when unknown
+	FixedIn  string
+	Severity string // critical, high, medium, low, info or unknown
+	Summary  string
+}
+
+// ScanReport is the parsed output of one scanner run
+type ScanReport struct {
+	Scanner  string
+	Dir      string
+	Findings []Finding
+}
+
+// vulnScanner runs one ecosystem's scanner and parses its JSON output
+type vulnScanner struct {
+	name  string
+	args  func(manifest string) []string
+	parse func(output []byte) ([]Finding, error)
+}
+
+// vulnScanners are keyed by projectStack name
+var vulnScanners = map[string]vulnScanner{
+	"go": {
+		name:  "govulncheck",
+		args:  func(string) []string { return []string{"govulncheck", "-json", "./..."} },
+		parse: parseGovulncheck,
+	},
+	"node": {
+		name:  "npm audit",
+		args:  func(string) []string { return []string{"npm", "audit", "--json"} },
+		parse: parseNpmAudit,
+	},
+	"python": {
+		name: "pip-audit",
+		args: func(manifest string) []string {
+			if manifest == "requirements.txt" {
+				return []string{"pip-audit", "-f", "json", "-r", manifest}
+			}
+			return []string{"pip-audit", "-f", "json", "."}
+		},
+		parse: parsePipAudit,
+	},
+}
+
+// errNoScanner marks a project no scanner can check
+var errNoScanner = errors.New("no vulnerability scanner available")
+
+// scanDependencies runs the scanner for the project in dir. Scanners exit
+// non-zero when they find vulnerabilities, so their output is parsed
+// regardless and the exit status only matters when it isn't valid JSON.
+func scanDependencies(ctx context.Context, dir string) (*ScanReport, error) {
+	stack, ok := detectStack(dir)
+	if !ok {
+		return nil, fmt.Errorf("%w: no go.mod, package.json, requirements.txt or pyproject.toml in %s", errNoScanner, dir)
+	}
+	scanner := vulnScanners[stack.name]
+	args := scanner.args(stack.manifest)
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, fmt.Errorf("%w: %s is not installed", errNoScanner, args[0])
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, defaultCheckTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(cmdCtx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	findings, err := scanner.parse(stdout.Bytes())
+	if err != nil || (runErr != nil && len(bytes.TrimSpace(stdout.Bytes())) == 0) {
+		if runErr != nil {
+			return nil, fmt.Errorf("%s failed: %w\n%s", scanner.name, runErr, tail(strings.TrimSpace(stderr.String()), maxCheckOutput))
+		}
+		return nil, fmt.Errorf("failed to parse %s output: %w", scanner.name, err)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityOrder[findings[i].Severity] < severityOrder[findings[j].Severity]
+	})
+	return &ScanReport{Scanner: scanner.name, Dir: dir, Findings: findings}, nil
+}
+
+// Summary counts findings by severity, most severe first
+func (r *ScanReport) Summary() string {
+	counts := make(map[string]int)
+	for _, f := range r.Findings {
+		counts[f.Severity]++
+	}
+	severities := make([]string, 0, len(counts))
+	for severity := range counts {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool { return severityOrder[severities[i]] < severityOrder[severities[j]] })
+
+	parts := make([]string, len(severities))
+	for i, severity := range severities {
+		parts[i] = fmt.Sprintf("%d %s", counts[severity], severity)
+	}
+	if len(parts) == 0 {
+		return "no known vulnerabilities"
+	}
+	return fmt.Sprintf("%d vulnerabilities (%s)", len(r.Findings), strings.Join(parts, ", "))
+}
+
+// Format describes the report for the agent: a summary line and one line per finding
+func (r *ScanReport) Format() string {
+	var report strings.Builder
+	report.WriteString(fmt.Sprintf("%s scan of %s: %s\n", r.Scanner, r.Dir, r.Summary()))
+	for i, f := range r.Findings {
+		if i == maxReportedFindings {
+			report.WriteString(fmt.Sprintf("... and %d more\n", len(r.Findings)-i))
+			break
+		}
+		line := fmt.Sprintf("- [%s] %s", f.Severity, f.Package)
+		if f.Version != "" {
+			line += "@" + f.Version
+		}
+		if f.ID != "" {
+			line += " " + f.ID
+		}
+		if f.FixedIn != "" {
+			line += " (fixed in " + f.FixedIn + ")"
+		}
+		if f.Summary != "" {
+			line += ": " + f.Summary
+		}
+		report.WriteString(line + "\n")
+	}
+	return strings.TrimRight(report.String(), "\n")
+}
+
+// parseGovulncheck reads govulncheck's stream of JSON messages. Only
+// vulnerabilities whose vulnerable symbols the code calls are reported,
+// matching govulncheck's own default output; it doesn't rate severity.
+func parseGovulncheck(output []byte) ([]Finding, error) {
+	type frame struct {
+		Module   string `json:"module"`
+		Version  string `json:"version"`
+		Package  string `json:"package"`
+		Function string `json:"function"`
+	}
+	type message struct {
+		OSV *struct {
+			ID      string `json:"id"`
+			Summary string `json:"summary"`
+		} `json:"osv"`
+		Finding *struct {
+			OSV          string  `json:"osv"`
+			FixedVersion string  `json:"fixed_version"`
+			Trace        []frame `json:"trace"`
+		} `json:"finding"`
+	}
+
+	summaries := make(map[string]string)
+	seen := make(map[string]bool)
+	var findings []Finding
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var msg message
+		if err := decoder.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if msg.OSV != nil {
+			summaries[msg.OSV.ID] = msg.OSV.Summary
+		}
+		f := msg.Finding
+		if f == nil || len(f.Trace) == 0 || f.Trace[0].Function == "" || seen[f.OSV] {
+			continue
+		}
+		seen[f.OSV] = true
+		findings = append(findings, Finding{
+			ID:       f.OSV,
+			Package:  f.Trace[0].Module,
+			Version:  f.Trace[0].Version,
+			FixedIn:  f.FixedVersion,
+			Severity: "unknown",
+		})
+	}
+
+	for i := range findings {
+		findings[i].Summary = summaries[findings[i].ID]
+	}
+	return findings, nil
+}
+
+// parseNpmAudit reads npm audit --json (npm 7+), one finding per vulnerable package
+func parseNpmAudit(output []byte) ([]Finding, error) {
+	var audit struct {
+		Vulnerabilities map[string]struct {
+			Name         string            `json:"name"`
+			Severity     string            `json:"severity"`
+			Range        string            `json:"range"`
+			Via          []json.RawMessage `json:"via"`
+			FixAvailable json.RawMessage   `json:"fixAvailable"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(output, &audit); err != nil {
+		return nil, err
+	}
+
+	findings := make([]Finding, 0, len(audit.Vulnerabilities))
+	for name, vuln := range audit.Vulnerabilities {
+		finding := Finding{Package: name, Version: vuln.Range, Severity: normalizeSeverity(vuln.Severity)}
+
+		// via lists advisories, or the names of vulnerable dependencies
+		var through []string
+		for _, raw := range vuln.Via {
+			var advisory struct {
+				Title string `json:"title"`
+				URL   string `json:"url"`
+			}
+			var dependency string
+			if json.Unmarshal(raw, &advisory) == nil && advisory.Title != "" {
+				if finding.ID == "" {
+					finding.ID, finding.Summary = advisory.URL, advisory.Title
+				}
+			} else if json.Unmarshal(raw, &dependency) == nil {
+				through = append(through, dependency)
+			}
+		}
+		if finding.Summary == "" {
+			finding.Summary = "vulnerable through " + strings.Join(through, ", ")
+		}
+
+		var fix struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if json.Unmarshal(vuln.FixAvailable, &fix) == nil && fix.Version != "" {
+			finding.FixedIn = fix.Name + "@" + fix.Version
+		}
+		findings = append(findings, finding)
+	}
+
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Package < findings[j].Package })
+	return findings, nil
+}
+
+// parsePipAudit reads pip-audit -f json, which is either a list of
+// dependencies or, in newer versions, an object with a dependencies list.
+// pip-audit doesn't rate severity.
+func parsePipAudit(output []byte) ([]Finding, error) {
+	type dependency struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Vulns   []struct {
+			ID          string   `json:"id"`
+			FixVersions []string `json:"fix_versions"`
+			Aliases     []string `json:"aliases"`
+			Description string   `json:"description"`
+		} `json:"vulns"`
+	}
+
+	var report struct {
+		Dependencies []dependency `json:"dependencies"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		if err := json.Unmarshal(output, &report.Dependencies); err != nil {
+			return nil, err
+		}
+	}
+
+	var findings []Finding
+	for _, dep := range report.Dependencies {
+		for _, vuln := range dep.Vulns {
+			summary, _, _ := strings.Cut(strings.TrimSpace(vuln.Description), "\n")
+			findings = append(findings, Finding{
+				ID:       vuln.ID,
+				Package:  dep.Name,
+				Version:  dep.Version,
+				FixedIn:  strings.Join(vuln.FixVersions, ", "),
+				Severity: "unknown",
+				Summary:  truncate(summary, 200),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// normalizeSeverity maps scanner severities onto critical, high, medium,
+// low, info and unknown
+func normalizeSeverity(severity string) string {
+	severity = strings.ToLower(severity)
+	if severity == "moderate" {
+		return "medium"
+	}
+	if _, ok := severityOrder[severity]; ok {
+		return severity
+	}
+	return "unknown"
+}
@@ -0,0 +1,447 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/integration"
+	"github.com/quantumflow/quantumflow/internal/memory"
+)
+
+// Config is the effective QuantumFlow configuration: each package's defaults,
+// overlaid by the config file, overlaid by environment variables
+type Config struct {
+	Inference     *inference.Config
+	Pool          *inference.PoolConfig
+	Memory        *memory.Config
+	MemoryEnabled bool
+	Integrations  *integration.Config
+	DatabaseDSN   string // Database the DataAgent's tools connect to; empty leaves them unconfigured
+
+	// Path is the config file that was loaded, or "" when none was found
+	Path string
+
+	// Warnings lists keys in the config file that QuantumFlow doesn't read,
+	// so a misspelled setting is reported rather than silently ignored
+	Warnings []string
+}
+
+// fileConfig mirrors config.yaml (see config.example.yaml). Unset fields keep
+// their defaults, so pointers are used where the zero value is a real setting.
+type fileConfig struct {
+	Model struct {
+		OllamaURL   string   `yaml:"ollama_url"`
+		Name        string   `yaml:"name"`
+		ContextSize int      `yaml:"context_size"`
+		Temperature *float64 `yaml:"temperature"`
+		Timeout     string   `yaml:"timeout"`
+		KeepAlive   string   `yaml:"keep_alive"`
+		Cache       struct {
+			TTL  string `yaml:"ttl"`
+			Size int    `yaml:"size"`
+		} `yaml:"cache"`
+	} `yaml:"model"`
+
+	Memory struct {
+		Enabled *bool `yaml:"enabled"`
+		Redis   struct {
+			URL             string `yaml:"url"`
+			Password        string `yaml:"password"`
+			DB              *int   `yaml:"db"`
+			PoolSize        int    `yaml:"pool_size"`
+			MinIdleConns    int    `yaml:"min_idle_conns"`
+			MaxRetries      int    `yaml:"max_retries"`
+			ConnectAttempts int    `yaml:"connect_attempts"`
+			ConnectBackoff  string `yaml:"connect_backoff"`
+			MigrateIndex    *bool  `yaml:"migrate_index"`
+		} `yaml:"redis"`
+		Dgraph struct {
+			URL      string `yaml:"url"`
+			AlphaURL string `yaml:"alpha_url"`
+		} `yaml:"dgraph"`
+		Badger struct {
+			Path       string `yaml:"path"`
+			GCInterval string `yaml:"gc_interval"`
+		} `yaml:"badger"`
+		MinSimilarity *float64 `yaml:"min_similarity"`
+		Extractor     string   `yaml:"extractor"`
+		Embedding     struct {
+			Provider   string `yaml:"provider"`
+			Model      string `yaml:"model"`
+			Dimensions int    `yaml:"dimensions"`
+			URL        string `yaml:"url"`
+		} `yaml:"embedding"`
+		Compaction struct {
+			Enabled        *bool          `yaml:"enabled"`
+			Interval       string         `yaml:"interval"`
+			RetentionDays  int            `yaml:"retention_days"`
+			RetentionByTag map[string]int `yaml:"retention_by_tag"`
+		} `yaml:"compaction"`
+	} `yaml:"memory"`
+
+	Pool struct {
+		Workers       int `yaml:"workers"`
+		QueueSize     int `yaml:"queue_size"`
+		MaxConcurrent int `yaml:"max_concurrent"`
+	} `yaml:"pool"`
+
+	Data struct {
+		DatabaseDSN string `yaml:"database_dsn"`
+	} `yaml:"data"`
+
+	Integrations struct {
+		GitHub     connectorFile `yaml:"github"`
+		Slack      connectorFile `yaml:"slack"`
+		Salesforce connectorFile `yaml:"salesforce"`
+		Zendesk    connectorFile `yaml:"zendesk"`
+		Audit      struct {
+			Enabled       *bool  `yaml:"enabled"`
+			Path          string `yaml:"path"`
+			RetentionDays *int   `yaml:"retention_days"`
+			RotateDaily   *bool  `yaml:"rotate_daily"`
+		} `yaml:"audit"`
+	} `yaml:"integrations"`
+}
+
+// connectorFile holds the settings shared by every integration in config.yaml
+type connectorFile struct {
+	Enabled  *bool  `yaml:"enabled"`
+	Timeout  string `yaml:"timeout"`
+	ProxyURL string `yaml:"proxy_url"`
+}
+
+// Environment variables that override the config file
+const (
+	EnvOllamaURL         = "QUANTUMFLOW_OLLAMA_URL"
+	EnvModel             = "QUANTUMFLOW_MODEL"
+	EnvTemperature       = "QUANTUMFLOW_TEMPERATURE"
+	EnvContextSize       = "QUANTUMFLOW_CONTEXT_SIZE"
+	EnvMemoryEnabled     = "QUANTUMFLOW_MEMORY"
+	EnvRedisURL          = "QUANTUMFLOW_REDIS_URL"
+	EnvRedisPassword     = "QUANTUMFLOW_REDIS_PASSWORD"
+	EnvDgraphURL         = "QUANTUMFLOW_DGRAPH_URL"
+	EnvDgraphAlphaURL    = "QUANTUMFLOW_DGRAPH_ALPHA_URL"
+	EnvBadgerPath        = "QUANTUMFLOW_BADGER_PATH"
+	EnvGitHubEnabled     = "QUANTUMFLOW_GITHUB_ENABLED"
+	EnvSlackEnabled      = "QUANTUMFLOW_SLACK_ENABLED"
+	EnvSalesforceEnabled = "QUANTUMFLOW_SALESFORCE_ENABLED"
+	EnvZendeskEnabled    = "QUANTUMFLOW_ZENDESK_ENABLED"
+	EnvDatabaseDSN       = "QUANTUMFLOW_DATABASE_DSN"
+)
+
+// DefaultPath returns the config file location, ~/.quantumflow/config.yaml
+func DefaultPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".quantumflow", "config.yaml")
+}
+
+// Default returns the configuration used when there is no file or environment override
+func Default() *Config {
+	cfg := &Config{
+		Inference:     inference.DefaultConfig(),
+		Pool:          inference.DefaultPoolConfig(),
+		Memory:        memory.DefaultConfig(),
+		MemoryEnabled: true,
+		Integrations:  integration.DefaultConfig(),
+	}
+	cfg.Pool.InferenceConfig = cfg.Inference
+	// The CLI fails fast at startup rather than retrying a backend that isn't
+	// running; memory.redis.connect_attempts raises it
+	cfg.Memory.RedisConnectAttempts = 1
+	return cfg
+}
+
+// Load builds the effective configuration from the file at path, then applies
+// environment overrides. An empty path reads DefaultPath and tolerates it being
+// missing; an explicit path must exist.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		var file fileConfig
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		cfg.Warnings = unknownKeys(data)
+		if err := cfg.applyFile(&file); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		cfg.Path = path
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+		// No config file; defaults and environment only
+	default:
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// unknownKeyPattern matches the decoder's error for a key with no matching field
+var unknownKeyPattern = regexp.MustCompile(`^(line \d+): field (\S+) not found in type .*$`)
+
+// unknownKeys decodes an already valid config file strictly and returns a
+// message for each key that doesn't correspond to a setting
+func unknownKeys(data []byte) []string {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var typeErr *yaml.TypeError
+	if err := decoder.Decode(&fileConfig{}); !errors.As(err, &typeErr) {
+		return nil
+	}
+
+	warnings := make([]string, len(typeErr.Errors))
+	for i, message := range typeErr.Errors {
+		warnings[i] = unknownKeyPattern.ReplaceAllString(message, "$1: unknown key $2")
+	}
+	return warnings
+}
+
+// applyFile overlays the settings present in the config file
+func (c *Config) applyFile(file *fileConfig) error {
+	model := file.Model
+	setString(&c.Inference.OllamaURL, model.OllamaURL)
+	setString(&c.Inference.Model, model.Name)
+	setInt(&c.Inference.ContextSize, model.ContextSize)
+	if model.Temperature != nil {
+		c.Inference.Temperature = *model.Temperature
+	}
+	if err := setDuration(&c.Inference.Timeout, "model.timeout", model.Timeout); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Inference.KeepAlive, "model.keep_alive", model.KeepAlive); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Inference.CacheTTL, "model.cache.ttl", model.Cache.TTL); err != nil {
+		return err
+	}
+	setInt(&c.Inference.CacheSize, model.Cache.Size)
+
+	mem := file.Memory
+	if mem.Enabled != nil {
+		c.MemoryEnabled = *mem.Enabled
+	}
+	setString(&c.Memory.RedisURL, mem.Redis.URL)
+	setString(&c.Memory.RedisPassword, mem.Redis.Password)
+	if mem.Redis.DB != nil {
+		c.Memory.RedisDB = *mem.Redis.DB
+	}
+	setInt(&c.Memory.RedisPoolSize, mem.Redis.PoolSize)
+	setInt(&c.Memory.RedisMinIdleConns, mem.Redis.MinIdleConns)
+	setInt(&c.Memory.RedisMaxRetries, mem.Redis.MaxRetries)
+	setInt(&c.Memory.RedisConnectAttempts, mem.Redis.ConnectAttempts)
+	if err := setDuration(&c.Memory.RedisConnectBackoff, "memory.redis.connect_backoff", mem.Redis.ConnectBackoff); err != nil {
+		return err
+	}
+	if mem.Redis.MigrateIndex != nil {
+		c.Memory.MigrateIndex = *mem.Redis.MigrateIndex
+	}
+	setString(&c.Memory.DgraphURL, mem.Dgraph.URL)
+	setString(&c.Memory.DgraphAlphaURL, mem.Dgraph.AlphaURL)
+	setString(&c.Memory.BadgerPath, mem.Badger.Path)
+	if err := setDuration(&c.Memory.BadgerGCInterval, "memory.badger.gc_interval", mem.Badger.GCInterval); err != nil {
+		return err
+	}
+	if mem.MinSimilarity != nil {
+		c.Memory.MinSimilarity = *mem.MinSimilarity
+	}
+	setString(&c.Memory.Extractor, mem.Extractor)
+	setString(&c.Memory.EmbeddingProvider, mem.Embedding.Provider)
+	setString(&c.Memory.EmbeddingModel, mem.Embedding.Model)
+	setInt(&c.Memory.EmbeddingDimensions, mem.Embedding.Dimensions)
+	setString(&c.Memory.EmbeddingURL, mem.Embedding.URL)
+	if mem.Compaction.Enabled != nil {
+		c.Memory.CompactionEnabled = *mem.Compaction.Enabled
+	}
+	if err := setDuration(&c.Memory.CompactionInterval, "memory.compaction.interval", mem.Compaction.Interval); err != nil {
+		return err
+	}
+	setInt(&c.Memory.RetentionDays, mem.Compaction.RetentionDays)
+	for tag, days := range mem.Compaction.RetentionByTag {
+		c.Memory.RetentionByTag[tag] = days
+	}
+
+	setString(&c.DatabaseDSN, file.Data.DatabaseDSN)
+
+	setInt(&c.Pool.Workers, file.Pool.Workers)
+	setInt(&c.Pool.QueueSize, file.Pool.QueueSize)
+	setInt(&c.Pool.MaxConcurrent, file.Pool.MaxConcurrent)
+
+	integrations := c.Integrations
+	connectors := []struct {
+		name    string
+		file    connectorFile
+		enabled *bool
+		http    *integration.HTTPOptions
+	}{
+		{"github", file.Integrations.GitHub, &integrations.GitHub.Enabled, &integrations.GitHub.HTTPOptions},
+		{"slack", file.Integrations.Slack, &integrations.Slack.Enabled, &integrations.Slack.HTTPOptions},
+		{"salesforce", file.Integrations.Salesforce, &integrations.Salesforce.Enabled, &integrations.Salesforce.HTTPOptions},
+		{"zendesk", file.Integrations.Zendesk, &integrations.Zendesk.Enabled, &integrations.Zendesk.HTTPOptions},
+	}
+	for _, connector := range connectors {
+		if connector.file.Enabled != nil {
+			*connector.enabled = *connector.file.Enabled
+		}
+		if err := setDuration(&connector.http.Timeout, "integrations."+connector.name+".timeout", connector.file.Timeout); err != nil {
+			return err
+		}
+		setString(&connector.http.ProxyURL, connector.file.ProxyURL)
+	}
+
+	audit := file.Integrations.Audit
+	if audit.Enabled != nil {
+		integrations.AuditLogEnabled = *audit.Enabled
+	}
+	setString(&integrations.AuditLogPath, audit.Path)
+	if audit.RetentionDays != nil {
+		integrations.AuditRetentionDays = *audit.RetentionDays
+	}
+	if audit.RotateDaily != nil {
+		integrations.AuditRotateDaily = *audit.RotateDaily
+	}
+
+	return nil
+}
+
+// applyEnv overlays the QUANTUMFLOW_* environment variables that are set
+func (c *Config) applyEnv() error {
+	setString(&c.Inference.OllamaURL, os.Getenv(EnvOllamaURL))
+	setString(&c.Inference.Model, os.Getenv(EnvModel))
+	setString(&c.Memory.RedisURL, os.Getenv(EnvRedisURL))
+	setString(&c.Memory.RedisPassword, os.Getenv(EnvRedisPassword))
+	setString(&c.Memory.DgraphURL, os.Getenv(EnvDgraphURL))
+	setString(&c.Memory.DgraphAlphaURL, os.Getenv(EnvDgraphAlphaURL))
+	setString(&c.Memory.BadgerPath, os.Getenv(EnvBadgerPath))
+	setString(&c.DatabaseDSN, os.Getenv(EnvDatabaseDSN))
+
+	if value := os.Getenv(EnvTemperature); value != "" {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvTemperature, err)
+		}
+		c.Inference.Temperature = temperature
+	}
+	if value := os.Getenv(EnvContextSize); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvContextSize, err)
+		}
+		c.Inference.ContextSize = size
+	}
+
+	flags := map[string]*bool{
+		EnvMemoryEnabled:     &c.MemoryEnabled,
+		EnvGitHubEnabled:     &c.Integrations.GitHub.Enabled,
+		EnvSlackEnabled:      &c.Integrations.Slack.Enabled,
+		EnvSalesforceEnabled: &c.Integrations.Salesforce.Enabled,
+		EnvZendeskEnabled:    &c.Integrations.Zendesk.Enabled,
+	}
+	for name, target := range flags {
+		if value := os.Getenv(name); value != "" {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = enabled
+		}
+	}
+
+	return nil
+}
+
+// Validate reports settings that cannot work
+func (c *Config) Validate() error {
+	if u, err := url.Parse(c.Inference.OllamaURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid Ollama URL %q", c.Inference.OllamaURL)
+	}
+	if c.Inference.Model == "" {
+		return fmt.Errorf("model name must not be empty")
+	}
+	if c.Inference.Temperature < 0 || c.Inference.Temperature > 2 {
+		return fmt.Errorf("temperature %.2f out of range 0-2", c.Inference.Temperature)
+	}
+	if c.Inference.ContextSize <= 0 {
+		return fmt.Errorf("context size must be positive, got %d", c.Inference.ContextSize)
+	}
+	if c.Inference.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive, got %s", c.Inference.Timeout)
+	}
+	if c.Memory.MinSimilarity < 0 || c.Memory.MinSimilarity > 1 {
+		return fmt.Errorf("min_similarity %.2f out of range 0-1", c.Memory.MinSimilarity)
+	}
+	switch c.Memory.Extractor {
+	case "", memory.ExtractorQwen, memory.ExtractorNoOp:
+	default:
+		return fmt.Errorf("memory.extractor must be %s or %s, got %q", memory.ExtractorQwen, memory.ExtractorNoOp, c.Memory.Extractor)
+	}
+	switch c.Memory.EmbeddingProvider {
+	case "", memory.EmbeddingSimple, memory.EmbeddingHuggingFace, memory.EmbeddingOllama:
+	default:
+		return fmt.Errorf("memory.embedding.provider must be %s, %s or %s, got %q",
+			memory.EmbeddingSimple, memory.EmbeddingHuggingFace, memory.EmbeddingOllama, c.Memory.EmbeddingProvider)
+	}
+	if c.Memory.EmbeddingDimensions <= 0 {
+		return fmt.Errorf("memory.embedding.dimensions must be positive, got %d", c.Memory.EmbeddingDimensions)
+	}
+	for tag, days := range c.Memory.RetentionByTag {
+		if days < 0 {
+			return fmt.Errorf("memory.compaction.retention_by_tag.%s must not be negative, got %d", tag, days)
+		}
+	}
+	if c.Integrations.AuditRetentionDays < 0 {
+		return fmt.Errorf("integrations.audit.retention_days must not be negative, got %d", c.Integrations.AuditRetentionDays)
+	}
+	return nil
+}
+
+// setString overwrites dst when value is set
+func setString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// setInt overwrites dst when value is set
+func setInt(dst *int, value int) {
+	if value != 0 {
+		*dst = value
+	}
+}
+
+// setDuration parses value into dst when it is set; key names the setting in errors
+func setDuration(dst *time.Duration, key, value string) error {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	*dst = d
+	return nil
+}
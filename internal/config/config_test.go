@@ -0,0 +1,200 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLoadMergesFileAndEnv tests that file settings overlay defaults and environment variables overlay both
+func TestLoadMergesFileAndEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+model:
+  ollama_url: http://gpu-box:11434
+  name: qwen2.5-coder:14b
+  temperature: 0
+  timeout: 5m
+  keep_alive: -1s
+  cache:
+    ttl: 10m
+memory:
+  enabled: false
+  redis:
+    url: redis.internal:6379
+    migrate_index: true
+  extractor: noop
+  embedding:
+    provider: ollama
+    model: nomic-embed-text
+    dimensions: 768
+  compaction:
+    retention_by_tag:
+      session-summary: 0
+      pinned: 730
+integrations:
+  github:
+    enabled: true
+    timeout: 45s
+  audit:
+    retention_days: 0
+    rotate_daily: true
+data:
+  database_dsn: sqlite://file.db
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvModel, "llama3.1:8b")
+	t.Setenv(EnvContextSize, "8192")
+	t.Setenv(EnvDatabaseDSN, "postgres://localhost/app")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	defaults := Default()
+	if cfg.Path != path {
+		t.Errorf("Expected path %s, got %s", path, cfg.Path)
+	}
+	if cfg.Inference.OllamaURL != "http://gpu-box:11434" {
+		t.Errorf("Expected file Ollama URL, got %s", cfg.Inference.OllamaURL)
+	}
+	if cfg.Inference.Model != "llama3.1:8b" {
+		t.Errorf("Expected environment model to win, got %s", cfg.Inference.Model)
+	}
+	if cfg.Inference.Temperature != 0 {
+		t.Errorf("Expected explicit zero temperature, got %.2f", cfg.Inference.Temperature)
+	}
+	if cfg.Inference.ContextSize != 8192 || cfg.Inference.Timeout != 5*time.Minute {
+		t.Errorf("Unexpected context size / timeout: %d / %s", cfg.Inference.ContextSize, cfg.Inference.Timeout)
+	}
+	if cfg.Inference.KeepAlive != -time.Second {
+		t.Errorf("Expected keep_alive -1s, got %s", cfg.Inference.KeepAlive)
+	}
+	if cfg.Inference.CacheTTL != 10*time.Minute || cfg.Inference.CacheSize != 0 {
+		t.Errorf("Unexpected response cache: %s / %d", cfg.Inference.CacheTTL, cfg.Inference.CacheSize)
+	}
+	if cfg.MemoryEnabled {
+		t.Error("Expected memory to be disabled by the file")
+	}
+	if cfg.Memory.RedisURL != "redis.internal:6379" || cfg.Memory.DgraphURL != defaults.Memory.DgraphURL {
+		t.Errorf("Unexpected memory backends: %s / %s", cfg.Memory.RedisURL, cfg.Memory.DgraphURL)
+	}
+	if !cfg.Memory.MigrateIndex {
+		t.Error("Expected migrate_index from the file")
+	}
+	if cfg.Memory.RedisConnectAttempts != 1 {
+		t.Errorf("Expected a single Redis connect attempt by default, got %d", cfg.Memory.RedisConnectAttempts)
+	}
+	if cfg.Memory.Extractor != "noop" {
+		t.Errorf("Expected the noop extractor, got %s", cfg.Memory.Extractor)
+	}
+	if cfg.Memory.EmbeddingProvider != "ollama" || cfg.Memory.EmbeddingModel != "nomic-embed-text" || cfg.Memory.EmbeddingDimensions != 768 {
+		t.Errorf("Unexpected embedding settings: %s / %s / %d", cfg.Memory.EmbeddingProvider, cfg.Memory.EmbeddingModel, cfg.Memory.EmbeddingDimensions)
+	}
+	if tags := cfg.Memory.RetentionByTag; len(tags) != 2 || tags["session-summary"] != 0 || tags["pinned"] != 730 {
+		t.Errorf("Unexpected retention by tag: %v", tags)
+	}
+	if cfg.DatabaseDSN != "postgres://localhost/app" {
+		t.Errorf("Expected environment database DSN to win, got %s", cfg.DatabaseDSN)
+	}
+	github := cfg.Integrations.GitHub
+	if !github.Enabled || github.Timeout != 45*time.Second || cfg.Integrations.Slack.Enabled {
+		t.Errorf("Unexpected integrations: github %v %s, slack %v", github.Enabled, github.Timeout, cfg.Integrations.Slack.Enabled)
+	}
+	if audit := cfg.Integrations; !audit.AuditLogEnabled || audit.AuditRetentionDays != 0 || !audit.AuditRotateDaily {
+		t.Errorf("Expected audit logging kept forever in daily files, got retention %d, rotate %v", audit.AuditRetentionDays, audit.AuditRotateDaily)
+	}
+}
+
+// TestLoadExampleConfig tests that the shipped config.example.yaml loads cleanly
+func TestLoadExampleConfig(t *testing.T) {
+	cfg, err := Load(filepath.Join("..", "..", "config.example.yaml"))
+	if err != nil || len(cfg.Warnings) > 0 {
+		t.Fatalf("Example config failed to load cleanly: %v %q", err, cfg.Warnings)
+	}
+	if cfg.Memory.BadgerGCInterval != 10*time.Minute || cfg.Integrations.Salesforce.Timeout != 120*time.Second {
+		t.Errorf("Unexpected example values: gc %s, salesforce timeout %s",
+			cfg.Memory.BadgerGCInterval, cfg.Integrations.Salesforce.Timeout)
+	}
+	if cfg.Memory.RedisConnectAttempts != 5 {
+		t.Errorf("Expected connect_attempts from the file, got %d", cfg.Memory.RedisConnectAttempts)
+	}
+}
+
+// TestLoadMissingFile tests that only an explicitly requested config file must exist
+func TestLoadMissingFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Expected defaults without a config file, got %v", err)
+	}
+	if cfg.Path != "" || cfg.Inference.Model != Default().Inference.Model {
+		t.Errorf("Expected default configuration, got %+v", cfg.Inference)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing explicit config file")
+	}
+}
+
+// TestLoadRejectsInvalidValues tests validation of file and environment values
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("model:\n  temperature: 3.5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected out-of-range temperature to be rejected")
+	}
+	if err := os.WriteFile(path, []byte("memory:\n  extractor: gpt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an unknown extractor to be rejected")
+	}
+	if err := os.WriteFile(path, []byte("memory:\n  embedding:\n    provider: openai\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an unknown embedding provider to be rejected")
+	}
+	if err := os.WriteFile(path, []byte("memory:\n  compaction:\n    retention_by_tag:\n      session-summary: -1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected a negative tag retention to be rejected")
+	}
+	if err := os.WriteFile(path, []byte("integrations:\n  audit:\n    retention_days: -7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected a negative audit retention to be rejected")
+	}
+	if err := os.WriteFile(path, []byte("memory:\n  redis:\n    conect_attempts: 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected unknown keys to load with a warning, got %v", err)
+	}
+	if len(cfg.Warnings) != 1 || cfg.Warnings[0] != "line 3: unknown key conect_attempts" {
+		t.Errorf("Expected a warning for the misspelled key, got %q", cfg.Warnings)
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(EnvOllamaURL, "not a url")
+	if _, err := Load(""); err == nil {
+		t.Error("Expected an invalid Ollama URL to be rejected")
+	}
+	t.Setenv(EnvOllamaURL, "")
+
+	t.Setenv(EnvMemoryEnabled, "maybe")
+	if _, err := Load(""); err == nil {
+		t.Error("Expected invalid boolean environment value to be rejected")
+	}
+}
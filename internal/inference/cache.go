@@ -0,0 +1,123 @@
+package inference
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// defaultCacheSize is how many responses are kept when Config.CacheSize is unset
+const defaultCacheSize = 256
+
+// CacheStats counts response cache lookups
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64 // Entries dropped for space or age
+	Size      int
+}
+
+// cacheEntry is one cached response
+type cacheEntry struct {
+	key      string
+	result   InferenceResult
+	storedAt time.Time
+}
+
+// ResponseCache keeps synchronous responses to deterministic requests, least
+// recently used first out, each for at most ttl
+type ResponseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	order   *list.List // Most recently used at the front
+	entries map[string]*list.Element
+	stats   CacheStats
+}
+
+// NewResponseCache creates a cache holding up to maxSize responses for ttl
+// each; maxSize <= 0 uses the default
+func NewResponseCache(maxSize int, ttl time.Duration) *ResponseCache {
+	if maxSize <= 0 {
+		maxSize = defaultCacheSize
+	}
+	return &ResponseCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// cacheable reports whether a request's response can be reused: only
+// non-streaming requests sampled at temperature 0 are deterministic
+func cacheable(req GenerateRequest) bool {
+	return !req.Stream && req.Temperature == 0 && len(req.Messages) == 0
+}
+
+// cacheKey identifies a request by everything that affects its response:
+// model, prompt, system prompt, format and sampling options
+func cacheKey(req GenerateRequest) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached response for key if it hasn't expired
+func (c *ResponseCache) Get(key string) (*InferenceResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Since(entry.storedAt) > c.ttl {
+		c.remove(element)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.Hits++
+	result := entry.result
+	return &result, true
+}
+
+// Put stores a response, evicting the least recently used when full
+func (c *ResponseCache) Put(key string, result *InferenceResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &cacheEntry{key: key, result: *result, storedAt: time.Now()}
+		c.order.MoveToFront(element)
+		return
+	}
+	for c.order.Len() >= c.maxSize {
+		c.remove(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: *result, storedAt: time.Now()})
+}
+
+// Stats returns the cache's hit and miss counts and current size
+func (c *ResponseCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// remove drops an entry, counting it as evicted
+func (c *ResponseCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+	c.stats.Evictions++
+}
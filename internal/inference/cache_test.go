@@ -0,0 +1,123 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestResponseCache tests that deterministic sync requests are served from
+// the cache, and that sampled and streaming requests always reach the model
+func TestResponseCache(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"response":"answer","done":true}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{OllamaURL: server.URL, Model: "test", CacheTTL: time.Minute})
+	ctx := context.Background()
+
+	first, err := client.GenerateSync(ctx, "route this")
+	if err != nil || first.Cached {
+		t.Fatalf("Expected a fresh response, got %+v, %v", first, err)
+	}
+	second, err := client.GenerateSync(ctx, "route this")
+	if err != nil || !second.Cached || second.Response != "answer" {
+		t.Errorf("Expected a cached response, got %+v, %v", second, err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected one model call, got %d", calls.Load())
+	}
+
+	// Different options are a different request
+	if _, err := client.GenerateSyncWithOptions(ctx, "route this", GenerateOptions{Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GenerateSyncWithOptions(ctx, "route this", GenerateOptions{Temperature: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GenerateSyncWithOptions(ctx, "route this", GenerateOptions{Temperature: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := client.Generate(ctx, "route this", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range stream {
+	}
+	if calls.Load() != 5 {
+		t.Errorf("Expected sampled and streaming requests to skip the cache, got %d calls", calls.Load())
+	}
+
+	stats, ok := client.CacheStats()
+	if !ok || stats.Hits != 1 || stats.Misses != 2 || stats.Size != 2 {
+		t.Errorf("Unexpected cache stats: %+v", stats)
+	}
+	if _, ok := NewClient(&Config{OllamaURL: server.URL}).CacheStats(); ok {
+		t.Error("Expected the cache to be off by default")
+	}
+}
+
+// TestDeterministicOptions tests that a deterministic call samples at
+// temperature 0 and is cached even though the default temperature isn't 0
+func TestDeterministicOptions(t *testing.T) {
+	var temperatures []float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		temperatures = append(temperatures, req.Options["temperature"].(float64))
+		w.Write([]byte(`{"response":"answer","done":true}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.OllamaURL = server.URL
+	config.CacheTTL = time.Minute
+	client := NewClient(config)
+	ctx := context.Background()
+
+	opts := GenerateOptions{Temperature: 0.9, Deterministic: true}
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateSyncWithOptions(ctx, "route this", opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.GenerateSync(ctx, "route this"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(temperatures) != 2 || temperatures[0] != 0 || temperatures[1] != config.Temperature {
+		t.Errorf("Expected one deterministic call then one at the default temperature, got %v", temperatures)
+	}
+}
+
+// TestResponseCacheEviction tests LRU eviction and expiry
+func TestResponseCacheEviction(t *testing.T) {
+	cache := NewResponseCache(2, time.Hour)
+	cache.Put("a", &InferenceResult{Response: "A"})
+	cache.Put("b", &InferenceResult{Response: "B"})
+	cache.Get("a")
+	cache.Put("c", &InferenceResult{Response: "C"})
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if result, ok := cache.Get("a"); !ok || result.Response != "A" {
+		t.Errorf("Expected a to be kept, got %v", result)
+	}
+
+	cache.ttl = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.Get("c"); ok {
+		t.Error("Expected an expired entry to be dropped")
+	}
+	if stats := cache.Stats(); stats.Size != 1 || stats.Evictions != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
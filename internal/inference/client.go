@@ -8,9 +8,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/quantumflow/quantumflow/internal/models"
+	"github.com/quantumflow/quantumflow/internal/tracing"
 )
 
 // Config holds the inference client configuration
@@ -20,6 +25,16 @@ type Config struct {
 	ContextSize int     // Default: 32768
 	Temperature float64 // Default: 0.7
 	Timeout     time.Duration
+
+	// CacheTTL enables the response cache: synchronous requests at
+	// temperature 0 are answered from it for this long (0 disables it).
+	// CacheSize bounds how many responses it keeps (0 uses 256).
+	CacheTTL  time.Duration
+	CacheSize int
+
+	// KeepAlive is how long Ollama keeps the model loaded after a request
+	// (0 uses Ollama's default of 5m; negative keeps it loaded indefinitely)
+	KeepAlive time.Duration
 }
 
 // DefaultConfig returns the default configuration
@@ -37,6 +52,10 @@ func DefaultConfig() *Config {
 type Client struct {
 	config     *Config
 	httpClient *http.Client
+	mu         sync.RWMutex // Guards config fields changed at runtime
+
+	interceptors []Interceptor
+	cache        *ResponseCache // nil unless Config.CacheTTL is set
 }
 
 // NewClient creates a new inference client
@@ -44,23 +63,70 @@ func NewClient(config *Config) *Client {
 	if config == nil {
 		config = DefaultConfig()
 	}
+	// Copy so runtime changes go through the client's setters
+	copied := *config
+	config = &copied
 
-	return &Client{
+	client := &Client{
 		config: config,
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
 	}
+	if config.CacheTTL > 0 {
+		client.cache = NewResponseCache(config.CacheSize, config.CacheTTL)
+	}
+	return client
+}
+
+// CacheStats returns the response cache's counters; ok is false when the
+// cache is disabled
+func (c *Client) CacheStats() (stats CacheStats, ok bool) {
+	if c.cache == nil {
+		return CacheStats{}, false
+	}
+	return c.cache.Stats(), true
+}
+
+// Config returns a copy of the client's current configuration
+func (c *Client) Config() Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return *c.config
+}
+
+// SetModel switches the model used by subsequent requests
+func (c *Client) SetModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Model = model
+}
+
+// SetTemperature changes the default sampling temperature for subsequent requests
+func (c *Client) SetTemperature(temperature float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Temperature = temperature
+}
+
+// SetContextSize changes the context window (num_ctx) for subsequent requests
+func (c *Client) SetContextSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.ContextSize = size
 }
 
 // GenerateRequest represents a request to Ollama
 type GenerateRequest struct {
 	Model       string          `json:"model"`
 	Prompt      string          `json:"prompt"`
+	System      string          `json:"system,omitempty"` // System prompt for /api/generate
 	Messages    []models.Message `json:"messages,omitempty"`
 	Stream      bool            `json:"stream"`
+	Format      string          `json:"format,omitempty"` // "json" constrains output to valid JSON
 	Temperature float64         `json:"temperature,omitempty"`
 	Options     map[string]interface{} `json:"options,omitempty"`
+	KeepAlive   string          `json:"keep_alive,omitempty"` // Duration, e.g. "30m"; negative keeps the model loaded
 }
 
 // GenerateResponse represents a response from Ollama
@@ -84,59 +150,139 @@ type InferenceResult struct {
 	TokensPerSec float64
 	Latency      time.Duration
 	Error        error
+	Cached       bool // Answered from the response cache
+
+	// LoadDuration is how long Ollama spent loading the model for this
+	// request; see ModelReloaded
+	LoadDuration time.Duration
 }
 
-// Generate generates a response using the configured model
-func (c *Client) Generate(ctx context.Context, prompt string, streaming bool) (<-chan string, error) {
-	req := GenerateRequest{
-		Model:       c.config.Model,
+// modelReloadThreshold is the load time above which a request is taken to
+// have loaded the model from disk; a resident model reports a few milliseconds
+const modelReloadThreshold = 500 * time.Millisecond
+
+// ModelReloaded reports whether the model had to be loaded for this request
+func (r *InferenceResult) ModelReloaded() bool {
+	return r.LoadDuration > modelReloadThreshold
+}
+
+// GenerateOptions overrides client defaults for a single call
+type GenerateOptions struct {
+	Temperature float64 // Sampling temperature; 0 uses the client default
+	MaxTokens   int     // Maximum tokens to generate; 0 means no limit
+	Format      string  // Output format, e.g. "json"; empty leaves output unconstrained
+
+	// Deterministic samples at temperature 0 whatever Temperature and the
+	// client default are, which also lets the response cache answer the call
+	Deterministic bool
+}
+
+// buildRequest creates a generate request, applying per-call overrides
+func (c *Client) buildRequest(prompt string, streaming bool, opts GenerateOptions) GenerateRequest {
+	config := c.Config()
+	temperature := config.Temperature
+	switch {
+	case opts.Deterministic:
+		temperature = 0
+	case opts.Temperature > 0:
+		temperature = opts.Temperature
+	}
+
+	options := map[string]interface{}{
+		"num_ctx":     config.ContextSize,
+		"temperature": temperature,
+	}
+	if opts.MaxTokens > 0 {
+		options["num_predict"] = opts.MaxTokens
+	}
+
+	return GenerateRequest{
+		Model:       config.Model,
 		Prompt:      prompt,
 		Stream:      streaming,
-		Temperature: c.config.Temperature,
-		Options: map[string]interface{}{
-			"num_ctx": c.config.ContextSize,
-		},
+		Format:      opts.Format,
+		Temperature: temperature,
+		Options:     options,
+		KeepAlive:   keepAlive(config.KeepAlive),
 	}
+}
+
+// keepAlive formats a keep-alive duration for Ollama, or "" for its default
+func keepAlive(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// Generate generates a response using the configured model
+func (c *Client) Generate(ctx context.Context, prompt string, streaming bool) (<-chan string, error) {
+	return c.GenerateWithOptions(ctx, prompt, streaming, GenerateOptions{})
+}
 
-	return c.generate(ctx, req)
+// GenerateWithOptions generates a response with per-call temperature and token overrides
+func (c *Client) GenerateWithOptions(ctx context.Context, prompt string, streaming bool, opts GenerateOptions) (<-chan string, error) {
+	return c.stream(ctx, c.buildRequest(prompt, streaming, opts), c.generate)
 }
 
 // GenerateWithMessages generates a response using the chat API with message history
 func (c *Client) GenerateWithMessages(ctx context.Context, messages []models.Message, streaming bool) (<-chan string, error) {
+	config := c.Config()
 	req := GenerateRequest{
-		Model:       c.config.Model,
+		Model:       config.Model,
 		Messages:    messages,
 		Stream:      streaming,
-		Temperature: c.config.Temperature,
+		Temperature: config.Temperature,
 		Options: map[string]interface{}{
-			"num_ctx": c.config.ContextSize,
+			"num_ctx": config.ContextSize,
 		},
+		KeepAlive: keepAlive(config.KeepAlive),
 	}
 
-	return c.generateChat(ctx, req)
+	return c.stream(ctx, req, c.generateChat)
+}
+
+// stream runs a call that returns a token stream through the interceptors,
+// sending it with send
+func (c *Client) stream(ctx context.Context, req GenerateRequest, send func(context.Context, GenerateRequest) (<-chan string, error)) (<-chan string, error) {
+	gen, err := c.intercept(func(ctx context.Context, req GenerateRequest) (*Generation, error) {
+		stream, err := send(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return &Generation{Stream: stream}, nil
+	})(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return gen.Stream, nil
 }
 
-// generate makes a request to Ollama's /api/generate endpoint
+// generate makes a request to Ollama's /api/generate endpoint. Its span ends
+// when the response stream does.
 func (c *Client) generate(ctx context.Context, req GenerateRequest) (<-chan string, error) {
+	ctx, span := startSpan(ctx, "inference.Generate", req)
+
 	body, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, tracing.EndSpan(span, fmt.Errorf("failed to marshal request: %w", err))
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.config.OllamaURL+"/api/generate", bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, tracing.EndSpan(span, fmt.Errorf("failed to create request: %w", err))
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, tracing.EndSpan(span, transportError("generate", err))
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, tracing.EndSpan(span, statusError("generate", req.Model, resp.StatusCode, bodyBytes))
 	}
 
 	// Create channel for streaming responses
@@ -144,6 +290,7 @@ func (c *Client) generate(ctx context.Context, req GenerateRequest) (<-chan stri
 
 	go func() {
 		defer close(responseChan)
+		defer span.End() // Before close, so the span is done once the stream is drained
 		defer resp.Body.Close()
 
 		scanner := bufio.NewScanner(resp.Body)
@@ -163,6 +310,9 @@ func (c *Client) generate(ctx context.Context, req GenerateRequest) (<-chan stri
 			}
 
 			if genResp.Done {
+				if genResp.LoadDuration > 0 {
+					span.SetAttributes(attribute.Int64("inference.load_ms", time.Duration(genResp.LoadDuration).Milliseconds()))
+				}
 				return
 			}
 		}
@@ -175,27 +325,31 @@ func (c *Client) generate(ctx context.Context, req GenerateRequest) (<-chan stri
 	return responseChan, nil
 }
 
-// generateChat makes a request to Ollama's /api/chat endpoint
+// generateChat makes a request to Ollama's /api/chat endpoint. Its span ends
+// when the response stream does.
 func (c *Client) generateChat(ctx context.Context, req GenerateRequest) (<-chan string, error) {
+	ctx, span := startSpan(ctx, "inference.Chat", req)
+
 	body, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, tracing.EndSpan(span, fmt.Errorf("failed to marshal request: %w", err))
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.config.OllamaURL+"/api/chat", bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, tracing.EndSpan(span, fmt.Errorf("failed to create request: %w", err))
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, tracing.EndSpan(span, transportError("chat", err))
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, tracing.EndSpan(span, statusError("chat", req.Model, resp.StatusCode, bodyBytes))
 	}
 
 	// Create channel for streaming responses
@@ -203,6 +357,7 @@ func (c *Client) generateChat(ctx context.Context, req GenerateRequest) (<-chan
 
 	go func() {
 		defer close(responseChan)
+		defer span.End() // Before close, so the span is done once the stream is drained
 		defer resp.Body.Close()
 
 		scanner := bufio.NewScanner(resp.Body)
@@ -212,7 +367,8 @@ func (c *Client) generateChat(ctx context.Context, req GenerateRequest) (<-chan
 					Role    string `json:"role"`
 					Content string `json:"content"`
 				} `json:"message"`
-				Done bool `json:"done"`
+				Done         bool  `json:"done"`
+				LoadDuration int64 `json:"load_duration,omitempty"`
 			}
 
 			if err := json.Unmarshal(scanner.Bytes(), &chatResp); err != nil {
@@ -228,6 +384,9 @@ func (c *Client) generateChat(ctx context.Context, req GenerateRequest) (<-chan
 			}
 
 			if chatResp.Done {
+				if chatResp.LoadDuration > 0 {
+					span.SetAttributes(attribute.Int64("inference.load_ms", time.Duration(chatResp.LoadDuration).Milliseconds()))
+				}
 				return
 			}
 		}
@@ -238,17 +397,48 @@ func (c *Client) generateChat(ctx context.Context, req GenerateRequest) (<-chan
 
 // GenerateSync performs a synchronous (non-streaming) generation
 func (c *Client) GenerateSync(ctx context.Context, prompt string) (*InferenceResult, error) {
-	startTime := time.Now()
+	return c.GenerateSyncWithOptions(ctx, prompt, GenerateOptions{})
+}
 
-	req := GenerateRequest{
-		Model:       c.config.Model,
-		Prompt:      prompt,
-		Stream:      false,
-		Temperature: c.config.Temperature,
-		Options: map[string]interface{}{
-			"num_ctx": c.config.ContextSize,
-		},
+// GenerateSyncWithOptions performs a synchronous generation with per-call overrides
+func (c *Client) GenerateSyncWithOptions(ctx context.Context, prompt string, opts GenerateOptions) (*InferenceResult, error) {
+	gen, err := c.intercept(func(ctx context.Context, req GenerateRequest) (*Generation, error) {
+		var key string
+		if c.cache != nil && cacheable(req) {
+			key = cacheKey(req)
+			if result, ok := c.cache.Get(key); ok {
+				result.Cached = true
+				result.Latency = 0
+				result.LoadDuration = 0
+				return &Generation{Result: result}, nil
+			}
+		}
+
+		ctx, span := startSpan(ctx, "inference.GenerateSync", req)
+		result, err := c.generateSync(ctx, req)
+		if err != nil {
+			return nil, tracing.EndSpan(span, err)
+		}
+		span.SetAttributes(attribute.Float64("inference.tokens_per_sec", result.TokensPerSec))
+		if result.LoadDuration > 0 {
+			span.SetAttributes(attribute.Int64("inference.load_ms", result.LoadDuration.Milliseconds()))
+		}
+		span.End()
+
+		if key != "" {
+			c.cache.Put(key, result)
+		}
+		return &Generation{Result: result}, nil
+	})(ctx, c.buildRequest(prompt, false, opts))
+	if err != nil {
+		return nil, err
 	}
+	return gen.Result, nil
+}
+
+// generateSync sends a non-streaming request to /api/generate
+func (c *Client) generateSync(ctx context.Context, req GenerateRequest) (*InferenceResult, error) {
+	startTime := time.Now()
 
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -263,18 +453,18 @@ func (c *Client) GenerateSync(ctx context.Context, prompt string) (*InferenceRes
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, transportError("generate", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, statusError("generate", req.Model, resp.StatusCode, bodyBytes)
 	}
 
 	var genResp GenerateResponse
 	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, decodeError("generate", err)
 	}
 
 	latency := time.Since(startTime)
@@ -287,9 +477,62 @@ func (c *Client) GenerateSync(ctx context.Context, prompt string) (*InferenceRes
 		Response:     genResp.Response,
 		TokensPerSec: tokensPerSec,
 		Latency:      latency,
+		LoadDuration: time.Duration(genResp.LoadDuration),
 	}, nil
 }
 
+// Warmup loads the configured model into memory so the first real request
+// doesn't pay for it, keeping it resident for Config.KeepAlive. It returns how
+// long Ollama spent loading the model, which is near zero if it was already
+// loaded.
+func (c *Client) Warmup(ctx context.Context) (time.Duration, error) {
+	config := c.Config()
+
+	// A generate request with an empty prompt only loads the model
+	req := GenerateRequest{Model: config.Model, KeepAlive: keepAlive(config.KeepAlive)}
+	ctx, span := startSpan(ctx, "inference.Warmup", req)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, tracing.EndSpan(span, fmt.Errorf("failed to marshal request: %w", err))
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", config.OllamaURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return 0, tracing.EndSpan(span, fmt.Errorf("failed to create request: %w", err))
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, tracing.EndSpan(span, transportError("warmup", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return 0, tracing.EndSpan(span, statusError("warmup", req.Model, resp.StatusCode, bodyBytes))
+	}
+
+	var genResp GenerateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
+		return 0, tracing.EndSpan(span, decodeError("warmup", err))
+	}
+
+	loadDuration := time.Duration(genResp.LoadDuration)
+	span.SetAttributes(attribute.Int64("inference.load_ms", loadDuration.Milliseconds()))
+	return loadDuration, tracing.EndSpan(span, nil)
+}
+
+// startSpan starts an inference span describing the request
+func startSpan(ctx context.Context, name string, req GenerateRequest) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(
+		attribute.String("inference.model", req.Model),
+		attribute.Bool("inference.stream", req.Stream),
+		attribute.Int("inference.prompt_chars", len(req.Prompt)),
+		attribute.Int("inference.messages", len(req.Messages)),
+	))
+}
+
 // ListModels lists available models
 func (c *Client) ListModels(ctx context.Context) ([]string, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.config.OllamaURL+"/api/tags", nil)
@@ -299,10 +542,15 @@ func (c *Client) ListModels(ctx context.Context) ([]string, error) {
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, transportError("list models", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, statusError("list models", "", resp.StatusCode, bodyBytes)
+	}
+
 	var result struct {
 		Models []struct {
 			Name string `json:"name"`
@@ -310,7 +558,7 @@ func (c *Client) ListModels(ctx context.Context) ([]string, error) {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, decodeError("list models", err)
 	}
 
 	models := make([]string, len(result.Models))
@@ -340,12 +588,13 @@ func (c *Client) PullModel(ctx context.Context, modelName string) error {
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
+		return transportError("pull", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return statusError("pull", modelName, resp.StatusCode, bodyBytes)
 	}
 
 	// Stream the pull progress
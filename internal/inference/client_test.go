@@ -2,8 +2,18 @@ package inference
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // TestClientInitialization tests client creation with default and custom config
@@ -33,6 +43,94 @@ func TestClientInitialization(t *testing.T) {
 	}
 }
 
+// TestGenerateOptions tests that per-call overrides replace client defaults only when set
+func TestGenerateOptions(t *testing.T) {
+	client := NewClient(&Config{Model: "qwen2:72b", ContextSize: 4096, Temperature: 0.7})
+
+	req := client.buildRequest("hello", false, GenerateOptions{})
+	if req.Options["temperature"] != 0.7 {
+		t.Errorf("Expected default temperature 0.7, got %v", req.Options["temperature"])
+	}
+	if _, ok := req.Options["num_predict"]; ok {
+		t.Error("Expected no token limit by default")
+	}
+	if req.Format != "" {
+		t.Errorf("Expected unconstrained output by default, got format %q", req.Format)
+	}
+
+	req = client.buildRequest("hello", false, GenerateOptions{Temperature: 0.1, MaxTokens: 256})
+	if req.Options["temperature"] != 0.1 || req.Temperature != 0.1 {
+		t.Errorf("Expected temperature override 0.1, got %v", req.Options["temperature"])
+	}
+	if req.Options["num_predict"] != 256 {
+		t.Errorf("Expected num_predict 256, got %v", req.Options["num_predict"])
+	}
+
+	req = client.buildRequest("hello", false, GenerateOptions{Format: "json"})
+	if req.Format != "json" {
+		t.Errorf("Expected format json, got %q", req.Format)
+	}
+}
+
+// TestKeepAliveAndLoadDuration tests that keep_alive is sent and the model
+// load time is reported
+func TestKeepAliveAndLoadDuration(t *testing.T) {
+	var keepAlive string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		keepAlive = req.KeepAlive
+		w.Write([]byte(`{"response":"hi","done":true,"load_duration":2500000000}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{OllamaURL: server.URL, Model: "test", KeepAlive: 30 * time.Minute})
+	result, err := client.GenerateSync(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateSync failed: %v", err)
+	}
+	if keepAlive != "30m0s" {
+		t.Errorf("Expected keep_alive 30m0s, got %q", keepAlive)
+	}
+	if result.LoadDuration != 2500*time.Millisecond || !result.ModelReloaded() {
+		t.Errorf("Expected a 2.5s model load, got %s", result.LoadDuration)
+	}
+
+	if req := NewClient(&Config{Model: "test"}).buildRequest("hello", false, GenerateOptions{}); req.KeepAlive != "" {
+		t.Errorf("Expected Ollama's default keep-alive when unset, got %q", req.KeepAlive)
+	}
+}
+
+// TestWarmup tests that warmup sends a prompt-less load request and reports
+// the load time, and that a missing model is a typed error
+func TestWarmup(t *testing.T) {
+	var req map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"model 'missing' not found"}`))
+			return
+		}
+		w.Write([]byte(`{"model":"test","response":"","done":true,"done_reason":"load","load_duration":4000000000}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{OllamaURL: server.URL, Model: "test", KeepAlive: -time.Second})
+	loadTime, err := client.Warmup(context.Background())
+	if err != nil || loadTime != 4*time.Second {
+		t.Fatalf("Expected a 4s load, got %s, %v", loadTime, err)
+	}
+	if req["prompt"] != "" || req["keep_alive"] != "-1s" {
+		t.Errorf("Expected a load-only request keeping the model resident, got %v", req)
+	}
+
+	client.SetModel("missing")
+	if _, err := client.Warmup(context.Background()); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
+}
+
 // TestGenerateSync tests synchronous generation (requires running Ollama)
 func TestGenerateSync(t *testing.T) {
 	if testing.Short() {
@@ -87,6 +185,124 @@ func TestListModels(t *testing.T) {
 	}
 }
 
+// TestTypedErrors tests that API failures map to sentinel errors and keep the status code
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		kind   error
+	}{
+		{"model not found", http.StatusNotFound, `{"error": "model 'missing' not found"}`, ErrModelNotFound},
+		{"server error", http.StatusServiceUnavailable, `{"error": "loading"}`, ErrServerUnavailable},
+		{"gateway timeout", http.StatusGatewayTimeout, ``, ErrTimeout},
+		{"bad response", http.StatusOK, `not json`, ErrBadResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewClient(&Config{OllamaURL: server.URL, Model: "missing", Timeout: 5 * time.Second})
+
+			_, err := client.GenerateSync(context.Background(), "hello")
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("Expected %v, got %v", tt.kind, err)
+			}
+
+			var inferenceErr *Error
+			if !errors.As(err, &inferenceErr) {
+				t.Fatalf("Expected *Error, got %T", err)
+			}
+			if inferenceErr.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, inferenceErr.StatusCode)
+			}
+		})
+	}
+
+	// Unreachable server
+	client := NewClient(&Config{OllamaURL: "http://127.0.0.1:1", Timeout: 5 * time.Second})
+	if _, err := client.ListModels(context.Background()); !errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("Expected ErrServerUnavailable, got %v", err)
+	}
+}
+
+// TestMissingModel tests that a 404 from Ollama reports which model is missing
+func TestMissingModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "model 'qwen2:72b' not found, try pulling it first"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{OllamaURL: server.URL, Model: "qwen2:72b", Timeout: 5 * time.Second})
+
+	_, err := client.Generate(context.Background(), "hello", true)
+	model, ok := MissingModel(err)
+	if !ok {
+		t.Fatalf("Expected missing model error, got %v", err)
+	}
+	if model != "qwen2:72b" {
+		t.Errorf("Expected model qwen2:72b, got %s", model)
+	}
+
+	if _, ok := MissingModel(errors.New("other failure")); ok {
+		t.Error("Expected unrelated error not to report a missing model")
+	}
+}
+
+// TestInferenceSpans tests that generation calls are traced once a tracer provider is installed
+func TestInferenceSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/chat" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"response": "hi", "done": true}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{OllamaURL: server.URL, Model: "qwen2.5-coder:7b", Timeout: 5 * time.Second})
+	ctx := context.Background()
+
+	if _, err := client.GenerateSync(ctx, "hello"); err != nil {
+		t.Fatalf("GenerateSync failed: %v", err)
+	}
+	stream, err := client.Generate(ctx, "hello", true)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for range stream {
+	}
+	if _, err := client.GenerateWithMessages(ctx, nil, false); err == nil {
+		t.Fatal("Expected chat request to fail")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 ended spans, got %d", len(spans))
+	}
+
+	names := []string{"inference.GenerateSync", "inference.Generate", "inference.Chat"}
+	for i, span := range spans {
+		if span.Name() != names[i] {
+			t.Errorf("Expected span %s, got %s", names[i], span.Name())
+		}
+	}
+	if spans[2].Status().Code != codes.Error {
+		t.Errorf("Expected failed chat span to have error status, got %v", spans[2].Status())
+	}
+}
+
 // BenchmarkGenerateSync benchmarks synchronous generation
 func BenchmarkGenerateSync(b *testing.B) {
 	client := NewClient(nil)
@@ -0,0 +1,123 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors identifying the kind of inference failure. Use errors.Is
+// to branch on them; the *Error wrapping them carries the details.
+var (
+	ErrModelNotFound     = errors.New("model not found")
+	ErrTimeout           = errors.New("inference request timed out")
+	ErrServerUnavailable = errors.New("inference server unavailable")
+	ErrBadResponse       = errors.New("malformed inference response")
+)
+
+// Error describes a failed call to the Ollama API
+type Error struct {
+	Op         string // API operation, e.g. "generate", "chat", "list models"
+	Model      string // Model the request targeted, if any
+	StatusCode int    // HTTP status code, 0 if no response was received
+	Kind       error  // One of the sentinel errors, nil if unclassified
+	Err        error  // Underlying cause
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (status %d)", e.StatusCode)
+	}
+	if e.Kind != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Kind.Error())
+		if e.Kind == ErrModelNotFound && e.Model != "" {
+			fmt.Fprintf(&b, " (%s)", e.Model)
+		}
+	}
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap exposes both the kind and the cause to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// transportError classifies a failure to complete an HTTP request
+func transportError(op string, err error) error {
+	var netErr net.Error
+	var kind error
+	switch {
+	case errors.Is(err, context.Canceled):
+		// Caller cancellation is not an inference failure; leave it unclassified
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		kind = ErrTimeout
+	default:
+		kind = ErrServerUnavailable
+	}
+	return &Error{Op: op, Kind: kind, Err: err}
+}
+
+// statusError classifies a non-200 response from Ollama. Ollama answers an
+// unknown model with 404 and {"error": "model '<name>' not found"}.
+func statusError(op, model string, statusCode int, body []byte) error {
+	var kind error
+	switch {
+	case statusCode == http.StatusNotFound && model != "":
+		kind = ErrModelNotFound
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusGatewayTimeout:
+		kind = ErrTimeout
+	case statusCode >= 500:
+		kind = ErrServerUnavailable
+	}
+
+	var cause error
+	if msg := ollamaErrorMessage(body); msg != "" {
+		cause = errors.New(msg)
+	}
+	return &Error{Op: op, Model: model, StatusCode: statusCode, Kind: kind, Err: cause}
+}
+
+// MissingModel returns the model name when err reports a model that isn't
+// installed, so callers can offer to pull it
+func MissingModel(err error) (string, bool) {
+	var inferenceErr *Error
+	if !errors.As(err, &inferenceErr) || !errors.Is(inferenceErr.Kind, ErrModelNotFound) {
+		return "", false
+	}
+	return inferenceErr.Model, true
+}
+
+// decodeError reports a response body that could not be parsed
+func decodeError(op string, err error) error {
+	return &Error{Op: op, StatusCode: http.StatusOK, Kind: ErrBadResponse, Err: err}
+}
+
+// ollamaErrorMessage extracts the message from an {"error": "..."} body,
+// falling back to the raw body text
+func ollamaErrorMessage(body []byte) string {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != "" {
+		return payload.Error
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,132 @@
+package inference
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// Generator is the generation API that agents, the planner, the router and
+// the memory extractor depend on. Client implements it against Ollama;
+// MockGenerator implements it for tests.
+type Generator interface {
+	// Generate streams (or returns in one token) a response to prompt
+	Generate(ctx context.Context, prompt string, streaming bool) (<-chan string, error)
+
+	// GenerateWithOptions is Generate with per-call overrides
+	GenerateWithOptions(ctx context.Context, prompt string, streaming bool, opts GenerateOptions) (<-chan string, error)
+
+	// GenerateSync returns the whole response to prompt
+	GenerateSync(ctx context.Context, prompt string) (*InferenceResult, error)
+
+	// GenerateSyncWithOptions is GenerateSync with per-call overrides
+	GenerateSyncWithOptions(ctx context.Context, prompt string, opts GenerateOptions) (*InferenceResult, error)
+
+	// GenerateWithMessages responds to a chat conversation
+	GenerateWithMessages(ctx context.Context, messages []models.Message, streaming bool) (<-chan string, error)
+}
+
+var _ Generator = (*Client)(nil)
+
+// MockCall is one call made to a MockGenerator
+type MockCall struct {
+	Prompt    string
+	Messages  []models.Message
+	Streaming bool
+	Options   GenerateOptions
+}
+
+// MockGenerator is a Generator that returns canned responses without a
+// model. Responses are returned in order and the last one repeats; Respond,
+// when set, answers instead. Every call is recorded in Calls.
+type MockGenerator struct {
+	Responses []string
+	Respond   func(call MockCall) (string, error)
+	Err       error // Returned by every call when set
+
+	mu    sync.Mutex
+	calls []MockCall
+}
+
+// NewMockGenerator creates a mock returning responses in order
+func NewMockGenerator(responses ...string) *MockGenerator {
+	return &MockGenerator{Responses: responses}
+}
+
+// Calls returns the calls made so far
+func (m *MockGenerator) Calls() []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCall(nil), m.calls...)
+}
+
+// respond records call and picks its response
+func (m *MockGenerator) respond(call MockCall) (string, error) {
+	m.mu.Lock()
+	n := len(m.calls)
+	m.calls = append(m.calls, call)
+	m.mu.Unlock()
+
+	switch {
+	case m.Err != nil:
+		return "", m.Err
+	case m.Respond != nil:
+		return m.Respond(call)
+	case len(m.Responses) == 0:
+		return "", nil
+	default:
+		return m.Responses[min(n, len(m.Responses)-1)], nil
+	}
+}
+
+// stream sends a response word by word, or in one token when not streaming
+func (m *MockGenerator) stream(call MockCall) (<-chan string, error) {
+	response, err := m.respond(call)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []string{response}
+	if call.Streaming {
+		tokens = strings.SplitAfter(response, " ")
+	}
+	ch := make(chan string, len(tokens))
+	for _, token := range tokens {
+		ch <- token
+	}
+	close(ch)
+	return ch, nil
+}
+
+// Generate returns the next canned response
+func (m *MockGenerator) Generate(ctx context.Context, prompt string, streaming bool) (<-chan string, error) {
+	return m.stream(MockCall{Prompt: prompt, Streaming: streaming})
+}
+
+// GenerateWithOptions returns the next canned response
+func (m *MockGenerator) GenerateWithOptions(ctx context.Context, prompt string, streaming bool, opts GenerateOptions) (<-chan string, error) {
+	return m.stream(MockCall{Prompt: prompt, Streaming: streaming, Options: opts})
+}
+
+// GenerateSync returns the next canned response
+func (m *MockGenerator) GenerateSync(ctx context.Context, prompt string) (*InferenceResult, error) {
+	return m.GenerateSyncWithOptions(ctx, prompt, GenerateOptions{})
+}
+
+// GenerateSyncWithOptions returns the next canned response
+func (m *MockGenerator) GenerateSyncWithOptions(ctx context.Context, prompt string, opts GenerateOptions) (*InferenceResult, error) {
+	response, err := m.respond(MockCall{Prompt: prompt, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+	return &InferenceResult{Response: response}, nil
+}
+
+// GenerateWithMessages returns the next canned response
+func (m *MockGenerator) GenerateWithMessages(ctx context.Context, messages []models.Message, streaming bool) (<-chan string, error) {
+	return m.stream(MockCall{Messages: messages, Streaming: streaming})
+}
+
+var _ Generator = (*MockGenerator)(nil)
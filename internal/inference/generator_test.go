@@ -0,0 +1,48 @@
+package inference
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestMockGenerator tests that the mock returns responses in order, repeats
+// the last one, streams word by word and records calls
+func TestMockGenerator(t *testing.T) {
+	mock := NewMockGenerator("first answer", "second answer")
+	ctx := context.Background()
+
+	for _, want := range []string{"first answer", "second answer", "second answer"} {
+		result, err := mock.GenerateSync(ctx, "q")
+		if err != nil || result.Response != want {
+			t.Errorf("Expected %q, got %v, %v", want, result, err)
+		}
+	}
+
+	stream, err := mock.GenerateWithOptions(ctx, "stream it", true, GenerateOptions{Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens []string
+	for token := range stream {
+		tokens = append(tokens, token)
+	}
+	if len(tokens) != 2 || strings.Join(tokens, "") != "second answer" {
+		t.Errorf("Expected the answer streamed in two tokens, got %q", tokens)
+	}
+
+	calls := mock.Calls()
+	if len(calls) != 4 || calls[3].Prompt != "stream it" || !calls[3].Streaming || calls[3].Options.Format != "json" {
+		t.Errorf("Unexpected calls: %+v", calls)
+	}
+
+	mock.Respond = func(call MockCall) (string, error) { return "echo " + call.Prompt, nil }
+	if result, _ := mock.GenerateSync(ctx, "hi"); result.Response != "echo hi" {
+		t.Errorf("Expected Respond to answer, got %q", result.Response)
+	}
+	mock.Err = errors.New("offline")
+	if _, err := mock.Generate(ctx, "hi", false); err == nil {
+		t.Error("Expected the configured error")
+	}
+}
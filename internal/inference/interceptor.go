@@ -0,0 +1,136 @@
+package inference
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// maxLoggedChars bounds the prompt and response text LoggingInterceptor records
+const maxLoggedChars = 4000
+
+// Generation is the outcome of a generation call: a token stream for the
+// Generate methods, or a result for the GenerateSync methods
+type Generation struct {
+	Stream <-chan string
+	Result *InferenceResult
+}
+
+// GenerateFunc performs one generation call
+type GenerateFunc func(ctx context.Context, req GenerateRequest) (*Generation, error)
+
+// Interceptor wraps every generation call the client makes. It may change
+// the request before calling next, and inspect or replace what next returns.
+type Interceptor func(next GenerateFunc) GenerateFunc
+
+// Use adds interceptors to the client. The first interceptor added is the
+// outermost: it sees the request first and the response last.
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+// intercept wraps send in the client's interceptors
+func (c *Client) intercept(send GenerateFunc) GenerateFunc {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		send = c.interceptors[i](send)
+	}
+	return send
+}
+
+// TapStream forwards a token stream, calling done with the full text once it
+// ends, so an interceptor can see a streamed response without consuming it
+func TapStream(ctx context.Context, stream <-chan string, done func(text string)) <-chan string {
+	tapped := make(chan string, cap(stream))
+	go func() {
+		defer close(tapped)
+
+		var text strings.Builder
+		defer func() { done(text.String()) }()
+		for token := range stream {
+			text.WriteString(token)
+			select {
+			case tapped <- token:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return tapped
+}
+
+// LoggingInterceptor logs each call's prompt and response at debug level,
+// and failures at warn level
+func LoggingInterceptor(logger *slog.Logger) Interceptor {
+	return func(next GenerateFunc) GenerateFunc {
+		return func(ctx context.Context, req GenerateRequest) (*Generation, error) {
+			start := time.Now()
+			logger.Debug("inference request", "model", req.Model, "stream", req.Stream,
+				"prompt", clip(requestText(req)), "system", clip(req.System))
+
+			gen, err := next(ctx, req)
+			if err != nil {
+				logger.Warn("inference failed", "model", req.Model, "error", err, "duration", time.Since(start))
+				return nil, err
+			}
+
+			logResponse := func(text string) {
+				logger.Debug("inference response", "model", req.Model, "response", clip(text), "duration", time.Since(start))
+			}
+			if gen.Stream != nil {
+				gen.Stream = TapStream(ctx, gen.Stream, logResponse)
+			} else if gen.Result != nil {
+				logResponse(gen.Result.Response)
+				if gen.Result.ModelReloaded() {
+					logger.Info("model loaded", "model", req.Model, "load_duration", gen.Result.LoadDuration)
+				}
+			}
+			return gen, nil
+		}
+	}
+}
+
+// SystemPromptInterceptor adds a system prompt to every call that doesn't
+// already set one: as the system field of generate requests, or as a leading
+// system message in chat requests
+func SystemPromptInterceptor(system string) Interceptor {
+	return func(next GenerateFunc) GenerateFunc {
+		return func(ctx context.Context, req GenerateRequest) (*Generation, error) {
+			if len(req.Messages) > 0 {
+				if req.Messages[0].Role != "system" {
+					req.Messages = append([]models.Message{{Role: "system", Content: system}}, req.Messages...)
+				}
+			} else if req.System == "" {
+				req.System = system
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// requestText is a request's prompt, or its chat messages one per line
+func requestText(req GenerateRequest) string {
+	if len(req.Messages) == 0 {
+		return req.Prompt
+	}
+	lines := make([]string, len(req.Messages))
+	for i, msg := range req.Messages {
+		lines[i] = msg.Role + ": " + msg.Content
+	}
+	return strings.Join(lines, "\n")
+}
+
+// clip shortens logged text to maxLoggedChars
+func clip(text string) string {
+	if len(text) <= maxLoggedChars {
+		return text
+	}
+	return text[:maxLoggedChars] + "..."
+}
@@ -0,0 +1,93 @@
+package inference
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestInterceptors tests that interceptors wrap sync, streaming and chat
+// calls in order, and that the built-in ones log and add a system prompt
+func TestInterceptors(t *testing.T) {
+	var bodies []GenerateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		bodies = append(bodies, req)
+
+		switch {
+		case r.URL.Path == "/api/chat":
+			w.Write([]byte(`{"message":{"role":"assistant","content":"hi"},"done":true}` + "\n"))
+		case req.Stream:
+			w.Write([]byte(`{"response":"str"}` + "\n" + `{"response":"eam","done":true}` + "\n"))
+		default:
+			w.Write([]byte(`{"response":"sync answer","done":true}`))
+		}
+	}))
+	defer server.Close()
+
+	var order []string
+	tag := func(name string) Interceptor {
+		return func(next GenerateFunc) GenerateFunc {
+			return func(ctx context.Context, req GenerateRequest) (*Generation, error) {
+				order = append(order, name+">")
+				gen, err := next(ctx, req)
+				order = append(order, "<"+name)
+				return gen, err
+			}
+		}
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := NewClient(&Config{OllamaURL: server.URL, Model: "test"})
+	client.Use(tag("outer"), tag("inner"))
+	client.Use(SystemPromptInterceptor("Be terse."), LoggingInterceptor(logger))
+
+	result, err := client.GenerateSync(context.Background(), "question")
+	if err != nil || result.Response != "sync answer" {
+		t.Fatalf("GenerateSync: got %v, %v", result, err)
+	}
+	if got := strings.Join(order, " "); got != "outer> inner> <inner <outer" {
+		t.Errorf("Expected the first interceptor outermost, got %s", got)
+	}
+	if bodies[0].System != "Be terse." {
+		t.Errorf("Expected the system prompt to be sent, got %q", bodies[0].System)
+	}
+
+	stream, err := client.Generate(context.Background(), "stream please", true)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	var streamed strings.Builder
+	for token := range stream {
+		streamed.WriteString(token)
+	}
+	if streamed.String() != "stream" {
+		t.Errorf("Expected the stream to pass through, got %q", streamed.String())
+	}
+
+	chat, err := client.GenerateWithMessages(context.Background(), []models.Message{{Role: "user", Content: "hello"}}, true)
+	if err != nil {
+		t.Fatalf("GenerateWithMessages failed: %v", err)
+	}
+	for range chat {
+	}
+	if msgs := bodies[2].Messages; len(msgs) != 2 || msgs[0].Role != "system" || msgs[0].Content != "Be terse." {
+		t.Errorf("Expected a leading system message, got %+v", msgs)
+	}
+
+	for _, want := range []string{`prompt=question`, `response="sync answer"`, `prompt="stream please"`, `response=stream`, `user: hello"`, `response=hi`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Expected the log to contain %s, got:\n%s", want, logs.String())
+		}
+	}
+}
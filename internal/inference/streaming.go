@@ -12,9 +12,12 @@ import (
 type StreamDisplay struct {
 	writer       io.Writer
 	buffer       strings.Builder
+	pending      strings.Builder // Tokens received but not yet shown
 	mu           sync.Mutex
 	tokens       int
+	estimated    bool // Token count comes from word-counting a complete response
 	startTime    time.Time
+	firstToken   time.Time // When the first streamed token arrived
 	lastUpdate   time.Time
 	updateDelay  time.Duration
 	enableColors bool
@@ -31,35 +34,71 @@ func NewStreamDisplay(writer io.Writer, enableColors bool) *StreamDisplay {
 	}
 }
 
+// SetUpdateDelay sets the minimum time between display updates that produces
+// the typewriter effect; zero writes every token immediately
+func (s *StreamDisplay) SetUpdateDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updateDelay = delay
+}
+
 // Write writes a token to the display
 func (s *StreamDisplay) Write(token string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.tokens == 0 {
+		s.firstToken = time.Now()
+	}
 	s.buffer.WriteString(token)
+	s.pending.WriteString(token)
 	s.tokens++
 
-	// Rate-limit updates for smoother display
+	// Rate-limit updates for smoother display; tokens arriving in between are
+	// held and shown with the next update
 	now := time.Now()
 	if now.Sub(s.lastUpdate) >= s.updateDelay {
-		_, err := fmt.Fprint(s.writer, token)
 		s.lastUpdate = now
-		return err
+		return s.flush()
 	}
 
 	return nil
 }
 
+// flush writes any held tokens; callers hold s.mu
+func (s *StreamDisplay) flush() error {
+	if s.pending.Len() == 0 {
+		return nil
+	}
+	_, err := fmt.Fprint(s.writer, s.pending.String())
+	s.pending.Reset()
+	return err
+}
+
+// Flush shows tokens still held back by rate limiting, for callers that
+// report their own statistics instead of calling Finalize
+func (s *StreamDisplay) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
 // WriteAll writes a complete response (non-streaming)
 func (s *StreamDisplay) WriteAll(text string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.flush(); err != nil {
+		return err
+	}
+
 	s.buffer.WriteString(text)
 	tokens := len(strings.Fields(text))
 	s.tokens += tokens
+	s.estimated = true
 
-	_, err := fmt.Fprintln(s.writer, text)
+	// Finalize ends the line, as it does for streamed output
+	_, err := fmt.Fprint(s.writer, text)
 	return err
 }
 
@@ -68,24 +107,36 @@ func (s *StreamDisplay) Finalize() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Flush any buffered content
+	// Flush tokens still held back by rate limiting
+	if err := s.flush(); err != nil {
+		return err
+	}
 	if s.buffer.Len() > 0 {
 		fmt.Fprintln(s.writer)
 	}
 
-	// Display statistics
+	// Display statistics; the rate is measured from the first streamed token
+	// so time spent before generation started is not counted against it
 	duration := time.Since(s.startTime)
+	generation := duration
+	if !s.firstToken.IsZero() {
+		generation = time.Since(s.firstToken)
+	}
 	tokensPerSec := 0.0
-	if duration.Seconds() > 0 {
-		tokensPerSec = float64(s.tokens) / duration.Seconds()
+	if generation.Seconds() > 0 {
+		tokensPerSec = float64(s.tokens) / generation.Seconds()
+	}
+	count := fmt.Sprintf("%d tokens", s.tokens)
+	if s.estimated {
+		count = fmt.Sprintf("~%d tokens (est.)", s.tokens)
 	}
 
 	if s.enableColors {
-		fmt.Fprintf(s.writer, "\n\033[90m⏱ %.2fs | 🚀 %.1f tokens/s | 📝 %d tokens\033[0m\n",
-			duration.Seconds(), tokensPerSec, s.tokens)
+		fmt.Fprintf(s.writer, "\n\033[90m⏱ %.2fs | 🚀 %.1f tokens/s | 📝 %s\033[0m\n",
+			duration.Seconds(), tokensPerSec, count)
 	} else {
-		fmt.Fprintf(s.writer, "\n[%.2fs | %.1f tokens/s | %d tokens]\n",
-			duration.Seconds(), tokensPerSec, s.tokens)
+		fmt.Fprintf(s.writer, "\n[%.2fs | %.1f tokens/s | %s]\n",
+			duration.Seconds(), tokensPerSec, count)
 	}
 
 	return nil
@@ -104,8 +155,11 @@ func (s *StreamDisplay) Reset() {
 	defer s.mu.Unlock()
 
 	s.buffer.Reset()
+	s.pending.Reset()
 	s.tokens = 0
+	s.estimated = false
 	s.startTime = time.Now()
+	s.firstToken = time.Time{}
 	s.lastUpdate = time.Now()
 }
 
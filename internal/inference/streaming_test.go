@@ -0,0 +1,79 @@
+package inference
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStreamDisplayKeepsRateLimitedTokens tests that tokens arriving within the update delay are shown, not dropped
+func TestStreamDisplayKeepsRateLimitedTokens(t *testing.T) {
+	var out bytes.Buffer
+	display := NewStreamDisplay(&out, false)
+	display.SetUpdateDelay(time.Hour)
+
+	tokens := []string{"Hello", ", ", "world", "!"}
+	for _, token := range tokens {
+		if err := display.Write(token); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("Expected tokens to be held within the update delay, got %q", out.String())
+	}
+
+	if err := display.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Hello, world!\n") {
+		t.Errorf("Expected every token to be shown on Finalize, got %q", out.String())
+	}
+	if display.GetContent() != "Hello, world!" {
+		t.Errorf("Unexpected content %q", display.GetContent())
+	}
+}
+
+// TestStreamDisplayFlushesHeldTokens tests that held tokens are shown with the next allowed update
+func TestStreamDisplayFlushesHeldTokens(t *testing.T) {
+	var out bytes.Buffer
+	display := NewStreamDisplay(&out, false)
+	display.SetUpdateDelay(20 * time.Millisecond)
+
+	display.Write("a")
+	display.Write("b")
+	time.Sleep(30 * time.Millisecond)
+	display.Write("c")
+
+	if out.String() != "abc" {
+		t.Errorf("Expected held tokens to be flushed in order, got %q", out.String())
+	}
+}
+
+// TestStreamDisplayLabelsEstimatedTokens tests that word-counted responses are labelled as estimates
+func TestStreamDisplayLabelsEstimatedTokens(t *testing.T) {
+	var out bytes.Buffer
+	display := NewStreamDisplay(&out, false)
+
+	if err := display.WriteAll("one two three"); err != nil {
+		t.Fatalf("WriteAll failed: %v", err)
+	}
+	if err := display.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "one two three\n") {
+		t.Errorf("Expected the response on its own line, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "~3 tokens (est.)") {
+		t.Errorf("Expected an estimated token count, got %q", out.String())
+	}
+
+	display.Reset()
+	out.Reset()
+	display.Write("streamed")
+	display.Finalize()
+	if strings.Contains(out.String(), "est.") || !strings.Contains(out.String(), "| 1 tokens]") {
+		t.Errorf("Expected an exact count for streamed tokens, got %q", out.String())
+	}
+}
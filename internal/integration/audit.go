@@ -3,53 +3,208 @@ package integration
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/quantumflow/quantumflow/internal/logging"
 )
 
+// auditContextKey is the context key for the identity set by WithAuditContext
+type auditContextKey struct{}
+
+// auditContext is the identity attached to a context for audit entries
+type auditContext struct {
+	userID    string
+	requestID string
+}
+
+// WithAuditContext attaches the user and request an operation is performed
+// for, so every connector API call made with the returned context is
+// attributed to them in the audit log
+func WithAuditContext(ctx context.Context, userID, requestID string) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, auditContext{userID: userID, requestID: requestID})
+}
+
+// auditIdentity returns the user and request attached by WithAuditContext,
+// or empty strings
+func auditIdentity(ctx context.Context) (userID, requestID string) {
+	identity, _ := ctx.Value(auditContextKey{}).(auditContext)
+	return identity.userID, identity.requestID
+}
+
+// defaultAuditPruneInterval is how often retention runs when
+// AuditLogOptions.PruneInterval is unset
+const defaultAuditPruneInterval = 24 * time.Hour
+
+// auditDateLayout dates rotated audit files
+const auditDateLayout = "2006-01-02"
+
+// AuditLogOptions configures retention and rotation for a SQLiteAuditLogger
+type AuditLogOptions struct {
+	// RetentionDays prunes entries older than this many days in the
+	// background; 0 keeps them forever
+	RetentionDays int
+
+	// PruneInterval is how often retention runs; defaults to daily
+	PruneInterval time.Duration
+
+	// RotateDaily writes each day's entries to its own file, the configured
+	// path with the date before the extension (audit-2026-10-16.db). Query
+	// and GetStats then only see the current day.
+	RotateDaily bool
+
+	// Logger receives background pruning failures (nil discards them)
+	Logger *slog.Logger
+}
+
 // SQLiteAuditLogger implements audit logging using SQLite
 type SQLiteAuditLogger struct {
-	db *sql.DB
+	path    string // Configured path; rotated files are named after it
+	options AuditLogOptions
+	logger  *slog.Logger
+
+	// mu guards db and day; rotation swaps them under the write lock, so
+	// operations hold the read lock while they use db
+	mu  sync.RWMutex
+	db  *sql.DB
+	day string // Date of the open file when rotating
+
+	// cancel stops background pruning; done is closed once it has returned
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
-// NewSQLiteAuditLogger creates a new SQLite audit logger
+// NewSQLiteAuditLogger creates a new SQLite audit logger that keeps every
+// entry in one file
 func NewSQLiteAuditLogger(dbPath string) (*SQLiteAuditLogger, error) {
+	return NewSQLiteAuditLoggerWithOptions(dbPath, AuditLogOptions{})
+}
+
+// NewAuditLogger creates the SQLite audit logger described by the audit
+// settings in config, or returns nil when audit logging is disabled
+func NewAuditLogger(config *Config) (*SQLiteAuditLogger, error) {
+	if !config.AuditLogEnabled {
+		return nil, nil
+	}
+	return NewSQLiteAuditLoggerWithOptions(config.AuditLogPath, AuditLogOptions{
+		RetentionDays: config.AuditRetentionDays,
+		RotateDaily:   config.AuditRotateDaily,
+	})
+}
+
+// NewSQLiteAuditLoggerWithOptions creates a SQLite audit logger with
+// retention and rotation. A leading ~/ in dbPath is the home directory. With
+// RetentionDays set, old entries are pruned now and then every PruneInterval
+// until Close.
+func NewSQLiteAuditLoggerWithOptions(dbPath string, options AuditLogOptions) (*SQLiteAuditLogger, error) {
 	// Expand path
 	if strings.HasPrefix(dbPath, "~/") {
 		home, _ := os.UserHomeDir()
 		dbPath = filepath.Join(home, dbPath[2:])
 	}
 
+	logger := &SQLiteAuditLogger{
+		path:    dbPath,
+		options: options,
+		logger:  logging.OrDiscard(options.Logger),
+	}
+
+	now := time.Now()
+	db, err := openAuditDB(logger.pathFor(now))
+	if err != nil {
+		return nil, err
+	}
+	logger.db = db
+	logger.day = now.Format(auditDateLayout)
+
+	if options.RetentionDays > 0 {
+		logger.startRetention()
+	}
+
+	return logger, nil
+}
+
+// openAuditDB opens the audit database at path, creating it if needed
+func openAuditDB(path string) (*sql.DB, error) {
 	// Create directory if it doesn't exist
-	dir := filepath.Dir(dbPath)
+	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	// Open database
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	logger := &SQLiteAuditLogger{db: db}
-
 	// Initialize schema
-	if err := logger.initSchema(); err != nil {
+	if err := initAuditSchema(db); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
-	return logger, nil
+	return db, nil
 }
 
-// initSchema creates the audit log table
-func (a *SQLiteAuditLogger) initSchema() error {
+// pathFor is the file holding entries logged at t
+func (a *SQLiteAuditLogger) pathFor(t time.Time) string {
+	if !a.options.RotateDaily {
+		return a.path
+	}
+	ext := filepath.Ext(a.path)
+	return strings.TrimSuffix(a.path, ext) + "-" + t.Format(auditDateLayout) + ext
+}
+
+// rotate switches to the file for now's date when rotating daily
+func (a *SQLiteAuditLogger) rotate(now time.Time) error {
+	if !a.options.RotateDaily {
+		return nil
+	}
+
+	day := now.Format(auditDateLayout)
+	a.mu.RLock()
+	current := a.day
+	a.mu.RUnlock()
+	if current == day {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.day == day {
+		return nil
+	}
+	db, err := openAuditDB(a.pathFor(now))
+	if err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	a.db.Close()
+	a.db, a.day = db, day
+	return nil
+}
+
+// withDB runs fn against the current file, rotating first if the day changed
+func (a *SQLiteAuditLogger) withDB(fn func(db *sql.DB) error) error {
+	if err := a.rotate(time.Now()); err != nil {
+		return err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return fn(a.db)
+}
+
+// initAuditSchema creates the audit log table
+func initAuditSchema(db *sql.DB) error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS audit_log (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -71,9 +226,10 @@ func (a *SQLiteAuditLogger) initSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_timestamp ON audit_log(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_service ON audit_log(service);
 	CREATE INDEX IF NOT EXISTS idx_user_id ON audit_log(user_id);
+	CREATE INDEX IF NOT EXISTS idx_request_id ON audit_log(request_id);
 	`
 
-	_, err := a.db.Exec(schema)
+	_, err := db.Exec(schema)
 	return err
 }
 
@@ -92,27 +248,28 @@ func (a *SQLiteAuditLogger) Log(ctx context.Context, entry *AuditEntry) error {
 		_ = entry.Metadata
 	}
 
-	_, err := a.db.ExecContext(ctx, query,
-		entry.Timestamp,
-		entry.Service,
-		entry.Operation,
-		entry.UserID,
-		entry.RequestID,
-		entry.Method,
-		entry.Endpoint,
-		entry.StatusCode,
-		entry.Duration.Milliseconds(),
-		entry.Success,
-		entry.Error,
-		metadataJSON,
-	)
-
-	return err
+	return a.withDB(func(db *sql.DB) error {
+		_, err := db.ExecContext(ctx, query,
+			entry.Timestamp,
+			entry.Service,
+			entry.Operation,
+			entry.UserID,
+			entry.RequestID,
+			entry.Method,
+			entry.Endpoint,
+			entry.StatusCode,
+			entry.Duration.Milliseconds(),
+			entry.Success,
+			entry.Error,
+			metadataJSON,
+		)
+		return err
+	})
 }
 
 // Query retrieves audit logs
 func (a *SQLiteAuditLogger) Query(ctx context.Context, filter *AuditFilter) ([]*AuditEntry, error) {
-	query := "SELECT id, timestamp, service, operation, user_id, method, endpoint, status_code, duration_ms, success, error FROM audit_log WHERE 1=1"
+	query := "SELECT id, timestamp, service, operation, user_id, request_id, method, endpoint, status_code, duration_ms, success, error FROM audit_log WHERE 1=1"
 	args := []interface{}{}
 
 	if filter.Service != nil {
@@ -135,6 +292,11 @@ func (a *SQLiteAuditLogger) Query(ctx context.Context, filter *AuditFilter) ([]*
 		args = append(args, *filter.UserID)
 	}
 
+	if filter.RequestID != nil {
+		query += " AND request_id = ?"
+		args = append(args, *filter.RequestID)
+	}
+
 	if filter.Success != nil {
 		query += " AND success = ?"
 		args = append(args, *filter.Success)
@@ -152,6 +314,8 @@ func (a *SQLiteAuditLogger) Query(ctx context.Context, filter *AuditFilter) ([]*
 		args = append(args, filter.Offset)
 	}
 
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	rows, err := a.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -170,6 +334,7 @@ func (a *SQLiteAuditLogger) Query(ctx context.Context, filter *AuditFilter) ([]*
 			&entry.Service,
 			&entry.Operation,
 			&entry.UserID,
+			&entry.RequestID,
 			&entry.Method,
 			&entry.Endpoint,
 			&entry.StatusCode,
@@ -189,8 +354,101 @@ func (a *SQLiteAuditLogger) Query(ctx context.Context, filter *AuditFilter) ([]*
 	return entries, rows.Err()
 }
 
-// Close closes the database connection
+// Prune deletes entries logged before olderThan and vacuums the file to
+// reclaim their space, returning how many were deleted. When rotating daily,
+// files for days entirely before olderThan are removed as well.
+func (a *SQLiteAuditLogger) Prune(ctx context.Context, olderThan time.Time) (int64, error) {
+	var deleted int64
+	err := a.withDB(func(db *sql.DB) error {
+		result, err := db.ExecContext(ctx, "DELETE FROM audit_log WHERE timestamp < ?", olderThan)
+		if err != nil {
+			return fmt.Errorf("failed to prune audit log: %w", err)
+		}
+		if deleted, err = result.RowsAffected(); err != nil || deleted == 0 {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, "VACUUM"); err != nil {
+			return fmt.Errorf("failed to vacuum audit log: %w", err)
+		}
+		return nil
+	})
+	if err != nil || !a.options.RotateDaily {
+		return deleted, err
+	}
+
+	return deleted, a.removeRotated(olderThan)
+}
+
+// removeRotated deletes rotated files for days before olderThan's date
+func (a *SQLiteAuditLogger) removeRotated(olderThan time.Time) error {
+	ext := filepath.Ext(a.path)
+	prefix := strings.TrimSuffix(a.path, ext) + "-"
+	files, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return err
+	}
+
+	cutoff := olderThan.Format(auditDateLayout)
+	var errs []error
+	for _, file := range files {
+		day := strings.TrimSuffix(strings.TrimPrefix(file, prefix), ext)
+		if _, err := time.Parse(auditDateLayout, day); err != nil || day >= cutoff {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// startRetention prunes entries past RetentionDays now and then every
+// PruneInterval until Close
+func (a *SQLiteAuditLogger) startRetention() {
+	interval := a.options.PruneInterval
+	if interval <= 0 {
+		interval = defaultAuditPruneInterval
+	}
+
+	var ctx context.Context
+	ctx, a.cancel = context.WithCancel(context.Background())
+	a.done = make(chan struct{})
+	go a.runRetention(ctx, interval)
+}
+
+// runRetention is the background pruning loop
+func (a *SQLiteAuditLogger) runRetention(ctx context.Context, interval time.Duration) {
+	defer close(a.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().AddDate(0, 0, -a.options.RetentionDays)
+		if deleted, err := a.Prune(ctx, cutoff); err != nil {
+			if ctx.Err() == nil {
+				a.logger.Warn("audit log pruning failed", "error", err)
+			}
+		} else if deleted > 0 {
+			a.logger.Info("pruned audit log", "deleted", deleted, "retention_days", a.options.RetentionDays)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops background pruning and closes the database connection
 func (a *SQLiteAuditLogger) Close() error {
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	return a.db.Close()
 }
 
@@ -208,11 +466,13 @@ func (a *SQLiteAuditLogger) GetStats(ctx context.Context, service ServiceType, s
 	var stats AuditStats
 	var avgDuration sql.NullFloat64
 
+	a.mu.RLock()
 	err := a.db.QueryRowContext(ctx, query, service, since).Scan(
 		&stats.TotalRequests,
 		&stats.SuccessfulRequests,
 		&avgDuration,
 	)
+	a.mu.RUnlock()
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,228 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordingAuditor keeps logged entries
+type recordingAuditor struct {
+	entries []*AuditEntry
+}
+
+func (a *recordingAuditor) Log(ctx context.Context, entry *AuditEntry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func (a *recordingAuditor) Query(ctx context.Context, filter *AuditFilter) ([]*AuditEntry, error) {
+	return a.entries, nil
+}
+
+// TestWithAuditContext tests that the user and request attached to a
+// context are recorded on every API call made with it
+func TestWithAuditContext(t *testing.T) {
+	slack, _ := newTestSlack(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok": true, "messages": [], "channel": "C1", "ts": "1.0"}`)
+	})
+	auditor := &recordingAuditor{}
+	slack.auditor = auditor
+
+	ctx := WithAuditContext(context.Background(), "U123", "req-42")
+	if _, err := slack.PostMessage(ctx, "C1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := slack.GetChannelHistory(ctx, "C1", 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := slack.GetChannelHistory(context.Background(), "C1", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(auditor.entries) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d", len(auditor.entries))
+	}
+	for _, entry := range auditor.entries[:2] {
+		if entry.UserID != "U123" || entry.RequestID != "req-42" {
+			t.Errorf("Expected the call attributed to U123/req-42, got %q/%q", entry.UserID, entry.RequestID)
+		}
+	}
+	if last := auditor.entries[2]; last.UserID != "" || last.RequestID != "" {
+		t.Errorf("Expected an unattributed call, got %q/%q", last.UserID, last.RequestID)
+	}
+}
+
+// TestSQLiteAuditLoggerRequestID tests that request IDs are stored, returned
+// and filterable
+func TestSQLiteAuditLoggerRequestID(t *testing.T) {
+	logger, err := NewSQLiteAuditLogger(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	ctx := context.Background()
+
+	for i, request := range []string{"req-1", "req-2", "req-1"} {
+		entry := &AuditEntry{Timestamp: time.Now().Add(time.Duration(i) * time.Second), Service: ServiceTypeSlack, Operation: "GET /x", UserID: "U1", RequestID: request, Success: true}
+		if err := logger.Log(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := "req-1"
+	entries, err := logger.Query(ctx, &AuditFilter{RequestID: &request})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].RequestID != "req-1" || entries[0].UserID != "U1" {
+		t.Errorf("Expected the 2 req-1 entries, got %+v", entries)
+	}
+}
+
+// logAt records a successful entry at t
+func logAt(t *testing.T, logger *SQLiteAuditLogger, at time.Time) {
+	t.Helper()
+	if err := logger.Log(context.Background(), &AuditEntry{Timestamp: at, Service: ServiceTypeGitHub, Operation: "GET /x", Success: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSQLiteAuditLoggerPrune tests deleting entries before a cutoff
+func TestSQLiteAuditLoggerPrune(t *testing.T) {
+	logger, err := NewSQLiteAuditLogger(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	for _, age := range []int{200, 100, 10, 0} {
+		logAt(t, logger, now.AddDate(0, 0, -age))
+	}
+
+	deleted, err := logger.Prune(ctx, now.AddDate(0, 0, -90))
+	if err != nil || deleted != 2 {
+		t.Fatalf("Expected 2 entries pruned, got %d, %v", deleted, err)
+	}
+	entries, err := logger.Query(ctx, &AuditFilter{})
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Expected 2 entries left, got %d, %v", len(entries), err)
+	}
+	if deleted, err := logger.Prune(ctx, now.AddDate(0, 0, -90)); err != nil || deleted != 0 {
+		t.Errorf("Expected nothing left to prune, got %d, %v", deleted, err)
+	}
+}
+
+// TestSQLiteAuditLoggerRotation tests writing each day to its own file and
+// pruning whole files past retention
+func TestSQLiteAuditLoggerRotation(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := NewSQLiteAuditLoggerWithOptions(filepath.Join(dir, "audit.db"), AuditLogOptions{RotateDaily: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	today := filepath.Join(dir, "audit-"+now.Format(auditDateLayout)+".db")
+	logAt(t, logger, now)
+	if _, err := os.Stat(today); err != nil {
+		t.Fatalf("Expected today's file: %v", err)
+	}
+
+	// A new day starts a new file
+	tomorrow := now.AddDate(0, 0, 1)
+	if err := logger.rotate(tomorrow); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := logger.Query(ctx, &AuditFilter{}); err != nil || len(entries) != 0 {
+		t.Errorf("Expected an empty file for the new day, got %d, %v", len(entries), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "audit-"+tomorrow.Format(auditDateLayout)+".db")); err != nil {
+		t.Errorf("Expected tomorrow's file: %v", err)
+	}
+
+	for _, name := range []string{"audit-2020-01-01.db", "audit-notes.db", "other.db"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := logger.Prune(ctx, now.AddDate(0, 0, -30)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "audit-2020-01-01.db")); !os.IsNotExist(err) {
+		t.Errorf("Expected the expired file removed, got %v", err)
+	}
+	for _, name := range []string{filepath.Base(today), "audit-notes.db", "other.db"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s kept: %v", name, err)
+		}
+	}
+}
+
+// TestSQLiteAuditLoggerRetention tests that retention prunes on start and
+// stops with Close
+func TestSQLiteAuditLoggerRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	logger, err := NewSQLiteAuditLogger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logAt(t, logger, time.Now().AddDate(0, 0, -40))
+	logAt(t, logger, time.Now())
+	logger.Close()
+
+	logger, err = NewSQLiteAuditLoggerWithOptions(path, AuditLogOptions{RetentionDays: 30, PruneInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries, err := logger.Query(context.Background(), &AuditFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the expired entry pruned, have %d entries", len(entries))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := logger.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+// TestNewAuditLogger tests that the configured path is expanded from the home
+// directory and that a disabled audit log isn't opened
+func TestNewAuditLogger(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	config := DefaultConfig()
+	auditLog, err := NewAuditLogger(config)
+	if err != nil || auditLog == nil {
+		t.Fatalf("Expected the default audit log opened, got %v", err)
+	}
+	defer auditLog.Close()
+	if _, err := os.Stat(filepath.Join(home, ".quantumflow", "audit.db")); err != nil {
+		t.Errorf("Expected the audit log under the home directory: %v", err)
+	}
+	if auditLog.options.RetentionDays != 0 {
+		t.Errorf("Expected entries kept forever by default, got %d days", auditLog.options.RetentionDays)
+	}
+
+	config.AuditLogEnabled = false
+	if disabled, err := NewAuditLogger(config); disabled != nil || err != nil {
+		t.Errorf("Expected no audit log when disabled, got %v, %v", disabled, err)
+	}
+}
@@ -2,12 +2,17 @@ package integration
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/quantumflow/quantumflow/internal/logging"
 )
 
 // GitHubConnector implements GitHub API integration
@@ -18,6 +23,7 @@ type GitHubConnector struct {
 	rateLimiter RateLimiter
 	auditor     AuditLogger
 	httpClient  *http.Client
+	logger      *slog.Logger
 	connected   bool
 	mu          sync.RWMutex
 }
@@ -34,9 +40,8 @@ func NewGitHubConnector(
 		vault:       vault,
 		rateLimiter: rateLimiter,
 		auditor:     auditor,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient:  newHTTPClient(config.HTTPOptions),
+		logger:      logging.OrDiscard(config.Logger),
 	}
 }
 
@@ -139,7 +144,7 @@ func (g *GitHubConnector) GetCommits(ctx context.Context, owner, repo string, si
 
 // SearchCode searches code across repositories
 func (g *GitHubConnector) SearchCode(ctx context.Context, query string) (*SearchResults, error) {
-	endpoint := fmt.Sprintf("/search/code?q=%s", query)
+	endpoint := fmt.Sprintf("/search/code?q=%s", url.QueryEscape(query))
 	
 	var result SearchResults
 	if err := g.apiCall(ctx, "GET", endpoint, nil, &result); err != nil {
@@ -149,6 +154,60 @@ func (g *GitHubConnector) SearchCode(ctx context.Context, query string) (*Search
 	return &result, nil
 }
 
+// Search implements Searchable with a code search, one resource per file
+func (g *GitHubConnector) Search(ctx context.Context, query string) ([]*Resource, error) {
+	results, err := g.SearchCode(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*Resource, len(results.Items))
+	for i, item := range results.Items {
+		resources[i] = &Resource{
+			Service: ServiceTypeGitHub,
+			Type:    "code",
+			ID:      item.Repository.FullName + ":" + item.Path,
+			Title:   item.Repository.FullName + "/" + item.Path,
+			URL:     item.HTMLURL,
+		}
+	}
+	return resources, nil
+}
+
+// GetTree lists every entry of a repository tree, recursively. ref may be a
+// branch, tag or commit SHA.
+func (g *GitHubConnector) GetTree(ctx context.Context, owner, repo, ref string) (*Tree, error) {
+	endpoint := fmt.Sprintf("/repos/%s/%s/git/trees/%s?recursive=1", owner, repo, url.PathEscape(ref))
+
+	var result Tree
+	if err := g.apiCall(ctx, "GET", endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetFileContents fetches a file from a repository at ref
+func (g *GitHubConnector) GetFileContents(ctx context.Context, owner, repo, path, ref string) (*FileContents, error) {
+	endpoint := fmt.Sprintf("/repos/%s/%s/contents/%s?ref=%s", owner, repo, escapePath(path), url.QueryEscape(ref))
+
+	var result FileContents
+	if err := g.apiCall(ctx, "GET", endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// escapePath escapes each segment of a repository path for a URL
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
 // apiCall makes an authenticated API call to GitHub
 func (g *GitHubConnector) apiCall(ctx context.Context, method, endpoint string, body interface{}, result interface{}) error {
 	startTime := time.Now()
@@ -163,7 +222,7 @@ func (g *GitHubConnector) apiCall(ctx context.Context, method, endpoint string,
 	if g.config.EnterpriseURL != "" {
 		baseURL = g.config.EnterpriseURL
 	}
-	url := baseURL + endpoint
+	requestURL := baseURL + endpoint
 
 	// Create request
 	var req *http.Request
@@ -174,9 +233,9 @@ func (g *GitHubConnector) apiCall(ctx context.Context, method, endpoint string,
 		if err != nil {
 			return fmt.Errorf("failed to marshal body: %w", err)
 		}
-		req, err = http.NewRequestWithContext(ctx, method, url, strings.NewReader(string(bodyJSON)))
+		req, err = http.NewRequestWithContext(ctx, method, requestURL, strings.NewReader(string(bodyJSON)))
 	} else {
-		req, err = http.NewRequestWithContext(ctx, method, url, nil)
+		req, err = http.NewRequestWithContext(ctx, method, requestURL, nil)
 	}
 
 	if err != nil {
@@ -233,8 +292,11 @@ func (g *GitHubConnector) logAudit(ctx context.Context, method, endpoint string,
 		Success:    success,
 		Error:      errorMsg,
 	}
+	entry.UserID, entry.RequestID = auditIdentity(ctx)
 
-	_ = g.auditor.Log(ctx, entry) // Ignore audit logging errors
+	if err := g.auditor.Log(ctx, entry); err != nil {
+		g.logger.Warn("audit log failed", "service", "github", "operation", entry.Operation, "error", err)
+	}
 }
 
 // GitHub data models
@@ -296,5 +358,36 @@ type SearchResults struct {
 type SearchItem struct {
 	Name       string     `json:"name"`
 	Path       string     `json:"path"`
+	HTMLURL    string     `json:"html_url"`
 	Repository Repository `json:"repository"`
 }
+
+type Tree struct {
+	SHA       string      `json:"sha"`
+	Tree      []TreeEntry `json:"tree"`
+	Truncated bool        `json:"truncated"` // GitHub stopped listing; the tree is incomplete
+}
+
+type TreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"` // "blob", "tree" or "commit" (submodule)
+	SHA  string `json:"sha"`
+	Size int64  `json:"size"`
+}
+
+type FileContents struct {
+	Path     string `json:"path"`
+	SHA      string `json:"sha"`
+	Size     int64  `json:"size"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+}
+
+// Decode returns the file's content, which GitHub sends base64 encoded
+func (f *FileContents) Decode() ([]byte, error) {
+	if f.Encoding != "base64" {
+		return []byte(f.Content), nil
+	}
+	// GitHub wraps the encoded content at 60 characters
+	return base64.StdEncoding.DecodeString(strings.ReplaceAll(f.Content, "\n", ""))
+}
@@ -0,0 +1,348 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/quantumflow/quantumflow/internal/logging"
+)
+
+const (
+	// defaultIndexMaxFileSize is the largest file indexed; bigger files are
+	// usually generated or vendored
+	defaultIndexMaxFileSize = 100 * 1024
+
+	// defaultIndexChunkLines is how many lines of a file go into one chunk
+	defaultIndexChunkLines = 60
+
+	// binarySniffLen is how much of a file is checked for NUL bytes
+	binarySniffLen = 8000
+)
+
+// binaryExtensions are skipped without fetching them
+var binaryExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".ico": true, ".webp": true, ".bmp": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tgz": true, ".tar": true, ".jar": true, ".7z": true,
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".a": true, ".o": true, ".class": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp3": true, ".mp4": true, ".mov": true, ".wav": true, ".db": true, ".sqlite": true,
+}
+
+// DocumentStore receives the chunks an indexer produces; memory.Service
+// satisfies it
+type DocumentStore interface {
+	StoreDocument(ctx context.Context, id, content string, metadata map[string]interface{}) error
+}
+
+// repoSource is the part of GitHubConnector the indexer uses
+type repoSource interface {
+	GetTree(ctx context.Context, owner, repo, ref string) (*Tree, error)
+	GetFileContents(ctx context.Context, owner, repo, path, ref string) (*FileContents, error)
+	GetRateLimits() *RateLimitStatus
+}
+
+// IndexerOptions configures a GitHubIndexer; zero values use the defaults
+type IndexerOptions struct {
+	MaxFileSize int64  // Files larger than this are skipped
+	ChunkLines  int    // Lines per stored chunk
+	StateDir    string // Where progress is saved for resuming (default ~/.quantumflow/index)
+
+	// Logger receives files that could not be indexed (nil discards them)
+	Logger *slog.Logger
+}
+
+// IndexState is the saved progress of indexing a repository. Files are
+// recorded by blob SHA, so an interrupted run resumes where it stopped and a
+// run against a newer tree only fetches files that changed.
+type IndexState struct {
+	Owner     string            `json:"owner"`
+	Repo      string            `json:"repo"`
+	TreeSHA   string            `json:"tree_sha"`
+	Complete  bool              `json:"complete"` // Every file of TreeSHA was handled
+	Files     map[string]string `json:"files"`    // path -> blob SHA indexed or skipped
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// IndexResult summarizes one indexing run
+type IndexResult struct {
+	TreeSHA   string
+	Indexed   int // Files fetched and stored
+	Chunks    int
+	Unchanged int // Files already indexed at the same blob SHA
+	Skipped   int // Binary, oversized or unreadable files
+	Truncated bool
+}
+
+// GitHubIndexer fetches a repository's files through the GitHub connector,
+// splits them into chunks and stores them as documents, so memory retrieval
+// surfaces relevant code for the CodeAgent
+type GitHubIndexer struct {
+	source repoSource
+	store  DocumentStore
+	opts   IndexerOptions
+	logger *slog.Logger
+}
+
+// NewGitHubIndexer creates an indexer that reads through connector and writes
+// chunks to store
+func NewGitHubIndexer(connector *GitHubConnector, store DocumentStore, opts IndexerOptions) *GitHubIndexer {
+	return newGitHubIndexer(connector, store, opts)
+}
+
+// newGitHubIndexer creates an indexer over any repository source
+func newGitHubIndexer(source repoSource, store DocumentStore, opts IndexerOptions) *GitHubIndexer {
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = defaultIndexMaxFileSize
+	}
+	if opts.ChunkLines <= 0 {
+		opts.ChunkLines = defaultIndexChunkLines
+	}
+	if opts.StateDir == "" {
+		homeDir, _ := os.UserHomeDir()
+		opts.StateDir = filepath.Join(homeDir, ".quantumflow", "index")
+	}
+
+	return &GitHubIndexer{
+		source: source,
+		store:  store,
+		opts:   opts,
+		logger: logging.OrDiscard(opts.Logger),
+	}
+}
+
+// Index stores the files of owner/repo at ref. Progress is saved after every
+// file, so a cancelled or failed run picks up where it stopped; API calls wait
+// out the connector's rate limit.
+func (x *GitHubIndexer) Index(ctx context.Context, owner, repo, ref string) (*IndexResult, error) {
+	state, err := x.loadState(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := x.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+	tree, err := x.source.GetTree(ctx, owner, repo, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s/%s at %s: %w", owner, repo, ref, err)
+	}
+
+	result := &IndexResult{TreeSHA: tree.SHA, Truncated: tree.Truncated}
+	if tree.Truncated {
+		x.logger.Warn("repository tree truncated; indexing the files listed", "repo", owner+"/"+repo)
+	}
+	if state.TreeSHA == tree.SHA && state.Complete {
+		return result, nil
+	}
+	if state.TreeSHA != tree.SHA {
+		state.TreeSHA = tree.SHA
+		state.Complete = false
+	}
+
+	for _, entry := range tree.Tree {
+		if err := ctx.Err(); err != nil {
+			return result, x.stopIndexing(state, err)
+		}
+		if entry.Type != "blob" {
+			continue
+		}
+		if state.Files[entry.Path] == entry.SHA {
+			result.Unchanged++
+			continue
+		}
+
+		if reason := skipReason(entry, x.opts.MaxFileSize); reason != "" {
+			x.logger.Debug("skipping file", "path", entry.Path, "reason", reason)
+			result.Skipped++
+		} else {
+			chunks, err := x.indexFile(ctx, owner, repo, tree.SHA, entry)
+			switch {
+			case errors.Is(err, errBinaryFile):
+				result.Skipped++
+			case err != nil && ctx.Err() != nil:
+				return result, x.stopIndexing(state, ctx.Err())
+			case err != nil:
+				// Left unrecorded so the next run tries the file again
+				x.logger.Warn("failed to index file", "path", entry.Path, "error", err)
+				result.Skipped++
+				continue
+			default:
+				result.Indexed++
+				result.Chunks += chunks
+			}
+		}
+
+		state.Files[entry.Path] = entry.SHA
+		if err := x.saveState(state); err != nil {
+			return result, err
+		}
+	}
+
+	state.Complete = true
+	return result, x.saveState(state)
+}
+
+// errBinaryFile marks a fetched file whose content isn't text
+var errBinaryFile = errors.New("binary file")
+
+// indexFile fetches one file and stores its chunks, returning how many
+func (x *GitHubIndexer) indexFile(ctx context.Context, owner, repo, treeSHA string, entry TreeEntry) (int, error) {
+	if err := x.waitForRateLimit(ctx); err != nil {
+		return 0, err
+	}
+	file, err := x.source.GetFileContents(ctx, owner, repo, entry.Path, treeSHA)
+	if err != nil {
+		return 0, err
+	}
+	content, err := file.Decode()
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode content: %w", err)
+	}
+	if isBinary(content) {
+		return 0, errBinaryFile
+	}
+
+	chunks := chunkLines(string(content), x.opts.ChunkLines)
+	for i, chunk := range chunks {
+		id := fmt.Sprintf("code:%s/%s:%s:%d", owner, repo, entry.Path, i)
+		text := fmt.Sprintf("%s/%s %s (lines %d-%d)\n%s", owner, repo, entry.Path, chunk.start, chunk.end, chunk.text)
+		metadata := map[string]interface{}{
+			"source": "github",
+			"repo":   owner + "/" + repo,
+			"path":   entry.Path,
+			"sha":    entry.SHA,
+			"lines":  fmt.Sprintf("%d-%d", chunk.start, chunk.end),
+		}
+		if err := x.store.StoreDocument(ctx, id, text, metadata); err != nil {
+			return i, fmt.Errorf("failed to store chunk %d: %w", i, err)
+		}
+	}
+	return len(chunks), nil
+}
+
+// waitForRateLimit blocks until the connector's rate limit resets when no
+// requests remain
+func (x *GitHubIndexer) waitForRateLimit(ctx context.Context) error {
+	status := x.source.GetRateLimits()
+	if status == nil || status.Remaining > 0 {
+		return nil
+	}
+
+	wait := time.Until(status.Reset)
+	if status.RetryAfter > wait {
+		wait = status.RetryAfter
+	}
+	if wait <= 0 {
+		return nil
+	}
+	x.logger.Info("rate limit reached; waiting", "wait", wait.Round(time.Second))
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopIndexing saves progress when a run is interrupted
+func (x *GitHubIndexer) stopIndexing(state *IndexState, cause error) error {
+	if err := x.saveState(state); err != nil {
+		return fmt.Errorf("%w (and saving progress failed: %w)", cause, err)
+	}
+	return cause
+}
+
+// statePath returns where a repository's index state is saved
+func (x *GitHubIndexer) statePath(owner, repo string) string {
+	return filepath.Join(x.opts.StateDir, owner+"_"+repo+".json")
+}
+
+// loadState reads a repository's index state, or starts a fresh one
+func (x *GitHubIndexer) loadState(owner, repo string) (*IndexState, error) {
+	state := &IndexState{Owner: owner, Repo: repo, Files: make(map[string]string)}
+
+	data, err := os.ReadFile(x.statePath(owner, repo))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse index state: %w", err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]string)
+	}
+	return state, nil
+}
+
+// saveState writes a repository's index state
+func (x *GitHubIndexer) saveState(state *IndexState) error {
+	if err := os.MkdirAll(x.opts.StateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create index state directory: %w", err)
+	}
+
+	state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index state: %w", err)
+	}
+	if err := os.WriteFile(x.statePath(state.Owner, state.Repo), data, 0644); err != nil {
+		return fmt.Errorf("failed to save index state: %w", err)
+	}
+	return nil
+}
+
+// skipReason reports why a tree entry shouldn't be fetched, or ""
+func skipReason(entry TreeEntry, maxSize int64) string {
+	if entry.Size > maxSize {
+		return "too large"
+	}
+	if binaryExtensions[strings.ToLower(path.Ext(entry.Path))] {
+		return "binary"
+	}
+	return ""
+}
+
+// isBinary reports whether content looks like binary data rather than text
+func isBinary(content []byte) bool {
+	sniff := content[:min(len(content), binarySniffLen)]
+	return bytes.IndexByte(sniff, 0) >= 0 || !utf8.Valid(sniff)
+}
+
+// lineChunk is a run of lines from a file; start and end are 1-based
+type lineChunk struct {
+	start, end int
+	text       string
+}
+
+// chunkLines splits text into chunks of at most size lines, dropping chunks
+// that are only whitespace
+func chunkLines(text string, size int) []lineChunk {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+	var chunks []lineChunk
+	for start := 0; start < len(lines); start += size {
+		end := min(start+size, len(lines))
+		chunk := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(chunk) == "" {
+			continue
+		}
+		chunks = append(chunks, lineChunk{start: start + 1, end: end, text: chunk})
+	}
+	return chunks
+}
@@ -0,0 +1,143 @@
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubRepo serves a fixed tree and file contents
+type stubRepo struct {
+	tree    *Tree
+	files   map[string]string
+	fetched []string
+	limits  *RateLimitStatus
+	onFetch func(path string) // Called after each file fetch
+}
+
+func (r *stubRepo) GetTree(ctx context.Context, owner, repo, ref string) (*Tree, error) {
+	return r.tree, nil
+}
+
+func (r *stubRepo) GetFileContents(ctx context.Context, owner, repo, path, ref string) (*FileContents, error) {
+	r.fetched = append(r.fetched, path)
+	if r.onFetch != nil {
+		r.onFetch(path)
+	}
+	content := base64.StdEncoding.EncodeToString([]byte(r.files[path]))
+	return &FileContents{Path: path, Encoding: "base64", Content: content}, nil
+}
+
+func (r *stubRepo) GetRateLimits() *RateLimitStatus {
+	return r.limits
+}
+
+// recordingStore keeps stored documents by ID
+type recordingStore struct {
+	docs map[string]string
+}
+
+func (s *recordingStore) StoreDocument(ctx context.Context, id, content string, metadata map[string]interface{}) error {
+	s.docs[id] = content
+	return nil
+}
+
+// TestGitHubIndexerResumes tests skipping binary and large files, resuming an
+// interrupted run and re-fetching only files whose blob changed
+func TestGitHubIndexerResumes(t *testing.T) {
+	repo := &stubRepo{
+		tree: &Tree{SHA: "tree1", Tree: []TreeEntry{
+			{Path: "cmd", Type: "tree"},
+			{Path: "cmd/main.go", Type: "blob", SHA: "a1", Size: 40},
+			{Path: "logo.png", Type: "blob", SHA: "b1", Size: 10},
+			{Path: "dump.sql", Type: "blob", SHA: "c1", Size: 1 << 20},
+			{Path: "data.bin", Type: "blob", SHA: "d1", Size: 4},
+			{Path: "README.md", Type: "blob", SHA: "e1", Size: 30},
+		}},
+		files: map[string]string{
+			"cmd/main.go": "package main\n\nfunc main() {}\n",
+			"data.bin":    "\x00\x01\x02\x03",
+			"README.md":   "# Demo\nline 2\nline 3\n",
+		},
+	}
+	store := &recordingStore{docs: make(map[string]string)}
+	indexer := newGitHubIndexer(repo, store, IndexerOptions{ChunkLines: 2, StateDir: t.TempDir()})
+
+	// Interrupt after the first file
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.onFetch = func(string) { cancel() }
+	if _, err := indexer.Index(ctx, "octo", "demo", "main"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the run to be cancelled, got %v", err)
+	}
+	if len(repo.fetched) != 1 || len(store.docs) != 2 {
+		t.Fatalf("Expected only the first file to be indexed, got %v, %v", repo.fetched, store.docs)
+	}
+
+	repo.onFetch = nil
+	repo.fetched = nil
+	result, err := indexer.Index(context.Background(), "octo", "demo", "main")
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if strings.Join(repo.fetched, ",") != "data.bin,README.md" {
+		t.Errorf("Expected to resume after main.go, skipping binary and large files unfetched, got %v", repo.fetched)
+	}
+	if result.Unchanged != 1 || result.Indexed != 1 || result.Skipped != 3 || result.Chunks != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if doc := store.docs["code:octo/demo:cmd/main.go:1"]; doc != "octo/demo cmd/main.go (lines 3-3)\nfunc main() {}" {
+		t.Errorf("Unexpected chunk: %q", doc)
+	}
+
+	// A completed tree isn't fetched again
+	repo.fetched = nil
+	if _, err := indexer.Index(context.Background(), "octo", "demo", "main"); err != nil || len(repo.fetched) != 0 {
+		t.Errorf("Expected nothing fetched for an indexed tree, got %v, %v", repo.fetched, err)
+	}
+
+	// A newer tree only fetches changed blobs
+	repo.tree = &Tree{SHA: "tree2", Tree: append([]TreeEntry{}, repo.tree.Tree...)}
+	repo.tree.Tree[5].SHA = "e2"
+	repo.files["README.md"] = "# Demo v2\n"
+	result, err = indexer.Index(context.Background(), "octo", "demo", "main")
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if len(repo.fetched) != 1 || repo.fetched[0] != "README.md" || result.Unchanged != 4 {
+		t.Errorf("Expected only README.md to be fetched, got %v, %+v", repo.fetched, result)
+	}
+}
+
+// TestGitHubIndexerWaitsForRateLimit tests that an exhausted rate limit is
+// waited out, and that cancelling stops the wait
+func TestGitHubIndexerWaitsForRateLimit(t *testing.T) {
+	repo := &stubRepo{limits: &RateLimitStatus{Remaining: 0, Reset: time.Now().Add(time.Hour)}}
+	indexer := newGitHubIndexer(repo, &recordingStore{}, IndexerOptions{StateDir: t.TempDir()})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := indexer.waitForRateLimit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+
+	repo.limits.Reset = time.Now().Add(10 * time.Millisecond)
+	start := time.Now()
+	if err := indexer.waitForRateLimit(context.Background()); err != nil {
+		t.Fatalf("waitForRateLimit failed: %v", err)
+	}
+	if waited := time.Since(start); waited < 5*time.Millisecond {
+		t.Errorf("Expected to wait for the reset, waited %s", waited)
+	}
+}
+
+// TestFileContentsDecode tests decoding GitHub's wrapped base64 content
+func TestFileContentsDecode(t *testing.T) {
+	file := &FileContents{Encoding: "base64", Content: "aGVsbG8g\nd29ybGQ=\n"}
+	content, err := file.Decode()
+	if err != nil || string(content) != "hello world" {
+		t.Errorf("Expected hello world, got %q, %v", content, err)
+	}
+}
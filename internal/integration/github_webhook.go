@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// VerifyWebhook checks a GitHub X-Hub-Signature-256 header ("sha256=<hex>")
+// against the raw payload. Webhooks are unauthenticated inbound requests, so
+// this does not require the connector to be connected.
+func (g *GitHubConnector) VerifyWebhook(payload []byte, signatureHeader, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("webhook secret not configured")
+	}
+
+	signature, ok := strings.CutPrefix(signatureHeader, "sha256=")
+	if !ok {
+		return fmt.Errorf("missing or unsupported webhook signature")
+	}
+
+	received, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("malformed webhook signature: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	if !hmac.Equal(mac.Sum(nil), received) {
+		return fmt.Errorf("webhook signature mismatch")
+	}
+
+	return nil
+}
+
+// ParseEvent decodes a webhook payload according to its X-GitHub-Event header.
+// It returns *PullRequestEvent, *PushEvent, or *IssuesEvent.
+func (g *GitHubConnector) ParseEvent(eventType string, payload []byte) (interface{}, error) {
+	var event interface{}
+
+	switch eventType {
+	case "pull_request":
+		event = &PullRequestEvent{}
+	case "push":
+		event = &PushEvent{}
+	case "issues":
+		event = &IssuesEvent{}
+	default:
+		return nil, fmt.Errorf("unsupported GitHub event type: %s", eventType)
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("failed to parse %s event: %w", eventType, err)
+	}
+
+	return event, nil
+}
+
+// GitHub webhook event models
+
+type PullRequestEvent struct {
+	Action      string       `json:"action"` // opened, closed, synchronize, reopened, ...
+	Number      int          `json:"number"`
+	PullRequest *PullRequest `json:"pull_request"`
+	Repository  *Repository  `json:"repository"`
+	Sender      *User        `json:"sender"`
+}
+
+type PushEvent struct {
+	Ref        string       `json:"ref"`
+	Before     string       `json:"before"`
+	After      string       `json:"after"`
+	Commits    []PushCommit `json:"commits"`
+	Repository *Repository  `json:"repository"`
+	Pusher     *GitUser     `json:"pusher"`
+	Sender     *User        `json:"sender"`
+}
+
+type PushCommit struct {
+	ID        string   `json:"id"`
+	Message   string   `json:"message"`
+	Timestamp string   `json:"timestamp"`
+	URL       string   `json:"url"`
+	Author    *GitUser `json:"author"`
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Modified  []string `json:"modified"`
+}
+
+type IssuesEvent struct {
+	Action     string      `json:"action"` // opened, edited, closed, labeled, ...
+	Issue      *Issue      `json:"issue"`
+	Repository *Repository `json:"repository"`
+	Sender     *User       `json:"sender"`
+}
+
+type Issue struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	State     string `json:"state"`
+	HTMLURL   string `json:"html_url"`
+	User      *User  `json:"user"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
@@ -0,0 +1,71 @@
+package integration
+
+import (
+	"testing"
+)
+
+// TestVerifyWebhook tests signature verification against GitHub's documented test vector
+func TestVerifyWebhook(t *testing.T) {
+	connector := NewGitHubConnector(&GitHubConfig{}, nil, nil, nil)
+
+	secret := "It's a Secret to Everybody"
+	payload := []byte("Hello, World!")
+	signature := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	if err := connector.VerifyWebhook(payload, signature, secret); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+
+	if err := connector.VerifyWebhook([]byte("Hello, World?"), signature, secret); err == nil {
+		t.Error("Expected tampered payload to be rejected")
+	}
+
+	if err := connector.VerifyWebhook(payload, signature, "wrong secret"); err == nil {
+		t.Error("Expected wrong secret to be rejected")
+	}
+
+	if err := connector.VerifyWebhook(payload, "sha1=abc", secret); err == nil {
+		t.Error("Expected non-sha256 signature to be rejected")
+	}
+}
+
+// TestParseEvent tests decoding of typed webhook events
+func TestParseEvent(t *testing.T) {
+	connector := NewGitHubConnector(&GitHubConfig{}, nil, nil, nil)
+
+	payload := []byte(`{
+		"action": "opened",
+		"number": 42,
+		"pull_request": {"number": 42, "title": "Add feature", "state": "open", "user": {"login": "octocat"}},
+		"repository": {"id": 1, "name": "hello", "full_name": "octocat/hello"},
+		"sender": {"login": "octocat"}
+	}`)
+
+	event, err := connector.ParseEvent("pull_request", payload)
+	if err != nil {
+		t.Fatalf("Expected pull_request event to parse, got %v", err)
+	}
+
+	pr, ok := event.(*PullRequestEvent)
+	if !ok {
+		t.Fatalf("Expected *PullRequestEvent, got %T", event)
+	}
+	if pr.Action != "opened" || pr.Number != 42 {
+		t.Errorf("Expected opened #42, got %s #%d", pr.Action, pr.Number)
+	}
+	if pr.PullRequest.Title != "Add feature" || pr.Repository.FullName != "octocat/hello" {
+		t.Errorf("Unexpected pull request contents: %+v", pr.PullRequest)
+	}
+
+	push, err := connector.ParseEvent("push", []byte(`{"ref": "refs/heads/main", "commits": [{"id": "abc", "modified": ["main.go"]}]}`))
+	if err != nil {
+		t.Fatalf("Expected push event to parse, got %v", err)
+	}
+	if p := push.(*PushEvent); p.Ref != "refs/heads/main" || len(p.Commits) != 1 {
+		t.Errorf("Unexpected push event: %+v", p)
+	}
+
+	if _, err := connector.ParseEvent("deployment", []byte(`{}`)); err == nil {
+		t.Error("Expected unsupported event type to return an error")
+	}
+}
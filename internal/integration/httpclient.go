@@ -0,0 +1,41 @@
+package integration
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultHTTPTimeout is used when a connector config leaves Timeout unset
+const defaultHTTPTimeout = 30 * time.Second
+
+// HTTPOptions configures the HTTP client a connector uses
+type HTTPOptions struct {
+	Timeout   time.Duration     // Per-request timeout; defaults to 30s
+	ProxyURL  string            // Optional proxy, e.g. http://proxy.internal:3128
+	Transport http.RoundTripper // Optional custom transport; takes precedence over ProxyURL
+}
+
+// newHTTPClient builds a connector HTTP client from its options
+func newHTTPClient(opts HTTPOptions) *http.Client {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	transport := opts.Transport
+	if transport == nil && opts.ProxyURL != "" {
+		proxyURL := opts.ProxyURL
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		// Parse lazily so an invalid proxy surfaces as a request error
+		t.Proxy = func(*http.Request) (*url.URL, error) {
+			return url.Parse(proxyURL)
+		}
+		transport = t
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
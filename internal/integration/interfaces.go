@@ -2,6 +2,7 @@ package integration
 
 import (
 	"context"
+	"log/slog"
 	"time"
 )
 
@@ -26,6 +27,22 @@ type Connector interface {
 	GetRateLimits() *RateLimitStatus
 }
 
+// Searchable is implemented by connectors that can search their service's
+// resources, so tooling can search every connected service the same way
+type Searchable interface {
+	// Search returns resources matching a free-text query
+	Search(ctx context.Context, query string) ([]*Resource, error)
+}
+
+// Resource is a search result from any connector
+type Resource struct {
+	Service ServiceType
+	Type    string // Kind of resource within the service, e.g. "ticket" or "message"
+	ID      string // Unique within Service and Type
+	Title   string
+	URL     string // Where a person can view it; may be empty
+}
+
 // ServiceType defines the type of external service
 type ServiceType string
 
@@ -123,6 +140,7 @@ type AuditFilter struct {
 	StartTime  *time.Time
 	EndTime    *time.Time
 	UserID     *string
+	RequestID  *string
 	Success    *bool
 	Limit      int
 	Offset     int
@@ -136,6 +154,12 @@ type Config struct {
 	// Slack configuration
 	Slack *SlackConfig
 
+	// Salesforce configuration
+	Salesforce *SalesforceConfig
+
+	// Zendesk configuration
+	Zendesk *ZendeskConfig
+
 	// Credential vault settings
 	VaultType string // "keyring", "env", "file"
 	VaultPath string
@@ -145,8 +169,10 @@ type Config struct {
 	DefaultRateLimit   int // requests per hour
 
 	// Audit logging
-	AuditLogEnabled bool
-	AuditLogPath    string
+	AuditLogEnabled    bool
+	AuditLogPath       string
+	AuditRetentionDays int  // 0 keeps entries forever
+	AuditRotateDaily   bool // One database file per day
 }
 
 // GitHubConfig holds GitHub-specific configuration
@@ -156,6 +182,11 @@ type GitHubConfig struct {
 	EnterpriseURL string // For GitHub Enterprise
 	DefaultOrg   string
 	DefaultRepo  string
+
+	HTTPOptions
+
+	// Logger receives failures the connector recovers from (nil discards them)
+	Logger *slog.Logger
 }
 
 // SlackConfig holds Slack-specific configuration
@@ -165,6 +196,11 @@ type SlackConfig struct {
 	BotToken    string
 	SigningSecret string
 	DefaultChannel string
+
+	HTTPOptions
+
+	// Logger receives failures the connector recovers from (nil discards them)
+	Logger *slog.Logger
 }
 
 // DefaultConfig returns default integration configuration
@@ -186,6 +222,12 @@ func DefaultConfig() *Config {
 				Scopes:   []string{"chat:write", "channels:read"},
 			},
 		},
+		Salesforce: &SalesforceConfig{
+			Enabled: false,
+		},
+		Zendesk: &ZendeskConfig{
+			Enabled: false,
+		},
 		VaultType:          "keyring",
 		EnableRateLimiting: true,
 		DefaultRateLimit:   5000, // GitHub's default
@@ -0,0 +1,199 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// resourceTitleLength is the longest title resourceTitle makes
+const resourceTitleLength = 80
+
+// ConnectorManager registers connectors and manages their lifecycle
+type ConnectorManager struct {
+	connectors map[ServiceType]Connector
+	auditLog   *SQLiteAuditLogger // Opened by NewConfiguredConnectorManager
+	mu         sync.RWMutex
+}
+
+// ConnectorHealth reports the status of a registered connector
+type ConnectorHealth struct {
+	Name       string
+	Type       ServiceType
+	Connected  bool
+	RateLimits *RateLimitStatus
+}
+
+// NewConnectorManager creates an empty connector manager
+func NewConnectorManager() *ConnectorManager {
+	return &ConnectorManager{
+		connectors: make(map[ServiceType]Connector),
+	}
+}
+
+// NewConfiguredConnectorManager registers a connector for each service
+// enabled in config. They share vault, a rate limiter allowing
+// DefaultRateLimit requests an hour per service when EnableRateLimiting is
+// set, and the audit log when AuditLogEnabled is set; Close closes it.
+func NewConfiguredConnectorManager(config *Config, vault CredentialVault) (*ConnectorManager, error) {
+	auditLog, err := NewAuditLogger(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	var auditor AuditLogger
+	if auditLog != nil {
+		auditor = auditLog // A nil *SQLiteAuditLogger would be a non-nil AuditLogger
+	}
+
+	limiter := NewTokenBucketRateLimiter()
+	var connectors []Connector
+	if config.GitHub != nil && config.GitHub.Enabled {
+		connectors = append(connectors, NewGitHubConnector(config.GitHub, vault, limiter, auditor))
+	}
+	if config.Slack != nil && config.Slack.Enabled {
+		connectors = append(connectors, NewSlackConnector(config.Slack, vault, limiter, auditor))
+	}
+	if config.Salesforce != nil && config.Salesforce.Enabled {
+		connectors = append(connectors, NewSalesforceConnector(config.Salesforce, vault, limiter, auditor))
+	}
+	if config.Zendesk != nil && config.Zendesk.Enabled {
+		connectors = append(connectors, NewZendeskConnector(config.Zendesk, vault, limiter, auditor))
+	}
+
+	manager := NewConnectorManager()
+	manager.auditLog = auditLog
+	for _, connector := range connectors {
+		if config.EnableRateLimiting {
+			limiter.RegisterService(connector.Name(), config.DefaultRateLimit)
+		}
+		manager.connectors[connector.Type()] = connector
+	}
+	return manager, nil
+}
+
+// Close closes the audit log opened by NewConfiguredConnectorManager
+func (m *ConnectorManager) Close() error {
+	if m.auditLog == nil {
+		return nil
+	}
+	return m.auditLog.Close()
+}
+
+// Register adds a connector to the manager
+func (m *ConnectorManager) Register(connector Connector) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if connector == nil {
+		return fmt.Errorf("cannot register nil connector")
+	}
+
+	serviceType := connector.Type()
+	if _, exists := m.connectors[serviceType]; exists {
+		return fmt.Errorf("connector of type %s already registered", serviceType)
+	}
+
+	m.connectors[serviceType] = connector
+	return nil
+}
+
+// Get returns the connector registered for a service type
+func (m *ConnectorManager) Get(serviceType ServiceType) (Connector, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	connector, ok := m.connectors[serviceType]
+	return connector, ok
+}
+
+// List returns all registered connectors
+func (m *ConnectorManager) List() []Connector {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	connectors := make([]Connector, 0, len(m.connectors))
+	for _, connector := range m.connectors {
+		connectors = append(connectors, connector)
+	}
+	return connectors
+}
+
+// ConnectAll connects every registered connector. A failing connector does not
+// prevent the others from connecting; all failures are returned together.
+func (m *ConnectorManager) ConnectAll(ctx context.Context) error {
+	var errs []error
+	for _, connector := range m.List() {
+		if connector.IsConnected() {
+			continue
+		}
+		if err := connector.Connect(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", connector.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DisconnectAll disconnects every registered connector
+func (m *ConnectorManager) DisconnectAll(ctx context.Context) error {
+	var errs []error
+	for _, connector := range m.List() {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := connector.Disconnect(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", connector.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SearchAll searches every connected connector that implements Searchable.
+// Results are grouped by connector; a failing connector does not prevent the
+// others' results, and all failures are returned together.
+func (m *ConnectorManager) SearchAll(ctx context.Context, query string) ([]*Resource, error) {
+	connectors := m.List()
+	sort.Slice(connectors, func(i, j int) bool { return connectors[i].Name() < connectors[j].Name() })
+
+	var resources []*Resource
+	var errs []error
+	for _, connector := range connectors {
+		searchable, ok := connector.(Searchable)
+		if !ok || !connector.IsConnected() {
+			continue
+		}
+		found, err := searchable.Search(ctx, query)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", connector.Name(), err))
+			continue
+		}
+		resources = append(resources, found...)
+	}
+	return resources, errors.Join(errs...)
+}
+
+// resourceTitle makes a title from free text: its first line, shortened
+func resourceTitle(text string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if runes := []rune(title); len(runes) > resourceTitleLength {
+		title = string(runes[:resourceTitleLength-3]) + "..."
+	}
+	return title
+}
+
+// HealthCheck reports connection and rate-limit status for each connector
+func (m *ConnectorManager) HealthCheck(ctx context.Context) map[ServiceType]*ConnectorHealth {
+	health := make(map[ServiceType]*ConnectorHealth)
+	for _, connector := range m.List() {
+		health[connector.Type()] = &ConnectorHealth{
+			Name:       connector.Name(),
+			Type:       connector.Type(),
+			Connected:  connector.IsConnected(),
+			RateLimits: connector.GetRateLimits(),
+		}
+	}
+	return health
+}
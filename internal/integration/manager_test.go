@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSearchAll tests searching every connected connector through
+// Searchable, normalizing each service's results
+func TestSearchAll(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/search/code":
+			queries = append(queries, "github:"+r.URL.Query().Get("q"))
+			fmt.Fprint(w, `{"total_count": 1, "items": [{"name": "pay.go", "path": "billing/pay.go", "html_url": "https://github.com/acme/api/blob/main/billing/pay.go", "repository": {"full_name": "acme/api"}}]}`)
+		case r.URL.Path == "/api/search.messages":
+			queries = append(queries, "slack:"+r.URL.Query().Get("query"))
+			fmt.Fprint(w, `{"ok": true, "messages": {"matches": [{"ts": "1700000000.000100", "text": "refund failed for order 42\nstack trace...", "channel": {"id": "C1"}, "permalink": "https://acme.slack.com/p1"}]}}`)
+		case r.URL.Path == "/api/v2/search.json":
+			queries = append(queries, "zendesk:"+r.URL.Query().Get("query"))
+			fmt.Fprint(w, `{"results": [{"id": 77, "subject": "Refund not received"}]}`)
+		case strings.HasSuffix(r.URL.Path, "/parameterizedSearch/"):
+			queries = append(queries, "salesforce:"+r.URL.Query().Get("q"))
+			fmt.Fprint(w, `{"searchRecords": [{"attributes": {"type": "Case"}, "Id": "500xx01", "Subject": "Refund dispute"}, {"attributes": {"type": "Account"}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	opts := HTTPOptions{Transport: rewriteTransport{target}}
+	limiter := &countingLimiter{}
+	creds := &Credentials{AccessToken: "token"}
+
+	github := NewGitHubConnector(&GitHubConfig{HTTPOptions: opts}, nil, limiter, nil)
+	github.credentials, github.connected = creds, true
+	slack := NewSlackConnector(&SlackConfig{HTTPOptions: opts}, nil, limiter, nil)
+	slack.credentials, slack.connected = creds, true
+	zendesk := NewZendeskConnector(&ZendeskConfig{Subdomain: "acme", HTTPOptions: opts}, nil, limiter, nil)
+	zendesk.credentials, zendesk.connected = creds, true
+	salesforce := NewSalesforceConnector(&SalesforceConfig{HTTPOptions: opts}, nil, limiter, nil)
+	salesforce.credentials, salesforce.instanceURL = creds, "https://acme.my.salesforce.com/"
+
+	manager := NewConnectorManager()
+	for _, connector := range []Connector{github, slack, zendesk, salesforce} {
+		if err := manager.Register(connector); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Disconnected connectors are skipped
+	resources, err := manager.SearchAll(context.Background(), "refund failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 3 || strings.Join(queries, ",") != "github:refund failed,slack:refund failed,zendesk:type:ticket refund failed" {
+		t.Fatalf("Unexpected search: %d resources from %v", len(resources), queries)
+	}
+
+	want := []Resource{
+		{ServiceTypeGitHub, "code", "acme/api:billing/pay.go", "acme/api/billing/pay.go", "https://github.com/acme/api/blob/main/billing/pay.go"},
+		{ServiceTypeSlack, "message", "C1:1700000000.000100", "refund failed for order 42", "https://acme.slack.com/p1"},
+		{ServiceTypeZendesk, "ticket", "77", "Refund not received", "https://acme.zendesk.com/agent/tickets/77"},
+	}
+	for i, resource := range resources {
+		if *resource != want[i] {
+			t.Errorf("Resource %d: expected %+v, got %+v", i, want[i], *resource)
+		}
+	}
+
+	salesforce.connected = true
+	resources, err = salesforce.Search(context.Background(), "refund")
+	if err != nil || len(resources) != 1 {
+		t.Fatalf("Expected one record with an ID, got %+v, %v", resources, err)
+	}
+	if got := *resources[0]; got != (Resource{ServiceTypeSalesforce, "Case", "500xx01", "Refund dispute", "https://acme.my.salesforce.com/500xx01"}) {
+		t.Errorf("Unexpected Salesforce resource: %+v", got)
+	}
+}
+
+// TestResourceTitle tests titles made from message text
+func TestResourceTitle(t *testing.T) {
+	if title := resourceTitle("  deploy done\nlogs follow"); title != "deploy done" {
+		t.Errorf("Expected the first line, got %q", title)
+	}
+	if title := resourceTitle(strings.Repeat("é", 100)); len([]rune(title)) != resourceTitleLength || !strings.HasSuffix(title, "...") {
+		t.Errorf("Expected a shortened title, got %q", title)
+	}
+}
+
+// TestNewConfiguredConnectorManager tests that enabled services get
+// connectors sharing the configured audit log
+func TestNewConfiguredConnectorManager(t *testing.T) {
+	config := DefaultConfig()
+	config.GitHub.Enabled = true
+	config.Zendesk.Enabled = true
+	config.AuditLogPath = filepath.Join(t.TempDir(), "audit.db")
+
+	manager, err := NewConfiguredConnectorManager(config, NewMemoryCredentialVault())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	if len(manager.List()) != 2 {
+		t.Fatalf("Expected the two enabled connectors, got %d", len(manager.List()))
+	}
+	github, _ := manager.Get(ServiceTypeGitHub)
+	if auditor := github.(*GitHubConnector).auditor; auditor == nil || auditor != AuditLogger(manager.auditLog) {
+		t.Errorf("Expected GitHub to log to the audit log, got %v", auditor)
+	}
+
+	config.AuditLogEnabled = false
+	manager, err = NewConfiguredConnectorManager(config, NewMemoryCredentialVault())
+	if err != nil {
+		t.Fatal(err)
+	}
+	zendesk, _ := manager.Get(ServiceTypeZendesk)
+	if auditor := zendesk.(*ZendeskConnector).auditor; auditor != nil {
+		t.Errorf("Expected no auditor with audit logging disabled, got %v", auditor)
+	}
+	if err := manager.Close(); err != nil {
+		t.Errorf("Expected Close without an audit log to succeed, got %v", err)
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/quantumflow/quantumflow/internal/logging"
 )
 
 // SalesforceConnector implements Salesforce CRM integration
@@ -19,6 +22,7 @@ type SalesforceConnector struct {
 	rateLimiter  RateLimiter
 	auditor      AuditLogger
 	httpClient   *http.Client
+	logger       *slog.Logger
 	instanceURL  string
 	connected    bool
 	mu           sync.RWMutex
@@ -31,6 +35,11 @@ type SalesforceConfig struct {
 	InstanceURL  string // e.g., https://yourinstance.salesforce.com
 	APIVersion   string // e.g., "v59.0"
 	IsSandbox    bool
+
+	HTTPOptions
+
+	// Logger receives failures the connector recovers from (nil discards them)
+	Logger *slog.Logger
 }
 
 // NewSalesforceConnector creates a new Salesforce connector
@@ -49,9 +58,8 @@ func NewSalesforceConnector(
 		vault:       vault,
 		rateLimiter: rateLimiter,
 		auditor:     auditor,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient:  newHTTPClient(config.HTTPOptions),
+		logger:      logging.OrDiscard(config.Logger),
 	}
 }
 
@@ -141,6 +149,77 @@ func (s *SalesforceConnector) CreateObject(ctx context.Context, objectType strin
 	return result.ID, nil
 }
 
+// salesforceCollectionLimit is the maximum number of records per sObject Collections request
+const salesforceCollectionLimit = 200
+
+// CreateObjects creates records in batches using the sObject Collections API.
+// Records are sent with allOrNone=false, so one invalid record does not roll
+// back the rest; the returned slice holds one result per input record, in order.
+// If ctx is cancelled between batches, the results for the batches already
+// sent are returned with the context's error.
+func (s *SalesforceConnector) CreateObjects(ctx context.Context, objectType string, records []map[string]interface{}) ([]*RecordResult, error) {
+	if objectType == "" {
+		return nil, fmt.Errorf("object type is required")
+	}
+
+	endpoint := fmt.Sprintf("/services/data/%s/composite/sobjects", s.config.APIVersion)
+	results := make([]*RecordResult, 0, len(records))
+
+	for start := 0; start < len(records); start += salesforceCollectionLimit {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("stopped after %d of %d records: %w", start, len(records), err)
+		}
+
+		end := start + salesforceCollectionLimit
+		if end > len(records) {
+			end = len(records)
+		}
+		batch := records[start:end]
+
+		payload := map[string]interface{}{
+			"allOrNone": false,
+			"records":   collectionRecords(objectType, batch),
+		}
+
+		var batchResults []*RecordResult
+		if err := s.apiCall(ctx, "POST", endpoint, payload, &batchResults); err != nil {
+			// The whole request failed; report it against every record in the batch
+			for range batch {
+				results = append(results, &RecordResult{
+					Errors: []RecordError{{Message: err.Error()}},
+				})
+			}
+			continue
+		}
+
+		for i := range batch {
+			if i < len(batchResults) && batchResults[i] != nil {
+				results = append(results, batchResults[i])
+			} else {
+				results = append(results, &RecordResult{
+					Errors: []RecordError{{Message: "missing result for record"}},
+				})
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// collectionRecords tags each record with the sObject type required by the Collections API
+func collectionRecords(objectType string, records []map[string]interface{}) []map[string]interface{} {
+	tagged := make([]map[string]interface{}, len(records))
+	for i, record := range records {
+		entry := make(map[string]interface{}, len(record)+1)
+		for k, v := range record {
+			entry[k] = v
+		}
+		entry["attributes"] = map[string]string{"type": objectType}
+		tagged[i] = entry
+	}
+	return tagged
+}
+
 // UpdateObject updates an existing Salesforce object
 func (s *SalesforceConnector) UpdateObject(ctx context.Context, objectType, id string, data map[string]interface{}) error {
 	endpoint := fmt.Sprintf("/services/data/%s/sobjects/%s/%s", s.config.APIVersion, objectType, id)
@@ -171,6 +250,43 @@ func (s *SalesforceConnector) SearchRecords(ctx context.Context, searchString st
 	return result, nil
 }
 
+// Search implements Searchable with a parameterized search across the
+// searchable objects, one resource per record
+func (s *SalesforceConnector) Search(ctx context.Context, query string) ([]*Resource, error) {
+	endpoint := fmt.Sprintf("/services/data/%s/parameterizedSearch/?q=%s", s.config.APIVersion, url.QueryEscape(query))
+
+	var result struct {
+		SearchRecords []map[string]interface{} `json:"searchRecords"`
+	}
+	if err := s.apiCall(ctx, "GET", endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+
+	resources := make([]*Resource, 0, len(result.SearchRecords))
+	for _, record := range result.SearchRecords {
+		id, _ := record["Id"].(string)
+		if id == "" {
+			continue
+		}
+		objectType := ""
+		if attributes, ok := record["attributes"].(map[string]interface{}); ok {
+			objectType, _ = attributes["type"].(string)
+		}
+		title, _ := record["Name"].(string)
+		if title == "" {
+			title, _ = record["Subject"].(string)
+		}
+		resources = append(resources, &Resource{
+			Service: ServiceTypeSalesforce,
+			Type:    objectType,
+			ID:      id,
+			Title:   title,
+			URL:     strings.TrimSuffix(s.instanceURL, "/") + "/" + id,
+		})
+	}
+	return resources, nil
+}
+
 func (s *SalesforceConnector) apiCall(ctx context.Context, method, endpoint string, body interface{}, result interface{}) error {
 	startTime := time.Now()
 
@@ -240,8 +356,11 @@ func (s *SalesforceConnector) logAudit(ctx context.Context, method, endpoint str
 		Success:    success,
 		Error:      errorMsg,
 	}
+	entry.UserID, entry.RequestID = auditIdentity(ctx)
 
-	_ = s.auditor.Log(ctx, entry)
+	if err := s.auditor.Log(ctx, entry); err != nil {
+		s.logger.Warn("audit log failed", "service", "salesforce", "operation", entry.Operation, "error", err)
+	}
 }
 
 // Salesforce data models
@@ -253,6 +372,19 @@ type QueryResult struct {
 	NextRecordsURL string                   `json:"nextRecordsUrl,omitempty"`
 }
 
+// RecordResult is the per-record outcome of a collection operation
+type RecordResult struct {
+	ID      string        `json:"id,omitempty"`
+	Success bool          `json:"success"`
+	Errors  []RecordError `json:"errors,omitempty"`
+}
+
+type RecordError struct {
+	StatusCode string   `json:"statusCode"`
+	Message    string   `json:"message"`
+	Fields     []string `json:"fields,omitempty"`
+}
+
 type ObjectMetadata struct {
 	Name       string   `json:"name"`
 	Label      string   `json:"label"`
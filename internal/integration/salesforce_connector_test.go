@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// cancellingLimiter cancels a context on its nth wait
+type cancellingLimiter struct {
+	countingLimiter
+	cancelOn int
+	cancel   context.CancelFunc
+}
+
+func (l *cancellingLimiter) Wait(ctx context.Context, service string) error {
+	l.countingLimiter.Wait(ctx, service)
+	if l.waits == l.cancelOn {
+		l.cancel()
+		return ctx.Err()
+	}
+	return nil
+}
+
+// newTestSalesforce creates a connected Salesforce connector whose API calls
+// are answered by handler
+func newTestSalesforce(t *testing.T, limiter RateLimiter, handler http.HandlerFunc) *SalesforceConnector {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+
+	salesforce := NewSalesforceConnector(&SalesforceConfig{APIVersion: "v59.0", HTTPOptions: HTTPOptions{Transport: rewriteTransport{target}}}, nil, limiter, nil)
+	salesforce.credentials = &Credentials{AccessToken: "token"}
+	salesforce.instanceURL = "https://acme.my.salesforce.com"
+	salesforce.connected = true
+	return salesforce
+}
+
+// collectionsHandler answers sObject Collections requests with one success per record
+func collectionsHandler(batches *[]int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Records []map[string]interface{} `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		*batches = append(*batches, len(payload.Records))
+
+		results := make([]string, len(payload.Records))
+		for i := range results {
+			results[i] = fmt.Sprintf(`{"id": "001%d", "success": true}`, i)
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(results, ","))
+	}
+}
+
+// TestCreateObjects tests splitting records into Collections-sized batches
+func TestCreateObjects(t *testing.T) {
+	var batches []int
+	salesforce := newTestSalesforce(t, &countingLimiter{}, collectionsHandler(&batches))
+
+	records := make([]map[string]interface{}, 450)
+	for i := range records {
+		records[i] = map[string]interface{}{"Name": fmt.Sprintf("Account %d", i)}
+	}
+	results, err := salesforce.CreateObjects(context.Background(), "Account", records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 450 || fmt.Sprint(batches) != "[200 200 50]" || !results[449].Success {
+		t.Errorf("Expected 450 results from batches of 200, 200 and 50, got %d from %v", len(results), batches)
+	}
+}
+
+// TestCreateObjectsCancelled tests that no batches are sent once the
+// context is cancelled
+func TestCreateObjectsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var batches []int
+	limiter := &cancellingLimiter{cancelOn: 2, cancel: cancel}
+	salesforce := newTestSalesforce(t, limiter, collectionsHandler(&batches))
+
+	records := make([]map[string]interface{}, 600)
+	for i := range records {
+		records[i] = map[string]interface{}{"Name": "Account"}
+	}
+	results, err := salesforce.CreateObjects(ctx, "Account", records)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the cancellation reported, got %v", err)
+	}
+	if len(batches) != 1 || limiter.waits != 2 {
+		t.Errorf("Expected one batch sent before cancelling, got %v after %d waits", batches, limiter.waits)
+	}
+	if len(results) != 400 || !results[0].Success || results[200].Success {
+		t.Errorf("Expected the first batch created and the interrupted one failed, got %d results", len(results))
+	}
+}
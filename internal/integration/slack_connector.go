@@ -4,10 +4,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/quantumflow/quantumflow/internal/logging"
+)
+
+const (
+	// slackPageSize is the page size Slack recommends for cursor pagination
+	slackPageSize = 200
+
+	// slackSearchPageSize is the most matches search.messages returns per page
+	slackSearchPageSize = 100
 )
 
 // SlackConnector implements Slack API integration
@@ -18,8 +31,12 @@ type SlackConnector struct {
 	rateLimiter RateLimiter
 	auditor     AuditLogger
 	httpClient  *http.Client
+	logger      *slog.Logger
 	connected   bool
 	mu          sync.RWMutex
+
+	// eventHandlers maps Events API event types to their callbacks
+	eventHandlers map[string]SlackEventCallback
 }
 
 // NewSlackConnector creates a new Slack connector
@@ -34,9 +51,8 @@ func NewSlackConnector(
 		vault:       vault,
 		rateLimiter: rateLimiter,
 		auditor:     auditor,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient:  newHTTPClient(config.HTTPOptions),
+		logger:      logging.OrDiscard(config.Logger),
 	}
 }
 
@@ -83,17 +99,52 @@ func (s *SlackConnector) GetRateLimits() *RateLimitStatus {
 	return s.rateLimiter.GetStatus(s.Name())
 }
 
-// PostMessage posts a message to a channel
+// PostMessage posts a top-level message to a channel
 func (s *SlackConnector) PostMessage(ctx context.Context, channel, text string) (*Message, error) {
+	return s.PostMessageWithOptions(ctx, channel, text, PostMessageOptions{})
+}
+
+// PostMessageOptions are the optional parts of a posted message
+type PostMessageOptions struct {
+	// ThreadTimestamp replies in the thread of the message with this ts
+	ThreadTimestamp string
+
+	// Broadcast also shows a thread reply in the channel
+	Broadcast bool
+
+	// Blocks and Attachments are Block Kit blocks and legacy attachments, as
+	// Slack's JSON objects. With blocks, text is the notification fallback.
+	Blocks      []map[string]interface{}
+	Attachments []map[string]interface{}
+}
+
+// PostMessageWithOptions posts a message to a channel, optionally in a
+// thread or with rich formatting. The returned message's Timestamp can be
+// passed as ThreadTimestamp to thread follow-ups.
+func (s *SlackConnector) PostMessageWithOptions(ctx context.Context, channel, text string, opts PostMessageOptions) (*Message, error) {
 	payload := map[string]interface{}{
 		"channel": channel,
 		"text":    text,
 	}
+	if opts.ThreadTimestamp != "" {
+		payload["thread_ts"] = opts.ThreadTimestamp
+		if opts.Broadcast {
+			payload["reply_broadcast"] = true
+		}
+	}
+	if len(opts.Blocks) > 0 {
+		payload["blocks"] = opts.Blocks
+	}
+	if len(opts.Attachments) > 0 {
+		payload["attachments"] = opts.Attachments
+	}
 
 	var result struct {
-		OK      bool    `json:"ok"`
-		Message Message `json:"message"`
-		Error   string  `json:"error,omitempty"`
+		OK        bool    `json:"ok"`
+		Channel   string  `json:"channel"`
+		Timestamp string  `json:"ts"`
+		Message   Message `json:"message"`
+		Error     string  `json:"error,omitempty"`
 	}
 
 	if err := s.apiCall(ctx, "POST", "/chat.postMessage", payload, &result); err != nil {
@@ -104,51 +155,247 @@ func (s *SlackConnector) PostMessage(ctx context.Context, channel, text string)
 		return nil, fmt.Errorf("slack API error: %s", result.Error)
 	}
 
-	return &result.Message, nil
+	// The posted message omits its channel, and its ts duplicates the top-level one
+	message := &result.Message
+	message.Channel = result.Channel
+	if result.Timestamp != "" {
+		message.Timestamp = result.Timestamp
+	}
+	return message, nil
 }
 
-// GetChannelHistory retrieves channel message history
+// GetChannelHistory retrieves up to limit of a channel's most recent
+// messages, from one page of results
 func (s *SlackConnector) GetChannelHistory(ctx context.Context, channel string, limit int) ([]*Message, error) {
-	endpoint := fmt.Sprintf("/conversations.history?channel=%s&limit=%d", channel, limit)
+	messages, _, err := s.historyPage(ctx, "/conversations.history", url.Values{
+		"channel": {channel},
+		"limit":   {strconv.Itoa(limit)},
+	})
+	return messages, err
+}
+
+// GetAllChannelHistory retrieves a channel's messages newest first,
+// following Slack's cursor pagination until opts.Limit messages are found or
+// the history runs out. Each page goes through the rate limiter.
+func (s *SlackConnector) GetAllChannelHistory(ctx context.Context, channel string, opts HistoryOptions) ([]*Message, error) {
+	return s.allPages(ctx, "/conversations.history", url.Values{"channel": {channel}}, opts)
+}
+
+// GetThreadReplies retrieves the messages of the thread started by the
+// message with timestamp threadTS, the parent first
+func (s *SlackConnector) GetThreadReplies(ctx context.Context, channel, threadTS string, opts HistoryOptions) ([]*Message, error) {
+	return s.allPages(ctx, "/conversations.replies", url.Values{"channel": {channel}, "ts": {threadTS}}, opts)
+}
+
+// allPages collects the messages of a cursor-paginated conversations method
+func (s *SlackConnector) allPages(ctx context.Context, endpoint string, params url.Values, opts HistoryOptions) ([]*Message, error) {
+	params.Set("limit", strconv.Itoa(opts.pageSize(slackPageSize)))
+	if !opts.Oldest.IsZero() {
+		params.Set("oldest", slackTimestamp(opts.Oldest))
+	}
+	if !opts.Latest.IsZero() {
+		params.Set("latest", slackTimestamp(opts.Latest))
+	}
+
+	var all []*Message
+	for {
+		messages, next, err := s.historyPage(ctx, endpoint, params)
+		if err != nil {
+			return all, err
+		}
+
+		all = append(all, messages...)
+		if opts.Limit > 0 && len(all) >= opts.Limit {
+			return all[:opts.Limit], nil
+		}
+		if next == "" {
+			return all, nil
+		}
+		params.Set("cursor", next)
+	}
+}
 
+// historyPage fetches one page of a conversations method, returning the
+// cursor of the next page, or "" on the last
+func (s *SlackConnector) historyPage(ctx context.Context, endpoint string, params url.Values) ([]*Message, string, error) {
 	var result struct {
-		OK       bool       `json:"ok"`
-		Messages []*Message `json:"messages"`
-		Error    string     `json:"error,omitempty"`
+		OK               bool       `json:"ok"`
+		Messages         []*Message `json:"messages"`
+		HasMore          bool       `json:"has_more"`
+		ResponseMetadata struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"response_metadata"`
+		Error string `json:"error,omitempty"`
 	}
 
-	if err := s.apiCall(ctx, "GET", endpoint, nil, &result); err != nil {
-		return nil, err
+	if err := s.apiCall(ctx, "GET", endpoint+"?"+params.Encode(), nil, &result); err != nil {
+		return nil, "", err
 	}
 
 	if !result.OK {
-		return nil, fmt.Errorf("slack API error: %s", result.Error)
+		return nil, "", fmt.Errorf("slack API error: %s", result.Error)
 	}
 
-	return result.Messages, nil
+	return result.Messages, result.ResponseMetadata.NextCursor, nil
 }
 
-// SearchMessages searches for messages containing query
+// SearchMessages searches for messages containing query, returning the
+// first page of matches
 func (s *SlackConnector) SearchMessages(ctx context.Context, query string) ([]*Message, error) {
-	endpoint := fmt.Sprintf("/search.messages?query=%s", query)
+	messages, _, err := s.searchPage(ctx, query, 1, slackSearchPageSize)
+	return messages, err
+}
+
+// Search implements Searchable with a message search
+func (s *SlackConnector) Search(ctx context.Context, query string) ([]*Resource, error) {
+	messages, err := s.SearchMessages(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*Resource, len(messages))
+	for i, message := range messages {
+		resources[i] = &Resource{
+			Service: ServiceTypeSlack,
+			Type:    "message",
+			ID:      message.Channel + ":" + message.Timestamp,
+			Title:   resourceTitle(message.Text),
+			URL:     message.Permalink,
+		}
+	}
+	return resources, nil
+}
+
+// SearchAllMessages searches for messages containing query, following
+// result pages until opts.Limit matches are found or the results run out.
+// Search only filters by day, so opts.Oldest and opts.Latest are applied to
+// each match's timestamp instead. Each page goes through the rate limiter.
+func (s *SlackConnector) SearchAllMessages(ctx context.Context, query string, opts HistoryOptions) ([]*Message, error) {
+	pageSize := opts.pageSize(slackSearchPageSize)
+	var all []*Message
+	for page := 1; ; page++ {
+		messages, pages, err := s.searchPage(ctx, query, page, pageSize)
+		if err != nil {
+			return all, err
+		}
+
+		for _, message := range messages {
+			if opts.contains(message.Timestamp) {
+				all = append(all, message)
+			}
+		}
+		if opts.Limit > 0 && len(all) >= opts.Limit {
+			return all[:opts.Limit], nil
+		}
+		if page >= pages {
+			return all, nil
+		}
+	}
+}
+
+// searchPage fetches one page of search results and the number of pages
+func (s *SlackConnector) searchPage(ctx context.Context, query string, page, count int) ([]*Message, int, error) {
+	params := url.Values{
+		"query": {query},
+		"page":  {strconv.Itoa(page)},
+		"count": {strconv.Itoa(count)},
+	}
 
 	var result struct {
 		OK       bool `json:"ok"`
 		Messages struct {
-			Matches []*Message `json:"matches"`
+			Matches []*slackSearchMatch `json:"matches"`
+			Paging  struct {
+				Pages int `json:"pages"`
+			} `json:"paging"`
 		} `json:"messages"`
 		Error string `json:"error,omitempty"`
 	}
 
-	if err := s.apiCall(ctx, "GET", endpoint, nil, &result); err != nil {
-		return nil, err
+	if err := s.apiCall(ctx, "GET", "/search.messages?"+params.Encode(), nil, &result); err != nil {
+		return nil, 0, err
 	}
 
 	if !result.OK {
-		return nil, fmt.Errorf("slack API error: %s", result.Error)
+		return nil, 0, fmt.Errorf("slack API error: %s", result.Error)
 	}
 
-	return result.Messages.Matches, nil
+	messages := make([]*Message, len(result.Messages.Matches))
+	for i, match := range result.Messages.Matches {
+		messages[i] = match.message()
+	}
+	return messages, result.Messages.Paging.Pages, nil
+}
+
+// slackSearchMatch is a search result; unlike history messages, its channel
+// is an object
+type slackSearchMatch struct {
+	Type      string `json:"type"`
+	User      string `json:"user"`
+	Text      string `json:"text"`
+	Timestamp string `json:"ts"`
+	Channel   struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Permalink string `json:"permalink"`
+}
+
+// message converts a search match to a Message
+func (m *slackSearchMatch) message() *Message {
+	return &Message{
+		Type:      m.Type,
+		User:      m.User,
+		Text:      m.Text,
+		Timestamp: m.Timestamp,
+		Channel:   m.Channel.ID,
+		Permalink: m.Permalink,
+	}
+}
+
+// HistoryOptions bounds a paginated read of Slack messages
+type HistoryOptions struct {
+	Oldest   time.Time // Only messages after this; zero for no bound
+	Latest   time.Time // Only messages before this; zero for no bound
+	Limit    int       // Most messages to return; 0 for all
+	PageSize int       // Messages per request; 0 uses Slack's recommended size
+}
+
+// pageSize is the number of messages to request per page, no more than
+// Limit and defaulting to def
+func (o HistoryOptions) pageSize(def int) int {
+	size := o.PageSize
+	if size <= 0 {
+		size = def
+	}
+	if o.Limit > 0 {
+		size = min(size, o.Limit)
+	}
+	return size
+}
+
+// contains reports whether a Slack timestamp falls within the time range
+func (o HistoryOptions) contains(ts string) bool {
+	at, err := parseSlackTimestamp(ts)
+	if err != nil {
+		return true
+	}
+	return (o.Oldest.IsZero() || at.After(o.Oldest)) && (o.Latest.IsZero() || at.Before(o.Latest))
+}
+
+// slackTimestamp formats t as a Slack message timestamp ("seconds.micros")
+func slackTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000)
+}
+
+// parseSlackTimestamp parses a Slack message timestamp
+func parseSlackTimestamp(ts string) (time.Time, error) {
+	secs, micros, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid slack timestamp %q", ts)
+	}
+	usec, _ := strconv.ParseInt(micros, 10, 64)
+	return time.Unix(sec, usec*1000), nil
 }
 
 // ListChannels lists all channels
@@ -242,18 +489,24 @@ func (s *SlackConnector) logAudit(ctx context.Context, method, endpoint string,
 		Success:    success,
 		Error:      errorMsg,
 	}
+	entry.UserID, entry.RequestID = auditIdentity(ctx)
 
-	_ = s.auditor.Log(ctx, entry)
+	if err := s.auditor.Log(ctx, entry); err != nil {
+		s.logger.Warn("audit log failed", "service", "slack", "operation", entry.Operation, "error", err)
+	}
 }
 
 // Slack data models
 
 type Message struct {
-	Type      string `json:"type"`
-	User      string `json:"user"`
-	Text      string `json:"text"`
-	Timestamp string `json:"ts"`
-	Channel   string `json:"channel,omitempty"`
+	Type            string `json:"type"`
+	User            string `json:"user"`
+	Text            string `json:"text"`
+	Timestamp       string `json:"ts"`
+	ThreadTimestamp string `json:"thread_ts,omitempty"` // Parent's ts for thread messages; fetch with GetThreadReplies
+	ReplyCount      int    `json:"reply_count,omitempty"`
+	Channel         string `json:"channel,omitempty"`
+	Permalink       string `json:"permalink,omitempty"` // Set on search results
 }
 
 type Channel struct {
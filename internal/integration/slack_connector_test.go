@@ -0,0 +1,197 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingLimiter allows every request and counts the waits
+type countingLimiter struct {
+	waits int
+}
+
+func (l *countingLimiter) Allow(ctx context.Context, service string) (bool, error) {
+	return true, nil
+}
+
+func (l *countingLimiter) Wait(ctx context.Context, service string) error {
+	l.waits++
+	return nil
+}
+
+func (l *countingLimiter) GetStatus(service string) *RateLimitStatus {
+	return &RateLimitStatus{}
+}
+
+// rewriteTransport sends every request to a test server instead
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newTestSlack creates a connected Slack connector whose API calls are
+// answered by handler
+func newTestSlack(t *testing.T, handler http.HandlerFunc) (*SlackConnector, *countingLimiter) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+
+	limiter := &countingLimiter{}
+	slack := NewSlackConnector(&SlackConfig{HTTPOptions: HTTPOptions{Transport: rewriteTransport{target}}}, nil, limiter, nil)
+	slack.credentials = &Credentials{AccessToken: "xoxb-test"}
+	slack.connected = true
+	return slack, limiter
+}
+
+// TestGetAllChannelHistory tests following cursors across pages, the time
+// range and limit parameters, and a wait on the rate limiter per page
+func TestGetAllChannelHistory(t *testing.T) {
+	var queries []url.Values
+	slack, limiter := newTestSlack(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		queries = append(queries, query)
+		switch query.Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"ok": true, "messages": [{"ts": "1700000003.000100", "text": "third"}, {"ts": "1700000002.000000", "text": "second", "thread_ts": "1700000002.000000", "reply_count": 2}],
+				"has_more": true, "response_metadata": {"next_cursor": "page2"}}`)
+		case "page2":
+			fmt.Fprint(w, `{"ok": true, "messages": [{"ts": "1700000001.000000", "text": "first"}], "response_metadata": {"next_cursor": ""}}`)
+		}
+	})
+
+	oldest := time.Unix(1699999999, 500000000)
+	messages, err := slack.GetAllChannelHistory(context.Background(), "C123", HistoryOptions{Oldest: oldest, PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages[2].Text != "first" || messages[1].ThreadTimestamp != "1700000002.000000" || messages[1].ReplyCount != 2 {
+		t.Fatalf("Unexpected messages: %+v", messages)
+	}
+	if len(queries) != 2 || limiter.waits != 2 {
+		t.Fatalf("Expected 2 rate-limited pages, got %d requests and %d waits", len(queries), limiter.waits)
+	}
+	if q := queries[0]; q.Get("channel") != "C123" || q.Get("limit") != "2" || q.Get("oldest") != "1699999999.500000" || q.Has("latest") {
+		t.Errorf("Unexpected first page query: %v", q)
+	}
+
+	// A limit stops paging early
+	queries = nil
+	messages, err = slack.GetAllChannelHistory(context.Background(), "C123", HistoryOptions{Limit: 1})
+	if err != nil || len(messages) != 1 || len(queries) != 1 || queries[0].Get("limit") != "1" {
+		t.Errorf("Expected one message from one page, got %d from %d pages, %v", len(messages), len(queries), err)
+	}
+}
+
+// TestGetThreadReplies tests fetching a thread by its parent's timestamp
+func TestGetThreadReplies(t *testing.T) {
+	slack, _ := newTestSlack(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/conversations.replies" || r.URL.Query().Get("ts") != "1700000002.000000" {
+			fmt.Fprint(w, `{"ok": false, "error": "thread_not_found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok": true, "messages": [{"ts": "1700000002.000000", "text": "parent"}, {"ts": "1700000005.000000", "thread_ts": "1700000002.000000", "text": "reply"}]}`)
+	})
+
+	replies, err := slack.GetThreadReplies(context.Background(), "C123", "1700000002.000000", HistoryOptions{})
+	if err != nil || len(replies) != 2 || replies[1].Text != "reply" {
+		t.Fatalf("Unexpected replies: %+v, %v", replies, err)
+	}
+	if _, err := slack.GetThreadReplies(context.Background(), "C123", "1", HistoryOptions{}); err == nil || !strings.Contains(err.Error(), "thread_not_found") {
+		t.Errorf("Expected the API error, got %v", err)
+	}
+}
+
+// TestSearchAllMessages tests following search pages, decoding match
+// channels and filtering matches by time
+func TestSearchAllMessages(t *testing.T) {
+	var pages []string
+	slack, limiter := newTestSlack(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		pages = append(pages, query.Get("page"))
+		if query.Get("query") != "deploy failed" {
+			t.Errorf("Unexpected query %q", query.Get("query"))
+		}
+		fmt.Fprintf(w, `{"ok": true, "messages": {"matches": [{"ts": "17000000%s0.000000", "text": "match %s", "channel": {"id": "C9", "name": "ops"}, "permalink": "https://example.slack.com/p%s"}],
+			"paging": {"count": 1, "total": 3, "page": %s, "pages": 3}}}`, query.Get("page"), query.Get("page"), query.Get("page"), query.Get("page"))
+	})
+
+	matches, err := slack.SearchAllMessages(context.Background(), "deploy failed", HistoryOptions{Latest: time.Unix(1700000025, 0), PageSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pages, ",") != "1,2,3" || limiter.waits != 3 {
+		t.Errorf("Expected 3 rate-limited pages, got %v and %d waits", pages, limiter.waits)
+	}
+	if len(matches) != 2 || matches[0].Channel != "C9" || matches[1].Text != "match 2" || matches[0].Permalink == "" {
+		t.Errorf("Expected the 2 matches before the latest bound, got %+v", matches)
+	}
+
+	first, err := slack.SearchMessages(context.Background(), "deploy failed")
+	if err != nil || len(first) != 1 || first[0].Channel != "C9" {
+		t.Errorf("Expected one page of matches, got %+v, %v", first, err)
+	}
+}
+
+// TestSlackTimestamp tests converting between times and Slack timestamps
+func TestSlackTimestamp(t *testing.T) {
+	at := time.Unix(1700000000, 123456000)
+	if ts := slackTimestamp(at); ts != "1700000000.123456" {
+		t.Errorf("Unexpected timestamp %q", ts)
+	}
+	if parsed, err := parseSlackTimestamp("1700000000.123456"); err != nil || !parsed.Equal(at) {
+		t.Errorf("Expected %v, got %v, %v", at, parsed, err)
+	}
+	if _, err := parseSlackTimestamp("yesterday"); err == nil {
+		t.Error("Expected an invalid timestamp to fail")
+	}
+}
+
+// TestPostMessageWithOptions tests posting a threaded reply with blocks and
+// returning its timestamp for follow-ups
+func TestPostMessageWithOptions(t *testing.T) {
+	var payloads []map[string]interface{}
+	slack, _ := newTestSlack(t, func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+		fmt.Fprint(w, `{"ok": true, "channel": "C123", "ts": "1700000009.000200", "message": {"type": "message", "text": "done", "ts": "1700000009.000200"}}`)
+	})
+	ctx := context.Background()
+
+	parent, err := slack.PostMessage(ctx, "C123", "Deploying")
+	if err != nil || parent.Timestamp != "1700000009.000200" || parent.Channel != "C123" {
+		t.Fatalf("Expected the posted message's ts and channel, got %+v, %v", parent, err)
+	}
+	if _, ok := payloads[0]["thread_ts"]; ok {
+		t.Errorf("Expected a top-level message, got %v", payloads[0])
+	}
+
+	_, err = slack.PostMessageWithOptions(ctx, "C123", "done", PostMessageOptions{
+		ThreadTimestamp: parent.Timestamp,
+		Broadcast:       true,
+		Blocks:          []map[string]interface{}{{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": "*done*"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := payloads[1]
+	if reply["thread_ts"] != parent.Timestamp || reply["reply_broadcast"] != true || len(reply["blocks"].([]interface{})) != 1 {
+		t.Errorf("Unexpected reply payload: %v", reply)
+	}
+	if _, ok := reply["attachments"]; ok {
+		t.Errorf("Expected no attachments, got %v", reply["attachments"])
+	}
+}
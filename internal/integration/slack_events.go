@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// slackSignatureMaxAge rejects requests older than this to prevent replay attacks
+const slackSignatureMaxAge = 5 * time.Minute
+
+// slackMaxEventBody bounds the size of an inbound event payload
+const slackMaxEventBody = 1 << 20
+
+// SlackEvent is an inner event delivered by the Slack Events API
+type SlackEvent struct {
+	Type            string `json:"type"`
+	Subtype         string `json:"subtype,omitempty"`
+	User            string `json:"user"`
+	BotID           string `json:"bot_id,omitempty"`
+	Text            string `json:"text"`
+	Channel         string `json:"channel"`
+	Timestamp       string `json:"ts"`
+	ThreadTimestamp string `json:"thread_ts,omitempty"`
+	EventTimestamp  string `json:"event_ts"`
+}
+
+// SlackEventCallback handles a dispatched Slack event
+type SlackEventCallback func(ctx context.Context, event *SlackEvent) error
+
+// slackEventEnvelope is the outer payload posted to the events endpoint
+type slackEventEnvelope struct {
+	Type      string      `json:"type"` // url_verification, event_callback
+	Token     string      `json:"token"`
+	Challenge string      `json:"challenge,omitempty"`
+	TeamID    string      `json:"team_id,omitempty"`
+	EventID   string      `json:"event_id,omitempty"`
+	Event     *SlackEvent `json:"event,omitempty"`
+}
+
+// OnEvent registers a callback for an event type such as "message" or "app_mention"
+func (s *SlackConnector) OnEvent(eventType string, callback SlackEventCallback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.eventHandlers == nil {
+		s.eventHandlers = make(map[string]SlackEventCallback)
+	}
+	s.eventHandlers[eventType] = callback
+}
+
+// EventHandler returns an http.Handler for the Slack Events API. It verifies
+// the request signature with SlackConfig.SigningSecret, answers the
+// url_verification challenge, and dispatches event callbacks asynchronously
+// so Slack receives its acknowledgement within the 3 second deadline.
+func (s *SlackConnector) EventHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, slackMaxEventBody))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		if err := VerifySlackSignature(
+			s.config.SigningSecret,
+			r.Header.Get("X-Slack-Request-Timestamp"),
+			r.Header.Get("X-Slack-Signature"),
+			body,
+			time.Now(),
+		); err != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var envelope slackEventEnvelope
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		switch envelope.Type {
+		case "url_verification":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(envelope.Challenge))
+			return
+		case "event_callback":
+			if envelope.Event != nil {
+				s.dispatchEvent(envelope.EventID, envelope.Event)
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// dispatchEvent runs the registered callback for an event, ignoring bot
+// messages so replies posted by QuantumFlow don't trigger further events.
+// API calls the callback makes are audited as the event's user and ID.
+func (s *SlackConnector) dispatchEvent(eventID string, event *SlackEvent) {
+	if event.BotID != "" || event.Subtype == "bot_message" {
+		return
+	}
+
+	s.mu.RLock()
+	callback := s.eventHandlers[event.Type]
+	s.mu.RUnlock()
+
+	if callback == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(WithAuditContext(context.Background(), event.User, eventID), 5*time.Minute)
+		defer cancel()
+		if err := callback(ctx, event); err != nil {
+			s.logger.Error("slack event handler failed", "type", event.Type, "channel", event.Channel, "error", err)
+		}
+	}()
+}
+
+// VerifySlackSignature checks an X-Slack-Signature header against the request
+// body using the app's signing secret (v0 HMAC-SHA256 scheme)
+func VerifySlackSignature(signingSecret, timestamp, signature string, body []byte, now time.Time) error {
+	if signingSecret == "" {
+		return fmt.Errorf("slack signing secret not configured")
+	}
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("missing slack signature headers")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid slack timestamp: %w", err)
+	}
+	age := now.Sub(time.Unix(ts, 0))
+	if age > slackSignatureMaxAge || age < -slackSignatureMaxAge {
+		return fmt.Errorf("slack request timestamp outside allowed window")
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("slack signature mismatch")
+	}
+
+	return nil
+}
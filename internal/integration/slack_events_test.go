@@ -0,0 +1,120 @@
+package integration
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestVerifySlackSignature tests signature verification against Slack's
+// documented example request
+func TestVerifySlackSignature(t *testing.T) {
+	secret := "8f742231b10e8888abcd99yyyzzz85a5"
+	timestamp := "1531420618"
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c")
+	signature := "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+	now := time.Unix(1531420618, 0).Add(time.Minute)
+
+	if err := VerifySlackSignature(secret, timestamp, signature, body, now); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if err := VerifySlackSignature(secret, timestamp, signature, append(body, '!'), now); err == nil {
+		t.Error("Expected tampered body to be rejected")
+	}
+	if err := VerifySlackSignature("wrong secret", timestamp, signature, body, now); err == nil {
+		t.Error("Expected wrong secret to be rejected")
+	}
+	if err := VerifySlackSignature(secret, timestamp, signature, body, now.Add(10*time.Minute)); err == nil {
+		t.Error("Expected stale timestamp to be rejected")
+	}
+	if err := VerifySlackSignature(secret, "yesterday", signature, body, now); err == nil {
+		t.Error("Expected invalid timestamp to be rejected")
+	}
+	if err := VerifySlackSignature("", timestamp, signature, body, now); err == nil {
+		t.Error("Expected missing signing secret to be rejected")
+	}
+}
+
+// signedSlackRequest builds an events request signed with secret at timestamp
+func signedSlackRequest(secret string, timestamp time.Time, body string) *http.Request {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+// TestSlackEventHandler tests the url_verification challenge, dispatching
+// event callbacks and rejecting unsigned, stale or non-POST requests
+func TestSlackEventHandler(t *testing.T) {
+	const secret = "signing-secret"
+	slack := NewSlackConnector(&SlackConfig{SigningSecret: secret}, nil, nil, nil)
+	events := make(chan *SlackEvent, 2)
+	slack.OnEvent("app_mention", func(ctx context.Context, event *SlackEvent) error {
+		events <- event
+		return nil
+	})
+	handler := slack.EventHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedSlackRequest(secret, time.Now(), `{"type": "url_verification", "challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}`))
+	if rec.Code != http.StatusOK || rec.Body.String() != "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P" {
+		t.Errorf("Expected the challenge echoed, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedSlackRequest(secret, time.Now(), `{"type": "event_callback", "event_id": "Ev1", "event": {"type": "app_mention", "user": "U1", "text": "hi", "channel": "C1"}}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the event acknowledged, got %d", rec.Code)
+	}
+	select {
+	case event := <-events:
+		if event.User != "U1" || event.Text != "hi" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the callback to run")
+	}
+
+	// Bot messages are acknowledged but not dispatched
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedSlackRequest(secret, time.Now(), `{"type": "event_callback", "event": {"type": "app_mention", "bot_id": "B1"}}`))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the bot event acknowledged, got %d", rec.Code)
+	}
+
+	rejected := map[string]*http.Request{
+		"bad signature": signedSlackRequest("other-secret", time.Now(), `{"type": "url_verification", "challenge": "x"}`),
+		"stale":         signedSlackRequest(secret, time.Now().Add(-10*time.Minute), `{"type": "url_verification", "challenge": "x"}`),
+		"unsigned":      httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(`{"type": "url_verification", "challenge": "x"}`)),
+	}
+	for name, req := range rejected {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slack/events", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be rejected, got %d", rec.Code)
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("Expected only the user event dispatched, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
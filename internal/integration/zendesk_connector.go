@@ -1,13 +1,21 @@
 package integration
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/quantumflow/quantumflow/internal/logging"
 )
 
 // ZendeskConnector implements Zendesk support integration
@@ -18,6 +26,7 @@ type ZendeskConnector struct {
 	rateLimiter RateLimiter
 	auditor     AuditLogger
 	httpClient  *http.Client
+	logger      *slog.Logger
 	connected   bool
 	mu          sync.RWMutex
 }
@@ -29,6 +38,15 @@ type ZendeskConfig struct {
 	Email     string // For basic auth as fallback
 	APIToken  string
 	OAuth2    *OAuth2Config
+
+	// AttachmentHost is an extra host DownloadAttachment sends credentials
+	// to, for accounts that serve attachments from a custom domain
+	AttachmentHost string
+
+	HTTPOptions
+
+	// Logger receives failures the connector recovers from (nil discards them)
+	Logger *slog.Logger
 }
 
 // NewZendeskConnector creates a new Zendesk connector
@@ -43,9 +61,8 @@ func NewZendeskConnector(
 		vault:       vault,
 		rateLimiter: rateLimiter,
 		auditor:     auditor,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient:  newHTTPClient(config.HTTPOptions),
+		logger:      logging.OrDiscard(config.Logger),
 	}
 }
 
@@ -141,6 +158,48 @@ func (z *ZendeskConnector) CreateTicket(ctx context.Context, ticket *TicketCreat
 	return result.Ticket, nil
 }
 
+// GetTicketByExternalID looks up a ticket by its external_id, returning nil if none exists
+func (z *ZendeskConnector) GetTicketByExternalID(ctx context.Context, externalID string) (*Ticket, error) {
+	endpoint := fmt.Sprintf("/api/v2/tickets.json?external_id=%s", url.QueryEscape(externalID))
+
+	var result struct {
+		Tickets []*Ticket `json:"tickets"`
+	}
+
+	if err := z.apiCall(ctx, "GET", endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Tickets) == 0 {
+		return nil, nil
+	}
+
+	return result.Tickets[0], nil
+}
+
+// CreateOrGetTicket creates a ticket unless one with the same external_id
+// already exists, making ticket creation safe to retry
+func (z *ZendeskConnector) CreateOrGetTicket(ctx context.Context, ticket *TicketCreate) (*Ticket, bool, error) {
+	if ticket.ExternalID == "" {
+		return nil, false, fmt.Errorf("external_id is required for idempotent ticket creation")
+	}
+
+	existing, err := z.GetTicketByExternalID(ctx, ticket.ExternalID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up ticket: %w", err)
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	created, err := z.CreateTicket(ctx, ticket)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return created, true, nil
+}
+
 // UpdateTicket updates an existing ticket
 func (z *ZendeskConnector) UpdateTicket(ctx context.Context, id int64, update *TicketUpdate) (*Ticket, error) {
 	endpoint := fmt.Sprintf("/api/v2/tickets/%d.json", id)
@@ -162,7 +221,7 @@ func (z *ZendeskConnector) UpdateTicket(ctx context.Context, id int64, update *T
 
 // SearchTickets searches tickets
 func (z *ZendeskConnector) SearchTickets(ctx context.Context, query string) ([]*Ticket, error) {
-	endpoint := fmt.Sprintf("/api/v2/search.json?query=type:ticket %s", query)
+	endpoint := "/api/v2/search.json?query=" + url.QueryEscape("type:ticket "+query)
 
 	var result struct {
 		Results []*Ticket `json:"results"`
@@ -175,12 +234,32 @@ func (z *ZendeskConnector) SearchTickets(ctx context.Context, query string) ([]*
 	return result.Results, nil
 }
 
+// Search implements Searchable with a ticket search
+func (z *ZendeskConnector) Search(ctx context.Context, query string) ([]*Resource, error) {
+	tickets, err := z.SearchTickets(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*Resource, len(tickets))
+	for i, ticket := range tickets {
+		resources[i] = &Resource{
+			Service: ServiceTypeZendesk,
+			Type:    "ticket",
+			ID:      strconv.FormatInt(ticket.ID, 10),
+			Title:   ticket.Subject,
+			URL:     fmt.Sprintf("https://%s.zendesk.com/agent/tickets/%d", z.config.Subdomain, ticket.ID),
+		}
+	}
+	return resources, nil
+}
+
 // GetUser retrieves a user by ID
-func (z *ZendeskConnector) GetUser(ctx context.Context, id int64) (*User, error) {
+func (z *ZendeskConnector) GetUser(ctx context.Context, id int64) (*ZendeskUser, error) {
 	endpoint := fmt.Sprintf("/api/v2/users/%d.json", id)
 
 	var result struct {
-		User *User `json:"user"`
+		User *ZendeskUser `json:"user"`
 	}
 
 	if err := z.apiCall(ctx, "GET", endpoint, nil, &result); err != nil {
@@ -190,6 +269,158 @@ func (z *ZendeskConnector) GetUser(ctx context.Context, id int64) (*User, error)
 	return result.User, nil
 }
 
+// zendeskMaxAttachmentSize bounds downloaded attachment content
+const zendeskMaxAttachmentSize = 50 << 20
+
+// UploadAttachment uploads a file and returns the upload token to reference
+// from Comment.Uploads when creating or updating a ticket
+func (z *ZendeskConnector) UploadAttachment(ctx context.Context, filename string, content io.Reader) (*Upload, error) {
+	endpoint := fmt.Sprintf("/api/v2/uploads.json?filename=%s", url.QueryEscape(filename))
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("uploaded_data", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize upload: %w", err)
+	}
+
+	var result struct {
+		Upload *Upload `json:"upload"`
+	}
+
+	if err := z.multipartCall(ctx, endpoint, writer.FormDataContentType(), &body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Upload == nil || result.Upload.Token == "" {
+		return nil, fmt.Errorf("upload response missing token")
+	}
+
+	return result.Upload, nil
+}
+
+// DownloadAttachment fetches attachment content from its content_url. The
+// URL must be https on the account's zendesk.com host or AttachmentHost, so
+// credentials are never sent elsewhere; larger than zendeskMaxAttachmentSize
+// is an error rather than a truncated file.
+func (z *ZendeskConnector) DownloadAttachment(ctx context.Context, contentURL string) ([]byte, error) {
+	startTime := time.Now()
+
+	if err := z.checkAttachmentURL(contentURL); err != nil {
+		return nil, err
+	}
+
+	if err := z.rateLimiter.Wait(ctx, z.Name()); err != nil {
+		return nil, fmt.Errorf("rate limit exceeded: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", contentURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	z.authorize(req)
+
+	resp, err := z.httpClient.Do(req)
+	if err != nil {
+		z.logAudit(ctx, "GET", contentURL, 0, time.Since(startTime), false, err.Error())
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		z.logAudit(ctx, "GET", contentURL, resp.StatusCode, time.Since(startTime), false, "HTTP error")
+		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, zendeskMaxAttachmentSize+1))
+	if err == nil && len(data) > zendeskMaxAttachmentSize {
+		err = fmt.Errorf("attachment exceeds %d MB", zendeskMaxAttachmentSize>>20)
+	}
+	if err != nil {
+		z.logAudit(ctx, "GET", contentURL, resp.StatusCode, time.Since(startTime), false, err.Error())
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	z.logAudit(ctx, "GET", contentURL, resp.StatusCode, time.Since(startTime), true, "")
+	return data, nil
+}
+
+// checkAttachmentURL refuses URLs that would send the account's credentials
+// to a host other than its own
+func (z *ZendeskConnector) checkAttachmentURL(contentURL string) error {
+	u, err := url.Parse(contentURL)
+	if err != nil {
+		return fmt.Errorf("invalid attachment URL: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("attachment URL must use https, got %q", u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == strings.ToLower(z.config.Subdomain)+".zendesk.com" ||
+		(z.config.AttachmentHost != "" && host == strings.ToLower(z.config.AttachmentHost)) {
+		return nil
+	}
+	return fmt.Errorf("attachment host %q is not the %s Zendesk account", u.Host, z.config.Subdomain)
+}
+
+// multipartCall posts a multipart body and decodes the JSON response. It
+// mirrors apiCall for binary uploads, keeping rate limiting and auditing.
+func (z *ZendeskConnector) multipartCall(ctx context.Context, endpoint, contentType string, body io.Reader, result interface{}) error {
+	startTime := time.Now()
+
+	if err := z.rateLimiter.Wait(ctx, z.Name()); err != nil {
+		return fmt.Errorf("rate limit exceeded: %w", err)
+	}
+
+	reqURL := fmt.Sprintf("https://%s.zendesk.com%s", z.config.Subdomain, endpoint)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	z.authorize(req)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := z.httpClient.Do(req)
+	if err != nil {
+		z.logAudit(ctx, "POST", endpoint, 0, time.Since(startTime), false, err.Error())
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		z.logAudit(ctx, "POST", endpoint, resp.StatusCode, time.Since(startTime), false, "HTTP error")
+		return fmt.Errorf("API error: status %d", resp.StatusCode)
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			z.logAudit(ctx, "POST", endpoint, resp.StatusCode, time.Since(startTime), false, err.Error())
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	z.logAudit(ctx, "POST", endpoint, resp.StatusCode, time.Since(startTime), true, "")
+	return nil
+}
+
+// authorize applies API token authentication to a request
+func (z *ZendeskConnector) authorize(req *http.Request) {
+	if email, ok := z.credentials.Metadata["email"]; ok {
+		tokenAuth := fmt.Sprintf("%s/token:%s", email, z.credentials.AccessToken)
+		req.SetBasicAuth(tokenAuth, "")
+	}
+}
+
 func (z *ZendeskConnector) apiCall(ctx context.Context, method, endpoint string, body interface{}, result interface{}) error {
 	startTime := time.Now()
 
@@ -217,10 +448,7 @@ func (z *ZendeskConnector) apiCall(ctx context.Context, method, endpoint string,
 	}
 
 	// Use API token authentication
-	if email, ok := z.credentials.Metadata["email"]; ok {
-		tokenAuth := fmt.Sprintf("%s/token:%s", email, z.credentials.AccessToken)
-		req.SetBasicAuth(tokenAuth, "")
-	}
+	z.authorize(req)
 
 	req.Header.Set("Content-Type", "application/json")
 
@@ -264,8 +492,11 @@ func (z *ZendeskConnector) logAudit(ctx context.Context, method, endpoint string
 		Success:    success,
 		Error:      errorMsg,
 	}
+	entry.UserID, entry.RequestID = auditIdentity(ctx)
 
-	_ = z.auditor.Log(ctx, entry)
+	if err := z.auditor.Log(ctx, entry); err != nil {
+		z.logger.Warn("audit log failed", "service", "zendesk", "operation", entry.Operation, "error", err)
+	}
 }
 
 // Zendesk data models
@@ -280,6 +511,7 @@ type Ticket struct {
 	RequesterID int64    `json:"requester_id"`
 	AssigneeID  int64    `json:"assignee_id,omitempty"`
 	Tags        []string `json:"tags"`
+	ExternalID  string   `json:"external_id,omitempty"`
 	CreatedAt   string   `json:"created_at"`
 	UpdatedAt   string   `json:"updated_at"`
 }
@@ -292,6 +524,7 @@ type TicketCreate struct {
 	Status      string                 `json:"status,omitempty"`
 	Type        string                 `json:"type,omitempty"`
 	Tags        []string               `json:"tags,omitempty"`
+	ExternalID  string                 `json:"external_id,omitempty"` // Caller-supplied key used to dedupe retries
 	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
 }
 
@@ -304,11 +537,26 @@ type TicketUpdate struct {
 }
 
 type Comment struct {
-	Body   string `json:"body"`
-	Public bool   `json:"public"`
+	Body    string   `json:"body"`
+	Public  bool     `json:"public"`
+	Uploads []string `json:"uploads,omitempty"` // Tokens returned by UploadAttachment
+}
+
+type Upload struct {
+	Token      string      `json:"token"`
+	ExpiresAt  string      `json:"expires_at"`
+	Attachment *Attachment `json:"attachment"`
+}
+
+type Attachment struct {
+	ID          int64  `json:"id"`
+	FileName    string `json:"file_name"`
+	ContentURL  string `json:"content_url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
 }
 
-type User struct {
+type ZendeskUser struct {
 	ID    int64  `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestZendesk creates a connected Zendesk connector for the "acme"
+// account whose API calls are answered by handler
+func newTestZendesk(t *testing.T, config *ZendeskConfig, handler http.HandlerFunc) *ZendeskConnector {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+
+	config.Subdomain = "acme"
+	config.HTTPOptions = HTTPOptions{Transport: rewriteTransport{target}}
+	zendesk := NewZendeskConnector(config, nil, &countingLimiter{}, nil)
+	zendesk.credentials = &Credentials{AccessToken: "secret-token", Metadata: map[string]string{"email": "agent@acme.example"}}
+	zendesk.connected = true
+	return zendesk
+}
+
+// TestDownloadAttachment tests that credentials only go to the account's own
+// hosts over https
+func TestDownloadAttachment(t *testing.T) {
+	var hosts []string
+	zendesk := newTestZendesk(t, &ZendeskConfig{AttachmentHost: "files.acme.example"}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("Expected credentials for %s", r.Host)
+		}
+		hosts = append(hosts, r.Host)
+		w.Write([]byte("attachment body"))
+	})
+	ctx := context.Background()
+
+	for _, contentURL := range []string{
+		"https://acme.zendesk.com/attachments/token/abc/?name=log.txt",
+		"https://ACME.zendesk.com/attachments/token/def/?name=log.txt",
+		"https://files.acme.example/abc/log.txt",
+	} {
+		data, err := zendesk.DownloadAttachment(ctx, contentURL)
+		if err != nil || string(data) != "attachment body" {
+			t.Errorf("%s: expected the attachment, got %q, %v", contentURL, data, err)
+		}
+	}
+
+	for _, contentURL := range []string{
+		"https://attacker.example/steal",
+		"https://other.zendesk.com/attachments/token/abc/",
+		"https://acme.zendesk.com.attacker.example/x",
+		"http://acme.zendesk.com/attachments/token/abc/",
+		"://bad",
+	} {
+		if _, err := zendesk.DownloadAttachment(ctx, contentURL); err == nil {
+			t.Errorf("Expected %s to be refused", contentURL)
+		}
+	}
+	if len(hosts) != 3 {
+		t.Errorf("Expected only the 3 allowed downloads requested, got %v", hosts)
+	}
+}
+
+// TestDownloadAttachmentTooLarge tests that oversized attachments fail
+// instead of returning a truncated prefix
+func TestDownloadAttachmentTooLarge(t *testing.T) {
+	size := zendeskMaxAttachmentSize
+	zendesk := newTestZendesk(t, &ZendeskConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", size)))
+	})
+	const contentURL = "https://acme.zendesk.com/attachments/token/abc/"
+
+	data, err := zendesk.DownloadAttachment(context.Background(), contentURL)
+	if err != nil || len(data) != zendeskMaxAttachmentSize {
+		t.Fatalf("Expected an attachment at the limit to download, got %d bytes, %v", len(data), err)
+	}
+
+	size++
+	if _, err := zendesk.DownloadAttachment(context.Background(), contentURL); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Expected an oversized attachment to fail, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package logging
+
+import "log/slog"
+
+// OrDiscard returns logger, or a logger that drops every record when it is nil
+func OrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return logger
+}
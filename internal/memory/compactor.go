@@ -3,20 +3,29 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
 )
 
+// duplicateSimilarity is the cosine similarity at which two episodic
+// memories are considered duplicates
+const duplicateSimilarity = 0.95
+
 // MemoryCompactor implements Compactor for memory deduplication and archival
 type MemoryCompactor struct {
-	episodic EpisodicStore
-	config   *Config
+	episodic   EpisodicStore
+	procedural ProceduralStore
+	config     *Config
 }
 
 // NewMemoryCompactor creates a new compactor instance
-func NewMemoryCompactor(episodic EpisodicStore, config *Config) *MemoryCompactor {
+func NewMemoryCompactor(episodic EpisodicStore, procedural ProceduralStore, config *Config) *MemoryCompactor {
 	return &MemoryCompactor{
-		episodic: episodic,
-		config:   config,
+		episodic:   episodic,
+		procedural: procedural,
+		config:     config,
 	}
 }
 
@@ -45,30 +54,100 @@ func (c *MemoryCompactor) Compact(ctx context.Context) (*CompactionResult, error
 	}
 	result.MemoriesRemoved += archiveCount
 
+	// Prune workflow patterns that haven't been used within the retention
+	// window, or half of it for patterns that mostly fail
+	if c.procedural != nil && c.config.RetentionDays > 0 {
+		pruned, err := c.procedural.PruneByAge(ctx, time.Duration(c.config.RetentionDays)*24*time.Hour)
+		if err != nil {
+			return nil, fmt.Errorf("pattern pruning failed: %w", err)
+		}
+		result.PatternsPruned = pruned
+	}
+
 	result.Duration = time.Since(start)
 	return result, nil
 }
 
-// Deduplicate removes duplicate memories
+// Deduplicate removes episodic memories whose embeddings are nearly
+// identical to an earlier one's. Pinned memories are never removed, and are
+// kept in preference to unpinned duplicates. Every pair is compared, which is
+// fine for the few thousand memories a local store holds.
 func (c *MemoryCompactor) Deduplicate(ctx context.Context) (int, error) {
-	// TODO: Implement sophisticated deduplication algorithm
-	// For now, return 0 (no duplicates removed)
-	// Real implementation would:
-	// 1. Fetch all memories
-	// 2. Compute similarity matrix using embeddings
-	// 3. Merge highly similar memories (>95% similarity)
-	// 4. Delete redundant entries
-	return 0, nil
+	if c.episodic == nil {
+		return 0, nil
+	}
+
+	memories, err := c.episodic.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	// Pinned memories first, then oldest first, so the copy kept is the pinned
+	// or original one
+	sort.SliceStable(memories, func(i, j int) bool {
+		if memories[i].Pinned != memories[j].Pinned {
+			return memories[i].Pinned
+		}
+		return memories[i].Timestamp.Before(memories[j].Timestamp)
+	})
+
+	var kept []*models.Memory
+	removed := 0
+	for _, memory := range memories {
+		if len(memory.Embedding) == 0 {
+			continue
+		}
+		if !memory.Pinned && duplicates(memory, kept) {
+			if err := c.episodic.Delete(ctx, memory.ID); err != nil {
+				return removed, fmt.Errorf("failed to delete duplicate %s: %w", memory.ID, err)
+			}
+			removed++
+			continue
+		}
+		kept = append(kept, memory)
+	}
+	return removed, nil
 }
 
-// Archive moves old memories to long-term storage
+// duplicates reports whether memory is nearly identical to one of kept
+func duplicates(memory *models.Memory, kept []*models.Memory) bool {
+	for _, other := range kept {
+		if cosineSimilarity(memory.Embedding, other.Embedding) >= duplicateSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+// Archive removes episodic memories older than olderThan. There is no
+// long-term store yet, so archived memories are deleted. Redis normally
+// expires them first; this catches ones stored before retention was set.
+// Pinned memories and ones whose tag has its own retention are skipped.
 func (c *MemoryCompactor) Archive(ctx context.Context, olderThan time.Duration) (int, error) {
-	// TODO: Implement archival strategy
-	// For now, return 0 (nothing archived)
-	// Real implementation would:
-	// 1. Query memories older than threshold
-	// 2. Export to compressed format (S3, long-term storage)
-	// 3. Delete from active memory
-	// 4. Maintain archive index for retrieval
-	return 0, nil
+	if c.episodic == nil || olderThan <= 0 {
+		return 0, nil
+	}
+
+	memories, err := c.episodic.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	archived := 0
+	for _, memory := range memories {
+		if memory.Pinned || memory.Timestamp.IsZero() || !memory.Timestamp.Before(cutoff) {
+			continue
+		}
+		if tag, ok := memory.Metadata["tag"].(string); ok {
+			if _, ok := c.config.RetentionByTag[tag]; ok {
+				continue
+			}
+		}
+		if err := c.episodic.Delete(ctx, memory.ID); err != nil {
+			return archived, fmt.Errorf("failed to archive %s: %w", memory.ID, err)
+		}
+		archived++
+	}
+	return archived, nil
 }
@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// fakeEpisodic is an in-memory EpisodicStore
+type fakeEpisodic struct {
+	memories map[string]*models.Memory
+}
+
+func newFakeEpisodic(memories ...*models.Memory) *fakeEpisodic {
+	store := &fakeEpisodic{memories: make(map[string]*models.Memory)}
+	for _, memory := range memories {
+		store.memories[memory.ID] = memory
+	}
+	return store
+}
+
+func (s *fakeEpisodic) Store(ctx context.Context, memory *models.Memory) error {
+	s.memories[memory.ID] = memory
+	return nil
+}
+
+func (s *fakeEpisodic) Search(ctx context.Context, embedding []float32, k int, minScore float64) ([]*models.Memory, error) {
+	return nil, nil
+}
+
+func (s *fakeEpisodic) Get(ctx context.Context, id string) (*models.Memory, error) {
+	if memory, ok := s.memories[id]; ok {
+		return memory, nil
+	}
+	return nil, ErrMemoryNotFound
+}
+
+func (s *fakeEpisodic) SetTTL(ctx context.Context, id string, ttl time.Duration) error {
+	_, err := s.Get(ctx, id)
+	return err
+}
+
+func (s *fakeEpisodic) Pin(ctx context.Context, id string) error {
+	memory, err := s.Get(ctx, id)
+	if err == nil {
+		memory.Pinned = true
+	}
+	return err
+}
+
+func (s *fakeEpisodic) Unpin(ctx context.Context, id string) error {
+	memory, err := s.Get(ctx, id)
+	if err == nil {
+		memory.Pinned = false
+	}
+	return err
+}
+
+func (s *fakeEpisodic) List(ctx context.Context) ([]*models.Memory, error) {
+	var memories []*models.Memory
+	for _, memory := range s.memories {
+		memories = append(memories, memory)
+	}
+	sort.Slice(memories, func(i, j int) bool { return memories[i].ID < memories[j].ID })
+	return memories, nil
+}
+
+func (s *fakeEpisodic) Delete(ctx context.Context, id string) error {
+	delete(s.memories, id)
+	return nil
+}
+
+func (s *fakeEpisodic) Count(ctx context.Context) (int64, error) {
+	return int64(len(s.memories)), nil
+}
+
+// Clear removes the keys under the episodic prefix, as the Redis store's scan does
+func (s *fakeEpisodic) Clear(ctx context.Context) (int64, error) {
+	var n int64
+	for id := range s.memories {
+		if strings.HasPrefix(id, episodicKeyPrefix) {
+			delete(s.memories, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *fakeEpisodic) Close() error { return nil }
+
+// TestDeduplicate tests that near-identical memories are removed, keeping
+// pinned copies first and then the oldest
+func TestDeduplicate(t *testing.T) {
+	now := time.Now()
+	store := newFakeEpisodic(
+		&models.Memory{ID: "a", Embedding: []float32{1, 0}, Timestamp: now.Add(-3 * time.Hour)},
+		&models.Memory{ID: "b", Embedding: []float32{1, 0.01}, Timestamp: now.Add(-time.Hour)},
+		&models.Memory{ID: "c", Embedding: []float32{0, 1}, Timestamp: now},
+		&models.Memory{ID: "d", Embedding: []float32{0.01, 1}, Timestamp: now.Add(-5 * time.Hour), Pinned: true},
+		&models.Memory{ID: "e", Embedding: []float32{0, 1}, Timestamp: now.Add(-6 * time.Hour), Pinned: true},
+		&models.Memory{ID: "f", Timestamp: now},
+	)
+
+	removed, err := NewMemoryCompactor(store, nil, DefaultConfig()).Deduplicate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 duplicates removed, got %d", removed)
+	}
+	for _, id := range []string{"a", "d", "e", "f"} {
+		if _, ok := store.memories[id]; !ok {
+			t.Errorf("Expected %s to be kept", id)
+		}
+	}
+	for _, id := range []string{"b", "c"} {
+		if _, ok := store.memories[id]; ok {
+			t.Errorf("Expected duplicate %s to be removed", id)
+		}
+	}
+}
+
+// TestArchive tests that old memories are removed unless pinned or kept by
+// their tag's retention
+func TestArchive(t *testing.T) {
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	store := newFakeEpisodic(
+		&models.Memory{ID: "old", Timestamp: old},
+		&models.Memory{ID: "recent", Timestamp: time.Now()},
+		&models.Memory{ID: "pinned", Timestamp: old, Pinned: true},
+		&models.Memory{ID: "summary", Timestamp: old, Metadata: map[string]interface{}{"tag": SessionSummaryTag}},
+		&models.Memory{ID: "undated"},
+	)
+	compactor := NewMemoryCompactor(store, nil, DefaultConfig())
+
+	archived, err := compactor.Archive(context.Background(), 90*24*time.Hour)
+	if err != nil || archived != 1 {
+		t.Fatalf("Expected 1 memory archived, got %d, %v", archived, err)
+	}
+	if _, ok := store.memories["old"]; ok || len(store.memories) != 4 {
+		t.Errorf("Expected only the old memory removed, left %d", len(store.memories))
+	}
+
+	if archived, _ := compactor.Archive(context.Background(), 0); archived != 0 {
+		t.Errorf("Expected no archival without a retention period, got %d", archived)
+	}
+}
+
+// TestPinShortID tests that the service accepts IDs without the key prefix
+func TestPinShortID(t *testing.T) {
+	store := newFakeEpisodic(&models.Memory{ID: "memory:episodic:42"})
+	service := &MemoryService{episodic: store}
+	ctx := context.Background()
+
+	if ShortID("memory:episodic:42") != "42" {
+		t.Errorf("Unexpected short ID %q", ShortID("memory:episodic:42"))
+	}
+	if err := service.Pin(ctx, "42"); err != nil || !store.memories["memory:episodic:42"].Pinned {
+		t.Fatalf("Expected the memory pinned, got %v", err)
+	}
+	if err := service.Unpin(ctx, "memory:episodic:42"); err != nil || store.memories["memory:episodic:42"].Pinned {
+		t.Fatalf("Expected the memory unpinned, got %v", err)
+	}
+	if err := (&MemoryService{}).Pin(ctx, "42"); err == nil {
+		t.Error("Expected pinning without an episodic store to fail")
+	}
+}
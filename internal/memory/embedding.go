@@ -4,12 +4,245 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/logging"
+)
+
+// Embedding provider names for Config.EmbeddingProvider
+const (
+	EmbeddingSimple      = "simple"      // Word hashing, no service needed
+	EmbeddingHuggingFace = "huggingface" // A local sentence-transformers HTTP API
+	EmbeddingOllama      = "ollama"      // Ollama's /api/embed
 )
 
+// embeddingProbeTimeout bounds the startup call that checks a provider's
+// vector size
+const embeddingProbeTimeout = 10 * time.Second
+
+// simpleEmbeddingVersion identifies SimpleEmbedding's hashing scheme. Bump it
+// whenever Generate's output changes, so episodic indexes holding the old
+// vectors are recreated instead of searched with incompatible ones.
+const simpleEmbeddingVersion = 2
+
+// ErrUnknownEmbeddingProvider is returned for an unrecognized Config.EmbeddingProvider
+var ErrUnknownEmbeddingProvider = errors.New("unknown embedding provider")
+
+// ErrEmbeddingDimensions is returned when a provider's vectors don't match
+// Config.EmbeddingDimensions, the size of the Redis vector index
+var ErrEmbeddingDimensions = errors.New("embedding dimensions mismatch")
+
+// NewEmbeddingGenerator creates the generator named by
+// Config.EmbeddingProvider. Service-backed providers are asked for one
+// embedding up front, so a provider that is unreachable or whose vectors
+// don't fit the Redis index fails at startup instead of corrupting search.
+// After that they are wrapped in a FallbackEmbedding, so a provider that
+// goes down mid-session degrades memory rather than breaking it.
+func NewEmbeddingGenerator(config *Config, client *inference.Client) (EmbeddingGenerator, error) {
+	var generator EmbeddingGenerator
+	switch config.EmbeddingProvider {
+	case "", EmbeddingSimple:
+		return NewSimpleEmbedding(config.EmbeddingDimensions), nil
+	case EmbeddingHuggingFace:
+		generator, _ = NewHuggingFaceEmbedding(config)
+	case EmbeddingOllama:
+		url := config.EmbeddingURL
+		if url == "" && client != nil {
+			url = client.Config().OllamaURL
+		}
+		generator = NewOllamaEmbedding(url, config.EmbeddingModel, config.EmbeddingDimensions)
+	default:
+		return nil, fmt.Errorf("%w %q (want %s, %s or %s)", ErrUnknownEmbeddingProvider,
+			config.EmbeddingProvider, EmbeddingSimple, EmbeddingHuggingFace, EmbeddingOllama)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), embeddingProbeTimeout)
+	defer cancel()
+
+	probe, err := generator.Generate(ctx, "dimension probe")
+	if err != nil {
+		return nil, fmt.Errorf("embedding provider %s unavailable: %w", config.EmbeddingProvider, err)
+	}
+	if len(probe) != config.EmbeddingDimensions {
+		return nil, fmt.Errorf("%w: %s model %q returns %d-dimension vectors, but embedding dimensions is %d",
+			ErrEmbeddingDimensions, config.EmbeddingProvider, config.EmbeddingModel, len(probe), config.EmbeddingDimensions)
+	}
+	fallback := NewFallbackEmbedding(generator, NewSimpleEmbedding(config.EmbeddingDimensions), config.Logger)
+	fallback.OnTrip = config.OnEmbeddingFallback
+	return fallback, nil
+}
+
+// embeddingSpace names the vector space config's embeddings are in: the
+// provider with its model, or the simple hashing scheme's version
+func embeddingSpace(config *Config) string {
+	switch config.EmbeddingProvider {
+	case "", EmbeddingSimple:
+		return fmt.Sprintf("%s/v%d", EmbeddingSimple, simpleEmbeddingVersion)
+	default:
+		return config.EmbeddingProvider + "/" + config.EmbeddingModel
+	}
+}
+
+const (
+	// fallbackRetryDelay is how long FallbackEmbedding waits before retrying
+	// a failed primary call, the retry that decides whether to trip
+	fallbackRetryDelay = 250 * time.Millisecond
+
+	// fallbackProbeInterval is how often a tripped FallbackEmbedding tries
+	// its primary again
+	fallbackProbeInterval = time.Minute
+)
+
+// ErrEmbeddingDegraded is returned when storing a vector while embeddings have
+// fallen back to word hashing, whose vectors can't be searched alongside the
+// provider's
+var ErrEmbeddingDegraded = errors.New("embeddings degraded to word hashing")
+
+// FallbackEmbedding is a circuit breaker around an embedding provider. When
+// the primary fails twice in a row, it logs one warning and uses the fallback,
+// trying the primary again every ProbeInterval until it answers. Fallback
+// vectors live in a different space from the primary's, so search quality
+// drops while degraded, but Store and Retrieve keep working.
+type FallbackEmbedding struct {
+	primary  EmbeddingGenerator
+	fallback EmbeddingGenerator
+	logger   *slog.Logger
+
+	// OnTrip, when set, is called with the primary's error each time the
+	// breaker trips
+	OnTrip func(err error)
+
+	RetryDelay    time.Duration // Pause before the retry that precedes tripping
+	ProbeInterval time.Duration // How often a tripped breaker tries the primary
+
+	mu      sync.Mutex
+	reason  error     // Why the breaker tripped; nil while the primary is in use
+	probeAt time.Time // When a tripped breaker next tries the primary
+}
+
+// NewFallbackEmbedding creates a generator using primary until it fails, then
+// fallback; a nil logger discards the warning
+func NewFallbackEmbedding(primary, fallback EmbeddingGenerator, logger *slog.Logger) *FallbackEmbedding {
+	return &FallbackEmbedding{
+		primary:       primary,
+		fallback:      fallback,
+		logger:        logging.OrDiscard(logger),
+		RetryDelay:    fallbackRetryDelay,
+		ProbeInterval: fallbackProbeInterval,
+	}
+}
+
+// Degraded returns why the primary was abandoned, or nil while it is in use
+func (e *FallbackEmbedding) Degraded() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.reason
+}
+
+// try calls the primary unless the breaker is open and no probe is due. A
+// closed breaker retries a failure once before tripping. ok is false when the
+// fallback should be used instead. Failures caused by ctx ending are the
+// caller's, not the provider's: they are returned and don't count.
+func (e *FallbackEmbedding) try(ctx context.Context, call func() error) (ok bool, err error) {
+	e.mu.Lock()
+	degraded := e.reason != nil
+	if degraded {
+		if time.Now().Before(e.probeAt) {
+			e.mu.Unlock()
+			return false, nil
+		}
+		e.probeAt = time.Now().Add(e.ProbeInterval) // One caller probes at a time
+	}
+	e.mu.Unlock()
+
+	err = call()
+	if err != nil && !degraded && ctx.Err() == nil {
+		select {
+		case <-time.After(e.RetryDelay):
+			err = call()
+		case <-ctx.Done():
+		}
+	}
+
+	switch {
+	case err == nil:
+		if degraded {
+			e.reset()
+		}
+		return true, nil
+	case ctx.Err() != nil:
+		return true, err
+	case !degraded:
+		e.trip(err)
+	}
+	return false, nil
+}
+
+// trip switches to the fallback after the primary failed with err
+func (e *FallbackEmbedding) trip(err error) {
+	e.mu.Lock()
+	first := e.reason == nil
+	if first {
+		e.reason = err
+		e.probeAt = time.Now().Add(e.ProbeInterval)
+	}
+	e.mu.Unlock()
+
+	if first {
+		e.logger.Warn("embedding provider failed, using word hashing until it recovers", "error", err)
+		if e.OnTrip != nil {
+			e.OnTrip(err)
+		}
+	}
+}
+
+// reset switches back to the primary after a probe succeeded
+func (e *FallbackEmbedding) reset() {
+	e.mu.Lock()
+	e.reason = nil
+	e.mu.Unlock()
+	e.logger.Info("embedding provider recovered")
+}
+
+// Generate embeds text with the primary, or the fallback while it is down
+func (e *FallbackEmbedding) Generate(ctx context.Context, text string) ([]float32, error) {
+	var embedding []float32
+	ok, err := e.try(ctx, func() (err error) {
+		embedding, err = e.primary.Generate(ctx, text)
+		return err
+	})
+	if !ok {
+		return e.fallback.Generate(ctx, text)
+	}
+	return embedding, err
+}
+
+// GenerateBatch embeds texts with the primary, or the fallback while it is down
+func (e *FallbackEmbedding) GenerateBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var embeddings [][]float32
+	ok, err := e.try(ctx, func() (err error) {
+		embeddings, err = e.primary.GenerateBatch(ctx, texts)
+		return err
+	})
+	if !ok {
+		return e.fallback.GenerateBatch(ctx, texts)
+	}
+	return embeddings, err
+}
+
+// Dimensions returns the embedding vector dimensionality
+func (e *FallbackEmbedding) Dimensions() int {
+	return e.primary.Dimensions()
+}
+
 // HuggingFaceEmbedding implements EmbeddingGenerator using local embedding models
 type HuggingFaceEmbedding struct {
 	apiURL     string
@@ -21,8 +254,12 @@ type HuggingFaceEmbedding struct {
 // NewHuggingFaceEmbedding creates a new embedding generator
 // Uses local sentence-transformers via HTTP API (typically running on localhost)
 func NewHuggingFaceEmbedding(config *Config) (*HuggingFaceEmbedding, error) {
+	apiURL := config.EmbeddingURL
+	if apiURL == "" {
+		apiURL = "http://localhost:8000" // sentence-transformers API
+	}
 	return &HuggingFaceEmbedding{
-		apiURL:     "http://localhost:8000", // sentence-transformers API
+		apiURL:     apiURL,
 		model:      config.EmbeddingModel,
 		dimensions: config.EmbeddingDimensions,
 		httpClient: &http.Client{},
@@ -83,6 +320,80 @@ func (e *HuggingFaceEmbedding) Dimensions() int {
 	return e.dimensions
 }
 
+// OllamaEmbedding implements EmbeddingGenerator using an Ollama embedding
+// model, e.g. nomic-embed-text
+type OllamaEmbedding struct {
+	url        string
+	model      string
+	dimensions int
+	httpClient *http.Client
+}
+
+// NewOllamaEmbedding creates an embedding generator for model served by the
+// Ollama instance at url
+func NewOllamaEmbedding(url, model string, dimensions int) *OllamaEmbedding {
+	return &OllamaEmbedding{
+		url:        strings.TrimRight(url, "/"),
+		model:      model,
+		dimensions: dimensions,
+		httpClient: &http.Client{},
+	}
+}
+
+// Generate creates an embedding vector for text
+func (e *OllamaEmbedding) Generate(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.GenerateBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("no embeddings generated")
+	}
+	return embeddings[0], nil
+}
+
+// GenerateBatch creates embeddings for multiple texts in one request
+func (e *OllamaEmbedding) GenerateBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.url+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embedding API error %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Embeddings, nil
+}
+
+// Dimensions returns the embedding vector dimensionality
+func (e *OllamaEmbedding) Dimensions() int {
+	return e.dimensions
+}
+
 // SimpleEmbedding is a fallback embedding generator using simple word hashing
 // Used when external embedding service is unavailable
 type SimpleEmbedding struct {
@@ -103,17 +414,13 @@ func (e *SimpleEmbedding) Generate(ctx context.Context, text string) ([]float32,
 	// Initialize embedding vector
 	embedding := make([]float32, e.dimensions)
 
-	// Simple word hashing with position weighting
+	// Simple word hashing with position weighting. Each word lands in one
+	// dimension, so texts sharing words point in similar directions.
 	for i, word := range words {
-		hash := simpleHash(word)
+		idx := simpleHash(word) % uint32(e.dimensions)
 		position := float32(i) / float32(len(words))
-
-		for j := 0; j < e.dimensions; j++ {
-			// Distribute word hash across dimensions with position decay
-			idx := (hash + uint32(j)) % uint32(e.dimensions)
-			weight := 1.0 / (1.0 + position) // Earlier words weigh more
-			embedding[idx] += weight
-		}
+		weight := 1.0 / (1.0 + position) // Earlier words weigh more
+		embedding[idx] += weight
 	}
 
 	// Normalize to unit vector
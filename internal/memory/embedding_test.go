@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/logging"
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestNewEmbeddingGenerator tests provider selection and that a provider
+// whose vectors don't fit the configured dimensions is rejected
+func TestNewEmbeddingGenerator(t *testing.T) {
+	var model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		model = req.Model
+		w.Write([]byte(`{"embeddings":[[0.1,0.2,0.3]]}`))
+	}))
+	defer server.Close()
+	client := inference.NewClient(&inference.Config{OllamaURL: server.URL, Model: "test"})
+
+	config := DefaultConfig()
+	generator, err := NewEmbeddingGenerator(config, client)
+	if _, ok := generator.(*SimpleEmbedding); err != nil || !ok {
+		t.Errorf("Expected simple embeddings by default, got %T, %v", generator, err)
+	}
+
+	config.EmbeddingProvider = EmbeddingOllama
+	config.EmbeddingModel = "nomic-embed-text"
+	config.EmbeddingDimensions = 3
+	generator, err = NewEmbeddingGenerator(config, client)
+	if err != nil {
+		t.Fatalf("Expected the Ollama provider, got %v", err)
+	}
+	if model != "nomic-embed-text" || generator.Dimensions() != 3 {
+		t.Errorf("Unexpected Ollama embedding: model %q, %d dimensions", model, generator.Dimensions())
+	}
+
+	config.EmbeddingDimensions = 384
+	if _, err := NewEmbeddingGenerator(config, client); !errors.Is(err, ErrEmbeddingDimensions) {
+		t.Errorf("Expected a dimension mismatch, got %v", err)
+	}
+
+	config.EmbeddingProvider = EmbeddingHuggingFace
+	config.EmbeddingURL = server.URL
+	if _, err := NewEmbeddingGenerator(config, client); err == nil || errors.Is(err, ErrEmbeddingDimensions) {
+		t.Errorf("Expected an unreachable provider to fail, got %v", err)
+	}
+
+	config.EmbeddingProvider = "openai"
+	if _, err := NewEmbeddingGenerator(config, client); !errors.Is(err, ErrUnknownEmbeddingProvider) {
+		t.Errorf("Expected an unknown provider to be rejected, got %v", err)
+	}
+}
+
+// TestFallbackEmbedding tests that a provider failing twice in a row switches
+// embeddings to word hashing, with one notification, that the degraded state
+// shows in the service stats and keeps vectors out of episodic memory, and
+// that a later probe switches back
+func TestFallbackEmbedding(t *testing.T) {
+	var down atomic.Bool
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			http.Error(w, "model unloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"embeddings":[[0.1,0.2,0.3]]}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.EmbeddingProvider = EmbeddingOllama
+	config.EmbeddingURL = server.URL
+	config.EmbeddingDimensions = 3
+	var trips []error
+	config.OnEmbeddingFallback = func(err error) { trips = append(trips, err) }
+
+	generator, err := NewEmbeddingGenerator(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	breaker := generator.(*FallbackEmbedding)
+	breaker.RetryDelay = time.Millisecond
+	episodic := newFakeEpisodic()
+	service := &MemoryService{embedding: generator, episodic: episodic, logger: logging.OrDiscard(nil), stats: &Stats{}}
+	ctx := context.Background()
+
+	if embedding, err := generator.Generate(ctx, "deploy"); err != nil || embedding[0] != 0.1 {
+		t.Fatalf("Expected the provider's embedding, got %v, %v", embedding, err)
+	}
+
+	down.Store(true)
+	for i := 0; i < 3; i++ {
+		embedding, err := generator.Generate(ctx, "deploy the api")
+		if err != nil || len(embedding) != 3 {
+			t.Fatalf("Expected a fallback embedding, got %v, %v", embedding, err)
+		}
+	}
+	if batch, err := generator.GenerateBatch(ctx, []string{"a", "b"}); err != nil || len(batch) != 2 {
+		t.Errorf("Expected fallback batch embeddings, got %v, %v", batch, err)
+	}
+	if len(trips) != 1 || requests.Load() != 4 {
+		t.Errorf("Expected one notification and no calls after the failure and its retry, got %d and %d requests", len(trips), requests.Load())
+	}
+
+	stats, _ := service.GetStats(ctx)
+	if !strings.Contains(stats.EmbeddingFallback, "503") {
+		t.Errorf("Expected the failure in the stats, got %q", stats.EmbeddingFallback)
+	}
+	if err := service.Store(ctx, &models.Interaction{ID: "i1", UserQuery: "deploy"}); err != nil || len(episodic.memories) != 0 {
+		t.Errorf("Expected episodic storage skipped while degraded, got %d memories, %v", len(episodic.memories), err)
+	}
+	if err := service.StoreDocument(ctx, "doc", "deploy", nil); !errors.Is(err, ErrEmbeddingDegraded) {
+		t.Errorf("Expected documents refused while degraded, got %v", err)
+	}
+
+	// Once the probe interval passes, a recovered provider is used again
+	down.Store(false)
+	breaker.mu.Lock()
+	breaker.probeAt = time.Now()
+	breaker.mu.Unlock()
+	if embedding, err := generator.Generate(ctx, "deploy"); err != nil || embedding[0] != 0.1 || breaker.Degraded() != nil {
+		t.Errorf("Expected the probe to restore the provider, got %v, %v, %v", embedding, err, breaker.Degraded())
+	}
+	if err := service.Store(ctx, &models.Interaction{ID: "i2", UserQuery: "deploy"}); err != nil || len(episodic.memories) != 1 {
+		t.Errorf("Expected episodic storage after recovery, got %d memories, %v", len(episodic.memories), err)
+	}
+
+	// A cancelled caller doesn't trip a healthy provider
+	healthy := NewFallbackEmbedding(failingEmbedding{}, NewSimpleEmbedding(3), nil)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := healthy.Generate(cancelled, "x"); err == nil || healthy.Degraded() != nil {
+		t.Errorf("Expected the cancellation returned without tripping, got %v, %v", err, healthy.Degraded())
+	}
+}
+
+// failingEmbedding fails every call with its context's error
+type failingEmbedding struct{}
+
+func (failingEmbedding) Generate(ctx context.Context, text string) ([]float32, error) {
+	return nil, ctx.Err()
+}
+
+func (failingEmbedding) GenerateBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, ctx.Err()
+}
+
+func (failingEmbedding) Dimensions() int { return 3 }
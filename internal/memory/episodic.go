@@ -2,58 +2,173 @@ package memory
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
-	"unsafe"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/quantumflow/quantumflow/internal/logging"
 	"github.com/quantumflow/quantumflow/internal/models"
 )
 
+const (
+	// episodicScanBatch is the SCAN COUNT hint used when counting keys
+	episodicScanBatch = 1000
+
+	// episodicCountTimeout bounds how long Count may spend scanning
+	episodicCountTimeout = 2 * time.Second
+)
+
+// ErrIndexDimensions is returned when the episodic index was built for a
+// different embedding size than the configured one
+var ErrIndexDimensions = errors.New("episodic index dimensions mismatch")
+
+// ErrIndexEmbedding is returned when the episodic index holds vectors from a
+// different embedding model or hashing scheme than the configured one
+var ErrIndexEmbedding = errors.New("episodic index embedding mismatch")
+
+// ErrMemoryNotFound is returned by Get for an ID with no stored memory
+var ErrMemoryNotFound = errors.New("memory not found")
+
 // RedisEpisodicStore implements EpisodicStore using Redis with vector indexing
 type RedisEpisodicStore struct {
 	client    *redis.Client
 	indexName string
+	spaceKey  string // Records the embedding space the index's vectors are in
 	ttl       time.Duration
+	tagTTL    map[string]time.Duration // By metadata "tag"; negative never expires
 }
 
 // NewRedisEpisodicStore creates a new Redis-backed episodic memory store
 func NewRedisEpisodicStore(config *Config) (*RedisEpisodicStore, error) {
 	client := redis.NewClient(&redis.Options{
-		Addr:     config.RedisURL,
-		Password: config.RedisPassword,
-		DB:       config.RedisDB,
+		Addr:         config.RedisURL,
+		Password:     config.RedisPassword,
+		DB:           config.RedisDB,
+		PoolSize:     config.RedisPoolSize,
+		MinIdleConns: config.RedisMinIdleConns,
+		MaxRetries:   config.RedisMaxRetries,
 	})
 
-	// Test connection
+	// Test connection, retrying while Redis may still be starting up
+	if err := pingWithRetry(client, config); err != nil {
+		client.Close()
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
-	}
-
 	store := &RedisEpisodicStore{
 		client:    client,
 		indexName: "memory:episodic:idx",
+		spaceKey:  "memory:episodic-embedding",
 		ttl:       time.Duration(config.RetentionDays) * 24 * time.Hour,
+		tagTTL:    make(map[string]time.Duration),
+	}
+	for tag, days := range config.RetentionByTag {
+		store.tagTTL[tag] = -1
+		if days > 0 {
+			store.tagTTL[tag] = time.Duration(days) * 24 * time.Hour
+		}
 	}
 
 	// Create vector index if it doesn't exist
-	if err := store.createIndex(ctx, config.EmbeddingDimensions); err != nil {
+	if err := store.createIndex(ctx, config.EmbeddingDimensions, embeddingSpace(config), config.MigrateIndex, logging.OrDiscard(config.Logger)); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to create vector index: %w", err)
 	}
 
 	return store, nil
 }
 
-// createIndex creates a Redis vector search index
-func (s *RedisEpisodicStore) createIndex(ctx context.Context, dimensions int) error {
+// pingWithRetry pings Redis with exponential backoff. Authentication failures
+// are returned immediately since retrying cannot fix a wrong password.
+func pingWithRetry(client *redis.Client, config *Config) error {
+	attempts := config.RedisConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := config.RedisConnectBackoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = client.Ping(ctx).Err()
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		if isRedisAuthError(err) {
+			return fmt.Errorf("redis authentication failed for %s (check RedisPassword): %w", config.RedisURL, err)
+		}
+		if attempt < attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return fmt.Errorf("redis unreachable at %s after %d attempts: %w", config.RedisURL, attempts, err)
+}
+
+// isRedisAuthError reports whether err was caused by missing or invalid credentials
+func isRedisAuthError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "WRONGPASS") ||
+		strings.Contains(msg, "NOAUTH") ||
+		strings.Contains(msg, "invalid password") ||
+		strings.Contains(msg, "invalid username-password pair")
+}
+
+// createIndex creates a Redis vector search index for vectors of the given
+// size from the given embedding space, as named by embeddingSpace
+func (s *RedisEpisodicStore) createIndex(ctx context.Context, dimensions int, space string, migrate bool, logger *slog.Logger) error {
 	// Check if index already exists
-	_, err := s.client.Do(ctx, "FT.INFO", s.indexName).Result()
+	info, err := s.client.Do(ctx, "FT.INFO", s.indexName).Result()
 	if err == nil {
-		return nil // Index already exists
+		stored, err := s.client.Get(ctx, s.spaceKey).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return fmt.Errorf("failed to read index embedding: %w", err)
+		}
+
+		existing, ok := indexDimensions(info)
+		sameSize := !ok || existing == dimensions
+		sameSpace := spaceCompatible(stored, space)
+		if sameSize && sameSpace {
+			if stored != space {
+				return s.client.Set(ctx, s.spaceKey, space, 0).Err()
+			}
+			return nil // Index already exists
+		}
+		if !migrate {
+			if !sameSize {
+				return fmt.Errorf("%w: index %s holds %d-dimension vectors but embeddings have %d; "+
+					"set memory.redis.migrate_index to drop and recreate it (this deletes stored episodic memories)",
+					ErrIndexDimensions, s.indexName, existing, dimensions)
+			}
+			return fmt.Errorf("%w: index %s holds %s vectors but embeddings are %s; "+
+				"set memory.redis.migrate_index to drop and recreate it (this deletes stored episodic memories)",
+				ErrIndexEmbedding, s.indexName, spaceName(stored), space)
+		}
+
+		// The old vectors can't be searched with the new embeddings, so
+		// their documents go with the index
+		logger.Warn("recreating episodic index for new embeddings; stored episodic memories are deleted",
+			"index", s.indexName, "from", spaceName(stored), "to", space, "dimensions", dimensions)
+		if err := s.client.Do(ctx, "FT.DROPINDEX", s.indexName, "DD").Err(); err != nil {
+			return fmt.Errorf("failed to drop index: %w", err)
+		}
 	}
 
 	// Create index with vector similarity search
@@ -79,6 +194,64 @@ func (s *RedisEpisodicStore) createIndex(ctx context.Context, dimensions int) er
 		return fmt.Errorf("failed to create index: %w", err)
 	}
 
+	if err := s.client.Set(ctx, s.spaceKey, space, 0).Err(); err != nil {
+		return fmt.Errorf("failed to record index embedding: %w", err)
+	}
+
+	return nil
+}
+
+// spaceCompatible reports whether vectors stored in the stored embedding
+// space can be searched with the configured one. Indexes from before the
+// space was recorded have none; their simple vectors came from an older
+// hashing scheme, but other providers' vectors are still usable.
+func spaceCompatible(stored, space string) bool {
+	if stored == "" {
+		return !strings.HasPrefix(space, EmbeddingSimple+"/")
+	}
+	return stored == space
+}
+
+// spaceName describes a stored embedding space for messages
+func spaceName(stored string) string {
+	if stored == "" {
+		return "unrecorded (pre-v2 simple) embeddings"
+	}
+	return stored
+}
+
+// indexDimensions reads the embedding field's vector size from an FT.INFO
+// reply. ok is false if the reply doesn't report it, as older RediSearch
+// versions don't.
+func indexDimensions(info interface{}) (dimensions int, ok bool) {
+	reply, isList := info.([]interface{})
+	if !isList {
+		return 0, false
+	}
+	attributes, _ := replyValue(reply, "attributes").([]interface{})
+	for _, attribute := range attributes {
+		fields, isList := attribute.([]interface{})
+		if !isList || fmt.Sprint(replyValue(fields, "identifier")) != "embedding" {
+			continue
+		}
+		switch dim := replyValue(fields, "dim").(type) {
+		case int64:
+			return int(dim), true
+		case string:
+			n, err := strconv.Atoi(dim)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// replyValue looks up key in a Redis reply of alternating keys and values
+func replyValue(pairs []interface{}, key string) interface{} {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if name, ok := pairs[i].(string); ok && strings.EqualFold(name, key) {
+			return pairs[i+1]
+		}
+	}
 	return nil
 }
 
@@ -109,11 +282,15 @@ func (s *RedisEpisodicStore) Store(ctx context.Context, memory *models.Memory) e
 		"type":      string(memory.Type),
 		"score":     memory.Score,
 		"metadata":  metadataJSON,
+		"pinned":    pinnedFlag(memory.Pinned),
 	})
 
-	// Set TTL if configured
-	if s.ttl > 0 {
-		pipe.Expire(ctx, memory.ID, s.ttl)
+	// Set TTL if configured, clearing one left by an earlier Store otherwise
+	switch ttl := s.ttlFor(memory); {
+	case ttl > 0:
+		pipe.Expire(ctx, memory.ID, ttl)
+	case ttl < 0:
+		pipe.Persist(ctx, memory.ID)
 	}
 
 	_, err = pipe.Exec(ctx)
@@ -124,8 +301,101 @@ func (s *RedisEpisodicStore) Store(ctx context.Context, memory *models.Memory) e
 	return nil
 }
 
-// Search performs vector similarity search
-func (s *RedisEpisodicStore) Search(ctx context.Context, embedding []float32, k int) ([]*models.Memory, error) {
+// ttlFor is how long memory is kept: forever when pinned, else its own TTL,
+// else its tag's retention, else the store's. Zero means no expiry is set;
+// negative removes any.
+func (s *RedisEpisodicStore) ttlFor(memory *models.Memory) time.Duration {
+	if memory.Pinned {
+		return -1
+	}
+	if memory.TTL != 0 {
+		return memory.TTL
+	}
+	if tag, ok := memory.Metadata["tag"].(string); ok {
+		if ttl, ok := s.tagTTL[tag]; ok {
+			return ttl
+		}
+	}
+	return s.ttl
+}
+
+// SetTTL changes how long the memory stored under id is kept from now; a
+// ttl of zero or less keeps it forever
+func (s *RedisEpisodicStore) SetTTL(ctx context.Context, id string, ttl time.Duration) error {
+	if err := s.exists(ctx, id); err != nil {
+		return err
+	}
+
+	var err error
+	if ttl > 0 {
+		err = s.client.Expire(ctx, id, ttl).Err()
+	} else {
+		err = s.client.Persist(ctx, id).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set TTL: %w", err)
+	}
+	return nil
+}
+
+// Pin marks the memory stored under id as never expiring and exempt from
+// compaction
+func (s *RedisEpisodicStore) Pin(ctx context.Context, id string) error {
+	if err := s.exists(ctx, id); err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, id, "pinned", pinnedFlag(true))
+	pipe.Persist(ctx, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to pin memory: %w", err)
+	}
+	return nil
+}
+
+// Unpin clears a memory's pin, restarting its tag's retention or the store's.
+// A TTL the memory was stored with is not restored.
+func (s *RedisEpisodicStore) Unpin(ctx context.Context, id string) error {
+	memory, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	memory.Pinned = false
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, id, "pinned", pinnedFlag(false))
+	if ttl := s.ttlFor(memory); ttl > 0 {
+		pipe.Expire(ctx, id, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to unpin memory: %w", err)
+	}
+	return nil
+}
+
+// exists returns ErrMemoryNotFound unless a memory is stored under id
+func (s *RedisEpisodicStore) exists(ctx context.Context, id string) error {
+	n, err := s.client.Exists(ctx, id).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check memory: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrMemoryNotFound, id)
+	}
+	return nil
+}
+
+// pinnedFlag is the "pinned" hash field value
+func pinnedFlag(pinned bool) string {
+	if pinned {
+		return "1"
+	}
+	return "0"
+}
+
+// Search performs vector similarity search, dropping results below minScore
+func (s *RedisEpisodicStore) Search(ctx context.Context, embedding []float32, k int, minScore float64) ([]*models.Memory, error) {
 	embeddingBytes, err := serializeEmbedding(embedding)
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize query embedding: %w", err)
@@ -137,7 +407,7 @@ func (s *RedisEpisodicStore) Search(ctx context.Context, embedding []float32, k
 		fmt.Sprintf("*=>[KNN %d @embedding $query_vec]", k),
 		"PARAMS", "2", "query_vec", embeddingBytes,
 		"DIALECT", "2",
-		"RETURN", "6", "content", "timestamp", "type", "score", "metadata", "__embedding_score",
+		"RETURN", "8", "content", "embedding", "timestamp", "type", "score", "metadata", "pinned", "__embedding_score",
 		"LIMIT", "0", k,
 	}
 
@@ -152,7 +422,18 @@ func (s *RedisEpisodicStore) Search(ctx context.Context, embedding []float32, k
 		return nil, fmt.Errorf("failed to parse search results: %w", err)
 	}
 
-	return memories, nil
+	// Drop weak matches and rank the rest by similarity
+	relevant := memories[:0]
+	for _, memory := range memories {
+		if memory.Score >= minScore {
+			relevant = append(relevant, memory)
+		}
+	}
+	sort.SliceStable(relevant, func(i, j int) bool {
+		return relevant[i].Score > relevant[j].Score
+	})
+
+	return relevant, nil
 }
 
 // parseSearchResults parses Redis FT.SEARCH results into Memory objects
@@ -182,28 +463,8 @@ func (s *RedisEpisodicStore) parseSearchResults(result interface{}) ([]*models.M
 		}
 
 		memory := &models.Memory{ID: id}
-		for j := 0; j < len(fields); j += 2 {
-			if j+1 >= len(fields) {
-				break
-			}
-
-			field := fmt.Sprint(fields[j])
-			value := fmt.Sprint(fields[j+1])
-
-			switch field {
-			case "content":
-				memory.Content = value
-			case "type":
-				memory.Type = models.MemoryType(value)
-			case "score", "__embedding_score":
-				fmt.Sscanf(value, "%f", &memory.Score)
-			case "timestamp":
-				var ts int64
-				fmt.Sscanf(value, "%d", &ts)
-				memory.Timestamp = time.Unix(ts, 0)
-			case "metadata":
-				json.Unmarshal([]byte(value), &memory.Metadata)
-			}
+		for j := 0; j+1 < len(fields); j += 2 {
+			setMemoryField(memory, fmt.Sprint(fields[j]), fmt.Sprint(fields[j+1]))
 		}
 
 		memories = append(memories, memory)
@@ -212,23 +473,146 @@ func (s *RedisEpisodicStore) parseSearchResults(result interface{}) ([]*models.M
 	return memories, nil
 }
 
+// setMemoryField sets the memory field stored under a Redis hash field.
+// Malformed values are skipped, leaving the field at its zero value.
+func setMemoryField(memory *models.Memory, field, value string) {
+	switch field {
+	case "content":
+		memory.Content = value
+	case "type":
+		memory.Type = models.MemoryType(value)
+	case "embedding":
+		if embedding, err := deserializeEmbedding([]byte(value)); err == nil {
+			memory.Embedding = embedding
+		}
+	case "__embedding_score":
+		// KNN returns cosine distance (0 = identical); convert to similarity
+		if distance, err := strconv.ParseFloat(value, 64); err == nil {
+			memory.Score = 1 - distance
+		}
+	case "timestamp":
+		if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+			memory.Timestamp = time.Unix(ts, 0)
+		}
+	case "metadata":
+		json.Unmarshal([]byte(value), &memory.Metadata)
+	case "pinned":
+		memory.Pinned = value == pinnedFlag(true)
+	}
+}
+
+// Get returns the memory stored under id, including its embedding
+func (s *RedisEpisodicStore) Get(ctx context.Context, id string) (*models.Memory, error) {
+	fields, err := s.client.HGetAll(ctx, id).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMemoryNotFound, id)
+	}
+
+	memory := &models.Memory{ID: id}
+	for field, value := range fields {
+		setMemoryField(memory, field, value)
+	}
+	return memory, nil
+}
+
 // Delete removes a memory entry
 func (s *RedisEpisodicStore) Delete(ctx context.Context, id string) error {
 	return s.client.Del(ctx, id).Err()
 }
 
-// Count returns total number of episodic memories
+// Count returns total number of episodic memories. Keys are scanned in
+// batches under a bounded deadline; if the deadline or ctx expires first, the
+// partial count is returned along with the error.
 func (s *RedisEpisodicStore) Count(ctx context.Context) (int64, error) {
-	// Count all keys matching the prefix
-	iter := s.client.Scan(ctx, 0, "memory:episodic:*", 0).Iterator()
+	ctx, cancel := context.WithTimeout(ctx, episodicCountTimeout)
+	defer cancel()
+
+	var cursor uint64
 	count := int64(0)
-	for iter.Next(ctx) {
-		count++
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, fmt.Errorf("count interrupted: %w", err)
+		}
+
+		keys, next, err := s.client.Scan(ctx, cursor, "memory:episodic:*", episodicScanBatch).Result()
+		if err != nil {
+			return count, err
+		}
+
+		count += int64(len(keys))
+		cursor = next
+		if cursor == 0 {
+			return count, nil
+		}
+	}
+}
+
+// List returns every episodic memory, including embeddings. Memories are
+// read in SCAN batches, so ones stored or deleted meanwhile may be missed.
+func (s *RedisEpisodicStore) List(ctx context.Context) ([]*models.Memory, error) {
+	var cursor uint64
+	var memories []*models.Memory
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, "memory:episodic:*", episodicScanBatch).Result()
+		if err != nil {
+			return memories, err
+		}
+
+		pipe := s.client.Pipeline()
+		reads := make([]*redis.StringStringMapCmd, len(keys))
+		for i, key := range keys {
+			reads[i] = pipe.HGetAll(ctx, key)
+		}
+		if len(keys) > 0 {
+			if _, err := pipe.Exec(ctx); err != nil {
+				return memories, fmt.Errorf("failed to read memories: %w", err)
+			}
+		}
+		for i, read := range reads {
+			fields := read.Val()
+			if len(fields) == 0 {
+				continue // Expired or deleted since the scan
+			}
+			memory := &models.Memory{ID: keys[i]}
+			for field, value := range fields {
+				setMemoryField(memory, field, value)
+			}
+			memories = append(memories, memory)
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return memories, nil
+		}
 	}
-	if err := iter.Err(); err != nil {
-		return 0, err
+}
+
+// Clear deletes every episodic memory, returning the number of entries removed
+func (s *RedisEpisodicStore) Clear(ctx context.Context) (int64, error) {
+	var cursor uint64
+	removed := int64(0)
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, "memory:episodic:*", episodicScanBatch).Result()
+		if err != nil {
+			return removed, err
+		}
+
+		if len(keys) > 0 {
+			n, err := s.client.Unlink(ctx, keys...).Result()
+			if err != nil {
+				return removed, err
+			}
+			removed += n
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return removed, nil
+		}
 	}
-	return count, nil
 }
 
 // Close closes the Redis connection
@@ -236,21 +620,36 @@ func (s *RedisEpisodicStore) Close() error {
 	return s.client.Close()
 }
 
+// Embeddings are stored in the "embedding" hash field and sent as KNN query
+// vectors in the layout RediSearch expects for FLOAT32 vector fields: each
+// component as an IEEE 754 float32, little-endian, 4 bytes per dimension,
+// with no header. A 384-dimension vector is 1536 bytes.
+
 // serializeEmbedding converts float32 slice to byte array for Redis
 func serializeEmbedding(embedding []float32) ([]byte, error) {
 	if embedding == nil {
 		return nil, fmt.Errorf("embedding is nil")
 	}
 
-	// Convert float32 to bytes (Redis expects raw bytes for vector fields)
 	bytes := make([]byte, len(embedding)*4)
 	for i, val := range embedding {
-		bits := *(*uint32)(unsafe.Pointer(&val))
-		bytes[i*4] = byte(bits)
-		bytes[i*4+1] = byte(bits >> 8)
-		bytes[i*4+2] = byte(bits >> 16)
-		bytes[i*4+3] = byte(bits >> 24)
+		binary.LittleEndian.PutUint32(bytes[i*4:], math.Float32bits(val))
 	}
 
 	return bytes, nil
 }
+
+// deserializeEmbedding converts a byte array stored by serializeEmbedding
+// back to a float32 slice
+func deserializeEmbedding(bytes []byte) ([]float32, error) {
+	if len(bytes)%4 != 0 {
+		return nil, fmt.Errorf("embedding is %d bytes, not a multiple of 4", len(bytes))
+	}
+
+	embedding := make([]float32, len(bytes)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(bytes[i*4:]))
+	}
+
+	return embedding, nil
+}
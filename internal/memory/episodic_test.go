@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestIndexDimensions tests reading the embedding size from FT.INFO replies
+func TestIndexDimensions(t *testing.T) {
+	reply := func(dim interface{}) interface{} {
+		return []interface{}{
+			"index_name", "memory:episodic:idx",
+			"attributes", []interface{}{
+				[]interface{}{"identifier", "content", "attribute", "content", "type", "TEXT"},
+				[]interface{}{"identifier", "embedding", "attribute", "embedding", "type", "VECTOR",
+					"algorithm", "FLAT", "data_type", "FLOAT32", "dim", dim, "distance_metric", "COSINE"},
+			},
+			"num_docs", "12",
+		}
+	}
+
+	if dim, ok := indexDimensions(reply(int64(384))); !ok || dim != 384 {
+		t.Errorf("Expected 384 dimensions, got %d, %v", dim, ok)
+	}
+	if dim, ok := indexDimensions(reply("768")); !ok || dim != 768 {
+		t.Errorf("Expected 768 dimensions from a string, got %d, %v", dim, ok)
+	}
+
+	// Older RediSearch versions don't report vector sizes
+	old := []interface{}{"index_name", "memory:episodic:idx", "attributes", []interface{}{
+		[]interface{}{"identifier", "embedding", "attribute", "embedding", "type", "VECTOR"},
+	}}
+	if _, ok := indexDimensions(old); ok {
+		t.Error("Expected no dimensions when the reply doesn't report them")
+	}
+	if _, ok := indexDimensions("unexpected"); ok {
+		t.Error("Expected no dimensions from a malformed reply")
+	}
+}
+
+// TestSpaceCompatible tests which stored embedding spaces an index can keep
+func TestSpaceCompatible(t *testing.T) {
+	simple := embeddingSpace(&Config{EmbeddingProvider: EmbeddingSimple})
+	ollama := embeddingSpace(&Config{EmbeddingProvider: EmbeddingOllama, EmbeddingModel: "nomic-embed-text"})
+	if simple != "simple/v2" || ollama != "ollama/nomic-embed-text" {
+		t.Fatalf("Unexpected embedding spaces %q, %q", simple, ollama)
+	}
+
+	tests := []struct {
+		stored, space string
+		compatible    bool
+	}{
+		{simple, simple, true},
+		{"simple/v1", simple, false},
+		{"", simple, false}, // Indexed before versions were recorded, with the old hashing
+		{"", ollama, true},
+		{ollama, simple, false},
+		{"ollama/all-minilm", ollama, false},
+	}
+	for _, tt := range tests {
+		if got := spaceCompatible(tt.stored, tt.space); got != tt.compatible {
+			t.Errorf("spaceCompatible(%q, %q) = %v, want %v", tt.stored, tt.space, got, tt.compatible)
+		}
+	}
+}
+
+// TestEmbeddingRoundTrip tests the byte layout Redis expects and reading
+// vectors back
+func TestEmbeddingRoundTrip(t *testing.T) {
+	embedding := []float32{1, -0.5, 0, float32(math.Inf(1)), 3.14159}
+	bytes, err := serializeEmbedding(embedding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bytes) != 20 || !reflect.DeepEqual(bytes[:8], []byte{0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00, 0xbf}) {
+		t.Errorf("Expected little-endian float32s, got % x", bytes)
+	}
+
+	back, err := deserializeEmbedding(bytes)
+	if err != nil || !reflect.DeepEqual(back, embedding) {
+		t.Errorf("Expected %v back, got %v, %v", embedding, back, err)
+	}
+
+	if _, err := serializeEmbedding(nil); err == nil {
+		t.Error("Expected a nil embedding to be rejected")
+	}
+	if _, err := deserializeEmbedding(bytes[:7]); err == nil {
+		t.Error("Expected a truncated embedding to be rejected")
+	}
+	if back, err := deserializeEmbedding(nil); err != nil || len(back) != 0 {
+		t.Errorf("Expected an empty embedding, got %v, %v", back, err)
+	}
+}
+
+// serializeEmbeddingUnsafe is the previous pointer-cast serialization, kept
+// as a benchmark baseline
+func serializeEmbeddingUnsafe(embedding []float32) []byte {
+	bytes := make([]byte, len(embedding)*4)
+	for i, val := range embedding {
+		bits := *(*uint32)(unsafe.Pointer(&val))
+		bytes[i*4] = byte(bits)
+		bytes[i*4+1] = byte(bits >> 8)
+		bytes[i*4+2] = byte(bits >> 16)
+		bytes[i*4+3] = byte(bits >> 24)
+	}
+	return bytes
+}
+
+// benchmarkEmbedding is a 384-dimension vector, the default embedding size
+func benchmarkEmbedding() []float32 {
+	embedding := make([]float32, 384)
+	for i := range embedding {
+		embedding[i] = float32(i) / 384
+	}
+	return embedding
+}
+
+// BenchmarkSerializeEmbedding benchmarks encoding/binary serialization
+func BenchmarkSerializeEmbedding(b *testing.B) {
+	embedding := benchmarkEmbedding()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		serializeEmbedding(embedding)
+	}
+}
+
+// BenchmarkSerializeEmbeddingUnsafe benchmarks the previous unsafe serialization
+func BenchmarkSerializeEmbeddingUnsafe(b *testing.B) {
+	embedding := benchmarkEmbedding()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		serializeEmbeddingUnsafe(embedding)
+	}
+}
+
+// BenchmarkDeserializeEmbedding benchmarks reading a vector back
+func BenchmarkDeserializeEmbedding(b *testing.B) {
+	bytes, _ := serializeEmbedding(benchmarkEmbedding())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		deserializeEmbedding(bytes)
+	}
+}
+
+// TestParseSearchResults tests that search results carry their embeddings
+// and similarity scores
+func TestParseSearchResults(t *testing.T) {
+	embedding, _ := serializeEmbedding([]float32{0.25, -1})
+	reply := []interface{}{int64(2),
+		[]interface{}{"memory:episodic:1", []interface{}{
+			"content", "deploy with helm", "embedding", string(embedding), "timestamp", "1760000000",
+			"type", "episodic", "metadata", `{"agent":"infra"}`, "pinned", "1", "__embedding_score", "0.25",
+		}},
+		[]interface{}{"memory:episodic:2", []interface{}{"content", "truncated", "embedding", "abc"}},
+	}
+
+	memories, err := (&RedisEpisodicStore{}).parseSearchResults(reply)
+	if err != nil || len(memories) != 2 {
+		t.Fatalf("Expected 2 memories, got %d, %v", len(memories), err)
+	}
+	first := memories[0]
+	if !reflect.DeepEqual(first.Embedding, []float32{0.25, -1}) || first.Score != 0.75 || first.Timestamp.Unix() != 1760000000 || first.Metadata["agent"] != "infra" || !first.Pinned {
+		t.Errorf("Unexpected memory: %+v", first)
+	}
+	if memories[1].Content != "truncated" || memories[1].Embedding != nil {
+		t.Errorf("Expected a malformed embedding to be skipped, got %+v", memories[1])
+	}
+}
+
+// TestTTLFor tests that a memory's own TTL wins over its tag's retention,
+// which wins over the store's
+func TestTTLFor(t *testing.T) {
+	day := 24 * time.Hour
+	store := &RedisEpisodicStore{ttl: 90 * day, tagTTL: map[string]time.Duration{SessionSummaryTag: -1, "release": 365 * day}}
+
+	tests := []struct {
+		name   string
+		memory *models.Memory
+		want   time.Duration
+	}{
+		{"default", &models.Memory{}, 90 * day},
+		{"untagged metadata", &models.Memory{Metadata: map[string]interface{}{"agent": "code"}}, 90 * day},
+		{"unknown tag", &models.Memory{Metadata: map[string]interface{}{"tag": "other"}}, 90 * day},
+		{"tag forever", &models.Memory{Metadata: map[string]interface{}{"tag": SessionSummaryTag}}, -1},
+		{"tag retention", &models.Memory{Metadata: map[string]interface{}{"tag": "release"}}, 365 * day},
+		{"own ttl", &models.Memory{TTL: time.Hour, Metadata: map[string]interface{}{"tag": SessionSummaryTag}}, time.Hour},
+		{"own forever", &models.Memory{TTL: -1}, -1},
+		{"pinned", &models.Memory{TTL: time.Hour, Pinned: true}, -1},
+	}
+	for _, tt := range tests {
+		if got := store.ttlFor(tt.memory); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
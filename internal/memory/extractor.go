@@ -11,13 +11,17 @@ import (
 	"github.com/quantumflow/quantumflow/internal/models"
 )
 
+// deterministic samples extraction calls at temperature 0, so the same text
+// extracts the same way and the response cache can serve repeats
+var deterministic = inference.GenerateOptions{Deterministic: true}
+
 // QwenExtractor implements Extractor using Qwen for extraction tasks
 type QwenExtractor struct {
-	client *inference.Client
+	client inference.Generator
 }
 
 // NewQwenExtractor creates a new Qwen-based extractor
-func NewQwenExtractor(client *inference.Client) *QwenExtractor {
+func NewQwenExtractor(client inference.Generator) *QwenExtractor {
 	return &QwenExtractor{client: client}
 }
 
@@ -31,27 +35,19 @@ Text:
 
 JSON:`, text)
 
-	infResult, err := e.client.GenerateSync(ctx, prompt)
+	infResult, err := e.client.GenerateSyncWithOptions(ctx, prompt, deterministic)
 	if err != nil {
 		return nil, fmt.Errorf("extraction failed: %w", err)
 	}
 
 	// Parse JSON response
-	response := cleanJSONResponse(infResult.Response)
+	response := cleanJSONArray(infResult.Response)
 	var facts []Fact
 	if err := json.Unmarshal([]byte(response), &facts); err != nil {
 		return nil, fmt.Errorf("failed to parse facts: %w", err)
 	}
 
-	// Add metadata
-	now := time.Now()
-	for i := range facts {
-		facts[i].ID = fmt.Sprintf("fact:%d:%d", now.Unix(), i)
-		facts[i].Timestamp = now
-		facts[i].Source = "qwen-extraction"
-	}
-
-	return facts, nil
+	return stampFacts(facts, time.Now()), nil
 }
 
 // ExtractEntities extracts named entities from text
@@ -64,34 +60,19 @@ Text:
 
 JSON:`, text)
 
-	result, err := e.client.GenerateSync(ctx, prompt)
+	result, err := e.client.GenerateSyncWithOptions(ctx, prompt, deterministic)
 	if err != nil {
 		return nil, fmt.Errorf("extraction failed: %w", err)
 	}
 
-	response := cleanJSONResponse(result.Response)
-
-	var entities []struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
-	}
+	response := cleanJSONArray(result.Response)
 
+	var entities []rawEntity
 	if err := json.Unmarshal([]byte(response), &entities); err != nil {
 		return nil, fmt.Errorf("failed to parse entities: %w", err)
 	}
 
-	// Convert to Entity models
-	entityModels := make([]*models.Entity, len(entities))
-	for i, e := range entities {
-		entityModels[i] = &models.Entity{
-			ID:         fmt.Sprintf("entity:%d:%d", time.Now().Unix(), i),
-			Name:       e.Name,
-			Type:       e.Type,
-			Attributes: make(map[string]interface{}),
-		}
-	}
-
-	return entityModels, nil
+	return toEntities(entities, time.Now()), nil
 }
 
 // ExtractRelationships identifies relationships between entities
@@ -104,37 +85,116 @@ Text:
 
 JSON:`, text)
 
-	result, err := e.client.GenerateSync(ctx, prompt)
+	result, err := e.client.GenerateSyncWithOptions(ctx, prompt, deterministic)
 	if err != nil {
 		return nil, fmt.Errorf("extraction failed: %w", err)
 	}
 
-	response := cleanJSONResponse(result.Response)
-
-	var rels []struct {
-		From       string  `json:"from"`
-		To         string  `json:"to"`
-		Type       string  `json:"type"`
-		Confidence float64 `json:"confidence"`
-	}
+	response := cleanJSONArray(result.Response)
 
+	var rels []rawRelationship
 	if err := json.Unmarshal([]byte(response), &rels); err != nil {
 		return nil, fmt.Errorf("failed to parse relationships: %w", err)
 	}
 
-	// Convert to Relationship models
+	return toRelationships(rels, time.Now()), nil
+}
+
+// Extract pulls facts, entities and relationships from text in a single
+// model call, instead of one call per kind
+func (e *QwenExtractor) Extract(ctx context.Context, text string) (*Extraction, error) {
+	prompt := fmt.Sprintf(`Extract information from the following text. Return one JSON object:
+{
+  "facts": [{"statement": "...", "subject": "...", "predicate": "...", "object": "...", "confidence": 0.9}],
+  "entities": [{"name": "...", "type": "PERSON|ORGANIZATION|LOCATION|DATE|OTHER"}],
+  "relationships": [{"from": "entity1", "to": "entity2", "type": "relationship_type", "confidence": 0.9}]
+}
+Relationships must connect entities from the entities list, by name. Use empty lists when nothing applies.
+
+Text:
+%s
+
+JSON:`, text)
+
+	result, err := e.client.GenerateSyncWithOptions(ctx, prompt, inference.GenerateOptions{Format: "json", Deterministic: true})
+	if err != nil {
+		return nil, fmt.Errorf("extraction failed: %w", err)
+	}
+
+	response := firstJSON(cleanJSONResponse(result.Response), "{")
+	if response == "" {
+		return nil, fmt.Errorf("failed to parse extraction: no JSON object in response")
+	}
+
+	var raw struct {
+		Facts         []Fact            `json:"facts"`
+		Entities      []rawEntity       `json:"entities"`
+		Relationships []rawRelationship `json:"relationships"`
+	}
+	if err := json.Unmarshal([]byte(response), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse extraction: %w", err)
+	}
+
+	now := time.Now()
+	return &Extraction{
+		Facts:         stampFacts(raw.Facts, now),
+		Entities:      toEntities(raw.Entities, now),
+		Relationships: toRelationships(raw.Relationships, now),
+	}, nil
+}
+
+// rawEntity is an entity as the model returns it
+type rawEntity struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// rawRelationship is a relationship as the model returns it, between entity names
+type rawRelationship struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Type       string  `json:"type"`
+	Confidence float64 `json:"confidence"`
+}
+
+// stampFacts gives extracted facts their IDs, timestamp and source
+func stampFacts(facts []Fact, now time.Time) []Fact {
+	for i := range facts {
+		facts[i].ID = fmt.Sprintf("fact:%d:%d", now.Unix(), i)
+		facts[i].Timestamp = now
+		facts[i].Source = "qwen-extraction"
+	}
+	return facts
+}
+
+// toEntities converts extracted entities to Entity models
+func toEntities(entities []rawEntity, now time.Time) []*models.Entity {
+	entityModels := make([]*models.Entity, len(entities))
+	for i, e := range entities {
+		entityModels[i] = &models.Entity{
+			ID:         fmt.Sprintf("entity:%d:%d", now.Unix(), i),
+			Name:       e.Name,
+			Type:       e.Type,
+			Attributes: make(map[string]interface{}),
+		}
+	}
+	return entityModels
+}
+
+// toRelationships converts extracted relationships to Relationship models;
+// their endpoints are entity names
+func toRelationships(rels []rawRelationship, now time.Time) []*models.Relationship {
 	relModels := make([]*models.Relationship, len(rels))
 	for i, r := range rels {
 		relModels[i] = &models.Relationship{
-			ID:         fmt.Sprintf("rel:%d:%d", time.Now().Unix(), i),
+			ID:         fmt.Sprintf("rel:%d:%d", now.Unix(), i),
 			FromID:     r.From,
 			ToID:       r.To,
 			Type:       r.Type,
 			Confidence: r.Confidence,
 		}
 	}
-
-	return relModels, nil
+	return relModels
 }
 
 // Summarize creates a concise summary of text
@@ -171,3 +231,77 @@ func cleanJSONResponse(response string) string {
 
 	return strings.TrimSpace(response)
 }
+
+// cleanJSONArray extracts the JSON array from a model response: the first
+// complete array or object in it, after stripping markdown fences, so prose
+// around the JSON is ignored. A lone object is wrapped in an array. If no
+// valid JSON is found the stripped response is returned for the caller's
+// parse error.
+func cleanJSONArray(response string) string {
+	response = cleanJSONResponse(response)
+
+	candidate := firstJSON(response, "[{")
+	switch {
+	case candidate == "":
+		return response
+	case candidate[0] == '{':
+		return "[" + candidate + "]"
+	default:
+		return candidate
+	}
+}
+
+// firstJSON returns the first valid JSON value in text that opens with one
+// of the brackets in open, or "" if there is none
+func firstJSON(text, open string) string {
+	for start := 0; start < len(text); start++ {
+		if strings.IndexByte(open, text[start]) == -1 {
+			continue
+		}
+		end := matchingBracket(text, start)
+		if end == -1 {
+			continue
+		}
+		if candidate := text[start : end+1]; json.Valid([]byte(candidate)) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// matchingBracket returns the index of the bracket closing the one at start,
+// skipping brackets inside strings, or -1 if it isn't closed
+func matchingBracket(s string, start int) int {
+	var (
+		depth    int
+		inString bool
+		escaped  bool
+	)
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
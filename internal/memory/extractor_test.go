@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/logging"
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestCleanJSONArray tests that extraction responses wrapped in fences or
+// prose, or holding a single object, are reduced to a JSON array
+func TestCleanJSONArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"bare array", `[{"name":"Redis"}]`, `[{"name":"Redis"}]`},
+		{"fenced", "```json\n[{\"name\":\"Redis\"}]\n```", `[{"name":"Redis"}]`},
+		{"prose around", "Here are the entities:\n[{\"name\":\"Redis\"}]\nLet me know if you need more.", `[{"name":"Redis"}]`},
+		{"brackets in strings", `Result: [{"name":"a]b","type":"[x"}] done`, `[{"name":"a]b","type":"[x"}]`},
+		{"single object", `The fact is {"statement":"Go is compiled","confidence":0.9}.`, `[{"statement":"Go is compiled","confidence":0.9}]`},
+		{"invalid bracket before json", `Entities [see below]: [{"name":"Redis"}]`, `[{"name":"Redis"}]`},
+		{"empty array", `None found: []`, `[]`},
+		{"no json", "I couldn't find any entities.", "I couldn't find any entities."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanJSONArray(tt.response); got != tt.want {
+				t.Errorf("cleanJSONArray(%q) = %q, want %q", tt.response, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtract tests that facts, entities and relationships come back from a
+// single model call
+func TestExtract(t *testing.T) {
+	extraction := `Sure! {"facts":[{"statement":"Redis stores sessions","subject":"Redis","predicate":"stores","object":"sessions","confidence":0.8}],` +
+		`"entities":[{"name":"Redis","type":"OTHER"},{"name":"Acme","type":"ORGANIZATION"}],` +
+		`"relationships":[{"from":"Acme","to":"Redis","type":"uses","confidence":0.9}]}`
+
+	var calls atomic.Int32
+	var format string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req inference.GenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		format = req.Format
+		body, _ := json.Marshal(map[string]interface{}{"response": extraction, "done": true})
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	extractor := NewQwenExtractor(inference.NewClient(&inference.Config{OllamaURL: server.URL, Model: "test"}))
+	result, err := extractor.Extract(context.Background(), "Acme keeps its sessions in Redis")
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if calls.Load() != 1 || format != "json" {
+		t.Errorf("Expected one JSON-format call, got %d calls with format %q", calls.Load(), format)
+	}
+	if len(result.Facts) != 1 || result.Facts[0].Source != "qwen-extraction" || result.Facts[0].ID == "" {
+		t.Errorf("Unexpected facts: %+v", result.Facts)
+	}
+	if len(result.Entities) != 2 || result.Entities[1].Name != "Acme" {
+		t.Errorf("Unexpected entities: %+v", result.Entities)
+	}
+	if len(result.Relationships) != 1 || result.Relationships[0].FromID != "Acme" || result.Relationships[0].Type != "uses" {
+		t.Errorf("Unexpected relationships: %+v", result.Relationships)
+	}
+}
+
+// recordingSemantic records the entities and relationships stored in it
+type recordingSemantic struct {
+	SemanticStore
+	entities []*models.Entity
+	rels     []*models.Relationship
+}
+
+func (s *recordingSemantic) StoreEntity(ctx context.Context, entity *models.Entity) error {
+	s.entities = append(s.entities, entity)
+	return nil
+}
+
+func (s *recordingSemantic) StoreRelationship(ctx context.Context, rel *models.Relationship) error {
+	s.rels = append(s.rels, rel)
+	return nil
+}
+
+func (s *recordingSemantic) QueryEntities(ctx context.Context, query string) ([]*models.Entity, error) {
+	var found []*models.Entity
+	for _, entity := range s.entities {
+		if strings.EqualFold(entity.Name, query) {
+			found = append(found, entity)
+		}
+	}
+	return found, nil
+}
+
+func (s *recordingSemantic) TraverseGraph(ctx context.Context, startID string, depth int) ([]*models.Entity, []*models.Relationship, error) {
+	return nil, s.rels, nil
+}
+
+// TestStoreExtraction tests that relationships are stored between the IDs of
+// the extracted entities, and skipped when an endpoint is unknown, and that
+// facts are kept with the entity they are about
+func TestStoreExtraction(t *testing.T) {
+	semantic := &recordingSemantic{}
+	service := &MemoryService{semantic: semantic, logger: logging.OrDiscard(nil)}
+
+	service.storeExtraction(context.Background(), &Extraction{
+		Entities: []*models.Entity{{ID: "entity:1", Name: "Acme"}, {ID: "entity:2", Name: "Redis"}},
+		Relationships: []*models.Relationship{
+			{ID: "rel:1", FromID: "Acme", ToID: "Redis", Type: "uses"},
+			{ID: "rel:2", FromID: "Acme", ToID: "Postgres", Type: "uses"},
+		},
+		Facts: []Fact{
+			{Statement: "Redis listens on port 6379", Subject: "redis"},
+			{Statement: "Postgres runs on version 16", Subject: "Postgres"},
+		},
+	})
+
+	if len(semantic.entities) != 2 {
+		t.Errorf("Expected both entities stored, got %d", len(semantic.entities))
+	}
+	if len(semantic.rels) != 1 || semantic.rels[0].FromID != "entity:1" || semantic.rels[0].ToID != "entity:2" {
+		t.Errorf("Expected one relationship between entity IDs, got %+v", semantic.rels)
+	}
+	if facts := entityFacts(semantic.entities[1]); len(facts) != 1 || facts[0] != "Redis listens on port 6379" {
+		t.Errorf("Expected the Redis fact stored with its entity, got %v", facts)
+	}
+	if facts := entityFacts(semantic.entities[0]); len(facts) != 0 {
+		t.Errorf("Expected no facts for Acme, got %v", facts)
+	}
+
+	// Read back from the graph, facts are decoded JSON
+	redis := &models.Entity{Name: "Redis", Type: "OTHER", Attributes: map[string]interface{}{"facts": []interface{}{"Redis listens on port 6379"}}}
+	if got := service.describeEntity(context.Background(), redis); got != "Redis (OTHER); Redis listens on port 6379" {
+		t.Errorf("Unexpected description %q", got)
+	}
+}
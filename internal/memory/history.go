@@ -0,0 +1,261 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+const (
+	// defaultKeepRecent is how many of the latest messages compaction keeps verbatim
+	defaultKeepRecent = 6
+
+	// defaultSummaryTokens is the length asked of a history summary
+	defaultSummaryTokens = 300
+
+	// defaultChunkTokens bounds the estimated tokens of transcript sent to
+	// one Summarize call
+	defaultChunkTokens = 4000
+
+	// keptShare is the fraction of the budget Fit keeps verbatim, leaving the
+	// rest for the summary and the turns that follow before the next compaction
+	keptShare = 2
+
+	// summaryPrefix starts the system message that replaces compacted turns
+	summaryPrefix = "Summary of the earlier conversation:\n"
+
+	// sessionSummaryTokens is the length asked of a session summary
+	sessionSummaryTokens = 200
+
+	// minSessionQueries is how many questions a session needs to be summarized
+	minSessionQueries = 2
+)
+
+// SessionSummaryTag marks the episodic memory summarizing a whole CLI session
+const SessionSummaryTag = "session-summary"
+
+// DocumentStore stores embedded documents; Service implements it
+type DocumentStore interface {
+	StoreDocument(ctx context.Context, id, content string, metadata map[string]interface{}) error
+}
+
+// Summarizer condenses text to roughly maxTokens tokens; QwenExtractor
+// implements it
+type Summarizer interface {
+	Summarize(ctx context.Context, text string, maxTokens int) (string, error)
+}
+
+// HistoryWindow keeps a conversation short enough for the model's context by
+// summarizing its oldest turns into a single system message
+type HistoryWindow struct {
+	summarizer    Summarizer
+	KeepRecent    int // Most latest messages kept verbatim
+	SummaryTokens int // Length asked of the summary
+	ChunkTokens   int // Most transcript tokens summarized in one call
+}
+
+// Compaction describes one compaction of a history
+type Compaction struct {
+	Summarized   int // Messages replaced by the summary
+	Kept         int // Recent messages kept verbatim
+	TokensBefore int
+	TokensAfter  int
+}
+
+// Saved is how many estimated tokens the compaction freed
+func (c *Compaction) Saved() int {
+	return c.TokensBefore - c.TokensAfter
+}
+
+// NewHistoryWindow creates a history window summarizing with summarizer
+func NewHistoryWindow(summarizer Summarizer) *HistoryWindow {
+	return &HistoryWindow{
+		summarizer:    summarizer,
+		KeepRecent:    defaultKeepRecent,
+		SummaryTokens: defaultSummaryTokens,
+		ChunkTokens:   defaultChunkTokens,
+	}
+}
+
+// EstimateTokens roughly counts the tokens in messages, at four characters
+// per token
+func EstimateTokens(messages []models.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += (len(msg.Role) + len(msg.Content)) / 4
+	}
+	return total
+}
+
+// Fit compacts history if its estimated size exceeds budget tokens, and
+// returns it unchanged with a nil Compaction otherwise. The latest messages
+// are kept verbatim only while they fit in half the budget, so long turns
+// don't leave the history over budget and compacting again every turn.
+func (w *HistoryWindow) Fit(ctx context.Context, history []models.Message, budget int) ([]models.Message, *Compaction, error) {
+	if EstimateTokens(history) <= budget {
+		return history, nil, nil
+	}
+	return w.compact(ctx, history, len(history)-w.recentWithin(history, budget/keptShare))
+}
+
+// Compact summarizes all but the latest KeepRecent messages into one system
+// message. A summary left by an earlier compaction is folded into the new
+// one. History with nothing older than the kept messages is returned
+// unchanged with a nil Compaction.
+func (w *HistoryWindow) Compact(ctx context.Context, history []models.Message) ([]models.Message, *Compaction, error) {
+	return w.compact(ctx, history, len(history)-w.KeepRecent)
+}
+
+// recentWithin counts the latest messages, at most KeepRecent, that fit in
+// tokens
+func (w *HistoryWindow) recentWithin(history []models.Message, tokens int) int {
+	kept, used := 0, 0
+	for i := len(history) - 1; i >= 0 && kept < w.KeepRecent; i-- {
+		used += EstimateTokens(history[i : i+1])
+		if used > tokens {
+			break
+		}
+		kept++
+	}
+	return kept
+}
+
+// compact summarizes the messages before split and keeps the rest
+func (w *HistoryWindow) compact(ctx context.Context, history []models.Message, split int) ([]models.Message, *Compaction, error) {
+	if split <= 0 || (split == 1 && IsHistorySummary(history[0])) {
+		return history, nil, nil
+	}
+	older, recent := history[:split], history[split:]
+
+	summary, err := summarizeChunks(ctx, w.summarizer, older, w.ChunkTokens, w.SummaryTokens)
+	if err != nil {
+		return history, nil, fmt.Errorf("failed to summarize history: %w", err)
+	}
+
+	metadata := map[string]interface{}{"summary": true}
+	for _, msg := range older {
+		// A summary mixing in forgotten turns is forgotten too
+		if forget, _ := msg.Metadata["forget"].(bool); forget {
+			metadata["forget"] = true
+			break
+		}
+	}
+
+	compacted := make([]models.Message, 0, len(recent)+1)
+	compacted = append(compacted, models.Message{
+		Role:      "system",
+		Content:   summaryPrefix + summary,
+		Metadata:  metadata,
+		Timestamp: time.Now(),
+	})
+	compacted = append(compacted, recent...)
+
+	return compacted, &Compaction{
+		Summarized:   len(older),
+		Kept:         len(recent),
+		TokensBefore: EstimateTokens(history),
+		TokensAfter:  EstimateTokens(compacted),
+	}, nil
+}
+
+// summarizeChunks summarizes messages to roughly maxTokens. Transcripts
+// longer than chunkTokens are summarized a chunk of whole messages at a time
+// and the chunk summaries summarized together, so no single call overflows
+// the model's context; chunkTokens <= 0 summarizes in one call.
+func summarizeChunks(ctx context.Context, summarizer Summarizer, messages []models.Message, chunkTokens, maxTokens int) (string, error) {
+	chunks := chunkMessages(messages, chunkTokens)
+	if len(chunks) == 1 {
+		return summarizer.Summarize(ctx, transcript(chunks[0]), maxTokens)
+	}
+
+	parts := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		part, err := summarizer.Summarize(ctx, transcript(chunk), maxTokens)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	return summarizer.Summarize(ctx, strings.Join(parts, "\n\n"), maxTokens)
+}
+
+// chunkMessages splits messages into runs of at most tokens estimated
+// tokens; a message larger than that is a chunk of its own
+func chunkMessages(messages []models.Message, tokens int) [][]models.Message {
+	if tokens <= 0 {
+		return [][]models.Message{messages}
+	}
+
+	var chunks [][]models.Message
+	start, size := 0, 0
+	for i := range messages {
+		n := EstimateTokens(messages[i : i+1])
+		if i > start && size+n > tokens {
+			chunks = append(chunks, messages[start:i])
+			start, size = i, 0
+		}
+		size += n
+	}
+	return append(chunks, messages[start:])
+}
+
+// StoreSessionSummary summarizes a session's history and stores it as one
+// episodic memory tagged SessionSummaryTag, so later sessions can recall what
+// was worked on without retrieving every turn. Messages whose "forget"
+// metadata is set, e.g. turns with agents that don't use memory, are left
+// out. Sessions with fewer than two questions are skipped and return false.
+func StoreSessionSummary(ctx context.Context, store DocumentStore, summarizer Summarizer, history []models.Message, started time.Time) (bool, error) {
+	var kept []models.Message
+	queries := 0
+	for _, msg := range history {
+		if forget, _ := msg.Metadata["forget"].(bool); forget {
+			continue
+		}
+		if msg.Role == "user" {
+			queries++
+		}
+		kept = append(kept, msg)
+	}
+	if queries < minSessionQueries {
+		return false, nil
+	}
+
+	summary, err := summarizeChunks(ctx, summarizer, kept, defaultChunkTokens, sessionSummaryTokens)
+	if err != nil {
+		return false, fmt.Errorf("failed to summarize session: %w", err)
+	}
+
+	content := fmt.Sprintf("Session summary (%s, %d questions): %s", started.Format("2006-01-02"), queries, summary)
+	metadata := map[string]interface{}{
+		"tag":      SessionSummaryTag,
+		"messages": len(kept),
+		"started":  started.Format(time.RFC3339),
+		"ended":    time.Now().Format(time.RFC3339),
+	}
+	if err := store.StoreDocument(ctx, fmt.Sprintf("session:%d", started.Unix()), content, metadata); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IsHistorySummary reports whether msg is a summary left by compaction
+func IsHistorySummary(msg models.Message) bool {
+	summary, _ := msg.Metadata["summary"].(bool)
+	return summary
+}
+
+// transcript renders messages one turn per paragraph for summarization
+func transcript(messages []models.Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		content := msg.Content
+		if IsHistorySummary(msg) {
+			content = strings.TrimPrefix(content, summaryPrefix)
+		}
+		fmt.Fprintf(&b, "%s: %s\n\n", msg.Role, content)
+	}
+	return strings.TrimSpace(b.String())
+}
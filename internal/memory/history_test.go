@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// fakeSummarizer records the text it is given and returns a fixed summary
+type fakeSummarizer struct {
+	texts []string
+	err   error
+}
+
+func (s *fakeSummarizer) Summarize(ctx context.Context, text string, maxTokens int) (string, error) {
+	s.texts = append(s.texts, text)
+	return fmt.Sprintf("summary %d", len(s.texts)), s.err
+}
+
+// conversation builds n alternating user and assistant messages
+func conversation(n int) []models.Message {
+	messages := make([]models.Message, n)
+	for i := range messages {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages[i] = models.Message{Role: role, Content: fmt.Sprintf("turn %d %s", i, strings.Repeat("x", 200))}
+	}
+	return messages
+}
+
+// TestHistoryWindowFit tests that history is compacted only once it exceeds
+// the budget, keeping recent turns and folding earlier summaries in
+func TestHistoryWindowFit(t *testing.T) {
+	summarizer := &fakeSummarizer{}
+	window := NewHistoryWindow(summarizer)
+	window.KeepRecent = 4
+	ctx := context.Background()
+
+	history := conversation(10)
+	same, compaction, err := window.Fit(ctx, history, EstimateTokens(history))
+	if err != nil || compaction != nil || len(same) != 10 {
+		t.Fatalf("Expected history within budget to be left alone, got %d messages, %+v, %v", len(same), compaction, err)
+	}
+
+	compacted, compaction, err := window.Fit(ctx, history, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compaction == nil || compaction.Summarized != 6 || compaction.Kept != 4 || compaction.Saved() <= 0 {
+		t.Fatalf("Unexpected compaction: %+v", compaction)
+	}
+	if len(compacted) != 5 || !IsHistorySummary(compacted[0]) || compacted[0].Role != "system" {
+		t.Fatalf("Expected a summary followed by the recent turns, got %+v", compacted)
+	}
+	if !strings.HasSuffix(compacted[0].Content, "summary 1") || compacted[1].Content != history[6].Content {
+		t.Errorf("Unexpected compacted history: %q, %q", compacted[0].Content, compacted[1].Content)
+	}
+	if !strings.Contains(summarizer.texts[0], "assistant: turn 5") || strings.Contains(summarizer.texts[0], "turn 6") {
+		t.Errorf("Expected only the older turns summarized, got %q", summarizer.texts[0])
+	}
+
+	// A later compaction folds the earlier summary into the new one
+	compacted = append(compacted, conversation(2)...)
+	compacted, compaction, err = window.Compact(ctx, compacted)
+	if err != nil || compaction == nil || len(compacted) != 5 {
+		t.Fatalf("Expected a second compaction, got %d messages, %+v, %v", len(compacted), compaction, err)
+	}
+	if !strings.HasPrefix(summarizer.texts[1], "system: summary 1") {
+		t.Errorf("Expected the earlier summary to be summarized again, got %q", summarizer.texts[1])
+	}
+
+	// Nothing but a summary and the kept turns: nothing to do
+	if _, compaction, _ := window.Compact(ctx, compacted); compaction != nil {
+		t.Errorf("Expected no compaction, got %+v", compaction)
+	}
+}
+
+// TestHistoryWindowFitLongTurns tests that Fit keeps only the recent turns
+// that fit in half the budget, so the next turn doesn't compact again
+func TestHistoryWindowFitLongTurns(t *testing.T) {
+	summarizer := &fakeSummarizer{}
+	window := NewHistoryWindow(summarizer)
+	ctx := context.Background()
+
+	history := conversation(4)
+	history = append(history, models.Message{Role: "user", Content: strings.Repeat("y", 1200)},
+		models.Message{Role: "assistant", Content: strings.Repeat("z", 1200)})
+
+	compacted, compaction, err := window.Fit(ctx, history, 500)
+	if err != nil || compaction == nil {
+		t.Fatalf("Expected a compaction, got %+v, %v", compaction, err)
+	}
+	if compaction.Kept != 0 || compaction.TokensAfter > 250 {
+		t.Fatalf("Expected the long turns summarized too, got %+v", compaction)
+	}
+
+	compacted = append(compacted, conversation(2)...)
+	if _, compaction, _ := window.Fit(ctx, compacted, 500); compaction != nil || len(summarizer.texts) != 1 {
+		t.Errorf("Expected no compaction on the next turn, got %+v after %d summaries", compaction, len(summarizer.texts))
+	}
+}
+
+// TestHistoryWindowChunks tests that long transcripts are summarized a chunk
+// at a time, then the chunk summaries together
+func TestHistoryWindowChunks(t *testing.T) {
+	summarizer := &fakeSummarizer{}
+	window := NewHistoryWindow(summarizer)
+	window.KeepRecent = 2
+	window.ChunkTokens = 120 // Two 53-token turns per chunk
+
+	compacted, compaction, err := window.Compact(context.Background(), conversation(8))
+	if err != nil || compaction == nil || compaction.Summarized != 6 {
+		t.Fatalf("Expected six turns summarized, got %+v, %v", compaction, err)
+	}
+	if len(summarizer.texts) != 4 {
+		t.Fatalf("Expected three chunk summaries and one of them together, got %d calls", len(summarizer.texts))
+	}
+	if !strings.Contains(summarizer.texts[0], "turn 1") || strings.Contains(summarizer.texts[0], "turn 2") {
+		t.Errorf("Expected the first chunk to hold two turns, got %q", summarizer.texts[0])
+	}
+	if summarizer.texts[3] != "summary 1\n\nsummary 2\n\nsummary 3" {
+		t.Errorf("Expected the chunk summaries summarized together, got %q", summarizer.texts[3])
+	}
+	if !strings.HasSuffix(compacted[0].Content, "summary 4") {
+		t.Errorf("Expected the combined summary kept, got %q", compacted[0].Content)
+	}
+}
+
+// TestHistoryWindowError tests that a failed summary leaves history intact
+func TestHistoryWindowError(t *testing.T) {
+	window := NewHistoryWindow(&fakeSummarizer{err: errors.New("model offline")})
+	history := conversation(10)
+
+	kept, compaction, err := window.Compact(context.Background(), history)
+	if err == nil || compaction != nil || len(kept) != 10 {
+		t.Errorf("Expected the history kept on error, got %d messages, %+v, %v", len(kept), compaction, err)
+	}
+}
+
+// documentRecorder records stored documents
+type documentRecorder struct {
+	ids      []string
+	contents []string
+	metadata []map[string]interface{}
+}
+
+func (r *documentRecorder) StoreDocument(ctx context.Context, id, content string, metadata map[string]interface{}) error {
+	r.ids = append(r.ids, id)
+	r.contents = append(r.contents, content)
+	r.metadata = append(r.metadata, metadata)
+	return nil
+}
+
+// TestStoreSessionSummary tests that a session is stored as one tagged
+// summary, leaving out forgotten turns, and that short sessions are skipped
+func TestStoreSessionSummary(t *testing.T) {
+	summarizer := &fakeSummarizer{}
+	store := &documentRecorder{}
+	started := time.Date(2026, 10, 9, 14, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	history := []models.Message{
+		{Role: "user", Content: "Refactor the auth middleware"},
+		{Role: "assistant", Content: "Done, tokens are now validated once"},
+		{Role: "user", Content: "Scan for secrets", Metadata: map[string]interface{}{"forget": true}},
+		{Role: "assistant", Content: "Found an API key", Metadata: map[string]interface{}{"forget": true}},
+	}
+	if stored, err := StoreSessionSummary(ctx, store, summarizer, history, started); err != nil || stored {
+		t.Fatalf("Expected a one-question session to be skipped, got %v, %v", stored, err)
+	}
+
+	history = append(history, models.Message{Role: "user", Content: "Add tests for it"}, models.Message{Role: "assistant", Content: "Added"})
+	stored, err := StoreSessionSummary(ctx, store, summarizer, history, started)
+	if err != nil || !stored {
+		t.Fatalf("Expected the session to be stored, got %v, %v", stored, err)
+	}
+
+	if len(store.ids) != 1 || store.ids[0] != fmt.Sprintf("session:%d", started.Unix()) {
+		t.Fatalf("Expected one session document, got %v", store.ids)
+	}
+	if store.contents[0] != "Session summary (2026-10-09, 2 questions): summary 1" {
+		t.Errorf("Unexpected content: %q", store.contents[0])
+	}
+	if store.metadata[0]["tag"] != SessionSummaryTag || store.metadata[0]["messages"] != 4 {
+		t.Errorf("Unexpected metadata: %v", store.metadata[0])
+	}
+	if strings.Contains(summarizer.texts[0], "API key") || !strings.Contains(summarizer.texts[0], "auth middleware") {
+		t.Errorf("Expected forgotten turns left out of the summary, got %q", summarizer.texts[0])
+	}
+}
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"context"
+	"log/slog"
 	"time"
 
 	"github.com/quantumflow/quantumflow/internal/models"
@@ -12,6 +13,9 @@ type Service interface {
 	// Store persists an interaction to memory
 	Store(ctx context.Context, interaction *models.Interaction) error
 
+	// StoreDocument embeds and stores a document, such as indexed source code, under id
+	StoreDocument(ctx context.Context, id, content string, metadata map[string]interface{}) error
+
 	// Retrieve fetches the top-k most relevant memories for a query
 	Retrieve(ctx context.Context, query string, k int) ([]*models.Memory, error)
 
@@ -24,6 +28,27 @@ type Service interface {
 	// GetStats returns memory service statistics
 	GetStats(ctx context.Context) (*Stats, error)
 
+	// ClearEpisodic wipes conversation history and returns how many entries were removed
+	ClearEpisodic(ctx context.Context) (int64, error)
+
+	// Pin keeps an episodic memory forever and exempts it from compaction
+	Pin(ctx context.Context, id string) error
+
+	// Unpin clears an episodic memory's pin, restarting its retention
+	Unpin(ctx context.Context, id string) error
+
+	// Status reports the availability of each backing store
+	Status() []*StoreStatus
+
+	// SuggestPatterns returns up to k proven workflow patterns relevant to a query
+	SuggestPatterns(ctx context.Context, query string, k int) ([]*models.WorkflowPattern, error)
+
+	// RecordPatternUse credits a workflow pattern that contributed to a successful run
+	RecordPatternUse(ctx context.Context, id string) error
+
+	// RecordWorkflow saves a successful workflow, or credits an existing near-identical pattern
+	RecordWorkflow(ctx context.Context, pattern *models.WorkflowPattern) error
+
 	// Close gracefully shuts down the memory service
 	Close() error
 }
@@ -33,8 +58,25 @@ type EpisodicStore interface {
 	// Store stores a memory entry with vector embedding
 	Store(ctx context.Context, memory *models.Memory) error
 
-	// Search performs vector similarity search
-	Search(ctx context.Context, embedding []float32, k int) ([]*models.Memory, error)
+	// Search performs vector similarity search, dropping results whose cosine
+	// similarity (returned in Memory.Score) is below minScore
+	Search(ctx context.Context, embedding []float32, k int, minScore float64) ([]*models.Memory, error)
+
+	// Get returns a memory by ID, including its embedding
+	Get(ctx context.Context, id string) (*models.Memory, error)
+
+	// SetTTL changes how long a memory is kept from now; zero or less keeps
+	// it forever
+	SetTTL(ctx context.Context, id string, ttl time.Duration) error
+
+	// Pin keeps a memory forever and exempts it from compaction
+	Pin(ctx context.Context, id string) error
+
+	// Unpin clears a memory's pin, restarting its retention
+	Unpin(ctx context.Context, id string) error
+
+	// List returns every memory, including embeddings
+	List(ctx context.Context) ([]*models.Memory, error)
 
 	// Delete removes a memory entry
 	Delete(ctx context.Context, id string) error
@@ -42,6 +84,9 @@ type EpisodicStore interface {
 	// Count returns total number of episodic memories
 	Count(ctx context.Context) (int64, error)
 
+	// Clear removes all episodic memories and returns how many were deleted
+	Clear(ctx context.Context) (int64, error)
+
 	// Close closes the store connection
 	Close() error
 }
@@ -60,6 +105,9 @@ type SemanticStore interface {
 	// Traverse performs graph traversal from a starting entity
 	Traverse(ctx context.Context, startID string, depth int) ([]*models.Entity, error)
 
+	// TraverseGraph performs graph traversal and also returns the typed edges between entities
+	TraverseGraph(ctx context.Context, startID string, depth int) ([]*models.Entity, []*models.Relationship, error)
+
 	// ResolveEntity finds or merges duplicate entities
 	ResolveEntity(ctx context.Context, name string, entityType string) (*models.Entity, error)
 
@@ -84,6 +132,13 @@ type ProceduralStore interface {
 	// GetTopPatterns returns most frequently used patterns
 	GetTopPatterns(ctx context.Context, limit int) ([]*models.WorkflowPattern, error)
 
+	// DeletePattern removes a pattern by ID
+	DeletePattern(ctx context.Context, id string) error
+
+	// PruneByAge removes patterns not used within olderThan, sooner for
+	// patterns with a low success rate, and returns how many were removed
+	PruneByAge(ctx context.Context, olderThan time.Duration) (int, error)
+
 	// Close closes the store connection
 	Close() error
 }
@@ -101,6 +156,17 @@ type Extractor interface {
 
 	// Summarize creates a concise summary of text
 	Summarize(ctx context.Context, text string, maxTokens int) (string, error)
+
+	// Extract pulls facts, entities and relationships from text at once
+	Extract(ctx context.Context, text string) (*Extraction, error)
+}
+
+// Extraction is everything extracted from one text. Relationship endpoints
+// are entity names.
+type Extraction struct {
+	Facts         []Fact
+	Entities      []*models.Entity
+	Relationships []*models.Relationship
 }
 
 // Compactor handles memory compaction and deduplication
@@ -150,6 +216,18 @@ type Stats struct {
 	AvgRetrievalMs  float64       `json:"avg_retrieval_ms"`
 	CacheHitRate    float64       `json:"cache_hit_rate"`
 	Uptime          time.Duration `json:"uptime"`
+
+	// EmbeddingFallback is why embeddings fell back to word hashing this
+	// session; empty while the configured provider works
+	EmbeddingFallback string `json:"embedding_fallback,omitempty"`
+}
+
+// StoreStatus reports whether a backing store initialized successfully
+type StoreStatus struct {
+	Store     string `json:"store"`   // episodic, semantic, procedural
+	Backend   string `json:"backend"` // redis, dgraph, badger
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
 }
 
 // CompactionResult contains results from a compaction operation
@@ -159,6 +237,7 @@ type CompactionResult struct {
 	SpaceSavedBytes    int64         `json:"space_saved_bytes"`
 	Duration           time.Duration `json:"duration"`
 	DeduplicationCount int           `json:"deduplication_count"`
+	PatternsPruned     int           `json:"patterns_pruned"`
 }
 
 // Config holds memory service configuration
@@ -168,44 +247,87 @@ type Config struct {
 	RedisPassword string
 	RedisDB       int
 
+	// Redis connection pool tuning (0 uses the go-redis defaults)
+	RedisPoolSize     int
+	RedisMinIdleConns int
+	RedisMaxRetries   int
+
+	// Initial Redis connection attempts and the base delay between them
+	RedisConnectAttempts int
+	RedisConnectBackoff  time.Duration
+
+	// MigrateIndex drops and recreates an episodic index built for other
+	// embedding dimensions or another embedding model or hashing version,
+	// deleting its memories; otherwise the episodic store refuses to start
+	MigrateIndex bool
+
 	// Dgraph configuration
 	DgraphURL      string
 	DgraphAlphaURL string
 
 	// BadgerDB configuration
-	BadgerPath string
+	BadgerPath       string
+	BadgerGCInterval time.Duration // Value-log GC interval (0 disables background GC)
 
 	// Compaction settings
 	CompactionEnabled  bool
 	CompactionInterval time.Duration
 	RetentionDays      int
+	RetentionByTag     map[string]int // Days to keep memories by metadata "tag"; 0 keeps them forever
+
+	// Retrieval settings
+	MinSimilarity float64 // Minimum cosine similarity for episodic search results
 
-	// Embedding configuration
+	// Embedding configuration. EmbeddingDimensions is also the size of the
+	// Redis vector index, so the provider's vectors must match it.
+	EmbeddingProvider   string // "simple", "huggingface" or "ollama"
 	EmbeddingDimensions int
 	EmbeddingModel      string // "sentence-transformers/all-MiniLM-L6-v2"
+	EmbeddingURL        string // Provider service URL; empty uses its default (ollama: the inference URL)
+
+	// OnEmbeddingFallback, when set, is called when the provider fails
+	// mid-session and embeddings fall back to word hashing
+	OnEmbeddingFallback func(err error)
+
+	// Extractor selects how facts and entities are extracted: "qwen" (the
+	// default) uses the model, "noop" returns canned results without one
+	Extractor string
 
 	// Performance tuning
 	CacheSize      int
 	BatchSize      int
 	MaxConcurrency int
+
+	// Logger receives background and best-effort failures (nil discards them)
+	Logger *slog.Logger
 }
 
 // DefaultConfig returns default memory service configuration
 func DefaultConfig() *Config {
 	return &Config{
-		RedisURL:            "localhost:6379",
-		RedisPassword:       "quantumflow123",
-		RedisDB:             0,
-		DgraphURL:           "localhost:8080",
-		DgraphAlphaURL:      "localhost:9080",
-		BadgerPath:          "~/.quantumflow/badger",
-		CompactionEnabled:   true,
-		CompactionInterval:  1 * time.Hour,
-		RetentionDays:       90,
-		EmbeddingDimensions: 384, // MiniLM-L6-v2 dimensions
-		EmbeddingModel:      "sentence-transformers/all-MiniLM-L6-v2",
-		CacheSize:           10000,
-		BatchSize:           32,
-		MaxConcurrency:      8,
+		RedisURL:             "localhost:6379",
+		RedisPassword:        "quantumflow123",
+		RedisDB:              0,
+		RedisPoolSize:        50,
+		RedisMinIdleConns:    5,
+		RedisMaxRetries:      3,
+		RedisConnectAttempts: 5,
+		RedisConnectBackoff:  500 * time.Millisecond,
+		DgraphURL:            "localhost:8080",
+		DgraphAlphaURL:       "localhost:9080",
+		BadgerPath:           "~/.quantumflow/badger",
+		BadgerGCInterval:     10 * time.Minute,
+		CompactionEnabled:    true,
+		CompactionInterval:   1 * time.Hour,
+		RetentionDays:        90,
+		RetentionByTag:       map[string]int{SessionSummaryTag: 365},
+		MinSimilarity:        0.3,
+		EmbeddingProvider:    EmbeddingSimple,
+		EmbeddingDimensions:  384, // MiniLM-L6-v2 dimensions
+		EmbeddingModel:       "sentence-transformers/all-MiniLM-L6-v2",
+		Extractor:            ExtractorQwen,
+		CacheSize:            10000,
+		BatchSize:            32,
+		MaxConcurrency:       8,
 	}
 }
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// Extractor names for Config.Extractor
+const (
+	ExtractorQwen = "qwen" // The configured model, via QwenExtractor
+	ExtractorNoOp = "noop" // Canned results without a model, for tests and offline runs
+)
+
+// ErrUnknownExtractor is returned for an unrecognized Config.Extractor
+var ErrUnknownExtractor = errors.New("unknown extractor")
+
+// NewExtractor creates the extractor named by Config.Extractor; an empty
+// name selects the model-backed QwenExtractor
+func NewExtractor(name string, client inference.Generator) (Extractor, error) {
+	switch name {
+	case "", ExtractorQwen:
+		return NewQwenExtractor(client), nil
+	case ExtractorNoOp:
+		return NewNoOpExtractor(), nil
+	default:
+		return nil, fmt.Errorf("%w %q (want %s or %s)", ErrUnknownExtractor, name, ExtractorQwen, ExtractorNoOp)
+	}
+}
+
+// NoOpExtractor implements Extractor without a model. Its results are
+// deterministic: the first sentence of the text as a single fact, and each
+// distinct capitalized word as an entity. It finds no relationships.
+type NoOpExtractor struct{}
+
+// NewNoOpExtractor creates an extractor that never calls a model
+func NewNoOpExtractor() *NoOpExtractor {
+	return &NoOpExtractor{}
+}
+
+// ExtractFacts returns the first sentence of text as a fact
+func (e *NoOpExtractor) ExtractFacts(ctx context.Context, text string) ([]Fact, error) {
+	statement := firstSentence(text)
+	if statement == "" {
+		return []Fact{}, nil
+	}
+	return []Fact{{
+		ID:         "fact:noop:0",
+		Statement:  statement,
+		Confidence: 1,
+		Source:     "noop-extraction",
+		Timestamp:  time.Now(),
+	}}, nil
+}
+
+// ExtractEntities returns each distinct capitalized word in text as an
+// entity of type OTHER
+func (e *NoOpExtractor) ExtractEntities(ctx context.Context, text string) ([]*models.Entity, error) {
+	seen := make(map[string]bool)
+	entities := []*models.Entity{}
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-'
+	}) {
+		if len(word) < 2 || !unicode.IsUpper([]rune(word)[0]) || seen[word] {
+			continue
+		}
+		seen[word] = true
+		entities = append(entities, &models.Entity{
+			ID:         "entity:noop:" + strings.ToLower(word),
+			Name:       word,
+			Type:       "OTHER",
+			Attributes: make(map[string]interface{}),
+		})
+	}
+	return entities, nil
+}
+
+// ExtractRelationships finds no relationships
+func (e *NoOpExtractor) ExtractRelationships(ctx context.Context, text string) ([]*models.Relationship, error) {
+	return []*models.Relationship{}, nil
+}
+
+// Summarize truncates text to about maxTokens tokens
+func (e *NoOpExtractor) Summarize(ctx context.Context, text string, maxTokens int) (string, error) {
+	text = strings.TrimSpace(text)
+	if limit := maxTokens * 4; maxTokens > 0 && len(text) > limit {
+		return text[:limit] + "...", nil
+	}
+	return text, nil
+}
+
+// Extract combines the facts, entities and relationships found in text
+func (e *NoOpExtractor) Extract(ctx context.Context, text string) (*Extraction, error) {
+	facts, _ := e.ExtractFacts(ctx, text)
+	entities, _ := e.ExtractEntities(ctx, text)
+	relationships, _ := e.ExtractRelationships(ctx, text)
+	return &Extraction{Facts: facts, Entities: entities, Relationships: relationships}, nil
+}
+
+// firstSentence is text up to and including its first full stop, question
+// or exclamation mark
+func firstSentence(text string) string {
+	text = strings.TrimSpace(text)
+	if end := strings.IndexAny(text, ".?!"); end != -1 {
+		return text[:end+1]
+	}
+	return text
+}
@@ -4,18 +4,38 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
+	"github.com/quantumflow/quantumflow/internal/logging"
 	"github.com/quantumflow/quantumflow/internal/models"
 )
 
+// badgerGCDiscardRatio is the fraction of stale data a value-log file must
+// contain before GC rewrites it
+const badgerGCDiscardRatio = 0.5
+
+// minPatternStringSimilarity is the action:tool match score above which a
+// pattern counts as similar without comparing embeddings
+const minPatternStringSimilarity = 0.5
+
+// minPatternEmbeddingSimilarity is the cosine similarity between step
+// description embeddings above which a pattern counts as similar
+const minPatternEmbeddingSimilarity = 0.75
+
 // BadgerProceduralStore implements ProceduralStore using BadgerDB
 type BadgerProceduralStore struct {
-	db *badger.DB
+	db        *badger.DB
+	logger    *slog.Logger
+	embedding EmbeddingGenerator // Optional; without it only exact step matches are found
+	stopCh    chan struct{}
+	doneCh    chan struct{}
 }
 
 // NewBadgerProceduralStore creates a new BadgerDB-backed procedural store
@@ -31,7 +51,59 @@ func NewBadgerProceduralStore(config *Config) (*BadgerProceduralStore, error) {
 		return nil, fmt.Errorf("failed to open BadgerDB: %w", err)
 	}
 
-	return &BadgerProceduralStore{db: db}, nil
+	store := &BadgerProceduralStore{
+		db:     db,
+		logger: logging.OrDiscard(config.Logger),
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+
+	// Start background value-log GC if enabled
+	if config.BadgerGCInterval > 0 {
+		go store.runValueLogGC(config.BadgerGCInterval)
+	} else {
+		close(store.doneCh)
+	}
+
+	return store, nil
+}
+
+// runValueLogGC periodically reclaims value-log space left behind by rewritten keys
+func (s *BadgerProceduralStore) runValueLogGC(interval time.Duration) {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Compact(); err != nil {
+				s.logger.Warn("badger value log GC failed", "error", err)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// SetEmbeddingGenerator enables embedding-based similarity search. Patterns
+// stored from then on carry an embedding of their step descriptions.
+func (s *BadgerProceduralStore) SetEmbeddingGenerator(embedding EmbeddingGenerator) {
+	s.embedding = embedding
+}
+
+// Compact runs value-log GC until no more files can be rewritten
+func (s *BadgerProceduralStore) Compact() error {
+	for {
+		err := s.db.RunValueLogGC(badgerGCDiscardRatio)
+		if err == badger.ErrNoRewrite || err == badger.ErrRejected {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("value log GC failed: %w", err)
+		}
+	}
 }
 
 // StorePattern saves a workflow pattern
@@ -40,6 +112,16 @@ func (s *BadgerProceduralStore) StorePattern(ctx context.Context, pattern *model
 		pattern.ID = fmt.Sprintf("pattern:%d", time.Now().UnixNano())
 	}
 
+	if s.embedding != nil && len(pattern.Embedding) == 0 {
+		embedding, err := s.embedding.Generate(ctx, stepDescriptions(pattern.Steps))
+		if err != nil {
+			// The pattern is still found by exact step matches
+			s.logger.Warn("failed to embed workflow pattern", "pattern", pattern.ID, "error", err)
+		} else {
+			pattern.Embedding = embedding
+		}
+	}
+
 	data, err := json.Marshal(pattern)
 	if err != nil {
 		return fmt.Errorf("failed to marshal pattern: %w", err)
@@ -77,15 +159,33 @@ func (s *BadgerProceduralStore) GetPattern(ctx context.Context, id string) (*mod
 	return &pattern, nil
 }
 
-// FindSimilarPatterns finds patterns similar to given steps
+// FindSimilarPatterns finds the k patterns most similar to given steps. The
+// cheap action:tool match runs first; patterns it rejects are compared by the
+// cosine similarity of their step description embeddings, so steps worded
+// differently but with the same intent still match.
 func (s *BadgerProceduralStore) FindSimilarPatterns(ctx context.Context, steps []models.WorkflowStep, k int) ([]*models.WorkflowPattern, error) {
 	// Extract action signatures for matching
 	signatures := make([]string, len(steps))
 	for i, step := range steps {
-		signatures[i] = fmt.Sprintf("%s:%s", step.Action, step.Tool)
+		signatures[i] = stepSignature(step)
 	}
 
-	var patterns []*models.WorkflowPattern
+	var query []float32
+	if s.embedding != nil && len(steps) > 0 {
+		var err error
+		query, err = s.embedding.Generate(ctx, stepDescriptions(steps))
+		if err != nil {
+			s.logger.Warn("failed to embed workflow steps; using exact matches only", "error", err)
+			query = nil
+		}
+	}
+
+	type scoredPattern struct {
+		pattern *models.WorkflowPattern
+		score   float64
+	}
+
+	var candidates []scoredPattern
 	err := s.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.Prefix = []byte("workflow:pattern:")
@@ -94,6 +194,10 @@ func (s *BadgerProceduralStore) FindSimilarPatterns(ctx context.Context, steps [
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			item := it.Item()
 			err := item.Value(func(val []byte) error {
 				var pattern models.WorkflowPattern
@@ -103,8 +207,14 @@ func (s *BadgerProceduralStore) FindSimilarPatterns(ctx context.Context, steps [
 
 				// Calculate similarity score
 				score := calculatePatternSimilarity(signatures, pattern.Steps)
-				if score > 0.5 { // Threshold for similarity
-					patterns = append(patterns, &pattern)
+				if score <= minPatternStringSimilarity && query != nil {
+					score = cosineSimilarity(query, pattern.Embedding)
+					if score < minPatternEmbeddingSimilarity {
+						return nil
+					}
+				}
+				if score > minPatternStringSimilarity {
+					candidates = append(candidates, scoredPattern{pattern: &pattern, score: score})
 				}
 
 				return nil
@@ -112,11 +222,6 @@ func (s *BadgerProceduralStore) FindSimilarPatterns(ctx context.Context, steps [
 			if err != nil {
 				continue
 			}
-
-			// Limit results
-			if len(patterns) >= k {
-				break
-			}
 		}
 		return nil
 	})
@@ -125,6 +230,23 @@ func (s *BadgerProceduralStore) FindSimilarPatterns(ctx context.Context, steps [
 		return nil, err
 	}
 
+	// Rank by similarity, breaking ties by usage frequency
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].pattern.Frequency > candidates[j].pattern.Frequency
+	})
+
+	if k > 0 && len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	patterns := make([]*models.WorkflowPattern, len(candidates))
+	for i, c := range candidates {
+		patterns[i] = c.pattern
+	}
+
 	return patterns, nil
 }
 
@@ -202,29 +324,173 @@ func (s *BadgerProceduralStore) GetTopPatterns(ctx context.Context, limit int) (
 	return patterns, nil
 }
 
-// Close closes the BadgerDB instance
+// DeletePattern removes a pattern by ID
+func (s *BadgerProceduralStore) DeletePattern(ctx context.Context, id string) error {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		key := []byte(fmt.Sprintf("workflow:pattern:%s", id))
+		if _, err := txn.Get(key); err != nil {
+			return err
+		}
+		return txn.Delete(key)
+	})
+
+	if err == badger.ErrKeyNotFound {
+		return fmt.Errorf("pattern not found: %s", id)
+	}
+	return err
+}
+
+// PruneByAge removes patterns not used within olderThan. Patterns whose
+// success rate is too low to be suggested only get half that, so they don't
+// crowd out working patterns in FindSimilarPatterns.
+func (s *BadgerProceduralStore) PruneByAge(ctx context.Context, olderThan time.Duration) (int, error) {
+	now := time.Now()
+
+	var staleKeys [][]byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte("workflow:pattern:")
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var pattern models.WorkflowPattern
+				if err := json.Unmarshal(val, &pattern); err != nil {
+					return nil
+				}
+				if patternExpired(&pattern, olderThan, now) {
+					staleKeys = append(staleKeys, item.KeyCopy(nil))
+				}
+				return nil
+			})
+			if err != nil {
+				continue
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(staleKeys) == 0 {
+		return 0, nil
+	}
+
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, key := range staleKeys {
+		if err := wb.Delete(key); err != nil {
+			return 0, fmt.Errorf("failed to delete pattern: %w", err)
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to prune patterns: %w", err)
+	}
+
+	return len(staleKeys), nil
+}
+
+// patternExpired reports whether a pattern has gone unused for longer than
+// its retention: olderThan, halved below minPatternSuccessRate
+func patternExpired(pattern *models.WorkflowPattern, olderThan time.Duration, now time.Time) bool {
+	if pattern.SuccessRate < minPatternSuccessRate {
+		olderThan /= 2
+	}
+	return pattern.LastUsed.Before(now.Add(-olderThan))
+}
+
+// Close stops background GC and closes the BadgerDB instance
 func (s *BadgerProceduralStore) Close() error {
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+	<-s.doneCh
 	return s.db.Close()
 }
 
-// calculatePatternSimilarity computes similarity between step signatures
+// calculatePatternSimilarity computes similarity between step signatures.
+// It blends order-insensitive overlap with positional agreement so that the
+// same steps in a different order still match, but exact sequences rank higher.
 func calculatePatternSimilarity(signatures []string, steps []models.WorkflowStep) float64 {
-	if len(steps) == 0 {
+	if len(steps) == 0 || len(signatures) == 0 {
 		return 0
 	}
 
-	matches := 0
+	longest := float64(max(len(signatures), len(steps)))
+
+	// Positional matches
+	positional := 0
 	for i, sig := range signatures {
 		if i >= len(steps) {
 			break
 		}
-		stepSig := fmt.Sprintf("%s:%s", steps[i].Action, steps[i].Tool)
-		if sig == stepSig {
-			matches++
+		if sig == stepSignature(steps[i]) {
+			positional++
 		}
 	}
 
-	return float64(matches) / float64(max(len(signatures), len(steps)))
+	// Order-insensitive (multiset) matches
+	remaining := make(map[string]int, len(steps))
+	for _, step := range steps {
+		remaining[stepSignature(step)]++
+	}
+	unordered := 0
+	for _, sig := range signatures {
+		if remaining[sig] > 0 {
+			remaining[sig]--
+			unordered++
+		}
+	}
+
+	return 0.7*float64(unordered)/longest + 0.3*float64(positional)/longest
+}
+
+// stepSignature identifies a workflow step for similarity matching
+func stepSignature(step models.WorkflowStep) string {
+	return fmt.Sprintf("%s:%s", step.Action, step.Tool)
+}
+
+// stepDescriptions is the text embedded for a workflow: its step actions, one
+// per line. Tool names are left out since most patterns share them.
+func stepDescriptions(steps []models.WorkflowStep) string {
+	lines := make([]string, len(steps))
+	for i, step := range steps {
+		lines[i] = step.Action
+		if lines[i] == "" {
+			lines[i] = step.Tool
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 when
+// their lengths differ or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
 }
 
 // sortByFrequency sorts patterns by frequency in descending order
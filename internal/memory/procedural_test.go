@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestFindSimilarPatternsByEmbedding tests that reworded steps match through
+// embeddings when the exact action:tool match fails
+func TestFindSimilarPatternsByEmbedding(t *testing.T) {
+	config := DefaultConfig()
+	config.BadgerPath = t.TempDir()
+	config.BadgerGCInterval = 0
+
+	store, err := NewBadgerProceduralStore(config)
+	if err != nil {
+		t.Fatalf("Failed to open procedural store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	stored := &models.WorkflowPattern{
+		Name: "Ship container",
+		Steps: []models.WorkflowStep{
+			{Action: "Build docker image", Tool: "shell"},
+			{Action: "Push image to registry", Tool: "shell"},
+		},
+	}
+
+	// Without a generator only exact step matches are found
+	if err := store.StorePattern(ctx, stored); err != nil {
+		t.Fatalf("StorePattern failed: %v", err)
+	}
+	reworded := []models.WorkflowStep{
+		{Action: "build the docker image", Tool: "infra"},
+		{Action: "push the image to a registry", Tool: "infra"},
+	}
+	if patterns, err := store.FindSimilarPatterns(ctx, reworded, 1); err != nil || len(patterns) != 0 {
+		t.Fatalf("Expected no match without embeddings, got %+v, %v", patterns, err)
+	}
+
+	store.SetEmbeddingGenerator(NewSimpleEmbedding(config.EmbeddingDimensions))
+	stored.ID = ""
+	if err := store.StorePattern(ctx, stored); err != nil {
+		t.Fatalf("StorePattern failed: %v", err)
+	}
+	if len(stored.Embedding) != config.EmbeddingDimensions {
+		t.Fatalf("Expected a %d-dim embedding, got %d", config.EmbeddingDimensions, len(stored.Embedding))
+	}
+
+	patterns, err := store.FindSimilarPatterns(ctx, reworded, 3)
+	if err != nil {
+		t.Fatalf("FindSimilarPatterns failed: %v", err)
+	}
+	if len(patterns) != 1 || patterns[0].ID != stored.ID {
+		t.Fatalf("Expected the embedded pattern to match, got %+v", patterns)
+	}
+
+	unrelated := []models.WorkflowStep{{Action: "Write unit tests", Tool: "shell"}}
+	if patterns, err := store.FindSimilarPatterns(ctx, unrelated, 3); err != nil || len(patterns) != 0 {
+		t.Errorf("Expected no match for unrelated steps, got %+v, %v", patterns, err)
+	}
+
+	// The exact match still wins on its own
+	patterns, err = store.FindSimilarPatterns(ctx, stored.Steps, 3)
+	if err != nil || len(patterns) != 2 {
+		t.Errorf("Expected both stored copies to match exactly, got %+v, %v", patterns, err)
+	}
+}
+
+// TestCosineSimilarity tests the vector comparison used for pattern search
+func TestCosineSimilarity(t *testing.T) {
+	if got := cosineSimilarity([]float32{1, 0}, []float32{1, 0}); got < 0.999 {
+		t.Errorf("Expected identical vectors to score 1, got %f", got)
+	}
+	if got := cosineSimilarity([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("Expected orthogonal vectors to score 0, got %f", got)
+	}
+	if got := cosineSimilarity([]float32{1, 0}, []float32{1, 0, 0}); got != 0 {
+		t.Errorf("Expected mismatched lengths to score 0, got %f", got)
+	}
+	if got := cosineSimilarity([]float32{0, 0}, []float32{1, 0}); got != 0 {
+		t.Errorf("Expected a zero vector to score 0, got %f", got)
+	}
+}
+
+// TestPruneByAge tests that unused patterns are pruned, low-success ones
+// after half the retention window
+func TestPruneByAge(t *testing.T) {
+	config := DefaultConfig()
+	config.BadgerPath = t.TempDir()
+	config.BadgerGCInterval = 0
+
+	store, err := NewBadgerProceduralStore(config)
+	if err != nil {
+		t.Fatalf("Failed to open procedural store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	day := 24 * time.Hour
+	patterns := map[string]*models.WorkflowPattern{
+		"recent":         {ID: "recent", SuccessRate: 1, LastUsed: time.Now().Add(-10 * day)},
+		"recent-failing": {ID: "recent-failing", SuccessRate: 0.2, LastUsed: time.Now().Add(-10 * day)},
+		"aging":          {ID: "aging", SuccessRate: 0.9, LastUsed: time.Now().Add(-60 * day)},
+		"aging-failing":  {ID: "aging-failing", SuccessRate: 0.5, LastUsed: time.Now().Add(-60 * day)},
+		"stale":          {ID: "stale", SuccessRate: 1, LastUsed: time.Now().Add(-100 * day)},
+	}
+	for _, pattern := range patterns {
+		if err := store.StorePattern(ctx, pattern); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := store.PruneByAge(ctx, 90*day)
+	if err != nil || pruned != 2 {
+		t.Fatalf("Expected 2 patterns pruned, got %d, %v", pruned, err)
+	}
+	remaining, err := store.GetTopPatterns(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := make(map[string]bool)
+	for _, pattern := range remaining {
+		kept[pattern.ID] = true
+	}
+	for _, id := range []string{"recent", "recent-failing", "aging"} {
+		if !kept[id] {
+			t.Errorf("Expected %s kept, have %v", id, kept)
+		}
+	}
+}
@@ -88,25 +88,14 @@ func (s *DgraphSemanticStore) initSchema(ctx context.Context) error {
 
 // StoreEntity stores an entity in the knowledge graph
 func (s *DgraphSemanticStore) StoreEntity(ctx context.Context, entity *models.Entity) error {
-	// Serialize attributes
-	attributesJSON, err := json.Marshal(entity.Attributes)
+	setJSON, err := entityMutationJSON(entity, time.Now())
 	if err != nil {
-		return fmt.Errorf("failed to marshal attributes: %w", err)
+		return err
 	}
 
 	mutation := &api.Mutation{
 		CommitNow: true,
-		SetJson: []byte(fmt.Sprintf(`{
-			"entity.id": "%s",
-			"entity.name": "%s",
-			"entity.type": "%s",
-			"entity.attributes": %s,
-			"entity.created": "%s",
-			"entity.updated": "%s",
-			"dgraph.type": "Entity"
-		}`, entity.ID, entity.Name, entity.Type, attributesJSON,
-			time.Now().Format(time.RFC3339),
-			time.Now().Format(time.RFC3339))),
+		SetJson:   setJSON,
 	}
 
 	txn := s.client.NewTxn()
@@ -116,6 +105,31 @@ func (s *DgraphSemanticStore) StoreEntity(ctx context.Context, entity *models.En
 	return err
 }
 
+// entityMutationJSON builds the mutation payload for an entity. Values are
+// marshaled rather than interpolated so names containing quotes or other
+// special characters cannot break out of the JSON document.
+func entityMutationJSON(entity *models.Entity, now time.Time) ([]byte, error) {
+	attributesJSON, err := json.Marshal(entity.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"entity.id":         entity.ID,
+		"entity.name":       entity.Name,
+		"entity.type":       entity.Type,
+		"entity.attributes": string(attributesJSON),
+		"entity.created":    now.Format(time.RFC3339),
+		"entity.updated":    now.Format(time.RFC3339),
+		"dgraph.type":       "Entity",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entity: %w", err)
+	}
+
+	return data, nil
+}
+
 // StoreRelationship adds a relationship between entities
 func (s *DgraphSemanticStore) StoreRelationship(ctx context.Context, rel *models.Relationship) error {
 	// First, find UIDs for from and to entities
@@ -129,19 +143,14 @@ func (s *DgraphSemanticStore) StoreRelationship(ctx context.Context, rel *models
 		return fmt.Errorf("failed to find to entity: %w", err)
 	}
 
+	setJSON, err := relationshipMutationJSON(rel, fromUID, toUID, time.Now())
+	if err != nil {
+		return err
+	}
+
 	mutation := &api.Mutation{
 		CommitNow: true,
-		SetJson: []byte(fmt.Sprintf(`{
-			"uid": "_:rel",
-			"rel.id": "%s",
-			"rel.type": "%s",
-			"rel.confidence": %f,
-			"rel.created": "%s",
-			"from": {"uid": "%s"},
-			"to": {"uid": "%s"},
-			"dgraph.type": "Relationship"
-		}`, rel.ID, rel.Type, rel.Confidence, time.Now().Format(time.RFC3339),
-			fromUID, toUID)),
+		SetJson:   setJSON,
 	}
 
 	txn := s.client.NewTxn()
@@ -151,11 +160,30 @@ func (s *DgraphSemanticStore) StoreRelationship(ctx context.Context, rel *models
 	return err
 }
 
+// relationshipMutationJSON builds the mutation payload for a relationship edge
+func relationshipMutationJSON(rel *models.Relationship, fromUID, toUID string, now time.Time) ([]byte, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"uid":            "_:rel",
+		"rel.id":         rel.ID,
+		"rel.type":       rel.Type,
+		"rel.confidence": rel.Confidence,
+		"rel.created":    now.Format(time.RFC3339),
+		"from":           map[string]string{"uid": fromUID},
+		"to":             map[string]string{"uid": toUID},
+		"dgraph.type":    "Relationship",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal relationship: %w", err)
+	}
+
+	return data, nil
+}
+
 // QueryEntities finds entities matching criteria
 func (s *DgraphSemanticStore) QueryEntities(ctx context.Context, query string) ([]*models.Entity, error) {
 	// GraphQL-style query
-	q := fmt.Sprintf(`{
-		entities(func: alloftext(entity.name, "%s")) {
+	q := `query entities($name: string) {
+		entities(func: alloftext(entity.name, $name)) {
 			uid
 			entity.id
 			entity.name
@@ -164,12 +192,12 @@ func (s *DgraphSemanticStore) QueryEntities(ctx context.Context, query string) (
 			entity.created
 			entity.updated
 		}
-	}`, query)
+	}`
 
 	txn := s.client.NewReadOnlyTxn()
 	defer txn.Discard(ctx)
 
-	resp, err := txn.Query(ctx, q)
+	resp, err := txn.QueryWithVars(ctx, q, map[string]string{"$name": query})
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -206,66 +234,135 @@ func (s *DgraphSemanticStore) QueryEntities(ctx context.Context, query string) (
 
 // Traverse performs graph traversal from a starting entity
 func (s *DgraphSemanticStore) Traverse(ctx context.Context, startID string, depth int) ([]*models.Entity, error) {
-	// Build recursive query with specified depth
-	q := fmt.Sprintf(`{
-		traverse(func: eq(entity.id, "%s")) @recurse(depth: %d) {
+	entities, _, err := s.TraverseGraph(ctx, startID, depth)
+	return entities, err
+}
+
+// graphNode is one level of a recursive traversal result. Entity nodes link to
+// their outgoing Relationship nodes via the ~from reverse edge, and each
+// Relationship node links to its target entity via to.
+type graphNode struct {
+	UID        string       `json:"uid"`
+	EntityID   string       `json:"entity.id"`
+	Name       string       `json:"entity.name"`
+	Type       string       `json:"entity.type"`
+	RelID      string       `json:"rel.id"`
+	RelType    string       `json:"rel.type"`
+	Confidence float64      `json:"rel.confidence"`
+	Outgoing   []*graphNode `json:"~from"`
+	To         []*graphNode `json:"to"`
+}
+
+// TraverseGraph walks outgoing relationships from a starting entity up to depth
+// hops and returns the reached entities together with the typed edges between them
+func (s *DgraphSemanticStore) TraverseGraph(ctx context.Context, startID string, depth int) ([]*models.Entity, []*models.Relationship, error) {
+	// Each entity hop spans two levels: entity -> relationship -> entity
+	q := fmt.Sprintf(`query traverse($id: string) {
+		traverse(func: eq(entity.id, $id)) @recurse(depth: %d, loop: false) {
 			uid
 			entity.id
 			entity.name
 			entity.type
-			from
+			rel.id
+			rel.type
+			rel.confidence
+			~from
 			to
 		}
-	}`, startID, depth)
+	}`, depth*2+1)
 
 	txn := s.client.NewReadOnlyTxn()
 	defer txn.Discard(ctx)
 
-	resp, err := txn.Query(ctx, q)
+	resp, err := txn.QueryWithVars(ctx, q, map[string]string{"$id": startID})
 	if err != nil {
-		return nil, fmt.Errorf("traverse failed: %w", err)
+		return nil, nil, fmt.Errorf("traverse failed: %w", err)
 	}
 
 	var result struct {
-		Traverse []struct {
-			ID   string `json:"entity.id"`
-			Name string `json:"entity.name"`
-			Type string `json:"entity.type"`
-		} `json:"traverse"`
+		Traverse []*graphNode `json:"traverse"`
 	}
 
 	if err := json.Unmarshal(resp.Json, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	entities := make([]*models.Entity, len(result.Traverse))
-	for i, e := range result.Traverse {
-		entities[i] = &models.Entity{
-			ID:   e.ID,
-			Name: e.Name,
-			Type: e.Type,
+	var entities []*models.Entity
+	var relationships []*models.Relationship
+	byID := make(map[string]*models.Entity)
+	seenRels := make(map[string]bool)
+
+	var visit func(node *graphNode) *models.Entity
+	visit = func(node *graphNode) *models.Entity {
+		if entity, ok := byID[node.EntityID]; ok {
+			return entity
+		}
+
+		entity := &models.Entity{
+			ID:   node.EntityID,
+			Name: node.Name,
+			Type: node.Type,
 		}
+		byID[node.EntityID] = entity
+		entities = append(entities, entity)
+
+		for _, rel := range node.Outgoing {
+			for _, target := range rel.To {
+				if target.EntityID == "" {
+					continue
+				}
+				to := visit(target)
+
+				key := rel.RelID
+				if key == "" {
+					key = rel.UID
+				}
+				key += "|" + to.ID
+				if seenRels[key] {
+					continue
+				}
+				seenRels[key] = true
+
+				edge := models.Relationship{
+					ID:         rel.RelID,
+					FromID:     entity.ID,
+					ToID:       to.ID,
+					Type:       rel.RelType,
+					Confidence: rel.Confidence,
+				}
+				entity.Relationships = append(entity.Relationships, edge)
+				relationships = append(relationships, &edge)
+			}
+		}
+
+		return entity
 	}
 
-	return entities, nil
+	for _, root := range result.Traverse {
+		if root.EntityID != "" {
+			visit(root)
+		}
+	}
+
+	return entities, relationships, nil
 }
 
 // ResolveEntity finds or merges duplicate entities
 func (s *DgraphSemanticStore) ResolveEntity(ctx context.Context, name string, entityType string) (*models.Entity, error) {
 	// Search for existing entity with similar name and type
-	q := fmt.Sprintf(`{
-		entities(func: alloftext(entity.name, "%s")) @filter(eq(entity.type, "%s")) {
+	q := `query resolve($name: string, $type: string) {
+		entities(func: alloftext(entity.name, $name)) @filter(eq(entity.type, $type)) {
 			uid
 			entity.id
 			entity.name
 			entity.type
 		}
-	}`, name, entityType)
+	}`
 
 	txn := s.client.NewReadOnlyTxn()
 	defer txn.Discard(ctx)
 
-	resp, err := txn.Query(ctx, q)
+	resp, err := txn.QueryWithVars(ctx, q, map[string]string{"$name": name, "$type": entityType})
 	if err != nil {
 		return nil, fmt.Errorf("resolve failed: %w", err)
 	}
@@ -296,16 +393,16 @@ func (s *DgraphSemanticStore) ResolveEntity(ctx context.Context, name string, en
 
 // getEntityUID retrieves the Dgraph UID for an entity by its ID
 func (s *DgraphSemanticStore) getEntityUID(ctx context.Context, entityID string) (string, error) {
-	q := fmt.Sprintf(`{
-		entity(func: eq(entity.id, "%s")) {
+	q := `query uid($id: string) {
+		entity(func: eq(entity.id, $id)) {
 			uid
 		}
-	}`, entityID)
+	}`
 
 	txn := s.client.NewReadOnlyTxn()
 	defer txn.Discard(ctx)
 
-	resp, err := txn.Query(ctx, q)
+	resp, err := txn.QueryWithVars(ctx, q, map[string]string{"$id": entityID})
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestEntityMutationEscaping tests that entity names with quotes and special characters survive the mutation payload
+func TestEntityMutationEscaping(t *testing.T) {
+	name := `Robert "Bobby" O'Neil\", "entity.type": "ADMIN"} {` + "\n\t<script>"
+
+	entity := &models.Entity{
+		ID:         "entity:1",
+		Name:       name,
+		Type:       "PERSON",
+		Attributes: map[string]interface{}{"note": `say "hi"`},
+	}
+
+	data, err := entityMutationJSON(entity, time.Now())
+	if err != nil {
+		t.Fatalf("Expected mutation to build, got %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, data)
+	}
+
+	if decoded["entity.name"] != name {
+		t.Errorf("Expected name %q, got %q", name, decoded["entity.name"])
+	}
+
+	if decoded["entity.type"] != "PERSON" {
+		t.Errorf("Expected type to be unaffected by name contents, got %q", decoded["entity.type"])
+	}
+
+	// Attributes are stored as a JSON string to match the schema
+	attrs, ok := decoded["entity.attributes"].(string)
+	if !ok {
+		t.Fatalf("Expected attributes to be a string, got %T", decoded["entity.attributes"])
+	}
+
+	var attrMap map[string]interface{}
+	if err := json.Unmarshal([]byte(attrs), &attrMap); err != nil {
+		t.Fatalf("Expected attributes to hold valid JSON, got %v", err)
+	}
+	if attrMap["note"] != `say "hi"` {
+		t.Errorf("Expected attribute to round-trip, got %q", attrMap["note"])
+	}
+}
+
+// TestRelationshipMutationEscaping tests that relationship types with special characters are escaped
+func TestRelationshipMutationEscaping(t *testing.T) {
+	rel := &models.Relationship{
+		ID:         "rel:1",
+		Type:       `depends "on"}`,
+		Confidence: 0.75,
+	}
+
+	data, err := relationshipMutationJSON(rel, "0x1", "0x2", time.Now())
+	if err != nil {
+		t.Fatalf("Expected mutation to build, got %v", err)
+	}
+
+	var decoded struct {
+		Type       string            `json:"rel.type"`
+		Confidence float64           `json:"rel.confidence"`
+		From       map[string]string `json:"from"`
+		To         map[string]string `json:"to"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, data)
+	}
+
+	if decoded.Type != rel.Type {
+		t.Errorf("Expected type %q, got %q", rel.Type, decoded.Type)
+	}
+	if decoded.Confidence != 0.75 {
+		t.Errorf("Expected confidence 0.75, got %f", decoded.Confidence)
+	}
+	if decoded.From["uid"] != "0x1" || decoded.To["uid"] != "0x2" {
+		t.Errorf("Expected edge 0x1 -> 0x2, got %s -> %s", decoded.From["uid"], decoded.To["uid"])
+	}
+}
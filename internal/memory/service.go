@@ -2,14 +2,24 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/logging"
 	"github.com/quantumflow/quantumflow/internal/models"
 )
 
+// ErrStoreUnavailable is returned when the store an operation needs failed to
+// initialize
+var ErrStoreUnavailable = errors.New("memory store unavailable")
+
 // MemoryService implements the main memory service orchestrating all stores
 type MemoryService struct {
 	episodic   EpisodicStore
@@ -20,94 +30,185 @@ type MemoryService struct {
 	compactor  Compactor
 
 	config *Config
+	logger *slog.Logger
 	stats  *Stats
 	mu     sync.RWMutex
 
 	startTime time.Time
-	stopCh    chan struct{}
+
+	// ctx is cancelled by Close, stopping background compaction mid-run;
+	// compactionDone is closed once the compaction goroutine has returned
+	ctx            context.Context
+	cancel         context.CancelFunc
+	compactionDone chan struct{}
+
+	// status records which stores initialized; unavailable stores are nil
+	status []*StoreStatus
 }
 
-// NewMemoryService creates a new memory service instance
+// NewMemoryService creates a new memory service instance. Stores whose
+// backends are unreachable are skipped rather than failing the whole service;
+// Status reports which ones are degraded. An error is returned only when no
+// store could be initialized.
 func NewMemoryService(config *Config, inferenceClient *inference.Client) (*MemoryService, error) {
 	if config == nil {
 		config = DefaultConfig()
 	}
 
+	extractor, err := NewExtractor(config.Extractor, inferenceClient)
+	if err != nil {
+		return nil, err
+	}
+	embedding, err := NewEmbeddingGenerator(config, inferenceClient)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &MemoryService{
+		embedding: embedding,
+		extractor: extractor,
+		config:    config,
+		logger:    logging.OrDiscard(config.Logger),
+		stats:     &Stats{},
+		startTime: time.Now(),
+	}
+
 	// Initialize episodic store (Redis)
 	episodic, err := NewRedisEpisodicStore(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create episodic store: %w", err)
+	service.status = append(service.status, newStoreStatus("episodic", "redis", err))
+	if err == nil {
+		service.episodic = episodic
 	}
 
 	// Initialize semantic store (Dgraph)
 	semantic, err := NewDgraphSemanticStore(config)
-	if err != nil {
-		episodic.Close()
-		return nil, fmt.Errorf("failed to create semantic store: %w", err)
+	service.status = append(service.status, newStoreStatus("semantic", "dgraph", err))
+	if err == nil {
+		service.semantic = semantic
 	}
 
 	// Initialize procedural store (BadgerDB)
 	procedural, err := NewBadgerProceduralStore(config)
-	if err != nil {
-		episodic.Close()
-		semantic.Close()
-		return nil, fmt.Errorf("failed to create procedural store: %w", err)
+	service.status = append(service.status, newStoreStatus("procedural", "badger", err))
+	if err == nil {
+		procedural.SetEmbeddingGenerator(service.embedding)
+		service.procedural = procedural
 	}
 
-	// Initialize embedding generator
-	// Try HuggingFace first, fallback to simple embeddings
-	embedding := NewSimpleEmbedding(config.EmbeddingDimensions)
-
-	// Initialize extractor
-	extractor := NewQwenExtractor(inferenceClient)
+	if service.episodic == nil && service.semantic == nil && service.procedural == nil {
+		return nil, fmt.Errorf("no memory stores available: %s", service.degradedSummary())
+	}
+	for _, status := range service.status {
+		if !status.Available {
+			service.logger.Warn("memory store unavailable", "store", status.Store, "backend", status.Backend, "error", status.Error)
+		}
+	}
 
 	// Initialize compactor
-	compactor := NewMemoryCompactor(episodic, config)
-
-	service := &MemoryService{
-		episodic:   episodic,
-		semantic:   semantic,
-		procedural: procedural,
-		embedding:  embedding,
-		extractor:  extractor,
-		compactor:  compactor,
-		config:     config,
-		stats:      &Stats{},
-		startTime:  time.Now(),
-		stopCh:     make(chan struct{}),
-	}
+	service.compactor = NewMemoryCompactor(service.episodic, service.procedural, config)
 
 	// Start background compaction if enabled
 	if config.CompactionEnabled {
-		go service.runPeriodicCompaction()
+		service.startCompaction()
 	}
 
 	return service, nil
 }
 
-// Store persists an interaction to memory
-func (m *MemoryService) Store(ctx context.Context, interaction *models.Interaction) error {
-	// Extract information from the interaction
-	facts, err := m.extractor.ExtractFacts(ctx, interaction.UserQuery+" "+interaction.AgentResponse)
+// newStoreStatus records the outcome of initializing a store
+func newStoreStatus(store, backend string, err error) *StoreStatus {
+	status := &StoreStatus{
+		Store:     store,
+		Backend:   backend,
+		Available: err == nil,
+	}
 	if err != nil {
-		// Log error but continue
-		_ = err
+		status.Error = err.Error()
 	}
-	_ = facts // TODO: Store facts in semantic graph
+	return status
+}
 
-	entities, err := m.extractor.ExtractEntities(ctx, interaction.UserQuery+" "+interaction.AgentResponse)
-	if err != nil {
-		_ = err
+// degradedSummary describes every store that failed to initialize
+func (m *MemoryService) degradedSummary() string {
+	var parts []string
+	for _, status := range m.status {
+		if !status.Available {
+			parts = append(parts, fmt.Sprintf("%s (%s): %s", status.Store, status.Backend, status.Error))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Status reports the availability of each backing store
+func (m *MemoryService) Status() []*StoreStatus {
+	statuses := make([]*StoreStatus, len(m.status))
+	for i, status := range m.status {
+		copied := *status
+		statuses[i] = &copied
+	}
+	return statuses
+}
+
+// Store persists an interaction to memory. Unavailable stores are skipped.
+func (m *MemoryService) Store(ctx context.Context, interaction *models.Interaction) error {
+	if m.semantic != nil {
+		// Extract information from the interaction in one model call
+		extraction, err := m.extractor.Extract(ctx, interaction.UserQuery+" "+interaction.AgentResponse)
+		if err != nil {
+			m.logger.Warn("extraction failed", "interaction", interaction.ID, "error", err)
+		} else {
+			m.storeExtraction(ctx, extraction)
+		}
+	}
+
+	if m.episodic != nil {
+		if err := m.storeEpisodic(ctx, interaction); err != nil {
+			return err
+		}
+	}
+
+	// Extract and store workflow patterns from tool calls
+	if m.procedural != nil && len(interaction.ToolCalls) > 0 {
+		pattern := &models.WorkflowPattern{
+			Name:        fmt.Sprintf("Pattern from %s", interaction.ID),
+			Steps:       make([]models.WorkflowStep, len(interaction.ToolCalls)),
+			Frequency:   1,
+			SuccessRate: calculateSuccessRate(interaction.ToolCalls),
+			LastUsed:    time.Now(),
+		}
+
+		for i, call := range interaction.ToolCalls {
+			pattern.Steps[i] = models.WorkflowStep{
+				Action:     call.Name,
+				Tool:       call.Name,
+				Parameters: call.Parameters,
+			}
+		}
+
+		if err := m.procedural.StorePattern(ctx, pattern); err != nil {
+			m.logger.Warn("failed to store workflow pattern", "interaction", interaction.ID, "error", err)
+		}
 	}
 
-	// Create episodic memory
+	return nil
+}
+
+// storeEpisodic embeds an interaction's query and stores the exchange as an
+// episodic memory. While embeddings are degraded it is skipped, since word
+// hashing vectors can't be searched alongside the provider's.
+func (m *MemoryService) storeEpisodic(ctx context.Context, interaction *models.Interaction) error {
 	embedding, err := m.embedding.Generate(ctx, interaction.UserQuery)
 	if err != nil {
 		return fmt.Errorf("failed to generate embedding: %w", err)
 	}
+	if err := m.embeddingDegraded(); err != nil {
+		m.logger.Debug("skipping episodic memory", "interaction", interaction.ID, "error", err)
+		return nil
+	}
 
 	memory := &models.Memory{
-		ID:        interaction.ID,
+		// Only keys under the episodic prefix are in the search index
+		ID:        episodicID(interaction.ID),
 		Type:      models.MemoryTypeEpisodic,
 		Content:   interaction.UserQuery + "\n" + interaction.AgentResponse,
 		Embedding: embedding,
@@ -118,49 +219,160 @@ func (m *MemoryService) Store(ctx context.Context, interaction *models.Interacti
 		},
 	}
 
-	// Store in episodic memory
 	if err := m.episodic.Store(ctx, memory); err != nil {
 		return fmt.Errorf("failed to store episodic memory: %w", err)
 	}
+	return nil
+}
 
-	// Store entities in semantic graph
-	for _, entity := range entities {
+// embeddingDegraded returns ErrEmbeddingDegraded wrapping why, while
+// embeddings have fallen back to word hashing
+func (m *MemoryService) embeddingDegraded() error {
+	if fallback, ok := m.embedding.(*FallbackEmbedding); ok {
+		if err := fallback.Degraded(); err != nil {
+			return fmt.Errorf("%w: %v", ErrEmbeddingDegraded, err)
+		}
+	}
+	return nil
+}
+
+// storeExtraction stores extracted entities in the semantic graph, then the
+// relationships between them. Facts are kept with the entity they are about,
+// in its "facts" attribute. Relationships and facts name their entities, so
+// those naming an entity that wasn't extracted are skipped.
+func (m *MemoryService) storeExtraction(ctx context.Context, extraction *Extraction) {
+	attachFacts(extraction.Entities, extraction.Facts)
+
+	stored := make(map[string]string, len(extraction.Entities)) // Name to ID
+	for _, entity := range extraction.Entities {
 		if err := m.semantic.StoreEntity(ctx, entity); err != nil {
-			// Log error but continue
-			_ = err
+			m.logger.Warn("failed to store entity", "entity", entity.Name, "error", err)
+			continue
 		}
+		stored[entity.Name] = entity.ID
 	}
 
-	// Extract and store workflow patterns from tool calls
-	if len(interaction.ToolCalls) > 0 {
-		pattern := &models.WorkflowPattern{
-			Name:        fmt.Sprintf("Pattern from %s", interaction.ID),
-			Steps:       make([]models.WorkflowStep, len(interaction.ToolCalls)),
-			Frequency:   1,
-			SuccessRate: calculateSuccessRate(interaction.ToolCalls),
-			LastUsed:    time.Now(),
+	for _, rel := range extraction.Relationships {
+		fromID, fromOK := stored[rel.FromID]
+		toID, toOK := stored[rel.ToID]
+		if !fromOK || !toOK {
+			continue
 		}
+		edge := *rel
+		edge.FromID, edge.ToID = fromID, toID
+		if err := m.semantic.StoreRelationship(ctx, &edge); err != nil {
+			m.logger.Warn("failed to store relationship", "type", rel.Type, "error", err)
+		}
+	}
+}
 
-		for i, call := range interaction.ToolCalls {
-			pattern.Steps[i] = models.WorkflowStep{
-				Action:     call.Name,
-				Tool:       call.Name,
-				Parameters: call.Parameters,
+// factsAttribute is the entity attribute holding statements about it
+const factsAttribute = "facts"
+
+// attachFacts adds each fact's statement to the facts attribute of the
+// entity named by its subject
+func attachFacts(entities []*models.Entity, facts []Fact) {
+	for _, fact := range facts {
+		for _, entity := range entities {
+			if fact.Statement == "" || !strings.EqualFold(entity.Name, strings.TrimSpace(fact.Subject)) {
+				continue
+			}
+			if entity.Attributes == nil {
+				entity.Attributes = make(map[string]interface{})
 			}
+			entity.Attributes[factsAttribute] = append(entityFacts(entity), fact.Statement)
+			break
 		}
+	}
+}
 
-		if err := m.procedural.StorePattern(ctx, pattern); err != nil {
-			_ = err
+// entityFacts returns the statements stored with an entity. Entities read
+// back from the graph hold them as decoded JSON.
+func entityFacts(entity *models.Entity) []string {
+	switch facts := entity.Attributes[factsAttribute].(type) {
+	case []string:
+		return facts
+	case []interface{}:
+		statements := make([]string, 0, len(facts))
+		for _, fact := range facts {
+			if statement, ok := fact.(string); ok {
+				statements = append(statements, statement)
+			}
 		}
+		return statements
+	}
+	return nil
+}
+
+// StoreDocument embeds content, e.g. a chunk of indexed source code, and
+// stores it in episodic memory under id so Retrieve can surface it. Storing
+// the same id again replaces the document. While embeddings are degraded it
+// returns ErrEmbeddingDegraded.
+func (m *MemoryService) StoreDocument(ctx context.Context, id, content string, metadata map[string]interface{}) error {
+	if m.episodic == nil {
+		return fmt.Errorf("%w: episodic", ErrStoreUnavailable)
 	}
 
+	embedding, err := m.embedding.Generate(ctx, content)
+	if err != nil {
+		return fmt.Errorf("failed to generate embedding: %w", err)
+	}
+	if err := m.embeddingDegraded(); err != nil {
+		return err
+	}
+
+	memory := &models.Memory{
+		// Only keys under the episodic prefix are in the search index
+		ID:        episodicID(id),
+		Type:      models.MemoryTypeEpisodic,
+		Content:   content,
+		Embedding: embedding,
+		Metadata:  metadata,
+		Timestamp: time.Now(),
+	}
+	if err := m.episodic.Store(ctx, memory); err != nil {
+		return fmt.Errorf("failed to store document: %w", err)
+	}
 	return nil
 }
 
-// Retrieve fetches the top-k most relevant memories for a query
+// Retrieve fetches the top-k most relevant memories for a query. Episodic
+// search and knowledge-graph lookups run concurrently, and their results are
+// interleaved so callers that keep only the first few see both kinds.
 func (m *MemoryService) Retrieve(ctx context.Context, query string, k int) ([]*models.Memory, error) {
 	start := time.Now()
 
+	// Enrich with knowledge-graph entities mentioned in the query
+	var semantic []*models.Memory
+	var wg sync.WaitGroup
+	if m.semantic != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semantic = m.retrieveSemantic(ctx, query, k)
+		}()
+	}
+
+	var episodic []*models.Memory
+	var err error
+	if m.episodic != nil {
+		episodic, err = m.retrieveEpisodic(ctx, query, k)
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	// Update stats
+	m.mu.Lock()
+	m.stats.AvgRetrievalMs = float64(time.Since(start).Milliseconds())
+	m.mu.Unlock()
+
+	return interleave(episodic, semantic, k), nil
+}
+
+// retrieveEpisodic searches episodic memory for the query's embedding
+func (m *MemoryService) retrieveEpisodic(ctx context.Context, query string, k int) ([]*models.Memory, error) {
 	// Generate embedding for query
 	embedding, err := m.embedding.Generate(ctx, query)
 	if err != nil {
@@ -168,17 +380,261 @@ func (m *MemoryService) Retrieve(ctx context.Context, query string, k int) ([]*m
 	}
 
 	// Search episodic memory
-	memories, err := m.episodic.Search(ctx, embedding, k)
+	memories, err := m.episodic.Search(ctx, embedding, k, m.config.MinSimilarity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search episodic memory: %w", err)
 	}
+	return memories, nil
+}
 
-	// Update stats
-	m.mu.Lock()
-	m.stats.AvgRetrievalMs = float64(time.Since(start).Milliseconds())
-	m.mu.Unlock()
+// interleave alternates between two ranked result lists, keeping at most k.
+// Episodic and semantic scores aren't comparable, so neither list is allowed
+// to crowd out the other.
+func interleave(a, b []*models.Memory, k int) []*models.Memory {
+	merged := make([]*models.Memory, 0, min(len(a)+len(b), max(k, 0)))
+	for i := 0; len(merged) < k && (i < len(a) || i < len(b)); i++ {
+		if i < len(a) {
+			merged = append(merged, a[i])
+		}
+		if i < len(b) && len(merged) < k {
+			merged = append(merged, b[i])
+		}
+	}
+	return merged
+}
 
-	return memories, nil
+// retrieveSemantic finds knowledge-graph entities matching the query terms and
+// describes each one with its outgoing relationships, best matches first.
+// The per-term lookups and per-entity traversals run concurrently. Semantic
+// lookups are best-effort: failures leave the episodic results untouched.
+func (m *MemoryService) retrieveSemantic(ctx context.Context, query string, k int) []*models.Memory {
+	terms := queryTerms(query, maxSemanticTerms)
+	if len(terms) == 0 || k <= 0 {
+		return nil
+	}
+
+	found := make([][]*models.Entity, len(terms))
+	var wg sync.WaitGroup
+	for i, term := range terms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found[i], _ = m.semantic.QueryEntities(ctx, term)
+		}()
+	}
+	wg.Wait()
+
+	// Merge in term order so ties are broken deterministically
+	matches := make(map[string]int)
+	var entities []*models.Entity
+	for _, termEntities := range found {
+		for _, entity := range termEntities {
+			if _, seen := matches[entity.ID]; !seen {
+				entities = append(entities, entity)
+			}
+			matches[entity.ID]++
+		}
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		return matches[entities[i].ID] > matches[entities[j].ID]
+	})
+	if len(entities) > k {
+		entities = entities[:k]
+	}
+
+	memories := make([]*models.Memory, len(entities))
+	for i, entity := range entities {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			memories[i] = &models.Memory{
+				ID:      entity.ID,
+				Type:    models.MemoryTypeSemantic,
+				Content: m.describeEntity(ctx, entity),
+				Score:   float64(matches[entity.ID]) / float64(len(terms)),
+				Metadata: map[string]interface{}{
+					"entity_type": entity.Type,
+				},
+				Timestamp: time.Now(),
+			}
+		}()
+	}
+	wg.Wait()
+
+	return memories
+}
+
+// describeEntity renders an entity with its outgoing relationships
+func (m *MemoryService) describeEntity(ctx context.Context, entity *models.Entity) string {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("%s (%s)", entity.Name, entity.Type))
+	for _, statement := range entityFacts(entity) {
+		content.WriteString("; " + statement)
+	}
+
+	related, relationships, err := m.semantic.TraverseGraph(ctx, entity.ID, 1)
+	if err != nil {
+		return content.String()
+	}
+
+	names := make(map[string]string, len(related))
+	for _, r := range related {
+		names[r.ID] = r.Name
+	}
+	for _, rel := range relationships {
+		if rel.FromID != entity.ID {
+			continue
+		}
+		target := names[rel.ToID]
+		if target == "" {
+			target = rel.ToID
+		}
+		content.WriteString(fmt.Sprintf("; %s %s", rel.Type, target))
+	}
+	return content.String()
+}
+
+// patternCandidateLimit bounds how many stored patterns are considered for suggestions
+const patternCandidateLimit = 50
+
+// minPatternSuccessRate is the success rate a pattern needs before it is suggested
+const minPatternSuccessRate = 0.8
+
+// SuggestPatterns returns up to k high-success workflow patterns whose name or
+// steps share terms with the query, most relevant first
+func (m *MemoryService) SuggestPatterns(ctx context.Context, query string, k int) ([]*models.WorkflowPattern, error) {
+	if m.procedural == nil {
+		return nil, nil
+	}
+
+	terms := queryTerms(query, maxSemanticTerms)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	candidates, err := m.procedural.GetTopPatterns(ctx, patternCandidateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow patterns: %w", err)
+	}
+
+	type scoredPattern struct {
+		pattern *models.WorkflowPattern
+		score   int
+	}
+
+	var matches []scoredPattern
+	for _, pattern := range candidates {
+		if pattern.SuccessRate < minPatternSuccessRate {
+			continue
+		}
+
+		text := strings.ToLower(pattern.Name)
+		for _, step := range pattern.Steps {
+			text += " " + strings.ToLower(step.Action)
+		}
+
+		score := 0
+		for _, term := range terms {
+			if strings.Contains(text, strings.ToLower(term)) {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scoredPattern{pattern: pattern, score: score})
+		}
+	}
+
+	// Candidates arrive in frequency order, so a stable sort keeps popular patterns first on ties
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+
+	patterns := make([]*models.WorkflowPattern, len(matches))
+	for i, match := range matches {
+		patterns[i] = match.pattern
+	}
+	return patterns, nil
+}
+
+// RecordPatternUse credits a workflow pattern that contributed to a successful run
+func (m *MemoryService) RecordPatternUse(ctx context.Context, id string) error {
+	if m.procedural == nil {
+		return nil
+	}
+	return m.procedural.UpdateFrequency(ctx, id)
+}
+
+// sameWorkflowSimilarity is the step similarity at which a recorded workflow is
+// treated as a repeat of an existing pattern rather than a new one
+const sameWorkflowSimilarity = 0.95
+
+// RecordWorkflow saves a successful workflow as a pattern. If a near-identical
+// pattern already exists it is credited instead, so repeated workflows don't
+// pile up; merely similar workflows are stored as patterns of their own.
+func (m *MemoryService) RecordWorkflow(ctx context.Context, pattern *models.WorkflowPattern) error {
+	if m.procedural == nil {
+		return nil
+	}
+
+	similar, err := m.procedural.FindSimilarPatterns(ctx, pattern.Steps, 1)
+	if err != nil {
+		return fmt.Errorf("failed to search workflow patterns: %w", err)
+	}
+	if len(similar) > 0 && sameWorkflow(pattern.Steps, similar[0].Steps) {
+		pattern.ID = similar[0].ID
+		return m.procedural.UpdateFrequency(ctx, similar[0].ID)
+	}
+
+	if pattern.Frequency == 0 {
+		pattern.Frequency = 1
+	}
+	if pattern.LastUsed.IsZero() {
+		pattern.LastUsed = time.Now()
+	}
+	return m.procedural.StorePattern(ctx, pattern)
+}
+
+// sameWorkflow reports whether two step sequences are near-identical
+func sameWorkflow(steps, existing []models.WorkflowStep) bool {
+	signatures := make([]string, len(steps))
+	for i, step := range steps {
+		signatures[i] = stepSignature(step)
+	}
+	return calculatePatternSimilarity(signatures, existing) >= sameWorkflowSimilarity
+}
+
+// maxSemanticTerms caps how many query terms are looked up in the knowledge graph
+const maxSemanticTerms = 5
+
+// queryTerms extracts distinct, meaningful words from a query for entity lookup
+func queryTerms(query string, limit int) []string {
+	stopwords := map[string]bool{
+		"the": true, "and": true, "for": true, "with": true, "how": true,
+		"what": true, "why": true, "does": true, "this": true, "that": true,
+		"can": true, "you": true, "are": true, "from": true, "into": true,
+	}
+
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-'
+	}) {
+		lower := strings.ToLower(word)
+		if len(lower) < 3 || stopwords[lower] || seen[lower] {
+			continue
+		}
+		seen[lower] = true
+		terms = append(terms, word)
+		if len(terms) >= limit {
+			break
+		}
+	}
+	return terms
 }
 
 // Compact runs memory compaction and deduplication
@@ -192,7 +648,12 @@ func (m *MemoryService) Compact(ctx context.Context) error {
 	m.stats.LastCompaction = time.Now()
 	m.mu.Unlock()
 
-	_ = result // Use compaction result for logging
+	m.logger.Info("memory compaction complete",
+		"removed", result.MemoriesRemoved,
+		"compacted", result.MemoriesCompacted,
+		"deduplicated", result.DeduplicationCount,
+		"patterns_pruned", result.PatternsPruned,
+		"duration", result.Duration)
 	return nil
 }
 
@@ -206,8 +667,14 @@ func (m *MemoryService) GetStats(ctx context.Context) (*Stats, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Get counts from stores
-	episodicCount, _ := m.episodic.Count(ctx)
+	// Get counts from stores; a timed-out count still reports what was scanned
+	var episodicCount int64
+	if m.episodic != nil {
+		var err error
+		if episodicCount, err = m.episodic.Count(ctx); err != nil {
+			m.logger.Warn("episodic count incomplete", "counted", episodicCount, "error", err)
+		}
+	}
 
 	stats := &Stats{
 		EpisodicCount:  episodicCount,
@@ -215,26 +682,89 @@ func (m *MemoryService) GetStats(ctx context.Context) (*Stats, error) {
 		AvgRetrievalMs: m.stats.AvgRetrievalMs,
 		Uptime:         time.Since(m.startTime),
 	}
+	if fallback, ok := m.embedding.(*FallbackEmbedding); ok {
+		if err := fallback.Degraded(); err != nil {
+			stats.EmbeddingFallback = err.Error()
+		}
+	}
 
 	return stats, nil
 }
 
-// Close gracefully shuts down the memory service
+// ClearEpisodic removes all conversation history from episodic memory
+func (m *MemoryService) ClearEpisodic(ctx context.Context) (int64, error) {
+	if m.episodic == nil {
+		return 0, nil
+	}
+
+	removed, err := m.episodic.Clear(ctx)
+	if err != nil {
+		return removed, fmt.Errorf("failed to clear episodic memory: %w", err)
+	}
+	return removed, nil
+}
+
+// Pin keeps the episodic memory id forever and exempts it from compaction.
+// id may omit the "memory:episodic:" prefix, as ShortID does.
+func (m *MemoryService) Pin(ctx context.Context, id string) error {
+	if m.episodic == nil {
+		return fmt.Errorf("%w: episodic", ErrStoreUnavailable)
+	}
+	return m.episodic.Pin(ctx, episodicID(id))
+}
+
+// Unpin clears the pin on the episodic memory id, restarting its retention
+func (m *MemoryService) Unpin(ctx context.Context, id string) error {
+	if m.episodic == nil {
+		return fmt.Errorf("%w: episodic", ErrStoreUnavailable)
+	}
+	return m.episodic.Unpin(ctx, episodicID(id))
+}
+
+// episodicKeyPrefix starts the key of every episodic memory
+const episodicKeyPrefix = "memory:episodic:"
+
+// ShortID is a memory's ID without the episodic key prefix, for display
+func ShortID(id string) string {
+	return strings.TrimPrefix(id, episodicKeyPrefix)
+}
+
+// episodicID adds the episodic key prefix to a short ID
+func episodicID(id string) string {
+	if strings.HasPrefix(id, episodicKeyPrefix) {
+		return id
+	}
+	return episodicKeyPrefix + id
+}
+
+// Close gracefully shuts down the memory service. A compaction in progress is
+// cancelled and waited for, so it never runs against closed stores.
 func (m *MemoryService) Close() error {
-	close(m.stopCh)
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if m.compactionDone != nil {
+		<-m.compactionDone
+	}
 
 	var errs []error
 
-	if err := m.episodic.Close(); err != nil {
-		errs = append(errs, err)
+	if m.episodic != nil {
+		if err := m.episodic.Close(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	if err := m.semantic.Close(); err != nil {
-		errs = append(errs, err)
+	if m.semantic != nil {
+		if err := m.semantic.Close(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	if err := m.procedural.Close(); err != nil {
-		errs = append(errs, err)
+	if m.procedural != nil {
+		if err := m.procedural.Close(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	if len(errs) > 0 {
@@ -244,21 +774,35 @@ func (m *MemoryService) Close() error {
 	return nil
 }
 
-// runPeriodicCompaction runs compaction at configured intervals
+// startCompaction starts background compaction, which Close stops
+func (m *MemoryService) startCompaction() {
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.compactionDone = make(chan struct{})
+	go m.runPeriodicCompaction()
+}
+
+// runPeriodicCompaction runs compaction at configured intervals until the
+// service is closed
 func (m *MemoryService) runPeriodicCompaction() {
+	defer close(m.compactionDone)
+
 	ticker := time.NewTicker(m.config.CompactionInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-			if err := m.Compact(ctx); err != nil {
-				// Log error
-				_ = err
-			}
+			ctx, cancel := context.WithTimeout(m.ctx, 5*time.Minute)
+			err := m.Compact(ctx)
 			cancel()
-		case <-m.stopCh:
+			if m.ctx.Err() != nil {
+				m.logger.Info("memory compaction cancelled by shutdown")
+				return
+			}
+			if err != nil {
+				m.logger.Error("periodic memory compaction failed", "error", err)
+			}
+		case <-m.ctx.Done():
 			return
 		}
 	}
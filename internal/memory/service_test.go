@@ -0,0 +1,310 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/logging"
+	"github.com/quantumflow/quantumflow/internal/models"
+)
+
+// TestDegradedServiceNoOps tests that a service with no initialized stores skips them instead of erroring
+func TestDegradedServiceNoOps(t *testing.T) {
+	config := DefaultConfig()
+	service := &MemoryService{
+		embedding: NewSimpleEmbedding(config.EmbeddingDimensions),
+		compactor: NewMemoryCompactor(nil, nil, config),
+		config:    config,
+		logger:    logging.OrDiscard(nil),
+		stats:     &Stats{},
+		startTime: time.Now(),
+		status: []*StoreStatus{
+			newStoreStatus("episodic", "redis", context.Canceled),
+			newStoreStatus("semantic", "dgraph", context.DeadlineExceeded),
+		},
+	}
+
+	ctx := context.Background()
+
+	interaction := &models.Interaction{
+		ID:        "test-1",
+		UserQuery: "How do I deploy?",
+		ToolCalls: []models.ToolCall{{Name: "kubectl"}},
+		Timestamp: time.Now(),
+	}
+	if err := service.Store(ctx, interaction); err != nil {
+		t.Errorf("Expected Store to skip missing stores, got %v", err)
+	}
+
+	memories, err := service.Retrieve(ctx, "deploy", 5)
+	if err != nil {
+		t.Errorf("Expected Retrieve to skip missing stores, got %v", err)
+	}
+	if len(memories) != 0 {
+		t.Errorf("Expected no memories, got %d", len(memories))
+	}
+
+	if err := service.Compact(ctx); err != nil {
+		t.Errorf("Expected Compact to succeed, got %v", err)
+	}
+
+	if _, err := service.GetStats(ctx); err != nil {
+		t.Errorf("Expected GetStats to succeed, got %v", err)
+	}
+
+	if err := service.Close(); err != nil {
+		t.Errorf("Expected Close to succeed, got %v", err)
+	}
+
+	status := service.Status()
+	if len(status) != 2 || status[0].Available || status[1].Available {
+		t.Fatalf("Unexpected status: %+v", status)
+	}
+	if status[1].Error == "" {
+		t.Error("Expected degraded store to report its error")
+	}
+}
+
+// TestWorkflowPatternReuse tests suggesting proven patterns and crediting repeated workflows
+func TestWorkflowPatternReuse(t *testing.T) {
+	config := DefaultConfig()
+	config.BadgerPath = t.TempDir()
+	config.BadgerGCInterval = 0
+
+	procedural, err := NewBadgerProceduralStore(config)
+	if err != nil {
+		t.Fatalf("Failed to open procedural store: %v", err)
+	}
+	service := &MemoryService{procedural: procedural, config: config}
+	defer procedural.Close()
+
+	ctx := context.Background()
+
+	steps := []models.WorkflowStep{
+		{Action: "Scaffold API", Tool: "code"},
+		{Action: "Write Dockerfile", Tool: "infra"},
+	}
+	if err := service.RecordWorkflow(ctx, &models.WorkflowPattern{Name: "REST API service", Steps: steps, SuccessRate: 1.0}); err != nil {
+		t.Fatalf("Expected workflow to be recorded, got %v", err)
+	}
+	if err := service.RecordWorkflow(ctx, &models.WorkflowPattern{Name: "Unreliable api", Steps: []models.WorkflowStep{{Action: "Guess", Tool: "code"}}, SuccessRate: 0.2}); err != nil {
+		t.Fatalf("Expected workflow to be recorded, got %v", err)
+	}
+
+	patterns, err := service.SuggestPatterns(ctx, "Build a REST API for todos", 3)
+	if err != nil {
+		t.Fatalf("SuggestPatterns failed: %v", err)
+	}
+	if len(patterns) != 1 || patterns[0].Name != "REST API service" {
+		t.Fatalf("Expected only the high-success API pattern, got %+v", patterns)
+	}
+
+	// Recording the same steps again credits the existing pattern
+	repeat := &models.WorkflowPattern{Name: "Another API", Steps: steps, SuccessRate: 1.0}
+	if err := service.RecordWorkflow(ctx, repeat); err != nil {
+		t.Fatalf("Expected repeat workflow to be recorded, got %v", err)
+	}
+	if repeat.ID != patterns[0].ID {
+		t.Errorf("Expected repeat to match pattern %s, got %s", patterns[0].ID, repeat.ID)
+	}
+
+	// A workflow that only shares some steps is stored as its own pattern
+	extended := &models.WorkflowPattern{
+		Name:        "API with CI",
+		Steps:       append(append([]models.WorkflowStep{}, steps...), models.WorkflowStep{Action: "Add CI pipeline", Tool: "infra"}),
+		SuccessRate: 1.0,
+	}
+	if err := service.RecordWorkflow(ctx, extended); err != nil {
+		t.Fatalf("Expected extended workflow to be recorded, got %v", err)
+	}
+	if extended.ID == "" || extended.ID == patterns[0].ID {
+		t.Errorf("Expected extended workflow stored as a new pattern, got ID %q", extended.ID)
+	}
+
+	if err := service.RecordPatternUse(ctx, patterns[0].ID); err != nil {
+		t.Fatalf("RecordPatternUse failed: %v", err)
+	}
+	stored, err := procedural.GetPattern(ctx, patterns[0].ID)
+	if err != nil {
+		t.Fatalf("GetPattern failed: %v", err)
+	}
+	if stored.Frequency != 3 {
+		t.Errorf("Expected frequency 3, got %d", stored.Frequency)
+	}
+
+	if suggestions, err := (&MemoryService{}).SuggestPatterns(ctx, "REST API", 3); err != nil || suggestions != nil {
+		t.Errorf("Expected no suggestions without a procedural store, got %v, %v", suggestions, err)
+	}
+}
+
+// blockingCompactor runs until its context is cancelled
+type blockingCompactor struct {
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (c *blockingCompactor) Compact(ctx context.Context) (*CompactionResult, error) {
+	close(c.started)
+	<-ctx.Done()
+	time.Sleep(20 * time.Millisecond) // Cleanup that Close must wait for
+	close(c.stopped)
+	return nil, ctx.Err()
+}
+
+func (c *blockingCompactor) Deduplicate(ctx context.Context) (int, error) { return 0, nil }
+func (c *blockingCompactor) Archive(ctx context.Context, olderThan time.Duration) (int, error) {
+	return 0, nil
+}
+
+// TestCloseCancelsCompaction tests that Close cancels a running compaction
+// and returns only after it has stopped
+func TestCloseCancelsCompaction(t *testing.T) {
+	config := DefaultConfig()
+	config.CompactionInterval = time.Millisecond
+	compactor := &blockingCompactor{started: make(chan struct{}), stopped: make(chan struct{})}
+	service := &MemoryService{
+		compactor: compactor,
+		config:    config,
+		logger:    logging.OrDiscard(nil),
+		stats:     &Stats{},
+	}
+	service.startCompaction()
+
+	select {
+	case <-compactor.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Compaction never started")
+	}
+
+	if err := service.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	select {
+	case <-compactor.stopped:
+	default:
+		t.Error("Expected Close to wait for the compaction to stop")
+	}
+}
+
+// TestStoreRetrieveNoOpExtractor tests storing an interaction and retrieving
+// its entities end to end, using the model-free extractor
+func TestStoreRetrieveNoOpExtractor(t *testing.T) {
+	config := DefaultConfig()
+	config.Extractor = ExtractorNoOp
+	extractor, err := NewExtractor(config.Extractor, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	semantic := &recordingSemantic{}
+	service := &MemoryService{semantic: semantic, extractor: extractor, config: config, logger: logging.OrDiscard(nil), stats: &Stats{}}
+	ctx := context.Background()
+
+	interaction := &models.Interaction{
+		ID:            "noop-1",
+		UserQuery:     "How do I deploy to Kubernetes?",
+		AgentResponse: "Use Helm charts with kubectl.",
+	}
+	if err := service.Store(ctx, interaction); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if len(semantic.entities) != 4 || semantic.entities[1].Name != "Kubernetes" || semantic.entities[3].Name != "Helm" {
+		t.Fatalf("Expected the capitalized words stored as entities, got %+v", semantic.entities)
+	}
+
+	memories, err := service.Retrieve(ctx, "kubernetes rollout", 5)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(memories) != 1 || memories[0].ID != "entity:noop:kubernetes" || memories[0].Type != models.MemoryTypeSemantic {
+		t.Errorf("Expected the Kubernetes entity retrieved, got %+v", memories)
+	}
+
+	if _, err := NewExtractor("gpt", nil); !errors.Is(err, ErrUnknownExtractor) {
+		t.Errorf("Expected an unknown extractor to be rejected, got %v", err)
+	}
+}
+
+// TestInterleave tests that truncating retrieval results keeps both kinds
+func TestInterleave(t *testing.T) {
+	memories := func(prefix string, n int) []*models.Memory {
+		var out []*models.Memory
+		for i := 0; i < n; i++ {
+			out = append(out, &models.Memory{ID: fmt.Sprintf("%s%d", prefix, i)})
+		}
+		return out
+	}
+	ids := func(memories []*models.Memory) string {
+		var out []string
+		for _, memory := range memories {
+			out = append(out, memory.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := ids(interleave(memories("e", 5), memories("s", 2), 5)); got != "e0,s0,e1,s1,e2" {
+		t.Errorf("Unexpected order %s", got)
+	}
+	if got := ids(interleave(nil, memories("s", 3), 2)); got != "s0,s1" {
+		t.Errorf("Expected semantic results alone, got %s", got)
+	}
+	if got := interleave(memories("e", 2), nil, 0); len(got) != 0 {
+		t.Errorf("Expected nothing for k=0, got %d", len(got))
+	}
+}
+
+// TestStoreKeysEpisodicUnderPrefix tests that interactions are stored under
+// the episodic key prefix, where search, clear and pin look for them
+func TestStoreKeysEpisodicUnderPrefix(t *testing.T) {
+	config := DefaultConfig()
+	episodic := newFakeEpisodic()
+	service := &MemoryService{
+		episodic:  episodic,
+		embedding: NewSimpleEmbedding(config.EmbeddingDimensions),
+		config:    config,
+		logger:    logging.OrDiscard(nil),
+		stats:     &Stats{},
+	}
+	ctx := context.Background()
+
+	interaction := &models.Interaction{ID: "req-1", UserQuery: "How do I deploy?", AgentResponse: "Use Helm."}
+	if err := service.Store(ctx, interaction); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, ok := episodic.memories[episodicKeyPrefix+"req-1"]; !ok || len(episodic.memories) != 1 {
+		t.Fatalf("Expected the interaction under %sreq-1, got %v", episodicKeyPrefix, episodic.memories)
+	}
+	if err := service.Pin(ctx, "req-1"); err != nil {
+		t.Errorf("Expected the interaction to be pinned by its short ID, got %v", err)
+	}
+}
+
+// TestClearRemovesStoredInteractions tests that /memory clear removes the
+// interactions Store saved
+func TestClearRemovesStoredInteractions(t *testing.T) {
+	config := DefaultConfig()
+	episodic := newFakeEpisodic()
+	service := &MemoryService{
+		episodic:  episodic,
+		embedding: NewSimpleEmbedding(config.EmbeddingDimensions),
+		config:    config,
+		logger:    logging.OrDiscard(nil),
+		stats:     &Stats{},
+	}
+	ctx := context.Background()
+
+	interaction := &models.Interaction{ID: "req-2", UserQuery: "Rotate the keys", AgentResponse: "Done."}
+	if err := service.Store(ctx, interaction); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	removed, err := service.ClearEpisodic(ctx)
+	if err != nil {
+		t.Fatalf("ClearEpisodic failed: %v", err)
+	}
+	if removed != 1 || len(episodic.memories) != 0 {
+		t.Errorf("Expected the stored interaction cleared, removed %d, left %v", removed, episodic.memories)
+	}
+}
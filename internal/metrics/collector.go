@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+	"github.com/quantumflow/quantumflow/internal/memory"
+)
+
+// namespace prefixes every exported metric name
+const namespace = "quantumflow"
+
+// latencyBuckets are the histogram buckets, in seconds, for agent and model calls
+var latencyBuckets = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300}
+
+// statsTimeout bounds how long a scrape waits for memory statistics
+const statsTimeout = 5 * time.Second
+
+// PoolSource is the part of an inference pool the collector reads at scrape time
+type PoolSource interface {
+	GetMetrics() inference.PoolMetrics
+	QueueLength() int
+}
+
+// Config holds the sources a Collector reports on; nil sources are skipped.
+// Programs that call an inference client directly rather than through a Pool
+// report those calls with Collector.Interceptor instead.
+type Config struct {
+	Pool   PoolSource     // Inference pool counters, queue length and in-flight requests
+	Memory memory.Service // Memory store sizes and retrieval performance
+}
+
+// Collector exports QuantumFlow metrics to Prometheus. It implements
+// prometheus.Collector and agent.RequestObserver, so the same value is
+// registered with a registry and set as the orchestrator's observer, and
+// its Interceptor records the model calls an inference client makes.
+type Collector struct {
+	pool   PoolSource
+	memory memory.Service
+
+	agentRequests *prometheus.CounterVec
+	agentLatency  *prometheus.HistogramVec
+	agentInflight *prometheus.GaugeVec
+
+	inferenceRequests *prometheus.CounterVec
+	inferenceLatency  *prometheus.HistogramVec
+
+	poolRequests *prometheus.Desc
+	poolLatency  *prometheus.Desc
+	poolQueue    *prometheus.Desc
+	poolInflight *prometheus.Desc
+
+	memoryItems     *prometheus.Desc
+	memorySize      *prometheus.Desc
+	memoryRetrieval *prometheus.Desc
+	memoryCacheHits *prometheus.Desc
+	memoryUptime    *prometheus.Desc
+}
+
+// NewCollector creates a collector for the configured sources
+func NewCollector(config Config) *Collector {
+	return &Collector{
+		pool:   config.Pool,
+		memory: config.Memory,
+
+		agentRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "agent_requests_total",
+			Help:      "Agent executions by agent and outcome.",
+		}, []string{"agent", "status"}),
+		agentLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "agent_request_duration_seconds",
+			Help:      "Agent request latency.",
+			Buckets:   latencyBuckets,
+		}, []string{"agent"}),
+		agentInflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "agent_inflight_requests",
+			Help:      "Agent requests currently executing.",
+		}, []string{"agent"}),
+
+		inferenceRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "inference_requests_total",
+			Help:      "Model generation calls by model and outcome.",
+		}, []string{"model", "status"}),
+		inferenceLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "inference_request_duration_seconds",
+			Help:      "Model generation latency, to the end of the stream for streamed calls.",
+			Buckets:   latencyBuckets,
+		}, []string{"model"}),
+
+		poolRequests: prometheus.NewDesc(namespace+"_pool_requests_total",
+			"Inference pool requests by outcome.", []string{"status"}, nil),
+		poolLatency: prometheus.NewDesc(namespace+"_pool_average_latency_seconds",
+			"Average latency of completed inference pool requests.", nil, nil),
+		poolQueue: prometheus.NewDesc(namespace+"_pool_queue_length",
+			"Requests waiting in the inference pool queue.", nil, nil),
+		poolInflight: prometheus.NewDesc(namespace+"_pool_inflight_requests",
+			"Inference pool requests currently executing.", nil, nil),
+
+		memoryItems: prometheus.NewDesc(namespace+"_memory_items",
+			"Items held by each memory store.", []string{"store"}, nil),
+		memorySize: prometheus.NewDesc(namespace+"_memory_size_bytes",
+			"Total size of stored memories.", nil, nil),
+		memoryRetrieval: prometheus.NewDesc(namespace+"_memory_retrieval_average_seconds",
+			"Average memory retrieval latency.", nil, nil),
+		memoryCacheHits: prometheus.NewDesc(namespace+"_memory_cache_hit_ratio",
+			"Fraction of memory retrievals served from cache.", nil, nil),
+		memoryUptime: prometheus.NewDesc(namespace+"_memory_uptime_seconds",
+			"Time since the memory service started.", nil, nil),
+	}
+}
+
+// AgentStarted marks an agent request as in flight
+func (c *Collector) AgentStarted(agent string) {
+	c.agentInflight.WithLabelValues(agent).Inc()
+}
+
+// AgentFinished records the outcome and latency of an agent request
+func (c *Collector) AgentFinished(agent string, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+
+	c.agentInflight.WithLabelValues(agent).Dec()
+	c.agentRequests.WithLabelValues(agent, status).Inc()
+	c.agentLatency.WithLabelValues(agent).Observe(duration.Seconds())
+}
+
+// Interceptor returns an inference interceptor that counts generation calls
+// by the model each request names, so switching models is reflected
+func (c *Collector) Interceptor() inference.Interceptor {
+	return func(next inference.GenerateFunc) inference.GenerateFunc {
+		return func(ctx context.Context, req inference.GenerateRequest) (*inference.Generation, error) {
+			start := time.Now()
+			gen, err := next(ctx, req)
+			if err != nil {
+				c.inferenceFinished(req.Model, start, "error")
+				return nil, err
+			}
+
+			if gen.Stream != nil {
+				gen.Stream = inference.TapStream(ctx, gen.Stream, func(string) {
+					c.inferenceFinished(req.Model, start, "ok")
+				})
+			} else {
+				c.inferenceFinished(req.Model, start, "ok")
+			}
+			return gen, nil
+		}
+	}
+}
+
+// inferenceFinished records the outcome and latency of a generation call
+func (c *Collector) inferenceFinished(model string, start time.Time, status string) {
+	c.inferenceRequests.WithLabelValues(model, status).Inc()
+	c.inferenceLatency.WithLabelValues(model).Observe(time.Since(start).Seconds())
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.agentRequests.Describe(ch)
+	c.agentLatency.Describe(ch)
+	c.agentInflight.Describe(ch)
+	c.inferenceRequests.Describe(ch)
+	c.inferenceLatency.Describe(ch)
+
+	ch <- c.poolRequests
+	ch <- c.poolLatency
+	ch <- c.poolQueue
+	ch <- c.poolInflight
+
+	ch <- c.memoryItems
+	ch <- c.memorySize
+	ch <- c.memoryRetrieval
+	ch <- c.memoryCacheHits
+	ch <- c.memoryUptime
+}
+
+// Collect implements prometheus.Collector, reading pool and memory
+// statistics at scrape time
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.agentRequests.Collect(ch)
+	c.agentLatency.Collect(ch)
+	c.agentInflight.Collect(ch)
+	c.inferenceRequests.Collect(ch)
+	c.inferenceLatency.Collect(ch)
+
+	if c.pool != nil {
+		c.collectPool(ch)
+	}
+	if c.memory != nil {
+		c.collectMemory(ch)
+	}
+}
+
+// collectPool reports the inference pool's counters and current load
+func (c *Collector) collectPool(ch chan<- prometheus.Metric) {
+	m := c.pool.GetMetrics()
+
+	ch <- prometheus.MustNewConstMetric(c.poolRequests, prometheus.CounterValue, float64(m.CompletedOK), "ok")
+	ch <- prometheus.MustNewConstMetric(c.poolRequests, prometheus.CounterValue, float64(m.CompletedError), "error")
+	ch <- prometheus.MustNewConstMetric(c.poolLatency, prometheus.GaugeValue, m.AverageLatency.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.poolQueue, prometheus.GaugeValue, float64(c.pool.QueueLength()))
+	ch <- prometheus.MustNewConstMetric(c.poolInflight, prometheus.GaugeValue, float64(m.CurrentInflight))
+}
+
+// collectMemory reports memory store statistics; a failed lookup skips them
+// for this scrape rather than failing the whole scrape
+func (c *Collector) collectMemory(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	stats, err := c.memory.GetStats(ctx)
+	if err != nil || stats == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.memoryItems, prometheus.GaugeValue, float64(stats.EpisodicCount), "episodic")
+	ch <- prometheus.MustNewConstMetric(c.memoryItems, prometheus.GaugeValue, float64(stats.SemanticCount), "semantic")
+	ch <- prometheus.MustNewConstMetric(c.memoryItems, prometheus.GaugeValue, float64(stats.ProceduralCount), "procedural")
+	ch <- prometheus.MustNewConstMetric(c.memorySize, prometheus.GaugeValue, float64(stats.TotalSize))
+	ch <- prometheus.MustNewConstMetric(c.memoryRetrieval, prometheus.GaugeValue, stats.AvgRetrievalMs/1000)
+	ch <- prometheus.MustNewConstMetric(c.memoryCacheHits, prometheus.GaugeValue, stats.CacheHitRate)
+	ch <- prometheus.MustNewConstMetric(c.memoryUptime, prometheus.GaugeValue, stats.Uptime.Seconds())
+}
+
+// Handler returns an http.Handler serving the collector's metrics, plus Go
+// runtime and process metrics, in the Prometheus exposition format
+func Handler(c *Collector) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		c,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quantumflow/quantumflow/internal/inference"
+)
+
+// fakePool reports fixed inference pool metrics
+type fakePool struct{}
+
+func (fakePool) GetMetrics() inference.PoolMetrics {
+	return inference.PoolMetrics{CompletedOK: 7, CompletedError: 2, AverageLatency: 1500 * time.Millisecond, CurrentInflight: 3}
+}
+
+func (fakePool) QueueLength() int { return 4 }
+
+// TestCollectorHandler tests that agent observations, model calls and pool
+// metrics are exposed
+func TestCollectorHandler(t *testing.T) {
+	collector := NewCollector(Config{Pool: fakePool{}})
+
+	collector.AgentStarted("CodeAgent")
+	collector.AgentFinished("CodeAgent", 2*time.Second, nil)
+	collector.AgentStarted("DataAgent")
+	collector.AgentFinished("DataAgent", time.Second, errors.New("timeout"))
+	collector.AgentStarted("InfraAgent")
+
+	ctx := context.Background()
+	generate := collector.Interceptor()(func(ctx context.Context, req inference.GenerateRequest) (*inference.Generation, error) {
+		if req.Prompt == "fail" {
+			return nil, errors.New("unavailable")
+		}
+		if req.Stream {
+			stream := make(chan string, 1)
+			stream <- "hi"
+			close(stream)
+			return &inference.Generation{Stream: stream}, nil
+		}
+		return &inference.Generation{Result: &inference.InferenceResult{Response: "hi"}}, nil
+	})
+	generate(ctx, inference.GenerateRequest{Model: "qwen2.5-coder:7b", Prompt: "hello"})
+	generate(ctx, inference.GenerateRequest{Model: "llama3", Prompt: "fail"})
+	gen, _ := generate(ctx, inference.GenerateRequest{Model: "llama3", Prompt: "hello", Stream: true})
+	for range gen.Stream {
+	}
+
+	recorder := httptest.NewRecorder()
+	Handler(collector).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+	output := string(body)
+
+	expected := []string{
+		`quantumflow_agent_requests_total{agent="CodeAgent",status="ok"} 1`,
+		`quantumflow_agent_requests_total{agent="DataAgent",status="error"} 1`,
+		`quantumflow_agent_request_duration_seconds_count{agent="CodeAgent"} 1`,
+		`quantumflow_agent_inflight_requests{agent="CodeAgent"} 0`,
+		`quantumflow_agent_inflight_requests{agent="InfraAgent"} 1`,
+		`quantumflow_inference_requests_total{model="qwen2.5-coder:7b",status="ok"} 1`,
+		`quantumflow_inference_requests_total{model="llama3",status="error"} 1`,
+		`quantumflow_inference_requests_total{model="llama3",status="ok"} 1`,
+		`quantumflow_inference_request_duration_seconds_count{model="llama3"} 2`,
+		`quantumflow_pool_requests_total{status="ok"} 7`,
+		`quantumflow_pool_requests_total{status="error"} 2`,
+		`quantumflow_pool_average_latency_seconds 1.5`,
+		`quantumflow_pool_queue_length 4`,
+		`quantumflow_pool_inflight_requests 3`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("Expected metrics output to contain %q", line)
+		}
+	}
+
+	if strings.Contains(output, "quantumflow_memory_") {
+		t.Error("Expected no memory metrics without a memory service")
+	}
+}
@@ -18,7 +18,9 @@ type Memory struct {
 	Embedding []float32              `json:"embedding"` // 768-dim vector
 	Metadata  map[string]interface{} `json:"metadata"`
 	Timestamp time.Time              `json:"timestamp"`
-	Score     float64                `json:"score"` // Relevance score
+	Score     float64                `json:"score"`            // Relevance score
+	TTL       time.Duration          `json:"ttl,omitempty"`    // Overrides the store's retention; negative never expires
+	Pinned    bool                   `json:"pinned,omitempty"` // Never expires and is exempt from compaction
 }
 
 // MemoryType defines the type of memory
@@ -67,6 +69,7 @@ type WorkflowPattern struct {
 	Frequency   int            `json:"frequency"`
 	SuccessRate float64        `json:"success_rate"`
 	LastUsed    time.Time      `json:"last_used"`
+	Embedding   []float32      `json:"embedding,omitempty"` // Embedding of the step descriptions
 }
 
 // WorkflowStep represents a single step in a workflow